@@ -13,9 +13,12 @@ type Point struct {
 }
 
 type Grid struct {
-	Width     int
-	Height    int
-	obstacles []bool // y*Width + x
+	Width  int
+	Height int
+	// NoCornerCutting이면 대각선 이동은 양옆 직교 셀이 모두 통과 가능할 때만 허용한다.
+	// 크기가 있는 차체가 장애물 모서리를 스치며 지나가는 경로를 막는다.
+	NoCornerCutting bool
+	obstacles       []bool // y*Width + x
 }
 
 func NewGrid(width, height int) *Grid {
@@ -142,6 +145,9 @@ func (g *Grid) FindPath(start, goal Point) []Point {
 
 			step := 1.0
 			if d[0] != 0 && d[1] != 0 {
+				if g.NoCornerCutting && (!g.IsValid(cur.x+d[0], cur.y) || !g.IsValid(cur.x, cur.y+d[1])) {
+					continue
+				}
 				step = math.Sqrt2
 			}
 			tentativeG := cur.g + step
@@ -112,6 +112,27 @@ func TestFindPath_WallAvoidance(t *testing.T) {
 	}
 }
 
+func TestFindPath_NoCornerCutting(t *testing.T) {
+	g := NewGrid(3, 3)
+	// (1,0)만 막혀 있으면 기본 설정은 (0,0)→(1,1)로 모서리를 스쳐 지나간다.
+	g.AddObstacle(1, 0)
+	if path := g.FindPath(pt(0, 0), pt(2, 1)); len(path) != 3 {
+		t.Fatalf("모서리 통과 허용 시 3칸 기대, got %v", pathCoords(path))
+	}
+
+	g.NoCornerCutting = true
+	path := g.FindPath(pt(0, 0), pt(2, 1))
+	if len(path) != 4 {
+		t.Fatalf("모서리 통과 금지 시 4칸 기대, got %v", pathCoords(path))
+	}
+	for i := 1; i < len(path); i++ {
+		dx, dy := int(path[i].X-path[i-1].X), int(path[i].Y-path[i-1].Y)
+		if dx != 0 && dy != 0 && (g.IsObstacle(int(path[i-1].X)+dx, int(path[i-1].Y)) || g.IsObstacle(int(path[i-1].X), int(path[i-1].Y)+dy)) {
+			t.Fatalf("장애물 모서리를 대각선으로 스침: %v", pathCoords(path))
+		}
+	}
+}
+
 func TestFindPath_Unreachable(t *testing.T) {
 	g := NewGrid(5, 5)
 	// 목표(4,4)를 ㄴ자 벽으로 봉쇄
@@ -0,0 +1,208 @@
+// simbatch는 웹 서버·DB 없이 시뮬레이터 에피소드를 병렬로 돌려 전략을 비교하는 배치 실행기다.
+// 정책 × stickiness × AGV 수 × seed 조합마다 에피소드를 하나씩 돌리고,
+// 에피소드별 결과(episodes.csv)와 설정별 평균(summary.csv, summary.json)을 -out 디렉터리에 쓴다.
+//
+//	go run ./cmd/simbatch -seeds 200 -policies lowest_hp,nearest,weighted -stickiness 0,0.2
+//	go run ./cmd/simbatch -scenario scenarios/chase.json -seeds 50 -out results/chase
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"sion-backend/models"
+	"sion-backend/services"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+func main() {
+	seeds := flag.Int("seeds", 20, "설정 조합마다 돌릴 seed 수")
+	seedBase := flag.Int64("seed-base", 1, "첫 seed (seed-base, seed-base+1, ...)")
+	policies := flag.String("policies", strings.Join([]string{
+		models.TargetPolicyLowestHP, models.TargetPolicyNearest, models.TargetPolicyWeighted, models.TargetPolicyHighestThreat,
+	}, ","), "비교할 타겟 정책 (쉼표 구분)")
+	stickiness := flag.String("stickiness", "0", "비교할 stickiness 값 (쉼표 구분)")
+	agvs := flag.String("agvs", "1", "비교할 AGV 수 (쉼표 구분, 시나리오를 쓰면 무시)")
+	scenarioPath := flag.String("scenario", "", "시나리오 JSON 파일 (지정하면 seed만 바꿔 같은 월드를 반복)")
+	maxTicks := flag.Int("max-ticks", 1200, "에피소드 최대 틱 수 (넘기면 timeout)")
+	workers := flag.Int("workers", runtime.NumCPU(), "병렬 실행 수")
+	outDir := flag.String("out", "simbatch-results", "결과 파일 디렉터리")
+	verbose := flag.Bool("v", false, "시뮬레이터 로그 출력")
+	flag.Parse()
+
+	if !*verbose {
+		// 에피소드마다 수백 줄씩 나오는 시뮬레이터 로그를 끈다. 진행 상황은 표준 에러로 따로 찍는다.
+		log.SetOutput(io.Discard)
+	}
+	fail := func(format string, args ...any) {
+		fmt.Fprintf(os.Stderr, "[FATAL] "+format+"\n", args...)
+		os.Exit(1)
+	}
+
+	var scenario *models.Scenario
+	if *scenarioPath != "" {
+		raw, err := os.ReadFile(*scenarioPath)
+		if err != nil {
+			fail("시나리오 읽기 실패: %v", err)
+		}
+		scenario = &models.Scenario{}
+		if err := json.Unmarshal(raw, scenario); err != nil {
+			fail("시나리오 파싱 실패: %v", err)
+		}
+	}
+	stickValues, err := parseFloats(*stickiness)
+	if err != nil {
+		fail("-stickiness: %v", err)
+	}
+	agvCounts, err := parseInts(*agvs)
+	if err != nil {
+		fail("-agvs: %v", err)
+	}
+	if scenario != nil {
+		agvCounts = []int{0}
+	}
+
+	var cfgs []services.EpisodeConfig
+	for _, policy := range strings.Split(*policies, ",") {
+		for _, stick := range stickValues {
+			targeting := services.DefaultTargetingConfig()
+			targeting.Policy = strings.TrimSpace(policy)
+			targeting.Stickiness = stick
+			if _, err := services.NewTargetSelector(targeting); err != nil {
+				fail("정책 %q: %v", policy, err)
+			}
+			for _, n := range agvCounts {
+				for i := 0; i < *seeds; i++ {
+					cfg := services.EpisodeConfig{
+						Seed:      *seedBase + int64(i),
+						AGVCount:  n,
+						Targeting: targeting,
+						MaxTicks:  *maxTicks,
+					}
+					if scenario != nil {
+						cfg.Scenario = scenario
+					}
+					cfgs = append(cfgs, cfg)
+				}
+			}
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	fmt.Fprintf(os.Stderr, "에피소드 %d개, 병렬 %d\n", len(cfgs), *workers)
+	results := services.RunBatch(ctx, cfgs, *workers, func(done, total int) {
+		if done%50 == 0 || done == total {
+			fmt.Fprintf(os.Stderr, "\r진행 %d/%d", done, total)
+		}
+	})
+	fmt.Fprintln(os.Stderr)
+
+	summaries := services.SummarizeBatch(results)
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		fail("결과 디렉터리 생성 실패: %v", err)
+	}
+	if err := writeEpisodesCSV(filepath.Join(*outDir, "episodes.csv"), results); err != nil {
+		fail("episodes.csv 쓰기 실패: %v", err)
+	}
+	if err := writeSummaryCSV(filepath.Join(*outDir, "summary.csv"), summaries); err != nil {
+		fail("summary.csv 쓰기 실패: %v", err)
+	}
+	if err := writeJSON(filepath.Join(*outDir, "summary.json"), summaries); err != nil {
+		fail("summary.json 쓰기 실패: %v", err)
+	}
+
+	for _, s := range summaries {
+		fmt.Println(s)
+	}
+	fmt.Fprintf(os.Stderr, "결과: %s\n", *outDir)
+}
+
+func parseFloats(spec string) ([]float64, error) {
+	var out []float64
+	for _, f := range strings.Split(spec, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil {
+			return nil, fmt.Errorf("숫자 오류: %q", f)
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func parseInts(spec string) ([]int, error) {
+	var out []int
+	for _, f := range strings.Split(spec, ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || v < 1 {
+			return nil, fmt.Errorf("양의 정수가 아님: %q", f)
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func writeEpisodesCSV(path string, results []services.EpisodeResult) error {
+	rows := [][]string{{
+		"seed", "scenario", "agv_count", "policy", "stickiness", "outcome", "ticks",
+		"time_to_clear_sec", "enemies", "kills", "battery_used", "collisions", "distance", "error",
+	}}
+	for _, r := range results {
+		rows = append(rows, []string{
+			strconv.FormatInt(r.Seed, 10), r.Scenario, strconv.Itoa(r.AGVCount), r.Policy, ftoa(r.Stickiness),
+			r.Outcome, strconv.Itoa(r.Ticks), ftoa(r.TimeToClearSec), strconv.Itoa(r.Enemies), strconv.Itoa(r.Kills),
+			strconv.Itoa(r.BatteryUsed), strconv.Itoa(r.Collisions), ftoa(r.Distance), r.Error,
+		})
+	}
+	return writeCSV(path, rows)
+}
+
+func writeSummaryCSV(path string, summaries []services.BatchSummary) error {
+	rows := [][]string{{
+		"scenario", "agv_count", "policy", "stickiness", "episodes", "errors", "clear_rate",
+		"mean_time_to_clear_sec", "mean_kills", "mean_battery_used", "mean_collisions", "mean_distance",
+	}}
+	for _, s := range summaries {
+		rows = append(rows, []string{
+			s.Scenario, strconv.Itoa(s.AGVCount), s.Policy, ftoa(s.Stickiness), strconv.Itoa(s.Episodes), strconv.Itoa(s.Errors),
+			ftoa(s.ClearRate), ftoa(s.MeanTimeToClearSec), ftoa(s.MeanKills), ftoa(s.MeanBatteryUsed), ftoa(s.MeanCollisions), ftoa(s.MeanDistance),
+		})
+	}
+	return writeCSV(path, rows)
+}
+
+func writeCSV(path string, rows [][]string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	if err := w.WriteAll(rows); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeJSON(path string, v any) error {
+	raw, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, raw, 0o644)
+}
+
+func ftoa(v float64) string {
+	return strconv.FormatFloat(v, 'f', 3, 64)
+}
@@ -0,0 +1,65 @@
+// virtualagv는 시뮬레이터를 실제 AGV처럼 원격 서버의 /websocket/agv에 접속시키는 독립 실행 파일이다.
+// 서버와 다른 머신에서 돌려 AGV 프로토콜을 종단 간으로 검증할 때 쓴다.
+//
+//	go run ./cmd/virtualagv -url ws://server:8001/websocket/agv -seed 42
+//	go run ./cmd/virtualagv -scenario scenarios/duel.json
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"sion-backend/models"
+	"sion-backend/services"
+	"syscall"
+	"time"
+)
+
+func main() {
+	url := flag.String("url", "ws://localhost:8001/websocket/agv", "AGV WebSocket 엔드포인트")
+	seed := flag.Int64("seed", 0, "월드 seed (0이면 현재 시각)")
+	agvs := flag.Int("agvs", 1, "기본 월드의 AGV 수")
+	scenarioPath := flag.String("scenario", "", "시나리오 JSON 파일 (지정하면 seed/agvs 무시)")
+	interval := flag.Duration("interval", 500*time.Millisecond, "시뮬레이션 틱 간격")
+	flag.Parse()
+
+	sim := services.NewAGVSimulator(nil)
+	sim.UpdateInterval = *interval
+
+	if *scenarioPath != "" {
+		raw, err := os.ReadFile(*scenarioPath)
+		if err != nil {
+			log.Fatalf("[FATAL] 시나리오 읽기 실패: %v", err)
+		}
+		var sc models.Scenario
+		if err := json.Unmarshal(raw, &sc); err != nil {
+			log.Fatalf("[FATAL] 시나리오 파싱 실패: %v", err)
+		}
+		if err := sim.LoadScenario(sc); err != nil {
+			log.Fatalf("[FATAL] 시나리오 로드 실패: %v", err)
+		}
+	} else {
+		if err := sim.SetAGVCount(*agvs); err != nil {
+			log.Fatalf("[FATAL] %v", err)
+		}
+		if *seed != 0 {
+			if err := sim.Reseed(*seed); err != nil {
+				log.Fatalf("[FATAL] %v", err)
+			}
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	vagv := services.NewVirtualAGV(*url, sim)
+	sim.Start()
+	defer sim.Stop()
+	log.Printf("[INFO] 가상 AGV 시작 (seed=%d) → %s", sim.Seed(), *url)
+
+	vagv.Run(ctx)
+	log.Println("[INFO] 가상 AGV 종료")
+}
@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"sion-backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// NewAGVListHandler는 핸드셰이크를 마치고 연결된 AGV 목록과 AGV별 마지막 status를 반환한다.
+// statuses에는 연결이 끊긴 AGV의 마지막 보고도 남아 있다.
+func NewAGVListHandler(broker *services.Broker) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"success":  true,
+			"agvs":     broker.AGVConnections(),
+			"statuses": broker.AGVStatuses(),
+		})
+	}
+}
@@ -85,6 +85,7 @@ func ExplainAGVEvent(eventType string, agvStatus *models.AGVStatus) {
 		if broker == nil {
 			return
 		}
+		var agvID string
 		eventData := models.AGVEventData{
 			EventType:   eventType,
 			Explanation: explanation,
@@ -92,11 +93,14 @@ func ExplainAGVEvent(eventType string, agvStatus *models.AGVStatus) {
 		}
 		if agvStatus != nil {
 			eventData.Position = agvStatus.Position
+			agvID = agvStatus.ID
 		}
+		services.LogAIExplanation(agvID, eventType, explanation)
 		broker.BroadcastToWeb(models.WebSocketMessage{
 			Type:      models.MessageTypeAGVEvent,
 			Data:      eventData,
 			Timestamp: time.Now().UnixMilli(),
+			AGVID:     agvID,
 		})
 	}()
 }
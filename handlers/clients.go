@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"sion-backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// NewClientQueueStatsHandler는 WebSocket 연결별 송신 큐 깊이·버린 메시지 수를 반환한다.
+// 큐가 자주 차는 연결을 찾는 데 쓴다.
+func NewClientQueueStatsHandler(cm *services.ClientManager) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"success": true,
+			"clients": cm.QueueStats(),
+		})
+	}
+}
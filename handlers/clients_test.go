@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"net"
+	"sion-backend/models"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// 읽지 않는 웹 클라이언트는 느린 소비자로 끊기고, 그동안 브로드캐스트는 막히지 않으며 다른 클라이언트는 계속 받는다.
+func TestWS_SlowWebClientDisconnected(t *testing.T) {
+	srv := newWSTestServer(t)
+	srv.cm.QueueSize = 4
+	srv.cm.SlowConsumerTimeout = 100 * time.Millisecond
+	srv.cm.WriteTimeout = 300 * time.Millisecond
+
+	slow := srv.dial(t, "/websocket/web")
+	readUntilType(t, slow, models.MessageTypeSystemInfo, time.Second)
+	fast := srv.dial(t, "/websocket/web")
+	readUntilType(t, fast, models.MessageTypeSystemInfo, time.Second)
+
+	var received atomic.Int64
+	go func() {
+		for {
+			if _, _, err := fast.ReadMessage(); err != nil {
+				return
+			}
+			received.Add(1)
+		}
+	}()
+
+	payload := strings.Repeat("x", 64*1024)
+	deadline := time.Now().Add(5 * time.Second)
+	for srv.cm.GetClientCount()["web"] > 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("느린 클라이언트가 끊기지 않음: %+v", srv.cm.QueueStats())
+		}
+		start := time.Now()
+		srv.broker.BroadcastToWeb(models.WebSocketMessage{Type: models.MessageTypeLog, Data: payload})
+		if d := time.Since(start); d > 50*time.Millisecond {
+			t.Fatalf("브로드캐스트가 느린 클라이언트를 기다리면 안 됨: %v", d)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	n := received.Load()
+	srv.broker.BroadcastToWeb(models.WebSocketMessage{Type: models.MessageTypeLog, Data: "after"})
+	waitFor(t, time.Second, func() bool { return received.Load() > n }, "남은 클라이언트는 계속 받아야 함")
+
+	// 소켓 버퍼가 차 있어 close 프레임은 못 갈 수 있다. 서버가 소켓을 닫았는지만 본다.
+	_ = slow.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if _, _, err := slow.ReadMessage(); err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				t.Fatalf("서버가 느린 클라이언트 소켓을 닫아야 함: %v", err)
+			}
+			break
+		}
+	}
+}
+
+// /api/clients는 연결별 송신 큐 지표를 보여 준다.
+func TestClientQueueStatsEndpoint(t *testing.T) {
+	srv := newWSTestServer(t)
+	web := srv.dial(t, "/websocket/web")
+	readUntilType(t, web, models.MessageTypeSystemInfo, time.Second)
+	srv.dialAGV(t, "sion-001")
+	waitFor(t, time.Second, srv.broker.IsAGVConnected, "AGV connected wait")
+
+	app := fiber.New()
+	app.Get("/api/clients", NewClientQueueStatsHandler(srv.cm))
+	code, body := doGet(t, app, "/api/clients")
+	if code != fiber.StatusOK {
+		t.Fatalf("200 기대, got %d", code)
+	}
+	clients, _ := body["clients"].([]any)
+	if len(clients) != 2 {
+		t.Fatalf("연결 두 개 기대: %+v", body)
+	}
+	agv := clients[0].(map[string]any)
+	web0 := clients[1].(map[string]any)
+	if agv["type"] != "agv" || web0["type"] != "web" {
+		t.Fatalf("종류 순 정렬 기대: %+v", clients)
+	}
+	if ids, _ := agv["agv_ids"].([]any); len(ids) != 1 || ids[0] != "sion-001" {
+		t.Fatalf("AGV ID가 실려야 함: %+v", agv)
+	}
+	if web0["capacity"] != float64(srv.cm.QueueSize) || web0["sent"].(float64) < 1 {
+		t.Fatalf("용량·보낸 수가 실려야 함: %+v", web0)
+	}
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"sion-backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// NewCommandStatusHandler는 명령 응답 대기 설정과 응답·완료를 기다리는 명령 목록을 반환한다.
+func NewCommandStatusHandler(commands *services.CommandTracker) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"success": true,
+			"config":  commands.Config(),
+			"pending": commands.Pending(),
+		})
+	}
+}
+
+// NewCommandConfigHandler는 응답 대기 설정(models.CommandAckConfig)을 바꾼다. 본문에 없는 필드는 현재 값을 유지한다.
+func NewCommandConfigHandler(commands *services.CommandTracker) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		cfg := commands.Config()
+		if err := c.BodyParser(&cfg); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"message": "잘못된 요청 형식입니다",
+			})
+		}
+		if err := commands.SetConfig(cfg); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"message": err.Error(),
+			})
+		}
+		return c.JSON(fiber.Map{"success": true, "config": cfg})
+	}
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sion-backend/models"
+	"sion-backend/services"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCommandEndpoints(t *testing.T) {
+	br := services.NewBroker(services.NewClientManager())
+	app := fiber.New()
+	app.Get("/api/commands", NewCommandStatusHandler(br.Commands()))
+	app.Post("/api/commands/config", NewCommandConfigHandler(br.Commands()))
+
+	post := func(body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/commands/config", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("app.Test 실패: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := post(`{"max_retries":5}`); code != http.StatusOK {
+		t.Fatalf("설정 200 기대, got %d", code)
+	}
+	if cfg := br.Commands().Config(); cfg.MaxRetries != 5 || cfg.AckTimeoutMs != services.DefaultCommandAckConfig().AckTimeoutMs {
+		t.Fatalf("max_retries만 바뀌어야 함: %+v", cfg)
+	}
+	if code := post(`{"ack_timeout_ms":1}`); code != http.StatusBadRequest {
+		t.Fatalf("범위 밖 timeout 400 기대, got %d", code)
+	}
+	code, body := doGet(t, app, "/api/commands")
+	if code != http.StatusOK || body["pending"] == nil {
+		t.Fatalf("대기 목록을 반환해야 함: %d %+v", code, body)
+	}
+}
+
+// readCommandStatus는 command_status를 받아 상태를 꺼낸다.
+func readCommandStatus(t *testing.T, web *websocket.Conn) models.CommandStatus {
+	t.Helper()
+	msg := readUntilType(t, web, models.MessageTypeCommandStatus, time.Second)
+	raw, _ := json.Marshal(msg.Data)
+	var st models.CommandStatus
+	if err := json.Unmarshal(raw, &st); err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+// 웹 명령은 command_id를 달고 AGV로 가며, AGV의 ack/completed가 명령을 보낸 웹에 command_status로 돌아온다.
+func TestWS_CommandAckFlow(t *testing.T) {
+	srv := newWSTestServer(t)
+	agv := srv.dialAGV(t, "sion-001")
+	waitFor(t, time.Second, srv.broker.IsAGVConnected, "AGV connected wait")
+	web := srv.dial(t, "/websocket/web")
+	readUntilType(t, web, models.MessageTypeSystemInfo, time.Second)
+
+	sendWebCommand(t, web, models.WebSocketMessage{
+		Type:      models.MessageTypeCommand,
+		Data:      models.MoveCommand{TargetX: 3, TargetY: 4},
+		CommandID: "web-1",
+	})
+	cmd := readUntilType(t, agv, models.MessageTypeCommand, time.Second)
+	if cmd.CommandID != "web-1" || cmd.AGVID != "sion-001" {
+		t.Fatalf("command_id·agv_id가 붙어 와야 함: %+v", cmd)
+	}
+	if st := readCommandStatus(t, web); st.CommandID != "web-1" || st.State != models.CommandStatePending {
+		t.Fatalf("pending 기대: %+v", st)
+	}
+
+	for _, step := range []struct{ reply, want string }{
+		{models.MessageTypeCommandAck, models.CommandStateAcked},
+		{models.MessageTypeCommandCompleted, models.CommandStateCompleted},
+	} {
+		raw, _ := json.Marshal(models.WebSocketMessage{Type: step.reply, CommandID: "web-1"})
+		if err := agv.WriteMessage(websocket.TextMessage, raw); err != nil {
+			t.Fatal(err)
+		}
+		if st := readCommandStatus(t, web); st.State != step.want {
+			t.Fatalf("%s 기대: %+v", step.want, st)
+		}
+	}
+	if p := srv.broker.Commands().Pending(); len(p) != 0 {
+		t.Fatalf("완료된 명령은 대기 목록에 없어야 함: %+v", p)
+	}
+}
+
+// 응답 없는 비상 정지는 재시도 뒤 모든 웹 클라이언트에 critical error로 알려진다.
+func TestWS_UnackedEmergencyStopEscalates(t *testing.T) {
+	srv := newWSTestServer(t)
+	if err := srv.broker.Commands().SetConfig(models.CommandAckConfig{AckTimeoutMs: 30, MaxRetries: 1}); err != nil {
+		t.Fatal(err)
+	}
+	agv := srv.dialAGV(t, "sion-001")
+	waitFor(t, time.Second, srv.broker.IsAGVConnected, "AGV connected wait")
+	origin := srv.dial(t, "/websocket/web")
+	readUntilType(t, origin, models.MessageTypeSystemInfo, time.Second)
+	observer := srv.dial(t, "/websocket/web")
+	readUntilType(t, observer, models.MessageTypeSystemInfo, time.Second)
+
+	sendWebCommand(t, origin, models.WebSocketMessage{Type: models.MessageTypeEmergencyStop, AGVID: "sion-001"})
+	first := readUntilType(t, agv, models.MessageTypeEmergencyStop, time.Second)
+	retry := readUntilType(t, agv, models.MessageTypeEmergencyStop, time.Second)
+	if first.CommandID == "" || retry.CommandID != first.CommandID {
+		t.Fatalf("재전송은 같은 command_id여야 함: %q %q", first.CommandID, retry.CommandID)
+	}
+
+	alert := readUntilType(t, observer, models.MessageTypeError, time.Second)
+	data := alert.Data.(map[string]any)
+	if data["severity"] != "critical" || alert.CommandID != first.CommandID {
+		t.Fatalf("critical escalation 기대: %+v", alert)
+	}
+	for {
+		st := readCommandStatus(t, origin)
+		if st.State == models.CommandStateTimeout {
+			break
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"sion-backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// NewFaultStatusHandler는 현재 장애 주입 설정과 장애별 발생 횟수를 반환한다.
+func NewFaultStatusHandler(faults *services.FaultInjector) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"success": true,
+			"config":  faults.Config(),
+			"counts":  faults.Counts(),
+		})
+	}
+}
+
+// NewFaultConfigHandler는 장애 주입 설정(models.FaultConfig)을 바꾼다. 본문에 없는 필드는 현재 값을 유지한다.
+// 적용하면 난수원·스케줄 기준 시각·발생 횟수가 새로 시작된다.
+func NewFaultConfigHandler(faults *services.FaultInjector) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		cfg := faults.Config()
+		if err := c.BodyParser(&cfg); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"message": "잘못된 요청 형식입니다",
+			})
+		}
+		if err := faults.SetConfig(cfg); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"message": err.Error(),
+			})
+		}
+		return c.JSON(fiber.Map{"success": true, "config": cfg})
+	}
+}
+
+// NewFaultClearHandler는 모든 장애를 끈다.
+func NewFaultClearHandler(faults *services.FaultInjector) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		cfg := services.DefaultFaultConfig()
+		_ = faults.SetConfig(cfg)
+		return c.JSON(fiber.Map{"success": true, "config": cfg})
+	}
+}
+
+// NewFaultDisconnectHandler는 확률과 무관하게 지금 바로 AGV 연결을 끊는다.
+func NewFaultDisconnectHandler(faults *services.FaultInjector, broker *services.Broker) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		n := broker.ForceDisconnect()
+		faults.RecordForced(services.FaultDisconnect)
+		return c.JSON(fiber.Map{"success": true, "disconnected": n})
+	}
+}
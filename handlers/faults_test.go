@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sion-backend/services"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestFaultEndpoints(t *testing.T) {
+	faults := services.NewFaultInjector()
+	br := services.NewBroker(services.NewClientManager())
+	app := fiber.New()
+	app.Get("/api/faults", NewFaultStatusHandler(faults))
+	app.Post("/api/faults", NewFaultConfigHandler(faults))
+	app.Delete("/api/faults", NewFaultClearHandler(faults))
+	app.Post("/api/faults/disconnect", NewFaultDisconnectHandler(faults, br))
+
+	post := func(path, body string) (int, map[string]any) {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("app.Test 실패: %v", err)
+		}
+		defer resp.Body.Close()
+		var out map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+
+	code, body := post("/api/faults", `{"message_duplicate":{"probability":0.3,"schedule":{"start_sec":5,"duration_sec":10}}}`)
+	if code != http.StatusOK {
+		t.Fatalf("설정 200 기대, got %d %+v", code, body)
+	}
+	cfg := faults.Config()
+	if cfg.MessageDuplicate.Probability != 0.3 || cfg.MessageDuplicate.Schedule.DurationSec != 10 {
+		t.Fatalf("설정이 반영돼야 함: %+v", cfg.MessageDuplicate)
+	}
+	if cfg.BatteryDrop.Amount != 20 {
+		t.Fatalf("본문에 없는 필드는 유지돼야 함: %+v", cfg.BatteryDrop)
+	}
+	if code, _ := post("/api/faults", `{"disconnect":{"probability":2}}`); code != http.StatusBadRequest {
+		t.Fatalf("범위 밖 확률 400 기대, got %d", code)
+	}
+
+	if code, body := post("/api/faults/disconnect", ``); code != http.StatusOK || body["disconnected"] != 0.0 {
+		t.Fatalf("AGV가 없으면 0개 끊김: %d %+v", code, body)
+	}
+	_, body = doGet(t, app, "/api/faults")
+	if counts, _ := body["counts"].(map[string]any); counts[services.FaultDisconnect] != 1.0 {
+		t.Fatalf("강제 끊김도 횟수에 들어가야 함: %+v", body["counts"])
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/faults", nil)
+	resp, err := app.Test(req, -1)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("해제 실패: %v", err)
+	}
+	resp.Body.Close()
+	if faults.Config().MessageDuplicate.Probability != 0 {
+		t.Fatal("해제 후 모든 장애가 꺼져야 함")
+	}
+}
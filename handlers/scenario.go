@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"sion-backend/models"
+	"sion-backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func NewScenarioListHandler(store *services.ScenarioStore) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		scenarios := store.List()
+		items := make([]fiber.Map, len(scenarios))
+		for i, sc := range scenarios {
+			items[i] = fiber.Map{
+				"name":        sc.Name,
+				"description": sc.Description,
+				"map_width":   sc.MapWidth,
+				"map_height":  sc.MapHeight,
+				"enemies":     len(sc.Enemies),
+				"victory":     sc.Victory,
+			}
+		}
+		return c.JSON(fiber.Map{
+			"success":   true,
+			"count":     len(items),
+			"scenarios": items,
+		})
+	}
+}
+
+func NewScenarioGetHandler(store *services.ScenarioStore) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		sc, err := store.Get(c.Params("name"))
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"success": false,
+				"message": err.Error(),
+			})
+		}
+		return c.JSON(fiber.Map{
+			"success":  true,
+			"scenario": sc,
+		})
+	}
+}
+
+func NewScenarioUploadHandler(store *services.ScenarioStore) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var sc models.Scenario
+		if err := c.BodyParser(&sc); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"message": "잘못된 요청 형식입니다",
+			})
+		}
+		if err := store.Save(sc); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"message": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"success": true,
+			"message": "시나리오 저장 완료",
+			"name":    sc.Name,
+		})
+	}
+}
+
+func NewScenarioStartHandler(sim *services.AGVSimulator, store *services.ScenarioStore) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		sc, err := store.Get(c.Params("name"))
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"success": false,
+				"message": err.Error(),
+			})
+		}
+		if err := sim.LoadScenario(sc); err != nil {
+			return simulatorConfigError(c, err)
+		}
+		sim.Start()
+		return c.JSON(fiber.Map{
+			"success":  true,
+			"message":  "시나리오 시작",
+			"scenario": sc.Name,
+			"seed":     sim.Seed(),
+		})
+	}
+}
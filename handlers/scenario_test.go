@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sion-backend/models"
+	"sion-backend/services"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func newScenarioApp(sim *services.AGVSimulator, store *services.ScenarioStore) *fiber.App {
+	app := newSimulatorApp(sim)
+	app.Get("/api/simulator/scenarios", NewScenarioListHandler(store))
+	app.Post("/api/simulator/scenarios", NewScenarioUploadHandler(store))
+	app.Get("/api/simulator/scenarios/:name", NewScenarioGetHandler(store))
+	app.Post("/api/simulator/scenarios/:name/start", NewScenarioStartHandler(sim, store))
+	return app
+}
+
+func postScenario(t *testing.T, app *fiber.App, sc any) int {
+	t.Helper()
+	raw, err := json.Marshal(sc)
+	if err != nil {
+		t.Fatalf("marshal 실패: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/simulator/scenarios", bytes.NewReader(raw))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("app.Test 실패: %v", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestScenarioHandlers_UploadListStart(t *testing.T) {
+	sim := services.NewAGVSimulator(func(_ models.WebSocketMessage) {})
+	app := newScenarioApp(sim, services.NewScenarioStore(""))
+
+	sc := models.Scenario{
+		Name:      "demo",
+		MapWidth:  12,
+		MapHeight: 12,
+		Enemies:   []models.ScenarioEnemy{{Name: "제드", HP: 40, X: 6, Y: 6}},
+		AGV:       models.ScenarioAGV{X: 1, Y: 1},
+	}
+	if status := postScenario(t, app, sc); status != http.StatusCreated {
+		t.Fatalf("업로드 201 기대, got %d", status)
+	}
+
+	_, body := doSimReq(t, app, http.MethodGet, "/api/simulator/scenarios")
+	if count, _ := body["count"].(float64); count != 1 {
+		t.Fatalf("시나리오 1개 기대, body=%+v", body)
+	}
+
+	status, body := doSimReq(t, app, http.MethodPost, "/api/simulator/scenarios/demo/start")
+	if status != http.StatusOK {
+		t.Fatalf("시나리오 start 200 기대, got %d body=%+v", status, body)
+	}
+	defer func() {
+		_, _ = doSimReq(t, app, http.MethodPost, "/api/simulator/stop")
+	}()
+
+	_, body = doSimReq(t, app, http.MethodGet, "/api/simulator/status")
+	if body["scenario"] != "demo" {
+		t.Fatalf("status.scenario=demo 기대, body=%+v", body)
+	}
+	mapSize := body["map_size"].(map[string]any)
+	if mapSize["width"].(float64) != 12 {
+		t.Fatalf("map_size.width=12 기대, got %v", mapSize["width"])
+	}
+
+	// 실행 중에는 다른 시나리오로 재구성 불가
+	if status, _ := doSimReq(t, app, http.MethodPost, "/api/simulator/scenarios/demo/start"); status != http.StatusConflict {
+		t.Fatalf("실행 중 시나리오 start → 409 기대, got %d", status)
+	}
+}
+
+func TestScenarioHandlers_InvalidUploadAndMissing(t *testing.T) {
+	sim := services.NewAGVSimulator(func(_ models.WebSocketMessage) {})
+	app := newScenarioApp(sim, services.NewScenarioStore(""))
+
+	bad := models.Scenario{Name: "bad", MapWidth: 5, MapHeight: 5, AGV: models.ScenarioAGV{X: 9, Y: 9}}
+	if status := postScenario(t, app, bad); status != http.StatusBadRequest {
+		t.Fatalf("맵 밖 AGV 업로드 → 400 기대, got %d", status)
+	}
+	if status, _ := doSimReq(t, app, http.MethodPost, "/api/simulator/scenarios/nope/start"); status != http.StatusNotFound {
+		t.Fatalf("없는 시나리오 → 404 기대, got %d", status)
+	}
+}
@@ -1,11 +1,22 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"sion-backend/models"
 	"sion-backend/services"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// SimulatorStartRequest는 시작 요청 본문. 본문이 비어 있으면 현재 월드를 그대로 이어서 실행한다.
+// Seed를 주면 해당 seed로 월드를 다시 생성한 뒤 시작하므로 같은 seed의 실행은 재현 가능하다.
+// AGVCount를 주면 그 수만큼 AGV를 띄운다.
+type SimulatorStartRequest struct {
+	Seed     *int64 `json:"seed"`
+	AGVCount *int   `json:"agv_count"`
+}
+
 func NewSimulatorStartHandler(sim *services.AGVSimulator) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		if sim.IsRunning() {
@@ -14,10 +25,30 @@ func NewSimulatorStartHandler(sim *services.AGVSimulator) fiber.Handler {
 				"message": "시뮬레이터가 이미 실행 중입니다",
 			})
 		}
+		var req SimulatorStartRequest
+		if body := c.Body(); len(body) > 0 {
+			if err := json.Unmarshal(body, &req); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"success": false,
+					"message": "잘못된 요청 형식입니다",
+				})
+			}
+		}
+		if req.AGVCount != nil {
+			if err := sim.SetAGVCount(*req.AGVCount); err != nil {
+				return simulatorConfigError(c, err)
+			}
+		}
+		if req.Seed != nil {
+			if err := sim.Reseed(*req.Seed); err != nil {
+				return simulatorConfigError(c, err)
+			}
+		}
 		sim.Start()
 		return c.JSON(fiber.Map{
 			"success": true,
 			"message": "AGV 시뮬레이터 시작",
+			"seed":    sim.Seed(),
 		})
 	}
 }
@@ -41,11 +72,26 @@ func NewSimulatorStopHandler(sim *services.AGVSimulator) fiber.Handler {
 func NewSimulatorStatusHandler(sim *services.AGVSimulator) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		status, enemies, mapW, mapH := sim.Snapshot()
+		scenario, outcome := sim.ScenarioInfo()
 		return c.JSON(fiber.Map{
-			"success":   true,
-			"running":   sim.IsRunning(),
-			"agv_state": status,
-			"enemies":   enemies,
+			"success":         true,
+			"running":         sim.IsRunning(),
+			"seed":            sim.Seed(),
+			"scenario":        scenario,
+			"outcome":         outcome,
+			"agv_state":       status,
+			"agvs":            sim.AGVSnapshots(),
+			"enemies":         enemies,
+			"enemy_behaviors": sim.EnemyBehaviors(),
+			"stats":           sim.GetStats(),
+			"agv_stats":       sim.StatsByAGV(),
+			"clock":           sim.ClockState(),
+			"docks":           sim.Docks(),
+			"sensor_config":   sim.SensorConfig(),
+			"targeting":       sim.TargetingConfig(),
+			"kinematics":      sim.KinematicsConfig(),
+			"world_params":    sim.WorldParams(),
+			"snapshots":       sim.Snapshots(),
 			"map_size": fiber.Map{
 				"width":  mapW,
 				"height": mapH,
@@ -53,3 +99,317 @@ func NewSimulatorStatusHandler(sim *services.AGVSimulator) fiber.Handler {
 		})
 	}
 }
+
+func NewSimulatorPauseHandler(sim *services.AGVSimulator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		sim.Pause()
+		return c.JSON(fiber.Map{"success": true, "clock": sim.ClockState()})
+	}
+}
+
+func NewSimulatorResumeHandler(sim *services.AGVSimulator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		sim.Resume()
+		return c.JSON(fiber.Map{"success": true, "clock": sim.ClockState()})
+	}
+}
+
+// SimulatorStepRequest는 단계 실행 요청. Ticks를 생략하면 한 틱만 진행한다.
+type SimulatorStepRequest struct {
+	Ticks int `json:"ticks"`
+}
+
+// NewSimulatorStepHandler는 정지 또는 일시정지 상태에서 지정한 틱 수만큼 진행한다.
+// 일시정지하지 않은 채 실행 중이면 409.
+func NewSimulatorStepHandler(sim *services.AGVSimulator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := SimulatorStepRequest{Ticks: 1}
+		if body := c.Body(); len(body) > 0 {
+			if err := json.Unmarshal(body, &req); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"success": false,
+					"message": "잘못된 요청 형식입니다",
+				})
+			}
+		}
+		outcome, err := sim.Step(req.Ticks)
+		if err != nil {
+			return simulatorConfigError(c, err)
+		}
+		return c.JSON(fiber.Map{
+			"success": true,
+			"outcome": outcome,
+			"clock":   sim.ClockState(),
+		})
+	}
+}
+
+// SimulatorClockRequest는 배속·헤드리스 설정. 생략한 필드는 바꾸지 않는다.
+type SimulatorClockRequest struct {
+	Speed    *float64 `json:"speed"`
+	Headless *bool    `json:"headless"`
+}
+
+// NewSimulatorClockHandler는 배속(0.25~50)과 헤드리스 모드를 바꾼다. 실행 중에도 바로 반영된다.
+func NewSimulatorClockHandler(sim *services.AGVSimulator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req SimulatorClockRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"message": "잘못된 요청 형식입니다",
+			})
+		}
+		if req.Speed != nil {
+			if err := sim.SetSpeed(*req.Speed); err != nil {
+				return simulatorConfigError(c, err)
+			}
+		}
+		if req.Headless != nil {
+			sim.SetHeadless(*req.Headless)
+		}
+		return c.JSON(fiber.Map{"success": true, "clock": sim.ClockState()})
+	}
+}
+
+// NewSimulatorSensorConfigHandler는 센서 노이즈 설정(models.SensorConfig)을 바꾼다. 본문에 없는 필드는 현재 값을 유지한다.
+// 실행 중에도 다음 틱부터 반영된다.
+func NewSimulatorSensorConfigHandler(sim *services.AGVSimulator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		cfg := sim.SensorConfig()
+		if err := c.BodyParser(&cfg); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"message": "잘못된 요청 형식입니다",
+			})
+		}
+		if err := sim.SetSensorConfig(cfg); err != nil {
+			return simulatorConfigError(c, err)
+		}
+		return c.JSON(fiber.Map{"success": true, "sensor_config": cfg})
+	}
+}
+
+// NewSimulatorTargetingHandler는 타겟 선택 정책(models.TargetingConfig)을 바꾼다. 본문에 없는 필드는 현재 값을 유지한다.
+// 실행 중에도 다음 틱부터 반영된다.
+func NewSimulatorTargetingHandler(sim *services.AGVSimulator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		cfg := sim.TargetingConfig()
+		if err := c.BodyParser(&cfg); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"message": "잘못된 요청 형식입니다",
+			})
+		}
+		if err := sim.SetTargetingConfig(cfg); err != nil {
+			return simulatorConfigError(c, err)
+		}
+		return c.JSON(fiber.Map{"success": true, "targeting": cfg})
+	}
+}
+
+// NewSimulatorKinematicsHandler는 구동 한계(models.KinematicsConfig)를 바꾼다. 본문에 없는 필드는 현재 값을 유지한다.
+// 실행 중에도 다음 틱부터 반영된다.
+func NewSimulatorKinematicsHandler(sim *services.AGVSimulator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		cfg := sim.KinematicsConfig()
+		if err := c.BodyParser(&cfg); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"message": "잘못된 요청 형식입니다",
+			})
+		}
+		if err := sim.SetKinematicsConfig(cfg); err != nil {
+			return simulatorConfigError(c, err)
+		}
+		return c.JSON(fiber.Map{"success": true, "kinematics": cfg})
+	}
+}
+
+// NewSimulatorEnemyBehaviorHandler는 적 한 명의 행동 모델을 바꾼다. 실행 중에도 바로 반영된다.
+// 본문은 models.EnemyBehaviorConfig ({"behavior":"patrol","waypoints":[...]} 등).
+func NewSimulatorEnemyBehaviorHandler(sim *services.AGVSimulator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var cfg models.EnemyBehaviorConfig
+		if err := c.BodyParser(&cfg); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"message": "잘못된 요청 형식입니다",
+			})
+		}
+		if err := sim.SetEnemyBehavior(c.Params("id"), cfg); err != nil {
+			return simulatorConfigError(c, err)
+		}
+		return c.JSON(fiber.Map{
+			"success":  true,
+			"enemy_id": c.Params("id"),
+			"behavior": sim.EnemyBehaviors()[c.Params("id")],
+		})
+	}
+}
+
+// SimulatorResetRequest는 월드 리셋 요청. Seed를 생략하면 현재 seed로 같은 월드를 다시 만든다.
+type SimulatorResetRequest struct {
+	Seed *int64 `json:"seed"`
+}
+
+// NewSimulatorResetHandler는 월드를 처음 상태로 되돌린다 (로드한 시나리오가 있으면 그 시나리오). 실행 중이면 409.
+func NewSimulatorResetHandler(sim *services.AGVSimulator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req SimulatorResetRequest
+		if body := c.Body(); len(body) > 0 {
+			if err := json.Unmarshal(body, &req); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"success": false,
+					"message": "잘못된 요청 형식입니다",
+				})
+			}
+		}
+		if err := sim.ResetWorld(req.Seed); err != nil {
+			return simulatorConfigError(c, err)
+		}
+		return c.JSON(fiber.Map{
+			"success": true,
+			"message": "월드 리셋",
+			"seed":    sim.Seed(),
+		})
+	}
+}
+
+// SimulatorRespawnRequest는 적 재배치 요청. Count를 생략하면 현재 적 수(enemy_count)만큼 배치한다.
+type SimulatorRespawnRequest struct {
+	Count *int `json:"count"`
+}
+
+// NewSimulatorRespawnEnemiesHandler는 장애물·AGV는 그대로 두고 적을 새로 배치한다. 실행 중이면 409.
+func NewSimulatorRespawnEnemiesHandler(sim *services.AGVSimulator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req SimulatorRespawnRequest
+		if body := c.Body(); len(body) > 0 {
+			if err := json.Unmarshal(body, &req); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"success": false,
+					"message": "잘못된 요청 형식입니다",
+				})
+			}
+		}
+		count := sim.WorldParams().EnemyCount
+		if req.Count != nil {
+			count = *req.Count
+		}
+		if err := sim.RespawnEnemies(count); err != nil {
+			return simulatorConfigError(c, err)
+		}
+		_, enemies, _, _ := sim.Snapshot()
+		return c.JSON(fiber.Map{"success": true, "enemies": enemies})
+	}
+}
+
+// NewSimulatorUpdateAGVHandler는 AGV 한 대를 옮기거나 배터리·모드를 바꾼다. 본문은 services.AGVUpdate
+// ({"x":3,"y":4,"angle":1.57,"battery":80,"mode":"manual"}, 생략한 필드는 유지). 실행 중이면 409.
+func NewSimulatorUpdateAGVHandler(sim *services.AGVSimulator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req services.AGVUpdate
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"message": "잘못된 요청 형식입니다",
+			})
+		}
+		id := c.Params("id")
+		if err := sim.UpdateAGV(id, req); err != nil {
+			return simulatorConfigError(c, err)
+		}
+		for _, status := range sim.AGVSnapshots() {
+			if status.ID == id {
+				return c.JSON(fiber.Map{"success": true, "agv": status})
+			}
+		}
+		return c.JSON(fiber.Map{"success": true})
+	}
+}
+
+// NewSimulatorWorldParamsHandler는 맵 크기·AGV 수·적 수·틱 간격(services.WorldParams)을 바꾼다.
+// 본문에 없는 필드는 현재 값을 유지한다. 실행 중이면 409.
+func NewSimulatorWorldParamsHandler(sim *services.AGVSimulator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		params := sim.WorldParams()
+		if err := c.BodyParser(&params); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"message": "잘못된 요청 형식입니다",
+			})
+		}
+		if err := sim.SetWorldParams(params); err != nil {
+			return simulatorConfigError(c, err)
+		}
+		return c.JSON(fiber.Map{"success": true, "world_params": params})
+	}
+}
+
+func NewSimulatorSnapshotListHandler(sim *services.AGVSimulator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{"success": true, "snapshots": sim.Snapshots()})
+	}
+}
+
+// SimulatorSnapshotRequest는 스냅샷 저장 요청.
+type SimulatorSnapshotRequest struct {
+	Name string `json:"name"`
+}
+
+// NewSimulatorSnapshotSaveHandler는 현재 월드를 이름을 붙여 저장한다. 같은 이름이 있으면 덮어쓴다.
+func NewSimulatorSnapshotSaveHandler(sim *services.AGVSimulator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req SimulatorSnapshotRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"message": "잘못된 요청 형식입니다",
+			})
+		}
+		info, err := sim.SaveSnapshot(req.Name)
+		if err != nil {
+			return simulatorConfigError(c, err)
+		}
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{"success": true, "snapshot": info})
+	}
+}
+
+// NewSimulatorSnapshotRestoreHandler는 저장한 월드로 되돌린다. 실행 중이면 409.
+func NewSimulatorSnapshotRestoreHandler(sim *services.AGVSimulator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := sim.RestoreSnapshot(c.Params("name")); err != nil {
+			return simulatorConfigError(c, err)
+		}
+		return c.JSON(fiber.Map{
+			"success": true,
+			"message": "스냅샷 복원: " + c.Params("name"),
+			"seed":    sim.Seed(),
+		})
+	}
+}
+
+func NewSimulatorSnapshotDeleteHandler(sim *services.AGVSimulator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := sim.DeleteSnapshot(c.Params("name")); err != nil {
+			return simulatorConfigError(c, err)
+		}
+		return c.JSON(fiber.Map{"success": true})
+	}
+}
+
+// simulatorConfigError는 재구성 실패를 HTTP 응답으로 바꾼다. 실행 상태와의 충돌은 409, 없는 대상은 404, 나머지 검증 실패는 400.
+func simulatorConfigError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusBadRequest
+	switch {
+	case errors.Is(err, services.ErrSimulatorRunning), errors.Is(err, services.ErrSimulatorNotPaused):
+		status = fiber.StatusConflict
+	case errors.Is(err, services.ErrEnemyNotFound), errors.Is(err, services.ErrAGVNotFound), errors.Is(err, services.ErrSnapshotNotFound):
+		status = fiber.StatusNotFound
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"success": false,
+		"message": err.Error(),
+	})
+}
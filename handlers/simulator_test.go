@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"sion-backend/models"
 	"sion-backend/services"
+	"strings"
 	"testing"
 
 	"github.com/gofiber/fiber/v2"
@@ -17,6 +18,17 @@ func newSimulatorApp(sim *services.AGVSimulator) *fiber.App {
 	app.Post("/api/simulator/start", NewSimulatorStartHandler(sim))
 	app.Post("/api/simulator/stop", NewSimulatorStopHandler(sim))
 	app.Get("/api/simulator/status", NewSimulatorStatusHandler(sim))
+	app.Post("/api/simulator/enemies/:id/behavior", NewSimulatorEnemyBehaviorHandler(sim))
+	app.Post("/api/simulator/pause", NewSimulatorPauseHandler(sim))
+	app.Post("/api/simulator/resume", NewSimulatorResumeHandler(sim))
+	app.Post("/api/simulator/step", NewSimulatorStepHandler(sim))
+	app.Post("/api/simulator/clock", NewSimulatorClockHandler(sim))
+	app.Post("/api/simulator/reset", NewSimulatorResetHandler(sim))
+	app.Post("/api/simulator/enemies/respawn", NewSimulatorRespawnEnemiesHandler(sim))
+	app.Post("/api/simulator/agvs/:id", NewSimulatorUpdateAGVHandler(sim))
+	app.Post("/api/simulator/world", NewSimulatorWorldParamsHandler(sim))
+	app.Post("/api/simulator/snapshots", NewSimulatorSnapshotSaveHandler(sim))
+	app.Post("/api/simulator/snapshots/:name/restore", NewSimulatorSnapshotRestoreHandler(sim))
 	return app
 }
 
@@ -119,3 +131,198 @@ func TestSimulatorHandlers_StopWhenNotRunningFails(t *testing.T) {
 		t.Fatalf("success=false 기대, body=%+v", body)
 	}
 }
+
+func TestSimulatorHandlers_StartWithSeedReportedInStatus(t *testing.T) {
+	sim := services.NewAGVSimulator(func(_ models.WebSocketMessage) {})
+	app := newSimulatorApp(sim)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/simulator/start", strings.NewReader(`{"seed": 1234}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("app.Test 실패: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("seed start 200 기대, got %d", resp.StatusCode)
+	}
+	defer func() {
+		_, _ = doSimReq(t, app, http.MethodPost, "/api/simulator/stop")
+	}()
+
+	_, body := doSimReq(t, app, http.MethodGet, "/api/simulator/status")
+	if seed, _ := body["seed"].(float64); seed != 1234 {
+		t.Fatalf("status.seed=1234 기대, body=%+v", body)
+	}
+}
+
+func TestSimulatorHandlers_StartWithAGVCount(t *testing.T) {
+	sim := services.NewAGVSimulator(func(_ models.WebSocketMessage) {})
+	app := newSimulatorApp(sim)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/simulator/start", strings.NewReader(`{"agv_count": 3}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("app.Test 실패: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("agv_count start 200 기대, got %d", resp.StatusCode)
+	}
+	defer func() {
+		_, _ = doSimReq(t, app, http.MethodPost, "/api/simulator/stop")
+	}()
+
+	_, body := doSimReq(t, app, http.MethodGet, "/api/simulator/status")
+	agvs, ok := body["agvs"].([]any)
+	if !ok || len(agvs) != 3 {
+		t.Fatalf("agvs 3개 기대, body=%+v", body["agvs"])
+	}
+}
+
+func TestSimulatorHandlers_SetEnemyBehavior(t *testing.T) {
+	sim := services.NewAGVSimulator(func(_ models.WebSocketMessage) {})
+	app := newSimulatorApp(sim)
+
+	post := func(id, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/simulator/enemies/"+id+"/behavior", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("app.Test 실패: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := post("enemy-1", `{"behavior":"escape","trigger_radius":4}`); code != http.StatusOK {
+		t.Fatalf("행동 변경 200 기대, got %d", code)
+	}
+	_, body := doSimReq(t, app, http.MethodGet, "/api/simulator/status")
+	behaviors, _ := body["enemy_behaviors"].(map[string]any)
+	if behaviors["enemy-1"] != models.EnemyBehaviorEscape {
+		t.Fatalf("enemy-1 escape 기대, body=%+v", body["enemy_behaviors"])
+	}
+	if code := post("enemy-1", `{"behavior":"patrol"}`); code != http.StatusBadRequest {
+		t.Fatalf("waypoints 없는 patrol 400 기대, got %d", code)
+	}
+	if code := post("nobody", `{"behavior":"wander"}`); code != http.StatusNotFound {
+		t.Fatalf("없는 적 404 기대, got %d", code)
+	}
+}
+
+func TestSimulatorHandlers_ClockControls(t *testing.T) {
+	sim := services.NewAGVSimulator(func(_ models.WebSocketMessage) {})
+	app := newSimulatorApp(sim)
+
+	postJSON := func(path, body string) (int, map[string]any) {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("app.Test 실패: %v", err)
+		}
+		defer resp.Body.Close()
+		var out map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+
+	if code, _ := postJSON("/api/simulator/clock", `{"speed": 100}`); code != http.StatusBadRequest {
+		t.Fatalf("범위 밖 배속 400 기대, got %d", code)
+	}
+	code, body := postJSON("/api/simulator/clock", `{"speed": 4, "headless": false}`)
+	if clock, _ := body["clock"].(map[string]any); code != http.StatusOK || clock["speed"] != 4.0 {
+		t.Fatalf("배속 변경 실패: %d %+v", code, body)
+	}
+
+	if code, _ := doSimReq(t, app, http.MethodPost, "/api/simulator/start"); code != http.StatusOK {
+		t.Fatalf("start 200 기대, got %d", code)
+	}
+	defer func() {
+		_, _ = doSimReq(t, app, http.MethodPost, "/api/simulator/stop")
+	}()
+
+	if code, _ := postJSON("/api/simulator/step", `{"ticks": 2}`); code != http.StatusConflict {
+		t.Fatalf("일시정지 전 step 409 기대, got %d", code)
+	}
+	if code, _ := doSimReq(t, app, http.MethodPost, "/api/simulator/pause"); code != http.StatusOK {
+		t.Fatalf("pause 200 기대, got %d", code)
+	}
+	if code, _ := postJSON("/api/simulator/step", `{"ticks": 2}`); code != http.StatusOK {
+		t.Fatalf("일시정지 중 step 200 기대, got %d", code)
+	}
+	_, status := doSimReq(t, app, http.MethodGet, "/api/simulator/status")
+	if clock, _ := status["clock"].(map[string]any); clock["paused"] != true {
+		t.Fatalf("status.clock.paused=true 기대, got %+v", status["clock"])
+	}
+}
+
+func TestSimulatorHandlers_ReconfigureWhileStopped(t *testing.T) {
+	sim := services.NewAGVSimulator(func(_ models.WebSocketMessage) {})
+	app := newSimulatorApp(sim)
+
+	postJSON := func(path, body string) (int, map[string]any) {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("app.Test 실패: %v", err)
+		}
+		defer resp.Body.Close()
+		var out map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+
+	if code, _ := postJSON("/api/simulator/reset", `{"seed": 12}`); code != http.StatusOK || sim.Seed() != 12 {
+		t.Fatalf("reset 200 + seed 12 기대, got %d seed=%d", code, sim.Seed())
+	}
+	code, body := postJSON("/api/simulator/world", `{"enemy_count": 7}`)
+	if params, _ := body["world_params"].(map[string]any); code != http.StatusOK || params["enemy_count"] != 7.0 || params["map_width"] != 30.0 {
+		t.Fatalf("부분 파라미터 변경 실패: %d %+v", code, body)
+	}
+	if code, body := postJSON("/api/simulator/enemies/respawn", `{"count": 2}`); code != http.StatusOK || len(body["enemies"].([]any)) != 2 {
+		t.Fatalf("적 재배치 실패: %d %+v", code, body)
+	}
+	if code, _ := postJSON("/api/simulator/agvs/ghost", `{"x": 1}`); code != http.StatusNotFound {
+		t.Fatalf("없는 AGV 404 기대, got %d", code)
+	}
+	if code, _ := postJSON("/api/simulator/agvs/sion-001", `{"battery": 150}`); code != http.StatusBadRequest {
+		t.Fatalf("범위 밖 배터리 400 기대, got %d", code)
+	}
+	if code, _ := postJSON("/api/simulator/snapshots", `{"name": "before"}`); code != http.StatusCreated {
+		t.Fatalf("스냅샷 저장 201 기대, got %d", code)
+	}
+	if code, _ := postJSON("/api/simulator/agvs/sion-001", `{"battery": 10}`); code != http.StatusOK {
+		t.Fatalf("배터리 변경 200 기대, got %d", code)
+	}
+	if code, _ := postJSON("/api/simulator/snapshots/before/restore", ``); code != http.StatusOK {
+		t.Fatalf("스냅샷 복원 200 기대, got %d", code)
+	}
+	if battery := sim.AGVSnapshots()[0].Battery; battery != 100 {
+		t.Fatalf("복원 후 배터리 100 기대, got %d", battery)
+	}
+	if code, _ := postJSON("/api/simulator/snapshots/missing/restore", ``); code != http.StatusNotFound {
+		t.Fatalf("없는 스냅샷 404 기대, got %d", code)
+	}
+
+	if code, _ := doSimReq(t, app, http.MethodPost, "/api/simulator/start"); code != http.StatusOK {
+		t.Fatalf("start 200 기대, got %d", code)
+	}
+	defer func() {
+		_, _ = doSimReq(t, app, http.MethodPost, "/api/simulator/stop")
+	}()
+	for path, body := range map[string]string{
+		"/api/simulator/reset":                    ``,
+		"/api/simulator/world":                    `{"enemy_count": 3}`,
+		"/api/simulator/enemies/respawn":          ``,
+		"/api/simulator/agvs/sion-001":            `{"x": 2}`,
+		"/api/simulator/snapshots/before/restore": ``,
+	} {
+		if code, _ := postJSON(path, body); code != http.StatusConflict {
+			t.Errorf("%s: 실행 중 409 기대, got %d", path, code)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"sion-backend/services"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// NewStatsHandler는 진행 중인 세션의 AGV별 누적 통계를 반환한다. 실제 AGV의 거리·시간은 position·status 보고로,
+// 충돌 수는 AGV가 보낸 collision 보고로 센다.
+func NewStatsHandler(tracker *services.StatsTracker) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"success": true,
+			"stats":   tracker.Live(),
+		})
+	}
+}
+
+// NewStatsHistoryHandler는 끝난 세션 기록을 최신순으로 반환한다. agv_id를 생략하면 모든 AGV.
+func NewStatsHistoryHandler(tracker *services.StatsTracker) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		limit, err := strconv.Atoi(c.Query("limit", "20"))
+		if err != nil || limit <= 0 {
+			limit = 20
+		}
+		records, err := tracker.History(c.Query("agv_id"), limit)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"success": false,
+				"message": "통계 기록 조회 실패",
+			})
+		}
+		return c.JSON(fiber.Map{
+			"success": true,
+			"count":   len(records),
+			"records": records,
+		})
+	}
+}
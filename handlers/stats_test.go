@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"sion-backend/models"
+	"sion-backend/services"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestStatsEndpoints(t *testing.T) {
+	tracker := services.NewStatsTracker()
+	app := fiber.New()
+	app.Get("/api/stats", NewStatsHandler(tracker))
+	app.Get("/api/stats/history", NewStatsHistoryHandler(tracker))
+
+	now := time.Now()
+	tracker.ObservePosition("sion-001", models.PositionData{X: 0, Y: 0}, now)
+	tracker.ObservePosition("sion-001", models.PositionData{X: 0, Y: 2}, now.Add(time.Second))
+	tracker.RecordKill("sion-001")
+
+	code, body := doGet(t, app, "/api/stats")
+	if code != fiber.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	live := body["stats"].(map[string]any)["sion-001"].(map[string]any)
+	if live["total_distance"].(float64) != 2 || live["enemies_defeated"].(float64) != 1 {
+		t.Fatalf("live 통계가 틀림: %+v", live)
+	}
+
+	tracker.EndSession("sion-001")
+	tracker.RecordKill("sion-002")
+	tracker.EndSession("sion-002")
+	code, body = doGet(t, app, "/api/stats/history?agv_id=sion-001")
+	if code != fiber.StatusOK || body["count"].(float64) != 1 {
+		t.Fatalf("history: %d %+v", code, body)
+	}
+	if _, body = doGet(t, app, "/api/stats/history"); body["count"].(float64) != 2 {
+		t.Fatalf("agv_id 없으면 전체 기록: %+v", body)
+	}
+}
+
+// position 없이 status만 보내는 실제 AGV도 거리·시간이 쌓이고, collision 보고는 충돌 수에 더해진다.
+func TestWS_RealAGVFeedsStatsTracker(t *testing.T) {
+	srv := newWSTestServer(t)
+	tracker := services.NewStatsTracker()
+	srv.broker.SetStatsTracker(tracker)
+	agv := srv.dialAGV(t, "sion-001")
+	waitFor(t, time.Second, srv.broker.IsAGVConnected, "AGV connected wait")
+
+	for _, y := range []float64{0, 3} {
+		sendWebCommand(t, agv, models.WebSocketMessage{
+			Type: models.MessageTypeStatus,
+			Data: models.AGVStatus{Battery: 80, Position: models.PositionData{X: 1, Y: y}},
+		})
+	}
+	sendWebCommand(t, agv, models.WebSocketMessage{
+		Type: models.MessageTypeCollision,
+		Data: models.CollisionReport{X: 1, Y: 3, With: "wall"},
+	})
+	waitFor(t, time.Second, func() bool {
+		live := tracker.Live()["sion-001"]
+		return live.TotalDistance == 3 && live.Collisions == 1
+	}, "status 위치·collision 보고 반영 대기")
+}
+
+// 가상 AGV 모드에서는 시뮬레이터 충돌이 collision 보고로 서버에 올라가 세션 통계에 쌓인다.
+func TestWS_VirtualAGVCollisionsFeedStatsTracker(t *testing.T) {
+	srv := newWSTestServer(t)
+	tracker := services.NewStatsTracker()
+	srv.broker.SetStatsTracker(tracker)
+
+	// AGV를 장애물 상자 안에 세워 두면 어느 쪽으로 움직여도 벽에 부딪힌다.
+	seed := int64(1)
+	sc := models.Scenario{
+		Name: "box", MapWidth: 10, MapHeight: 10, Seed: &seed,
+		AGV:     models.ScenarioAGV{X: 5.5, Y: 5.5},
+		Victory: models.VictoryCondition{TimeoutSec: 300},
+	}
+	for row := 4; row <= 6; row++ {
+		for col := 4; col <= 6; col++ {
+			if row != 5 || col != 5 {
+				sc.Obstacles = append(sc.Obstacles, models.Obstacle{Position: models.GridCoordinate{Row: row, Col: col}, Size: 1})
+			}
+		}
+	}
+	sim := services.NewAGVSimulator(nil)
+	sim.UpdateInterval = 20 * time.Millisecond
+	if err := sim.LoadScenario(sc); err != nil {
+		t.Fatal(err)
+	}
+	vagv := services.NewVirtualAGV("ws://"+srv.addr+"/websocket/agv", sim)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go vagv.Run(ctx)
+	sim.Start()
+	defer sim.Stop()
+	waitFor(t, 2*time.Second, func() bool { return srv.broker.IsAGVConnectedID("sion-001") }, "가상 AGV 연결 대기")
+
+	sim.HandleWebCommand(models.WebSocketMessage{
+		Type:  models.MessageTypeMotorControl,
+		AGVID: "sion-001",
+		Data:  models.MotorControl{LeftSpeed: 1, RightSpeed: 1, Duration: 5000},
+	})
+	waitFor(t, 2*time.Second, func() bool { return tracker.Live()["sion-001"].Collisions > 0 }, "가상 AGV collision 보고 반영 대기")
+}
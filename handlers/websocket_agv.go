@@ -1,7 +1,7 @@
 package handlers
 
 import (
-	"encoding/json"
+	"fmt"
 	"log"
 	"sion-backend/models"
 	"sion-backend/services"
@@ -10,49 +10,153 @@ import (
 	"github.com/gofiber/websocket/v2"
 )
 
+// helloWait는 AGV가 접속 후 hello를 보내기까지 기다리는 최대 시간. 테스트에서 줄일 수 있게 var로 둔다.
+var helloWait = 5 * time.Second
+
 func NewAGVHandler(cm *services.ClientManager, broker *services.Broker) func(*websocket.Conn) {
 	return func(c *websocket.Conn) {
 		cm.Register(c, services.AGVClient)
 		// defer는 LIFO: ① stopKeepalive로 ping 고루틴 종료
-		// → ② SetAGVConnected(false)로 web에 disconnect 브로드캐스트
-		// → ③ Unregister(c)로 자기 자신을 풀에서 제거. 자신의 conn에 마지막 알림이 가지 않게 한다.
+		// → ② 이 연결에 묶인 AGV마다 SetAGVDisconnected로 web에 disconnect 브로드캐스트
+		// → ③ Unregister(c)로 자기 자신을 풀과 AGV ID 표에서 제거. 자신의 conn에 마지막 알림이 가지 않게 한다.
 		defer cm.Unregister(c)
-		defer broker.SetAGVConnected(false)
+		defer func() {
+			for _, id := range cm.AGVIDsOf(c) {
+				broker.SetAGVDisconnected(id)
+			}
+		}()
 		stopKeepalive := installKeepalive(cm, c, "AGV")
 		defer stopKeepalive()
 
-		broker.SetAGVConnected(true)
+		messages := broker.Messages()
+		hello, err := readAGVHello(c, messages)
+		if err == nil {
+			_, err = cm.BindAGV(c, hello.AGVID)
+		}
+		if err != nil {
+			log.Printf("[WARN] AGV 핸드셰이크 실패 (%s): %v", c.RemoteAddr(), err)
+			sendInvalidPayloadError(cm, c, err.Error())
+			cm.CloseConn(c, websocket.ClosePolicyViolation, "handshake failed")
+			return
+		}
+		agvID := hello.AGVID
+		log.Printf("[INFO] AGV 핸드셰이크: %s %v (%s)", agvID, hello.Capabilities, c.RemoteAddr())
+		broker.SetAGVConnected(agvID, hello)
+		// declared는 이 연결이 보고할 수 있는 AGV ID. hello에 적은 ID만 받아 다른 AGV의 ID를 가로채지 못하게 한다.
+		declared := map[string]bool{agvID: true}
+		declareAGVs(cm, broker, c, declared, hello)
 
 		for {
 			_, p, err := c.ReadMessage()
 			if err != nil {
 				if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
-					log.Printf("[WARN] AGV 비정상 종료: %v", err)
+					log.Printf("[WARN] AGV %s 비정상 종료: %v", agvID, err)
 				} else {
-					log.Println("[INFO] AGV 연결 종료")
+					log.Printf("[INFO] AGV %s 연결 종료", agvID)
 				}
 				break
 			}
 
-			var msg models.WebSocketMessage
-			if err := json.Unmarshal(p, &msg); err != nil {
-				log.Printf("[WARN] AGV 메시지 파싱 오류: %v", err)
+			// 형식이 틀린 메시지는 어느 필드가 틀렸는지 AGV에 알리고 웹으로 흘리지 않는다.
+			msg, err := messages.Decode(p)
+			if err != nil {
+				log.Printf("[WARN] AGV %s 메시지 거부 (%s): %v", agvID, msg.Type, err)
+				sendDecodeError(cm, c, msg, err)
+				continue
+			}
+
+			// 게이트웨이는 접속 뒤 늘어난 AGV를 hello를 다시 보내 알린다. 대표 ID는 바꾸지 않는다.
+			if msg.Type == models.MessageTypeHello {
+				more, _ := services.PayloadAs[models.AGVHello](msg)
+				declareAGVs(cm, broker, c, declared, more)
 				continue
 			}
 
+			// agv_id가 없으면 핸드셰이크 ID로 채운다. 다른 ID면 hello에 적은, 게이트웨이가 대신 보고하는 AGV여야 한다.
+			patch := map[string]any{}
+			if msg.AGVID == "" {
+				msg.AGVID = agvID
+				patch["agv_id"] = agvID
+			} else if !declared[msg.AGVID] {
+				log.Printf("[WARN] AGV %s: hello에 없는 agv_id %s 보고 거부 (%s)", agvID, msg.AGVID, msg.Type)
+				sendDecodeError(cm, c, msg, &services.FieldError{Field: "agv_id", Reason: "hello에 적지 않은 AGV ID입니다"})
+				continue
+			} else if msg.AGVID != agvID && !bindAGV(cm, broker, c, msg.AGVID, hello) {
+				continue
+			}
 			if msg.Timestamp == 0 {
 				msg.Timestamp = time.Now().UnixMilli()
-				updated, err := json.Marshal(msg)
+				patch["timestamp"] = msg.Timestamp
+			}
+			// 레지스트리가 고친 data(범위를 벗어난 배터리 등)는 원본 프레임에도 반영한다.
+			if n, ok := msg.Data.(interface{ Normalized() bool }); ok && n.Normalized() {
+				patch["data"] = msg.Data
+			}
+			if len(patch) > 0 {
+				updated, err := services.PatchEnvelope(p, patch)
 				if err != nil {
-					log.Printf("[WARN] AGV 메시지 timestamp 보정 marshal 실패: %v", err)
+					log.Printf("[WARN] AGV 메시지 봉투 보정 실패: %v", err)
 					continue
 				}
 				p = updated
 			}
 
-			go services.LogAGVEvent(msg, "sion-001", "agv")
-			log.Printf("[INFO] AGV 메시지: %s", msg.Type)
+			go services.LogAGVEvent(msg, msg.AGVID, "agv")
+			log.Printf("[INFO] AGV %s 메시지: %s", msg.AGVID, msg.Type)
 			broker.OnAGVMessage(msg, p)
 		}
 	}
 }
+
+// readAGVHello는 첫 메시지를 hello로 읽는다. helloWait 안에 오지 않거나 hello가 아니면 에러.
+// 읽은 뒤에는 keepalive의 read deadline을 되살린다.
+func readAGVHello(c *websocket.Conn, messages *services.MessageRegistry) (models.AGVHello, error) {
+	var hello models.AGVHello
+	if err := c.SetReadDeadline(time.Now().Add(helloWait)); err != nil {
+		return hello, err
+	}
+	_, p, err := c.ReadMessage()
+	if err != nil {
+		return hello, fmt.Errorf("hello 수신 실패: %w", err)
+	}
+	msg, err := messages.Decode(p)
+	if err == nil && msg.Type != models.MessageTypeHello {
+		err = fmt.Errorf("첫 메시지는 hello여야 합니다 (받은 타입: %q)", msg.Type)
+	}
+	if err != nil {
+		return hello, err
+	}
+	if hello, err = services.PayloadAs[models.AGVHello](msg); err != nil {
+		return hello, err
+	}
+	if err := c.SetReadDeadline(time.Now().Add(pongWait)); err != nil {
+		log.Printf("[WARN] AGV SetReadDeadline 실패: %v", err)
+	}
+	return hello, nil
+}
+
+// declareAGVs는 hello의 ID를 declared에 더하고 연결 c에 묶는다. 다른 연결이 쓰는 ID는 declared에 남아
+// 그 연결이 끊긴 뒤 첫 보고 때 묶인다.
+func declareAGVs(cm *services.ClientManager, broker *services.Broker, c *websocket.Conn, declared map[string]bool, hello models.AGVHello) {
+	for _, id := range append([]string{hello.AGVID}, hello.AGVIDs...) {
+		if id == "" {
+			continue
+		}
+		declared[id] = true
+		bindAGV(cm, broker, c, id, hello)
+	}
+}
+
+// bindAGV는 id를 연결 c에 묶고, 새로 묶였으면 broker에 연결을 알린다. 다른 연결이 쓰는 ID면 false.
+func bindAGV(cm *services.ClientManager, broker *services.Broker, c *websocket.Conn, id string, hello models.AGVHello) bool {
+	added, err := cm.BindAGV(c, id)
+	if err != nil {
+		log.Printf("[WARN] AGV ID %s 등록 거부: %v", id, err)
+		sendInvalidPayloadError(cm, c, fmt.Sprintf("%s: %v", id, err))
+		return false
+	}
+	if added {
+		broker.SetAGVConnected(id, hello)
+	}
+	return true
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"encoding/json"
+	"sion-backend/models"
+	"testing"
+	"time"
+
+	"github.com/fasthttp/websocket"
+)
+
+// hello 없이 다른 메시지부터 보내면 error를 받고 연결이 닫힌다.
+func TestWS_AGVHandshake_RequiresHello(t *testing.T) {
+	srv := newWSTestServer(t)
+
+	agv := srv.dial(t, "/websocket/agv")
+	raw, _ := json.Marshal(models.WebSocketMessage{Type: models.MessageTypeStatus, Data: map[string]any{"battery": 50}})
+	if err := agv.WriteMessage(websocket.TextMessage, raw); err != nil {
+		t.Fatalf("AGV WriteMessage 실패: %v", err)
+	}
+
+	readUntilType(t, agv, models.MessageTypeError, time.Second)
+	if err := agv.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := agv.ReadMessage(); !websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+		t.Fatalf("핸드셰이크 실패 시 policy violation으로 닫혀야 함: %v", err)
+	}
+	waitFor(t, time.Second, func() bool { return srv.cm.GetClientCount()["agv"] == 0 }, "핸드셰이크 실패 연결이 풀에 남음")
+	if srv.broker.IsAGVConnected() {
+		t.Fatal("핸드셰이크 없이 연결 상태가 되면 안 됨")
+	}
+}
+
+// 이미 연결된 ID로 다시 접속하면 거부되고 기존 연결은 그대로다.
+func TestWS_AGVHandshake_DuplicateIDRejected(t *testing.T) {
+	srv := newWSTestServer(t)
+
+	first := srv.dialAGV(t, "sion-001")
+	waitFor(t, time.Second, srv.broker.IsAGVConnected, "AGV connected wait")
+
+	second := srv.dialAGV(t, "sion-001")
+	readUntilType(t, second, models.MessageTypeError, time.Second)
+	waitFor(t, time.Second, func() bool { return srv.cm.GetClientCount()["agv"] == 1 }, "중복 ID 연결이 풀에 남음")
+
+	web := srv.dial(t, "/websocket/web")
+	readUntilType(t, web, models.MessageTypeSystemInfo, time.Second)
+	raw, _ := json.Marshal(models.WebSocketMessage{
+		Type:  models.MessageTypeModeChange,
+		Data:  map[string]any{"mode": "auto"},
+		AGVID: "sion-001",
+	})
+	if err := web.WriteMessage(websocket.TextMessage, raw); err != nil {
+		t.Fatal(err)
+	}
+	readUntilType(t, first, models.MessageTypeModeChange, time.Second)
+}
+
+// hello에 적지 않은 agv_id 보고는 거부되고, 그 ID는 이 연결에 묶이지 않아 실제 로봇이 나중에 접속할 수 있다.
+// 게이트웨이는 hello를 다시 보내 ID를 늘릴 수 있다.
+func TestWS_AGVUndeclaredIDRefused(t *testing.T) {
+	srv := newWSTestServer(t)
+	gw := srv.dialAGV(t, "sion-001")
+	waitFor(t, time.Second, srv.broker.IsAGVConnected, "AGV connected wait")
+
+	sendWebCommand(t, gw, models.WebSocketMessage{
+		Type:  models.MessageTypeStatus,
+		Data:  map[string]any{"battery": 50},
+		AGVID: "sion-002",
+	})
+	data := readUntilType(t, gw, models.MessageTypeError, time.Second).Data.(map[string]any)
+	if data["field"] != "agv_id" || data["agv_id"] != "sion-002" {
+		t.Fatalf("agv_id field error 기대, got %+v", data)
+	}
+	if srv.broker.IsAGVConnectedID("sion-002") || len(srv.cm.AGVIDs()) != 1 {
+		t.Fatalf("선언하지 않은 ID가 묶이면 안 됨: %v", srv.cm.AGVIDs())
+	}
+
+	// 진짜 sion-002는 그 ID로 접속할 수 있다.
+	srv.dialAGV(t, "sion-002")
+	waitFor(t, time.Second, func() bool { return srv.broker.IsAGVConnectedID("sion-002") }, "실제 sion-002 접속 대기")
+
+	// 게이트웨이가 hello로 sion-003을 더 알리면 그 ID로 보고할 수 있다.
+	sendWebCommand(t, gw, models.WebSocketMessage{
+		Type: models.MessageTypeHello,
+		Data: models.AGVHello{AGVID: "sion-001", AGVIDs: []string{"sion-003"}},
+	})
+	waitFor(t, time.Second, func() bool { return srv.broker.IsAGVConnectedID("sion-003") }, "추가 선언한 sion-003 연결 대기")
+}
+
+// AGV 두 대가 붙으면 명령은 agv_id로 지정한 AGV에만 가고, status·연결 상태는 AGV별로 따로 남는다.
+func TestWS_MultiAGV_RegistryKeyedByID(t *testing.T) {
+	srv := newWSTestServer(t)
+	web := srv.dial(t, "/websocket/web")
+	readUntilType(t, web, models.MessageTypeSystemInfo, time.Second)
+
+	agv1 := srv.dialAGV(t, "sion-001")
+	if got := readUntilType(t, web, models.MessageTypeAGVConnected, time.Second); got.AGVID != "sion-001" {
+		t.Fatalf("agv_connected의 agv_id가 sion-001이어야 함: %+v", got)
+	}
+	agv2 := srv.dialAGV(t, "sion-002")
+	got := readUntilType(t, web, models.MessageTypeAGVConnected, time.Second)
+	if got.AGVID != "sion-002" {
+		t.Fatalf("agv_connected의 agv_id가 sion-002여야 함: %+v", got)
+	}
+	if caps := got.Data.(map[string]any)["capabilities"].([]any); len(caps) != 1 || caps[0] != models.CapabilityMove {
+		t.Fatalf("hello의 capabilities가 실려야 함: %+v", got.Data)
+	}
+
+	// agv_id 없이 보낸 status도 연결에 묶인 ID로 기록된다.
+	for i, c := range []*websocket.Conn{agv1, agv2} {
+		raw, _ := json.Marshal(models.WebSocketMessage{
+			Type: models.MessageTypeStatus,
+			Data: models.AGVStatus{Battery: 50 + i},
+		})
+		if err := c.WriteMessage(websocket.TextMessage, raw); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor(t, time.Second, func() bool {
+		s1, s2 := srv.broker.GetAGVStatusByID("sion-001"), srv.broker.GetAGVStatusByID("sion-002")
+		return s1 != nil && s2 != nil && s1.Battery == 50 && s2.Battery == 51
+	}, "AGV별 status가 따로 남아야 함")
+
+	raw, _ := json.Marshal(models.WebSocketMessage{
+		Type:  models.MessageTypeCommand,
+		Data:  map[string]any{"target_x": 3.0, "target_y": 4.0},
+		AGVID: "sion-002",
+	})
+	if err := web.WriteMessage(websocket.TextMessage, raw); err != nil {
+		t.Fatal(err)
+	}
+	if cmd := readUntilType(t, agv2, models.MessageTypeCommand, time.Second); cmd.AGVID != "sion-002" {
+		t.Fatalf("sion-002 명령이어야 함: %+v", cmd)
+	}
+	if err := agv1.SetReadDeadline(time.Now().Add(200 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if _, p, err := agv1.ReadMessage(); err == nil {
+		t.Fatalf("sion-001은 sion-002 명령을 받으면 안 됨: %s", p)
+	}
+
+	_ = agv1.Close()
+	if got := readUntilType(t, web, models.MessageTypeAGVDisconnected, time.Second); got.AGVID != "sion-001" {
+		t.Fatalf("agv_disconnected의 agv_id가 sion-001이어야 함: %+v", got)
+	}
+	if !srv.broker.IsAGVConnectedID("sion-002") || srv.broker.IsAGVConnectedID("sion-001") {
+		t.Fatalf("sion-002만 연결 상태여야 함: %+v", srv.broker.AGVConnections())
+	}
+	if s := srv.broker.GetAGVStatusByID("sion-001"); s == nil {
+		t.Fatal("끊긴 AGV의 마지막 status는 남아 있어야 함")
+	}
+}
+
+// sendWebCommand는 웹 연결로 명령 봉투를 보낸다.
+func sendWebCommand(t *testing.T, web *websocket.Conn, msg models.WebSocketMessage) {
+	t.Helper()
+	raw, _ := json.Marshal(msg)
+	if err := web.WriteMessage(websocket.TextMessage, raw); err != nil {
+		t.Fatalf("Web WriteMessage 실패: %v", err)
+	}
+}
+
+// 대상 AGV가 없거나 지정되지 않았으면 보낸 웹 클라이언트가 error를 받는다.
+func TestWS_WebCommandRouting_Errors(t *testing.T) {
+	srv := newWSTestServer(t)
+	srv.dialAGV(t, "sion-001")
+	srv.dialAGV(t, "sion-002")
+	waitFor(t, time.Second, func() bool { return len(srv.broker.AGVConnections()) == 2 }, "AGV 두 대 연결 대기")
+	web := srv.dial(t, "/websocket/web")
+	readUntilType(t, web, models.MessageTypeSystemInfo, time.Second)
+
+	cases := []struct {
+		name string
+		msg  models.WebSocketMessage
+	}{
+		{"offline", models.WebSocketMessage{Type: models.MessageTypeCommand, Data: map[string]any{"target_x": 1.0, "target_y": 1.0}, AGVID: "sion-009"}},
+		{"ambiguous", models.WebSocketMessage{Type: models.MessageTypeModeChange, Data: map[string]any{"mode": "auto"}}},
+		{"broadcast move", models.WebSocketMessage{Type: models.MessageTypeCommand, Data: map[string]any{"target_x": 1.0, "target_y": 1.0}, AGVID: models.AGVIDBroadcast}},
+	}
+	for _, tc := range cases {
+		sendWebCommand(t, web, tc.msg)
+		got := readUntilType(t, web, models.MessageTypeError, time.Second)
+		data := got.Data.(map[string]any)
+		if data["request_type"] != tc.msg.Type || data["agv_id"] != tc.msg.AGVID || data["message"] == "" {
+			t.Fatalf("%s: 요청 타입·대상이 담긴 error 기대, got %+v", tc.name, data)
+		}
+	}
+}
+
+// 전체 비상 정지는 연결된 모든 AGV에 각자의 agv_id로 간다. agv_id가 비어 있어도 전체로 본다.
+func TestWS_FleetEmergencyStop(t *testing.T) {
+	srv := newWSTestServer(t)
+	agvs := map[string]*websocket.Conn{
+		"sion-001": srv.dialAGV(t, "sion-001"),
+		"sion-002": srv.dialAGV(t, "sion-002"),
+	}
+	waitFor(t, time.Second, func() bool { return len(srv.broker.AGVConnections()) == 2 }, "AGV 두 대 연결 대기")
+	web := srv.dial(t, "/websocket/web")
+	readUntilType(t, web, models.MessageTypeSystemInfo, time.Second)
+
+	for _, target := range []string{models.AGVIDBroadcast, ""} {
+		sendWebCommand(t, web, models.WebSocketMessage{
+			Type:  models.MessageTypeEmergencyStop,
+			Data:  map[string]any{"reason": "fleet"},
+			AGVID: target,
+		})
+		for id, c := range agvs {
+			if got := readUntilType(t, c, models.MessageTypeEmergencyStop, time.Second); got.AGVID != id {
+				t.Fatalf("%q 비상 정지는 %s 주소로 와야 함: %+v", target, id, got)
+			}
+		}
+	}
+}
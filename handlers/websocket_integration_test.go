@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"net"
 	"sion-backend/models"
@@ -72,6 +73,20 @@ func (s *wsTestServer) dial(t *testing.T, path string) *websocket.Conn {
 	return conn
 }
 
+// dialAGV는 /websocket/agv에 접속해 agvID로 hello 핸드셰이크를 보낸다.
+func (s *wsTestServer) dialAGV(t *testing.T, agvID string) *websocket.Conn {
+	t.Helper()
+	conn := s.dial(t, "/websocket/agv")
+	hello := models.WebSocketMessage{
+		Type: models.MessageTypeHello,
+		Data: models.AGVHello{AGVID: agvID, Capabilities: []string{models.CapabilityMove}},
+	}
+	if err := conn.WriteJSON(hello); err != nil {
+		t.Fatalf("hello 전송 실패: %v", err)
+	}
+	return conn
+}
+
 // readJSON은 deadline 안에 한 메시지를 받아 디코드한다.
 func readJSON(t *testing.T, c *websocket.Conn, dst any, deadline time.Duration) {
 	t.Helper()
@@ -135,7 +150,7 @@ func TestWS_AGVConnect_SetsAGVConnected(t *testing.T) {
 		t.Fatalf("초기에 AGV connected가 true이면 안 됨")
 	}
 
-	agv := srv.dial(t, "/websocket/agv")
+	agv := srv.dialAGV(t, "sion-001")
 	waitFor(t, 1*time.Second, srv.broker.IsAGVConnected, "AGV가 연결되어도 IsAGVConnected가 true가 되지 않음")
 	_ = agv.Close()
 	waitFor(t, 1*time.Second, func() bool { return !srv.broker.IsAGVConnected() }, "AGV 연결 종료 후에도 IsAGVConnected가 false가 되지 않음")
@@ -151,7 +166,7 @@ func TestWS_AGVStatusBroadcastsToWeb(t *testing.T) {
 	// 첫 메시지는 system_info welcome — 소비
 	readUntilType(t, web, models.MessageTypeSystemInfo, 1*time.Second)
 
-	agv := srv.dial(t, "/websocket/agv")
+	agv := srv.dialAGV(t, "sion-001")
 	// AGV가 등록 완료되어 broker가 connected = true 되면 web에는 agv_connected 메시지가 가지만
 	// 우린 곧장 status를 보낼 거라 그 알림은 readUntilType이 건너뛴다.
 	waitFor(t, 1*time.Second, srv.broker.IsAGVConnected, "AGV connected wait")
@@ -194,7 +209,7 @@ func TestWS_AGVStatusBroadcastsToWeb(t *testing.T) {
 func TestWS_WebCommandRoutesToAGV(t *testing.T) {
 	srv := newWSTestServer(t)
 
-	agv := srv.dial(t, "/websocket/agv")
+	agv := srv.dialAGV(t, "sion-001")
 	waitFor(t, 1*time.Second, srv.broker.IsAGVConnected, "AGV connected wait")
 
 	web := srv.dial(t, "/websocket/web")
@@ -202,7 +217,7 @@ func TestWS_WebCommandRoutesToAGV(t *testing.T) {
 
 	cmd := models.WebSocketMessage{
 		Type: models.MessageTypeCommand,
-		Data: map[string]any{"action": "go", "x": 5, "y": 6},
+		Data: map[string]any{"target_x": 5, "target_y": 6},
 	}
 	raw, _ := json.Marshal(cmd)
 	if err := web.WriteMessage(websocket.TextMessage, raw); err != nil {
@@ -211,8 +226,45 @@ func TestWS_WebCommandRoutesToAGV(t *testing.T) {
 
 	got := readUntilType(t, agv, models.MessageTypeCommand, 1*time.Second)
 	data := got.Data.(map[string]any)
-	if data["action"].(string) != "go" {
-		t.Fatalf("expected action=go, got %v", data["action"])
+	if data["target_x"] != 5.0 || data["target_y"] != 6.0 {
+		t.Fatalf("expected target=(5,6), got %v", data)
+	}
+}
+
+// 시뮬레이터가 실행 중이면 실제 AGV 없이도 웹 명령이 시뮬레이터로 가고 status에 반영된다.
+func TestWS_WebCommandRoutesToRunningSimulator(t *testing.T) {
+	srv := newWSTestServer(t)
+	sim := services.NewAGVSimulator(srv.broker.BroadcastToWeb)
+	sim.UpdateInterval = 20 * time.Millisecond
+	srv.broker.SetCommandSink(sim)
+	sim.Start()
+	defer sim.Stop()
+
+	web := srv.dial(t, "/websocket/web")
+	readUntilType(t, web, models.MessageTypeSystemInfo, 1*time.Second)
+
+	raw, _ := json.Marshal(models.WebSocketMessage{
+		Type: models.MessageTypeEmergencyStop,
+		Data: models.EmergencyStopCommand{Reason: "통합 테스트"},
+	})
+	if err := web.WriteMessage(websocket.TextMessage, raw); err != nil {
+		t.Fatalf("Web WriteMessage 실패: %v", err)
+	}
+
+	waitFor(t, 2*time.Second, func() bool {
+		status, _, _, _ := sim.Snapshot()
+		return status.State == models.StateEmergency
+	}, "시뮬레이터 비상 정지 대기")
+
+	// 이후 broadcast되는 status도 emergency 상태여야 한다.
+	for i := 0; ; i++ {
+		if i == 50 {
+			t.Fatal("emergency 상태 status를 받지 못함")
+		}
+		msg := readUntilType(t, web, models.MessageTypeStatus, 1*time.Second)
+		if data, ok := msg.Data.(map[string]any); ok && data["state"] == models.StateEmergency {
+			break
+		}
 	}
 }
 
@@ -242,7 +294,7 @@ func TestWS_WebInvalidJSON_ReceivesError(t *testing.T) {
 func TestWS_AGVDisconnect_NotifiesWeb(t *testing.T) {
 	srv := newWSTestServer(t)
 
-	agv := srv.dial(t, "/websocket/agv")
+	agv := srv.dialAGV(t, "sion-001")
 	waitFor(t, 1*time.Second, srv.broker.IsAGVConnected, "AGV connected wait")
 
 	web := srv.dial(t, "/websocket/web")
@@ -261,3 +313,122 @@ func TestWS_AGVDisconnect_NotifiesWeb(t *testing.T) {
 		t.Fatalf("broker.IsAGVConnected가 여전히 true")
 	}
 }
+
+// =====================================================================
+// 가상 AGV: 시뮬레이터가 /websocket/agv로 접속해 실제 AGV 경로를 그대로 탄다
+// =====================================================================
+func TestWS_VirtualAGVEndToEnd(t *testing.T) {
+	srv := newWSTestServer(t)
+
+	web := srv.dial(t, "/websocket/web")
+	readUntilType(t, web, models.MessageTypeSystemInfo, 1*time.Second)
+
+	sim := services.NewAGVSimulator(nil)
+	sim.UpdateInterval = 20 * time.Millisecond
+	vagv := services.NewVirtualAGV("ws://"+srv.addr+"/websocket/agv", sim)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		vagv.Run(ctx)
+		close(done)
+	}()
+	sim.Start()
+	defer sim.Stop()
+
+	readUntilType(t, web, models.MessageTypeAGVConnected, 2*time.Second)
+	readUntilType(t, web, models.MessageTypeStatus, 2*time.Second)
+	waitFor(t, 1*time.Second, func() bool { return srv.broker.GetAGVStatus() != nil }, "Broker가 AGV status 수신")
+
+	// 웹 명령은 Broker → /websocket/agv → 가상 AGV → 시뮬레이터 순으로 전달된다.
+	raw, _ := json.Marshal(models.WebSocketMessage{
+		Type: models.MessageTypeEmergencyStop,
+		Data: models.EmergencyStopCommand{Reason: "가상 AGV 테스트"},
+	})
+	if err := web.WriteMessage(websocket.TextMessage, raw); err != nil {
+		t.Fatalf("Web WriteMessage 실패: %v", err)
+	}
+	waitFor(t, 2*time.Second, func() bool {
+		status := srv.broker.GetAGVStatus()
+		return status != nil && status.State == models.StateEmergency
+	}, "Broker가 emergency status 수신")
+
+	// 접속 뒤 늘어난 AGV는 가상 AGV가 hello를 다시 보내 알린 뒤 보고한다.
+	sim.Stop()
+	if err := sim.SetAGVCount(2); err != nil {
+		t.Fatal(err)
+	}
+	sim.Start()
+	waitFor(t, 2*time.Second, func() bool { return srv.broker.IsAGVConnectedID("sion-002") }, "늘어난 sion-002 연결")
+
+	cancel()
+	<-done
+	readUntilType(t, web, models.MessageTypeAGVDisconnected, 2*time.Second)
+	if srv.broker.IsAGVConnected() {
+		t.Fatal("가상 AGV 종료 후에는 연결 해제 상태여야 함")
+	}
+}
+
+// 실제 AGV도 배터리가 부족하다고 보고하면 Broker가 충전소 좌표로 이동 명령을 내린다.
+func TestWS_BatteryMissionSendsReturnCommandToAGV(t *testing.T) {
+	srv := newWSTestServer(t)
+	srv.broker.SetBatteryMission(services.NewBatteryMission([]models.ChargingDock{{ID: "dock-1", X: 1, Y: 2}}))
+
+	agv := srv.dialAGV(t, "sion-001")
+	waitFor(t, 1*time.Second, srv.broker.IsAGVConnected, "AGV connected wait")
+
+	raw, _ := json.Marshal(models.WebSocketMessage{
+		Type: models.MessageTypeStatus,
+		Data: models.AGVStatus{
+			ID:       "sion-001",
+			Mode:     models.ModeAuto,
+			State:    models.StateSearching,
+			Battery:  10,
+			Position: models.PositionData{X: 20, Y: 2},
+		},
+	})
+	if err := agv.WriteMessage(websocket.TextMessage, raw); err != nil {
+		t.Fatalf("AGV WriteMessage 실패: %v", err)
+	}
+
+	got := readUntilType(t, agv, models.MessageTypeCommand, 1*time.Second)
+	data := got.Data.(map[string]any)
+	if data["target_x"] != 1.0 || data["target_y"] != 2.0 {
+		t.Fatalf("충전소 좌표로 이동 명령 기대, got %+v", data)
+	}
+}
+
+// =====================================================================
+// 장애 주입: AGV 메시지 수신 시 강제 연결 끊김이 평소 끊김 경로를 탄다
+// =====================================================================
+func TestWS_FaultInjectedDisconnect(t *testing.T) {
+	srv := newWSTestServer(t)
+	faults := services.NewFaultInjector()
+	cfg := services.DefaultFaultConfig()
+	cfg.Disconnect.Probability = 1
+	if err := faults.SetConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	srv.broker.SetFaultInjector(faults)
+
+	agv := srv.dialAGV(t, "sion-001")
+	waitFor(t, 1*time.Second, srv.broker.IsAGVConnected, "AGV connected wait")
+	web := srv.dial(t, "/websocket/web")
+	readUntilType(t, web, models.MessageTypeSystemInfo, 1*time.Second)
+
+	raw, _ := json.Marshal(models.WebSocketMessage{Type: models.MessageTypePosition, Data: map[string]any{"x": 1.0, "y": 1.0}})
+	if err := agv.WriteMessage(websocket.TextMessage, raw); err != nil {
+		t.Fatalf("AGV WriteMessage 실패: %v", err)
+	}
+
+	readUntilType(t, web, models.MessageTypeAGVDisconnected, 2*time.Second)
+	waitFor(t, 1*time.Second, func() bool { return srv.cm.GetClientCount()["agv"] == 0 }, "AGV 연결이 풀에서 빠지지 않음")
+	if err := agv.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := agv.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Fatalf("서버가 close 프레임으로 AGV 소켓을 닫아야 함: %v", err)
+	}
+	if faults.Counts()[services.FaultDisconnect] != 1 {
+		t.Fatalf("disconnect 1회 기대: %+v", faults.Counts())
+	}
+}
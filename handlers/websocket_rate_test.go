@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"sion-backend/models"
+	"testing"
+	"time"
+)
+
+// ?rates=로 빈도를 낮춘 클라이언트는 간격 안의 position을 마지막 하나로 합쳐 받는다.
+func TestWS_WebRateLimit_CoalescesTelemetry(t *testing.T) {
+	srv := newWSTestServer(t)
+	web := srv.dial(t, "/websocket/web?rates=position=4")
+	welcome := readUntilType(t, web, models.MessageTypeSystemInfo, time.Second)
+	rates := welcome.Data.(map[string]any)["rates"].(map[string]any)
+	if rates["rates"].(map[string]any)["position"] != 4.0 || rates["defaults"].(map[string]any)["position"] == nil {
+		t.Fatalf("welcome에 빈도 설정과 기본 상한이 실려야 함: %+v", rates)
+	}
+
+	for i := 0; i < 20; i++ {
+		srv.broker.BroadcastToWeb(models.WebSocketMessage{
+			Type:  models.MessageTypePosition,
+			Data:  map[string]any{"x": i},
+			AGVID: "sion-001",
+		})
+	}
+	first := readUntilType(t, web, models.MessageTypePosition, time.Second)
+	if first.Data.(map[string]any)["x"] != 0.0 {
+		t.Fatalf("첫 프레임은 바로 와야 함: %+v", first.Data)
+	}
+	last := readUntilType(t, web, models.MessageTypePosition, time.Second)
+	if last.Data.(map[string]any)["x"] != 19.0 {
+		t.Fatalf("간격 뒤에는 마지막 프레임만 와야 함: %+v", last.Data)
+	}
+	if err := web.SetReadDeadline(time.Now().Add(400 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if _, p, err := web.ReadMessage(); err == nil {
+		t.Fatalf("합친 뒤 더 올 프레임이 없어야 함: %s", p)
+	}
+	if st := srv.cm.QueueStats(); len(st) != 1 || st[0].Coalesced != 18 {
+		t.Fatalf("합쳐진 프레임 18개 기대: %+v", st)
+	}
+}
+
+// set_rate는 바뀐 설정을 rates로 돌려주고, 잘못된 빈도는 error로 거부한다.
+func TestWS_WebSetRate(t *testing.T) {
+	srv := newWSTestServer(t)
+	web := srv.dial(t, "/websocket/web")
+	readUntilType(t, web, models.MessageTypeSystemInfo, time.Second)
+
+	sendWebCommand(t, web, models.WebSocketMessage{
+		Type: models.MessageTypeSetRate,
+		Data: models.RateLimitData{Rates: map[string]float64{"status@sion-001": 1, "position": 2}},
+	})
+	got := readUntilType(t, web, models.MessageTypeRates, time.Second).Data.(map[string]any)
+	if r := got["rates"].(map[string]any); len(r) != 2 || r["status@sion-001"] != 1.0 {
+		t.Fatalf("설정이 반영돼야 함: %+v", got)
+	}
+
+	sendWebCommand(t, web, models.WebSocketMessage{
+		Type: models.MessageTypeSetRate,
+		Data: models.RateLimitData{Rates: map[string]float64{"position": 0}},
+	})
+	got = readUntilType(t, web, models.MessageTypeRates, time.Second).Data.(map[string]any)
+	if r := got["rates"].(map[string]any); len(r) != 1 || r["position"] != nil {
+		t.Fatalf("0은 설정을 지워야 함: %+v", got)
+	}
+
+	sendWebCommand(t, web, models.WebSocketMessage{
+		Type: models.MessageTypeSetRate,
+		Data: models.RateLimitData{Rates: map[string]float64{"position": 1000}},
+	})
+	readUntilType(t, web, models.MessageTypeError, time.Second)
+}
+
+// 한도를 넘은 chat은 버리고 retry_after_ms가 담긴 error를 돌려준다.
+func TestWS_WebInboundRateLimit(t *testing.T) {
+	srv := newWSTestServer(t)
+	web := srv.dial(t, "/websocket/web")
+	readUntilType(t, web, models.MessageTypeSystemInfo, time.Second)
+
+	for i := 0; i < 4; i++ {
+		sendWebCommand(t, web, models.WebSocketMessage{
+			Type: models.MessageTypeChat,
+			Data: models.ChatMessageData{Message: "hi"},
+		})
+	}
+	data := readUntilType(t, web, models.MessageTypeError, time.Second).Data.(map[string]any)
+	if data["request_type"] != models.MessageTypeChat || data["retry_after_ms"].(float64) <= 0 {
+		t.Fatalf("chat 한도 초과 error 기대: %+v", data)
+	}
+}
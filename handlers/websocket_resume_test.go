@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"sion-backend/models"
+	"testing"
+	"time"
+
+	"github.com/fasthttp/websocket"
+)
+
+// readStream은 welcome의 stream 필드를 꺼낸다.
+func readStream(t *testing.T, c *websocket.Conn) models.StreamState {
+	t.Helper()
+	welcome := readUntilType(t, c, models.MessageTypeSystemInfo, time.Second)
+	raw, _ := json.Marshal(welcome.Data.(map[string]any)["stream"])
+	var st models.StreamState
+	if err := json.Unmarshal(raw, &st); err != nil {
+		t.Fatalf("stream 파싱 실패: %v", err)
+	}
+	return st
+}
+
+func broadcastChat(srv *wsTestServer, text string) {
+	srv.broker.BroadcastToWeb(models.WebSocketMessage{
+		Type: models.MessageTypeChatResponse,
+		Data: models.ChatResponseData{Message: text},
+	})
+}
+
+// 재접속한 클라이언트는 마지막 seq 뒤로 놓친 브로드캐스트를 welcome 바로 뒤에 순서대로 받고, 이어서 라이브를 받는다.
+func TestWS_WebResume_ReplaysMissedBroadcasts(t *testing.T) {
+	srv := newWSTestServer(t)
+	web := srv.dial(t, "/websocket/web")
+	first := readStream(t, web)
+	if first.Epoch == "" || first.Resumed {
+		t.Fatalf("새 접속 stream 불일치: %+v", first)
+	}
+
+	broadcastChat(srv, "one")
+	got := readUntilType(t, web, models.MessageTypeChatResponse, time.Second)
+	if got.Seq != first.Seq+1 {
+		t.Fatalf("브로드캐스트에 다음 seq가 붙어야 함: %d → %d", first.Seq, got.Seq)
+	}
+	_ = web.Close()
+	waitFor(t, time.Second, func() bool { return srv.cm.GetClientCount()["web"] == 0 }, "웹 연결 해제 대기")
+
+	broadcastChat(srv, "two")
+	for i := 0; i < 3; i++ {
+		srv.broker.BroadcastToWeb(models.WebSocketMessage{Type: models.MessageTypePosition, Data: map[string]any{"x": i}, AGVID: "sion-001"})
+	}
+	broadcastChat(srv, "three")
+
+	again := srv.dial(t, fmt.Sprintf("/websocket/web?resume_after=%d&epoch=%s", got.Seq, first.Epoch))
+	st := readStream(t, again)
+	if !st.Resumed || st.Snapshot || st.Replayed != 3 {
+		t.Fatalf("chat 둘과 마지막 position 하나를 재전송해야 함: %+v", st)
+	}
+	var replayed []models.WebSocketMessage
+	for i := 0; i < st.Replayed; i++ {
+		var m models.WebSocketMessage
+		readJSON(t, again, &m, time.Second)
+		replayed = append(replayed, m)
+	}
+	if replayed[0].Type != models.MessageTypeChatResponse || replayed[1].Type != models.MessageTypePosition ||
+		replayed[1].Data.(map[string]any)["x"] != 2.0 || replayed[2].Type != models.MessageTypeChatResponse {
+		t.Fatalf("놓친 메시지 순서 불일치: %+v", replayed)
+	}
+	if replayed[2].Seq != st.Seq || replayed[0].Seq <= got.Seq {
+		t.Fatalf("seq 범위 불일치: %+v (stream %+v)", replayed, st)
+	}
+
+	broadcastChat(srv, "live")
+	if live := readUntilType(t, again, models.MessageTypeChatResponse, time.Second); live.Seq != st.Seq+1 {
+		t.Fatalf("재전송 뒤 라이브 seq가 이어져야 함: %+v", live)
+	}
+}
+
+// epoch가 다르거나(서버 재시작) 너무 오래된 seq면 snapshot으로 남은 버퍼를 보낸다.
+func TestWS_WebResume_SnapshotWhenEpochUnknown(t *testing.T) {
+	srv := newWSTestServer(t)
+	broadcastChat(srv, "before")
+
+	web := srv.dial(t, "/websocket/web?resume_after=5&epoch=old")
+	st := readStream(t, web)
+	if !st.Resumed || !st.Snapshot || st.Replayed != 1 {
+		t.Fatalf("snapshot 기대: %+v", st)
+	}
+	if m := readUntilType(t, web, models.MessageTypeChatResponse, time.Second); m.Seq != 1 {
+		t.Fatalf("남은 버퍼를 보내야 함: %+v", m)
+	}
+
+	bad := srv.dial(t, "/websocket/web?resume_after=x")
+	readUntilType(t, bad, models.MessageTypeError, time.Second)
+	if st := readStream(t, bad); st.Resumed {
+		t.Fatalf("잘못된 resume_after는 새 접속으로 본다: %+v", st)
+	}
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"sion-backend/models"
+	"testing"
+	"time"
+
+	"github.com/fasthttp/websocket"
+)
+
+// 형식이 틀린 웹 명령은 AGV로 가지 않고, 보낸 클라이언트가 틀린 필드를 담은 error를 받는다.
+func TestWS_WebCommandValidation_FieldErrors(t *testing.T) {
+	srv := newWSTestServer(t)
+	agv := srv.dialAGV(t, "sion-001")
+	waitFor(t, time.Second, srv.broker.IsAGVConnected, "AGV connected wait")
+	web := srv.dial(t, "/websocket/web")
+	readUntilType(t, web, models.MessageTypeSystemInfo, time.Second)
+
+	cases := []struct {
+		name, field string
+		msg         models.WebSocketMessage
+	}{
+		{"unknown field", "data.topic", models.WebSocketMessage{Type: models.MessageTypeSubscribe, Data: map[string]any{"topics": []string{"status"}, "topic": "x"}}},
+		{"missing target", "data.target_y", models.WebSocketMessage{Type: models.MessageTypeCommand, Data: map[string]any{"target_x": 5}}},
+		{"outside map", "data.target_x", models.WebSocketMessage{Type: models.MessageTypeCommand, Data: map[string]any{"target_x": 500, "target_y": 1}}},
+		{"wrong type", "data.target_y", models.WebSocketMessage{Type: models.MessageTypeCommand, Data: map[string]any{"target_x": 1, "target_y": "one"}}},
+		{"motor speed", "data.left_speed", models.WebSocketMessage{Type: models.MessageTypeMotorControl, Data: map[string]any{"left_speed": 99, "right_speed": 0}}},
+		{"bad mode", "data.mode", models.WebSocketMessage{Type: models.MessageTypeModeChange, Data: map[string]any{"mode": "turbo"}}},
+		{"unknown type", "type", models.WebSocketMessage{Type: "teleport"}},
+	}
+	for _, tc := range cases {
+		tc.msg.AGVID = "sion-001"
+		sendWebCommand(t, web, tc.msg)
+		data := readUntilType(t, web, models.MessageTypeError, time.Second).Data.(map[string]any)
+		if data["field"] != tc.field || data["request_type"] != tc.msg.Type || data["message"] == "" {
+			t.Fatalf("%s: field=%s error 기대, got %+v", tc.name, tc.field, data)
+		}
+	}
+
+	// 거부된 명령은 하나도 AGV에 가지 않았으므로 AGV가 받는 첫 명령은 이것이다.
+	// 서버가 모르는 필드가 붙은 명령은 거부하지 않고 그 필드까지 AGV에 전달한다.
+	sendWebCommand(t, web, models.WebSocketMessage{
+		Type:  models.MessageTypeCommand,
+		Data:  map[string]any{"target_x": 5, "target_y": 6, "speed": 0.5},
+		AGVID: "sion-001",
+	})
+	data := readUntilType(t, agv, models.MessageTypeCommand, time.Second).Data.(map[string]any)
+	if data["target_x"] != 5.0 || data["speed"] != 0.5 {
+		t.Fatalf("확장 필드가 그대로 전달돼야 함 (거부된 명령이 먼저 오면 안 됨): %+v", data)
+	}
+	if err := agv.SetReadDeadline(time.Now().Add(200 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if _, p, err := agv.ReadMessage(); err == nil {
+		t.Fatalf("거부된 명령이 AGV로 가면 안 됨: %s", p)
+	}
+}
+
+// 비상 정지는 data에 모르는 필드가 있거나 객체가 아니거나 없어도 AGV까지 간다.
+func TestWS_EmergencyStopNeverBlockedAtIngress(t *testing.T) {
+	srv := newWSTestServer(t)
+	agv := srv.dialAGV(t, "sion-001")
+	waitFor(t, time.Second, srv.broker.IsAGVConnected, "AGV connected wait")
+	web := srv.dial(t, "/websocket/web")
+	readUntilType(t, web, models.MessageTypeSystemInfo, time.Second)
+
+	for _, data := range []any{
+		map[string]any{"reason": "x", "source": "ui"},
+		"stop",
+		nil,
+		map[string]any{},
+	} {
+		sendWebCommand(t, web, models.WebSocketMessage{Type: models.MessageTypeEmergencyStop, Data: data, AGVID: "sion-001"})
+		got := readUntilType(t, agv, models.MessageTypeEmergencyStop, time.Second)
+		if data == "stop" && got.Data != "stop" {
+			t.Fatalf("받은 data가 그대로 가야 함: %+v", got.Data)
+		}
+		if m, ok := data.(map[string]any); ok && m["source"] != nil && got.Data.(map[string]any)["source"] != "ui" {
+			t.Fatalf("모르는 필드도 그대로 가야 함: %+v", got.Data)
+		}
+	}
+
+	// 봉투 필드의 타입이 틀려도 비상 정지는 간다.
+	if err := web.WriteMessage(websocket.TextMessage, []byte(`{"type":"emergency_stop","agv_id":"sion-001","timestamp":"now"}`)); err != nil {
+		t.Fatal(err)
+	}
+	readUntilType(t, agv, models.MessageTypeEmergencyStop, time.Second)
+}
+
+// 형식이 틀린 AGV 보고는 웹으로 흘리지 않고 AGV에 error로 알린다. 배터리 잔량만 범위를 벗어난 status는
+// 거부하지 않고 잘라서 받는다.
+func TestWS_AGVReportValidation(t *testing.T) {
+	srv := newWSTestServer(t)
+	agv := srv.dialAGV(t, "sion-001")
+	waitFor(t, time.Second, srv.broker.IsAGVConnected, "AGV connected wait")
+	web := srv.dial(t, "/websocket/web")
+	readUntilType(t, web, models.MessageTypeSystemInfo, time.Second)
+
+	sendWebCommand(t, agv, models.WebSocketMessage{
+		Type: models.MessageTypeStatus,
+		Data: map[string]any{"battery": 150, "position": map[string]any{"x": 1, "y": 2}},
+	})
+	got := readUntilType(t, web, models.MessageTypeStatus, time.Second).Data.(map[string]any)
+	if got["battery"] != 100.0 {
+		t.Fatalf("배터리는 100으로 잘려 웹에 가야 함: %+v", got)
+	}
+	if status := srv.broker.GetAGVStatus(); status == nil || status.Battery != 100 || status.Position.Y != 2 {
+		t.Fatalf("잘린 status가 캐시돼야 함: %+v", status)
+	}
+
+	sendWebCommand(t, agv, models.WebSocketMessage{
+		Type: models.MessageTypePosition,
+		Data: map[string]any{"x": 3},
+	})
+	data := readUntilType(t, agv, models.MessageTypeError, time.Second).Data.(map[string]any)
+	if data["field"] != "data.y" {
+		t.Fatalf("data.y error 기대, got %+v", data)
+	}
+	if err := web.SetReadDeadline(time.Now().Add(200 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		_, p, err := web.ReadMessage()
+		if err != nil {
+			break
+		}
+		var msg models.WebSocketMessage
+		if json.Unmarshal(p, &msg) == nil && msg.Type == models.MessageTypePosition {
+			t.Fatalf("거부된 position이 웹으로 가면 안 됨: %s", p)
+		}
+	}
+}
@@ -1,10 +1,12 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"sion-backend/models"
 	"sion-backend/services"
+	"strconv"
 	"time"
 
 	"github.com/gofiber/websocket/v2"
@@ -17,22 +19,57 @@ func NewWebHandler(cm *services.ClientManager, broker *services.Broker, llm *ser
 		stopKeepalive := installKeepalive(cm, c, "Web")
 		defer stopKeepalive()
 
-		welcomeData := map[string]interface{}{
-			"message":       "웹 클라이언트 연결됨",
-			"connected_at":  time.Now().Format(time.RFC3339),
-			"agv_connected": broker.IsAGVConnected(),
-		}
-		if agvStatus := broker.GetAGVStatus(); agvStatus != nil {
-			welcomeData["agv_status"] = agvStatus
+		// ?topics=a,b로 접속하면 처음부터 그 토픽만 받는다. 없으면 전부("*") 받는다.
+		if q := c.Query("topics"); q != "" {
+			topics, err := services.ParseTopicList(q)
+			if err == nil {
+				cm.Unsubscribe(c, []string{services.TopicAll})
+				_, err = cm.Subscribe(c, topics)
+			}
+			if err != nil {
+				log.Printf("[WARN] 접속 topics 무시: %v", err)
+				sendInvalidPayloadError(cm, c, err.Error())
+			}
 		}
-		welcomeMsg := models.WebSocketMessage{
-			Type:      models.MessageTypeSystemInfo,
-			Data:      welcomeData,
-			Timestamp: time.Now().UnixMilli(),
+		// ?rates=position=2,status=1로 접속하면 처음부터 그 빈도로 받는다.
+		if q := c.Query("rates"); q != "" {
+			rates, err := services.ParseRateList(q)
+			if err == nil {
+				_, err = cm.SetRates(c, rates)
+			}
+			if err != nil {
+				log.Printf("[WARN] 접속 rates 무시: %v", err)
+				sendInvalidPayloadError(cm, c, err.Error())
+			}
 		}
-		if err := cm.WriteJSON(c, welcomeMsg); err != nil {
-			log.Printf("[WARN] welcome 메시지 전송 실패: %v", err)
+		limiter := services.NewInboundLimiter(c.RemoteAddr().String())
+
+		// ?resume_after=<seq>&epoch=<epoch>로 재접속하면 welcome 뒤에 놓친 브로드캐스트를 다시 받는다.
+		resume, err := parseResume(c)
+		if err != nil {
+			log.Printf("[WARN] 접속 resume 무시: %v", err)
+			sendInvalidPayloadError(cm, c, err.Error())
 		}
+		broker.AttachWeb(c, resume, func(stream models.StreamState) models.WebSocketMessage {
+			welcomeData := map[string]interface{}{
+				"message":       "웹 클라이언트 연결됨",
+				"connected_at":  time.Now().Format(time.RFC3339),
+				"agv_connected": broker.IsAGVConnected(),
+				"agvs":          broker.AGVConnections(),
+				"agv_statuses":  broker.AGVStatuses(),
+				"subscriptions": cm.Subscriptions(c),
+				"rates":         models.RateLimitData{Rates: cm.Rates(c), Defaults: cm.DefaultRates},
+				"stream":        stream,
+			}
+			if agvStatus := broker.GetAGVStatus(); agvStatus != nil {
+				welcomeData["agv_status"] = agvStatus
+			}
+			return models.WebSocketMessage{
+				Type:      models.MessageTypeSystemInfo,
+				Data:      welcomeData,
+				Timestamp: time.Now().UnixMilli(),
+			}
+		})
 
 		for {
 			_, p, err := c.ReadMessage()
@@ -45,47 +82,63 @@ func NewWebHandler(cm *services.ClientManager, broker *services.Broker, llm *ser
 				break
 			}
 
-			var msg models.WebSocketMessage
-			if err := json.Unmarshal(p, &msg); err != nil {
-				log.Printf("[WARN] 웹 메시지 파싱 오류: %v", err)
-				sendInvalidPayloadError(cm, c, "잘못된 메시지 형식")
+			// 수신 지점에서 한 번만 디코드한다. 이후 msg.Data는 타입별 페이로드 구조체다.
+			msg, decodeErr := broker.Messages().Decode(p)
+			if ok, retry := limiter.Allow(msg.Type, time.Now()); !ok {
+				if !errors.Is(decodeErr, services.ErrMalformedMessage) {
+					sendRateLimitError(cm, c, msg, retry)
+				}
+				continue
+			}
+			if decodeErr != nil {
+				log.Printf("[WARN] 웹 메시지 거부 (%s): %v", msg.Type, decodeErr)
+				sendDecodeError(cm, c, msg, decodeErr)
 				continue
 			}
 
 			if msg.Timestamp == 0 {
 				msg.Timestamp = time.Now().UnixMilli()
 			}
-			go services.LogAGVEvent(msg, "sion-001", "web-user")
+			go services.LogAGVEvent(msg, msg.AGVID, "web-user")
 			log.Printf("[INFO] 웹 메시지: %s", msg.Type)
 
 			switch msg.Type {
 			case models.MessageTypeChat:
-				var chatData models.ChatMessageData
-				raw, err := json.Marshal(msg.Data)
-				if err != nil {
-					log.Printf("[WARN] chat marshal 실패: %v", err)
-					sendInvalidPayloadError(cm, c, "잘못된 chat 페이로드")
-					break
-				}
-				if err := json.Unmarshal(raw, &chatData); err != nil {
-					log.Printf("[WARN] chat 파싱 실패: %v", err)
-					sendInvalidPayloadError(cm, c, "잘못된 chat 페이로드")
-					break
-				}
-				if chatData.Message != "" {
-					go handleChatViaWebSocket(chatData.Message, broker, llm)
-				}
+				chatData, _ := services.PayloadAs[models.ChatMessageData](msg)
+				go handleChatViaWebSocket(chatData.Message, msg.AGVID, broker, llm)
+			case models.MessageTypeSubscribe, models.MessageTypeUnsubscribe:
+				handleSubscription(cm, c, msg)
+			case models.MessageTypeSetRate:
+				handleSetRate(cm, c, msg)
 			case models.MessageTypeCommand,
 				models.MessageTypeModeChange,
-				models.MessageTypeEmergencyStop:
-				broker.OnWebMessage(msg)
+				models.MessageTypeEmergencyStop,
+				models.MessageTypeMotorControl:
+				if err := broker.OnWebMessage(msg, c); err != nil {
+					log.Printf("[WARN] 웹 명령 %s → %q 전달 실패: %v", msg.Type, msg.AGVID, err)
+					sendCommandError(cm, c, msg, err)
+				}
 			default:
 				log.Printf("[WARN] 알 수 없는 메시지 타입: %s", msg.Type)
+				sendDecodeError(cm, c, msg, &services.FieldError{Field: "type", Reason: fmt.Sprintf("웹에서 보낼 수 없는 메시지 타입입니다: %q", msg.Type)})
 			}
 		}
 	}
 }
 
+// parseResume은 접속 URL의 resume_after·epoch를 읽는다. resume_after가 없으면 새 접속(nil).
+func parseResume(c *websocket.Conn) (*services.ResumeRequest, error) {
+	after := c.Query("resume_after")
+	if after == "" {
+		return nil, nil
+	}
+	seq, err := strconv.ParseUint(after, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("잘못된 resume_after %q", after)
+	}
+	return &services.ResumeRequest{After: seq, Epoch: c.Query("epoch")}, nil
+}
+
 func sendInvalidPayloadError(cm *services.ClientManager, c *websocket.Conn, reason string) {
 	errMsg := models.WebSocketMessage{
 		Type:      models.MessageTypeError,
@@ -97,12 +150,128 @@ func sendInvalidPayloadError(cm *services.ClientManager, c *websocket.Conn, reas
 	}
 }
 
-func handleChatViaWebSocket(message string, broker *services.Broker, llm *services.LLMService) {
+// handleSubscription은 subscribe/unsubscribe를 적용하고 바뀐 뒤의 구독 목록을 subscriptions로 돌려준다.
+func handleSubscription(cm *services.ClientManager, c *websocket.Conn, msg models.WebSocketMessage) {
+	data, err := services.PayloadAs[models.SubscriptionData](msg)
+	if err != nil {
+		sendDecodeError(cm, c, msg, err)
+		return
+	}
+
+	var topics []string
+	if msg.Type == models.MessageTypeSubscribe {
+		if topics, err = cm.Subscribe(c, data.Topics); err != nil {
+			sendInvalidPayloadError(cm, c, err.Error())
+			return
+		}
+	} else {
+		topics = cm.Unsubscribe(c, data.Topics)
+	}
+	log.Printf("[INFO] 웹 구독 변경 (%s): %v", c.RemoteAddr(), topics)
+	reply := models.WebSocketMessage{
+		Type:      models.MessageTypeSubscriptions,
+		Data:      models.SubscriptionData{Topics: topics},
+		Timestamp: time.Now().UnixMilli(),
+	}
+	if err := cm.WriteJSON(c, reply); err != nil {
+		log.Printf("[WARN] subscriptions 전송 실패: %v", err)
+	}
+}
+
+// handleSetRate는 set_rate를 적용하고 바뀐 뒤의 설정과 서버 기본 상한을 rates로 돌려준다.
+func handleSetRate(cm *services.ClientManager, c *websocket.Conn, msg models.WebSocketMessage) {
+	data, err := services.PayloadAs[models.RateLimitData](msg)
+	if err != nil {
+		sendDecodeError(cm, c, msg, err)
+		return
+	}
+	rates, err := cm.SetRates(c, data.Rates)
+	if err != nil {
+		sendInvalidPayloadError(cm, c, err.Error())
+		return
+	}
+	log.Printf("[INFO] 웹 갱신 빈도 변경 (%s): %v", c.RemoteAddr(), rates)
+	reply := models.WebSocketMessage{
+		Type:      models.MessageTypeRates,
+		Data:      models.RateLimitData{Rates: rates, Defaults: cm.DefaultRates},
+		Timestamp: time.Now().UnixMilli(),
+	}
+	if err := cm.WriteJSON(c, reply); err != nil {
+		log.Printf("[WARN] rates 전송 실패: %v", err)
+	}
+}
+
+// sendRateLimitError는 요청 한도를 넘은 메시지를 버렸음을 보낸 웹 클라이언트에 알린다.
+func sendRateLimitError(cm *services.ClientManager, c *websocket.Conn, msg models.WebSocketMessage, retry time.Duration) {
+	errMsg := models.WebSocketMessage{
+		Type: models.MessageTypeError,
+		Data: map[string]interface{}{
+			"message":        "요청이 너무 많습니다. 잠시 후 다시 보내세요",
+			"request_type":   msg.Type,
+			"retry_after_ms": retry.Milliseconds(),
+		},
+		Timestamp: time.Now().UnixMilli(),
+		CommandID: msg.CommandID,
+	}
+	if err := cm.WriteJSON(c, errMsg); err != nil {
+		log.Printf("[WARN] 에러 메시지 전송 실패: %v", err)
+	}
+}
+
+// sendCommandError는 명령을 전달하지 못했을 때 보낸 웹 클라이언트에만 error를 돌려준다.
+// 어떤 명령이 어느 AGV로 가다 실패했는지 request_type·agv_id로 알린다.
+func sendCommandError(cm *services.ClientManager, c *websocket.Conn, msg models.WebSocketMessage, cause error) {
+	errMsg := models.WebSocketMessage{
+		Type: models.MessageTypeError,
+		Data: map[string]interface{}{
+			"message":      cause.Error(),
+			"request_type": msg.Type,
+			"agv_id":       msg.AGVID,
+		},
+		Timestamp: time.Now().UnixMilli(),
+		AGVID:     msg.AGVID,
+		CommandID: msg.CommandID,
+	}
+	if err := cm.WriteJSON(c, errMsg); err != nil {
+		log.Printf("[WARN] 에러 메시지 전송 실패: %v", err)
+	}
+}
+
+// sendDecodeError는 디코드·검사에 실패한 메시지를 보낸 쪽에 error로 알린다. 필드 문제면 field에 경로(예: data.target_x)를 담는다.
+func sendDecodeError(cm *services.ClientManager, c *websocket.Conn, msg models.WebSocketMessage, cause error) {
+	var fe *services.FieldError
+	if !errors.As(cause, &fe) {
+		sendInvalidPayloadError(cm, c, cause.Error())
+		return
+	}
+	errMsg := models.WebSocketMessage{
+		Type: models.MessageTypeError,
+		Data: map[string]interface{}{
+			"message":      "잘못된 " + msg.Type + " 메시지: " + fe.Error(),
+			"field":        fe.Field,
+			"reason":       fe.Reason,
+			"request_type": msg.Type,
+			"agv_id":       msg.AGVID,
+		},
+		Timestamp: time.Now().UnixMilli(),
+		AGVID:     msg.AGVID,
+		CommandID: msg.CommandID,
+	}
+	if err := cm.WriteJSON(c, errMsg); err != nil {
+		log.Printf("[WARN] 에러 메시지 전송 실패: %v", err)
+	}
+}
+
+// handleChatViaWebSocket은 agvID AGV의 상태를 근거로 답한다. agvID가 없으면 가장 최근에 보고한 AGV.
+func handleChatViaWebSocket(message, agvID string, broker *services.Broker, llm *services.LLMService) {
 	if llm == nil {
 		log.Println("[WARN] LLM 서비스 미초기화")
 		return
 	}
 	status := broker.GetAGVStatus()
+	if agvID != "" {
+		status = broker.GetAGVStatusByID(agvID)
+	}
 	response, err := llm.AnswerQuestion(message, status)
 	if err != nil {
 		log.Printf("[ERROR] LLM 응답 실패: %v", err)
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"sion-backend/models"
+	"testing"
+	"time"
+
+	"github.com/fasthttp/websocket"
+)
+
+// 구독을 좁힌 웹 클라이언트는 고른 토픽만 받고, 전체를 받는 클라이언트는 그대로 전부 받는다.
+func TestWS_WebSubscriptions_FilterBroadcasts(t *testing.T) {
+	srv := newWSTestServer(t)
+	agv1 := srv.dialAGV(t, "sion-001")
+	agv2 := srv.dialAGV(t, "sion-002")
+	waitFor(t, time.Second, func() bool { return len(srv.broker.AGVConnections()) == 2 }, "AGV 두 대 연결 대기")
+
+	all := srv.dial(t, "/websocket/web")
+	readUntilType(t, all, models.MessageTypeSystemInfo, time.Second)
+	spectator := srv.dial(t, "/websocket/web")
+	readUntilType(t, spectator, models.MessageTypeSystemInfo, time.Second)
+
+	sendWebCommand(t, spectator, models.WebSocketMessage{
+		Type: models.MessageTypeUnsubscribe,
+		Data: models.SubscriptionData{Topics: []string{"*"}},
+	})
+	if got := readUntilType(t, spectator, models.MessageTypeSubscriptions, time.Second); len(got.Data.(map[string]any)["topics"].([]any)) != 0 {
+		t.Fatalf("구독이 비어야 함: %+v", got.Data)
+	}
+	sendWebCommand(t, spectator, models.WebSocketMessage{
+		Type: models.MessageTypeSubscribe,
+		Data: models.SubscriptionData{Topics: []string{"status@sion-002"}},
+	})
+	readUntilType(t, spectator, models.MessageTypeSubscriptions, time.Second)
+
+	send := func(c *websocket.Conn, msgType string, data any) {
+		raw, _ := json.Marshal(models.WebSocketMessage{Type: msgType, Data: data})
+		if err := c.WriteMessage(websocket.TextMessage, raw); err != nil {
+			t.Fatal(err)
+		}
+	}
+	send(agv1, models.MessageTypeStatus, models.AGVStatus{Battery: 10})
+	send(agv2, models.MessageTypePosition, map[string]any{"x": 1.0, "y": 1.0})
+	send(agv2, models.MessageTypeStatus, models.AGVStatus{Battery: 20})
+
+	// 전체 구독자는 세 개 모두 받는다. 두 AGV 연결 사이의 도착 순서는 정해져 있지 않다.
+	seen := map[string]int{}
+	for seen[models.MessageTypeStatus] < 2 || seen[models.MessageTypePosition] < 1 {
+		var msg models.WebSocketMessage
+		readJSON(t, all, &msg, time.Second)
+		seen[msg.Type]++
+	}
+	// 관전자는 sion-002의 status 하나만 받는다.
+	var first models.WebSocketMessage
+	readJSON(t, spectator, &first, time.Second)
+	if first.Type != models.MessageTypeStatus || first.AGVID != "sion-002" {
+		t.Fatalf("sion-002 status만 와야 함: %+v", first)
+	}
+	if err := spectator.SetReadDeadline(time.Now().Add(150 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if _, p, err := spectator.ReadMessage(); err == nil {
+		t.Fatalf("구독하지 않은 메시지가 옴: %s", p)
+	}
+}
+
+// ?topics=로 접속하면 처음부터 그 토픽만 받고, 잘못된 토픽 구독은 error로 거부된다.
+func TestWS_WebSubscriptions_QueryAndValidation(t *testing.T) {
+	srv := newWSTestServer(t)
+	web := srv.dial(t, "/websocket/web?topics=chat,agv_*")
+	info := readUntilType(t, web, models.MessageTypeSystemInfo, time.Second)
+	subs := info.Data.(map[string]any)["subscriptions"].([]any)
+	if len(subs) != 2 || subs[0] != "agv_*" || subs[1] != "chat" {
+		t.Fatalf("접속 토픽이 구독돼야 함: %+v", subs)
+	}
+
+	srv.dialAGV(t, "sion-001")
+	if got := readUntilType(t, web, models.MessageTypeAGVConnected, time.Second); got.AGVID != "sion-001" {
+		t.Fatalf("agv_connected 기대: %+v", got)
+	}
+
+	sendWebCommand(t, web, models.WebSocketMessage{
+		Type: models.MessageTypeSubscribe,
+		Data: models.SubscriptionData{Topics: []string{"[bad"}},
+	})
+	readUntilType(t, web, models.MessageTypeError, time.Second)
+}
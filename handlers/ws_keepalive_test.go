@@ -29,7 +29,7 @@ func TestWS_KeepaliveTimeout_DisconnectsSilentClient(t *testing.T) {
 
 	srv := newWSTestServer(t)
 
-	agv := srv.dial(t, "/websocket/agv")
+	agv := srv.dialAGV(t, "sion-001")
 	// 클라이언트가 ping을 받아도 pong을 자동으로 돌려보내지 않게 한다.
 	agv.SetPingHandler(func(string) error { return nil })
 
@@ -54,7 +54,7 @@ func TestWS_KeepalivePongResponse_StaysConnected(t *testing.T) {
 
 	srv := newWSTestServer(t)
 
-	agv := srv.dial(t, "/websocket/agv")
+	agv := srv.dialAGV(t, "sion-001")
 	waitFor(t, 1*time.Second, srv.broker.IsAGVConnected, "AGV connected wait")
 
 	// pongWait(200ms)의 약 3배가 지나도 살아 있어야 한다.
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"sion-backend/handlers"
 	"sion-backend/models"
 	"sion-backend/services"
+	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -27,7 +29,37 @@ func main() {
 	services.InitLogging(50, 10*time.Second)
 	defer services.StopLogging()
 
+	// 웹·AGV 연결마다 송신 큐를 둔다. WS_SEND_QUEUE_SIZE·WS_SLOW_CONSUMER_TIMEOUT으로 용량과 느린 소비자 판정 시간을 바꾼다.
 	cm := services.NewClientManager()
+	if v := os.Getenv("WS_SEND_QUEUE_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			log.Fatalf("[FATAL] WS_SEND_QUEUE_SIZE 파싱 실패: %q", v)
+		}
+		cm.QueueSize = n
+	}
+	if v := os.Getenv("WS_SLOW_CONSUMER_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("[FATAL] WS_SLOW_CONSUMER_TIMEOUT 파싱 실패: %q", v)
+		}
+		cm.SlowConsumerTimeout = d
+	}
+	// WS_DEFAULT_RATES="position=10,status=5"로 웹에 보내는 텔레메트리 기본 상한(Hz)을 바꾼다. 적은 토픽만 바뀐다.
+	if v := os.Getenv("WS_DEFAULT_RATES"); v != "" {
+		rates, err := services.ParseRateList(v)
+		if err != nil {
+			log.Fatalf("[FATAL] WS_DEFAULT_RATES 파싱 실패: %v", err)
+		}
+		for topic, hz := range rates {
+			if hz == 0 {
+				delete(cm.DefaultRates, topic)
+			} else {
+				cm.DefaultRates[topic] = hz
+			}
+		}
+		log.Printf("[INFO] 웹 텔레메트리 기본 상한: %v", cm.DefaultRates)
+	}
 	br := services.NewBroker(cm)
 
 	handlers.InitLLMService()
@@ -36,6 +68,127 @@ func main() {
 	sim := services.NewAGVSimulator(func(msg models.WebSocketMessage) {
 		br.BroadcastToWeb(msg)
 	})
+	// command 목표 좌표는 시뮬레이터가 받는 명령이면 시뮬레이터 맵으로, 실제 AGV로 가면 AGV_ARENA_SIZE="<가로>,<세로>"로 검사한다.
+	br.Messages().SetSimulatorBounds(sim.CommandBounds)
+	if v := os.Getenv("AGV_ARENA_SIZE"); v != "" {
+		w, h, err := services.ParseArenaBounds(v)
+		if err == nil {
+			err = br.Messages().SetArenaBounds(w, h)
+		}
+		if err != nil {
+			log.Fatalf("[FATAL] AGV_ARENA_SIZE 파싱 실패: %v", err)
+		}
+		log.Printf("[INFO] 실제 AGV 작업 영역 %gx%g", w, h)
+	}
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8001"
+	}
+
+	// 실제 AGV와 시뮬레이터 status 스트림에서 이벤트를 감지해 자동으로 해설을 붙인다.
+	events := services.NewEventDetector(handlers.ExplainAGVEvent)
+	br.SetEventDetector(events)
+
+	// AGV별 세션 통계(주행 거리·시간·격살·충돌)를 쌓고 STATS_PUSH_INTERVAL마다 웹에 푸시한다.
+	stats := services.NewStatsTracker()
+	br.SetStatsTracker(stats)
+	statsInterval := 2 * time.Second
+	if v := os.Getenv("STATS_PUSH_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("[FATAL] STATS_PUSH_INTERVAL 파싱 실패: %q", v)
+		}
+		statsInterval = d
+	}
+	statsCtx, stopStats := context.WithCancel(context.Background())
+	defer stopStats()
+	go stats.Run(statsCtx, statsInterval, br.BroadcastToWeb)
+
+	// 장애 주입은 기본으로 꺼져 있고 /api/faults로 켠다. 시뮬레이터 AGV와 Broker 송수신에 함께 적용된다.
+	faults := services.NewFaultInjector()
+	br.SetFaultInjector(faults)
+	sim.SetFaultInjector(faults)
+
+	// COMMAND_ACK_TIMEOUT_MS·COMMAND_MAX_RETRIES로 AGV 명령 응답 대기 시간과 재전송 횟수를 바꾼다.
+	commands := br.Commands()
+	if v, r := os.Getenv("COMMAND_ACK_TIMEOUT_MS"), os.Getenv("COMMAND_MAX_RETRIES"); v != "" || r != "" {
+		cfg := commands.Config()
+		if v != "" {
+			ms, err := strconv.Atoi(v)
+			if err != nil {
+				log.Fatalf("[FATAL] COMMAND_ACK_TIMEOUT_MS 파싱 실패: %v", err)
+			}
+			cfg.AckTimeoutMs = ms
+		}
+		if r != "" {
+			n, err := strconv.Atoi(r)
+			if err != nil {
+				log.Fatalf("[FATAL] COMMAND_MAX_RETRIES 파싱 실패: %v", err)
+			}
+			cfg.MaxRetries = n
+		}
+		if err := commands.SetConfig(cfg); err != nil {
+			log.Fatalf("[FATAL] 명령 응답 설정 실패: %v", err)
+		}
+		log.Printf("[INFO] 명령 응답 대기 %dms, 재전송 %d회", cfg.AckTimeoutMs, cfg.MaxRetries)
+	}
+
+	// SIMULATOR_MODE=virtual_agv면 시뮬레이터가 /websocket/agv에 실제 AGV처럼 접속해
+	// AGV 프로토콜 경로 전체를 거친다. 기본(direct)은 브로커로 바로 브로드캐스트한다.
+	if os.Getenv("SIMULATOR_MODE") == "virtual_agv" {
+		vagv := services.NewVirtualAGV("ws://127.0.0.1:"+port+"/websocket/agv", sim)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go vagv.Run(ctx)
+		log.Println("[INFO] 시뮬레이터 모드: virtual_agv")
+	} else {
+		br.SetCommandSink(sim)
+		// direct 모드의 시뮬레이터 상태는 Broker를 거치지 않으므로 직접 감지기에 넘긴다.
+		sim.StatusFunc = func(status *models.AGVStatus) { events.Observe(status) }
+		sim.SetStatsTracker(stats)
+	}
+
+	// CHARGING_DOCKS가 있으면 실제 AGV에도 배터리 복귀 판단을 적용한다.
+	if spec := os.Getenv("CHARGING_DOCKS"); spec != "" {
+		docks, err := services.ParseChargingDocks(spec)
+		if err != nil {
+			log.Fatalf("[FATAL] CHARGING_DOCKS 파싱 실패: %v", err)
+		}
+		br.SetBatteryMission(services.NewBatteryMission(docks))
+		log.Printf("[INFO] 충전소 %d개 등록", len(docks))
+	}
+
+	// TARGET_POLICY가 있으면 실제 AGV 결정 로그와 시뮬레이터 모두 그 타겟 선택 정책을 쓴다.
+	if policy := os.Getenv("TARGET_POLICY"); policy != "" {
+		cfg := services.DefaultTargetingConfig()
+		cfg.Policy = policy
+		if v := os.Getenv("TARGET_STICKINESS"); v != "" {
+			stickiness, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				log.Fatalf("[FATAL] TARGET_STICKINESS 파싱 실패: %v", err)
+			}
+			cfg.Stickiness = stickiness
+		}
+		selector, err := services.NewTargetSelector(cfg)
+		if err != nil {
+			log.Fatalf("[FATAL] 타겟 정책 설정 실패: %v", err)
+		}
+		br.SetTargetSelector(selector)
+		if err := sim.SetTargetingConfig(cfg); err != nil {
+			log.Fatalf("[FATAL] 타겟 정책 설정 실패: %v", err)
+		}
+		log.Printf("[INFO] 타겟 선택 정책: %s (stickiness %.2f)", cfg.Policy, cfg.Stickiness)
+	}
+
+	scenarioDir := os.Getenv("SCENARIO_DIR")
+	if scenarioDir == "" {
+		scenarioDir = "scenarios"
+	}
+	scenarios := services.NewScenarioStore(scenarioDir)
+	if err := scenarios.LoadDir(); err != nil {
+		log.Printf("[WARN] 시나리오 로드 실패: %v", err)
+	}
 
 	app := fiber.New()
 	app.Use(logger.New())
@@ -71,10 +224,46 @@ func main() {
 	logsAPI.Get("/type", handlers.HandleGetLogsByEventType)
 	logsAPI.Get("/stats", handlers.HandleGetLogStats)
 
+	faultsAPI := api.Group("/faults")
+	faultsAPI.Get("/", handlers.NewFaultStatusHandler(faults))
+	faultsAPI.Post("/", handlers.NewFaultConfigHandler(faults))
+	faultsAPI.Delete("/", handlers.NewFaultClearHandler(faults))
+	faultsAPI.Post("/disconnect", handlers.NewFaultDisconnectHandler(faults, br))
+
+	api.Get("/agvs", handlers.NewAGVListHandler(br))
+	api.Get("/clients", handlers.NewClientQueueStatsHandler(cm))
+
+	commandsAPI := api.Group("/commands")
+	commandsAPI.Get("/", handlers.NewCommandStatusHandler(commands))
+	commandsAPI.Post("/config", handlers.NewCommandConfigHandler(commands))
+
+	api.Get("/stats", handlers.NewStatsHandler(stats))
+	api.Get("/stats/history", handlers.NewStatsHistoryHandler(stats))
+
 	simAPI := api.Group("/simulator")
 	simAPI.Post("/start", handlers.NewSimulatorStartHandler(sim))
 	simAPI.Post("/stop", handlers.NewSimulatorStopHandler(sim))
 	simAPI.Get("/status", handlers.NewSimulatorStatusHandler(sim))
+	simAPI.Post("/pause", handlers.NewSimulatorPauseHandler(sim))
+	simAPI.Post("/resume", handlers.NewSimulatorResumeHandler(sim))
+	simAPI.Post("/step", handlers.NewSimulatorStepHandler(sim))
+	simAPI.Post("/clock", handlers.NewSimulatorClockHandler(sim))
+	simAPI.Post("/sensors", handlers.NewSimulatorSensorConfigHandler(sim))
+	simAPI.Post("/targeting", handlers.NewSimulatorTargetingHandler(sim))
+	simAPI.Post("/kinematics", handlers.NewSimulatorKinematicsHandler(sim))
+	simAPI.Post("/enemies/:id/behavior", handlers.NewSimulatorEnemyBehaviorHandler(sim))
+	simAPI.Post("/reset", handlers.NewSimulatorResetHandler(sim))
+	simAPI.Post("/enemies/respawn", handlers.NewSimulatorRespawnEnemiesHandler(sim))
+	simAPI.Post("/agvs/:id", handlers.NewSimulatorUpdateAGVHandler(sim))
+	simAPI.Post("/world", handlers.NewSimulatorWorldParamsHandler(sim))
+	simAPI.Get("/snapshots", handlers.NewSimulatorSnapshotListHandler(sim))
+	simAPI.Post("/snapshots", handlers.NewSimulatorSnapshotSaveHandler(sim))
+	simAPI.Post("/snapshots/:name/restore", handlers.NewSimulatorSnapshotRestoreHandler(sim))
+	simAPI.Delete("/snapshots/:name", handlers.NewSimulatorSnapshotDeleteHandler(sim))
+	simAPI.Get("/scenarios", handlers.NewScenarioListHandler(scenarios))
+	simAPI.Post("/scenarios", handlers.NewScenarioUploadHandler(scenarios))
+	simAPI.Get("/scenarios/:name", handlers.NewScenarioGetHandler(scenarios))
+	simAPI.Post("/scenarios/:name/start", handlers.NewScenarioStartHandler(sim, scenarios))
 
 	testAPI := api.Group("/test")
 	testAPI.Post("/position", handlers.NewTestPositionHandler(br))
@@ -92,10 +281,6 @@ func main() {
 	app.Get("/websocket/agv", websocket.New(handlers.NewAGVHandler(cm, br)))
 	app.Get("/websocket/web", websocket.New(handlers.NewWebHandler(cm, br, handlers.GetLLMService())))
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8001"
-	}
 	log.Printf("[INFO] 서버 시작: http://localhost:%s", port)
 	log.Fatal(app.Listen(":" + port))
 }
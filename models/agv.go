@@ -14,6 +14,10 @@ const (
 	StateSearching = "searching"
 	StateStopped   = "stopped"
 	StateEmergency = "emergency"
+
+	// StateCharging은 적에게 돌진하는 상태다. 배터리 충전은 아래 두 상태로 표현한다.
+	StateReturning  = "returning"  // 충전소로 복귀 중
+	StateRecharging = "recharging" // 충전소에서 충전 중
 )
 
 type AGVStatus struct {
@@ -36,6 +40,7 @@ type AGVStatus struct {
 	DetectedEnemies []Enemy `json:"detected_enemies"`
 
 	Sensors SensorData `json:"sensors"`
+	Drive   DriveState `json:"drive"`
 }
 
 type SensorData struct {
@@ -54,6 +59,50 @@ type SensorData struct {
 	ObjectsDetected int  `json:"objects_detected"`
 }
 
+// SensorNoise는 센서 채널 하나의 노이즈 모델.
+// Dropout 확률로 측정이 빠지면 그 채널 값은 0으로 보고되고, LatencyTicks만큼 이전 틱의 측정값이 보고된다.
+type SensorNoise struct {
+	StdDev       float64 `json:"std_dev"`
+	Dropout      float64 `json:"dropout"`
+	LatencyTicks int     `json:"latency_ticks"`
+}
+
+// SensorConfig는 시뮬레이터 센서 설정. Range는 전방/좌/우 거리, IMU는 가속도·자이로, Camera는 ObjectsDetected에 적용된다.
+type SensorConfig struct {
+	MaxRange     float64     `json:"max_range"`
+	CameraFOVDeg float64     `json:"camera_fov_deg"`
+	Range        SensorNoise `json:"range"`
+	IMU          SensorNoise `json:"imu"`
+	Camera       SensorNoise `json:"camera"`
+}
+
+// ChargingDock은 충전소. Rate는 초당 충전량(%)이며 0이면 기본값을 쓴다.
+type ChargingDock struct {
+	ID   string  `json:"id"`
+	X    float64 `json:"x"`
+	Y    float64 `json:"y"`
+	Rate float64 `json:"rate,omitempty"`
+}
+
+// KinematicsConfig는 차동 구동 AGV의 구동 한계. 길이는 셀, 시간은 초 단위.
+// MinTurnRadius는 주행 중 허용하는 최소 회전 반경이며, 선속도가 0일 때의 제자리 회전은 막지 않는다.
+type KinematicsConfig struct {
+	WheelBase       float64 `json:"wheel_base"`
+	MaxWheelSpeed   float64 `json:"max_wheel_speed"`
+	MaxLinearAccel  float64 `json:"max_linear_accel"`
+	MaxAngularAccel float64 `json:"max_angular_accel"`
+	MinTurnRadius   float64 `json:"min_turn_radius"`
+}
+
+// DriveState는 AGV의 실제 구동 상태. Linear는 셀/초, Angular는 rad/s, 바퀴 속도는 셀/초.
+type DriveState struct {
+	Linear     float64 `json:"linear"`
+	Angular    float64 `json:"angular"`
+	LeftWheel  float64 `json:"left_wheel"`
+	RightWheel float64 `json:"right_wheel"`
+}
+
+// MotorControl은 좌우 바퀴 속도(셀/초) 직접 입력. Duration(ms) 동안 유지하며 0 이하면 기본 시간 뒤 멈춘다.
 type MotorControl struct {
 	LeftSpeed  float64 `json:"left_speed"`
 	RightSpeed float64 `json:"right_speed"`
@@ -67,3 +116,15 @@ type AGVStats struct {
 	Collisions      int       `json:"collisions"`
 	StartTime       time.Time `json:"start_time"`
 }
+
+// AGVStatsRecord는 끝난 세션 하나의 누적 통계(DB 저장용). TotalTime은 초 단위.
+type AGVStatsRecord struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	AGVID           string    `gorm:"index" json:"agv_id"`
+	StartTime       time.Time `json:"start_time"`
+	EndTime         time.Time `json:"end_time"`
+	TotalDistance   float64   `json:"total_distance"`
+	TotalTime       int64     `json:"total_time"`
+	EnemiesDefeated int       `json:"enemies_defeated"`
+	Collisions      int       `json:"collisions"`
+}
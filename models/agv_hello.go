@@ -0,0 +1,21 @@
+package models
+
+// AGVHello는 AGV 핸드셰이크 페이로드. AGV는 /websocket/agv에 접속하면 가장 먼저 이 메시지를 보내 자기 ID와 능력을 알린다.
+// 한 연결이 여러 AGV를 대신하는 게이트웨이(가상 AGV 등)는 AGVIDs에 나머지 ID를 적는다. 서버는 hello에 적은
+// ID의 보고만 받으므로, 접속 뒤 늘어난 AGV는 hello를 다시 보내 알린다(대표 AGVID는 바뀌지 않는다).
+type AGVHello struct {
+	AGVID        string   `json:"agv_id"`
+	AGVIDs       []string `json:"agv_ids,omitempty"`
+	Name         string   `json:"name,omitempty"`
+	Version      string   `json:"version,omitempty"`
+	Capabilities []string `json:"capabilities,omitempty"`
+}
+
+// AGV 능력. hello의 capabilities에 넣는다. 서버는 모르는 값도 그대로 보관한다.
+const (
+	CapabilityMove            = "move"
+	CapabilityMotorControl    = "motor_control"
+	CapabilityEmergencyStop   = "emergency_stop"
+	CapabilityTargetSelection = "target_selection"
+	CapabilitySensors         = "sensors"
+)
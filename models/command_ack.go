@@ -0,0 +1,35 @@
+package models
+
+// 명령 진행 상태. command_status의 state 값.
+const (
+	CommandStatePending   = "pending"   // AGV로 보냈고 응답 대기
+	CommandStateRetrying  = "retrying"  // 응답이 없어 같은 command_id로 다시 보냄
+	CommandStateAcked     = "acked"     // AGV가 받았음
+	CommandStateNacked    = "nacked"    // AGV가 거부함
+	CommandStateCompleted = "completed" // AGV가 수행을 마침
+	CommandStateTimeout   = "timeout"   // 재시도를 다 써도 응답 없음
+	CommandStateFailed    = "failed"    // 보낼 수 없거나 응답 전에 연결이 끊김
+	CommandStateReplaced  = "replaced"  // 같은 AGV에 같은 종류의 새 명령이 가서 더 추적하지 않음
+)
+
+// CommandReply는 AGV가 보내는 command_ack/command_nack/command_completed의 페이로드. command_id는 봉투에 싣는다.
+type CommandReply struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// CommandStatus는 명령 진행 상태. 명령을 보낸 웹 클라이언트에 command_status로 보낸다.
+type CommandStatus struct {
+	CommandID   string `json:"command_id"`
+	AGVID       string `json:"agv_id"`
+	CommandType string `json:"command_type"`
+	State       string `json:"state"`
+	Attempts    int    `json:"attempts"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// CommandAckConfig는 명령 응답 대기 설정. AckTimeoutMs 안에 command_ack(또는 nack·completed)가 없으면
+// 같은 command_id로 최대 MaxRetries번 다시 보낸다.
+type CommandAckConfig struct {
+	AckTimeoutMs int `json:"ack_timeout_ms"`
+	MaxRetries   int `json:"max_retries"`
+}
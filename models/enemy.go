@@ -13,6 +13,30 @@ const (
 	EnemyStateEscaped  = "escaped"
 )
 
+// 시뮬레이터 적 행동 모델
+const (
+	EnemyBehaviorStationary = "stationary"
+	EnemyBehaviorPatrol     = "patrol"
+	EnemyBehaviorWander     = "wander"
+	EnemyBehaviorFlee       = "flee"
+	EnemyBehaviorEscape     = "escape"
+)
+
+const (
+	ThreatLow      = "low"
+	ThreatMedium   = "medium"
+	ThreatHigh     = "high"
+	ThreatCritical = "critical"
+)
+
+// 타겟 선택 정책
+const (
+	TargetPolicyWeighted      = "weighted"
+	TargetPolicyNearest       = "nearest"
+	TargetPolicyLowestHP      = "lowest_hp"
+	TargetPolicyHighestThreat = "highest_threat"
+)
+
 type Enemy struct {
 	ID    string `json:"id"`
 	Type  string `json:"type"`
@@ -64,9 +88,26 @@ type PriorityConfig struct {
 	ThreatWeight   float64 `json:"threat_weight"`
 }
 
+// TargetingConfig는 타겟 선택 설정. Weights는 weighted 정책에서만 쓰인다.
+// Stickiness가 0보다 크면 어떤 정책이든 현재 타겟을 우선해, 새 후보 점수가 현재 타겟보다
+// Stickiness 비율 이상 높을 때만 타겟을 바꾼다(히스테리시스).
+type TargetingConfig struct {
+	Policy     string         `json:"policy"`
+	Weights    PriorityConfig `json:"weights"`
+	Stickiness float64        `json:"stickiness"`
+}
+
 type TargetFoundEvent struct {
 	Enemy           Enemy     `json:"enemy"`
 	DetectionMethod string    `json:"detection_method"`
 	Confidence      float64   `json:"confidence"`
 	Timestamp       time.Time `json:"timestamp"`
 }
+
+// EnemyBehaviorConfig는 시뮬레이터 적 한 명의 행동 설정.
+// Waypoints는 patrol에서, TriggerRadius는 flee/escape가 AGV에 반응하는 거리로 쓰인다.
+type EnemyBehaviorConfig struct {
+	Behavior      string           `json:"behavior"`
+	Waypoints     []RealCoordinate `json:"waypoints,omitempty"`
+	TriggerRadius float64          `json:"trigger_radius,omitempty"`
+}
@@ -0,0 +1,54 @@
+package models
+
+// FaultSchedule은 장애가 켜지는 시간대. 기준 시각부터 StartSec 뒤에 켜져 DurationSec 동안 유지되고,
+// PeriodSec가 있으면 그 주기로 반복한다. DurationSec가 0이면 켜진 뒤 계속 유지된다.
+// 기준 시각은 시뮬레이터 장애(센서·배터리·모터)는 시뮬레이션 경과 시간, 메시지·연결 장애는 설정을 적용한 시각이다.
+type FaultSchedule struct {
+	StartSec    float64 `json:"start_sec"`
+	DurationSec float64 `json:"duration_sec"`
+	PeriodSec   float64 `json:"period_sec"`
+}
+
+// FaultSpec은 장애 하나의 발생 조건. 스케줄 시간대 안에서 기회마다(틱·AGV당 또는 메시지당) Probability로 발생한다.
+// Probability가 0이면 꺼져 있다.
+type FaultSpec struct {
+	Probability float64       `json:"probability"`
+	Schedule    FaultSchedule `json:"schedule"`
+}
+
+// MessageDelayFault는 메시지를 MinMs~MaxMs 사이 무작위 시간만큼 늦게 보낸다. 뒤 메시지가 먼저 도착할 수 있다.
+type MessageDelayFault struct {
+	FaultSpec
+	MinMs int `json:"min_ms"`
+	MaxMs int `json:"max_ms"`
+}
+
+// BatteryDropFault는 배터리를 Amount(%)만큼 한 번에 떨어뜨린다.
+type BatteryDropFault struct {
+	FaultSpec
+	Amount int `json:"amount"`
+}
+
+// StuckMotorFault는 DurationSec 동안 바퀴를 묶는다. 제어기는 주행 명령을 계속 내지만 AGV는 움직이지 않는다.
+type StuckMotorFault struct {
+	FaultSpec
+	DurationSec float64 `json:"duration_sec"`
+}
+
+// FaultConfig는 장애 주입 설정. Seed가 0이면 적용 시각으로 난수원을 만든다.
+type FaultConfig struct {
+	Seed int64 `json:"seed"`
+
+	// 시뮬레이터 AGV 틱마다 판정
+	SensorDropout FaultSpec        `json:"sensor_dropout"`
+	BatteryDrop   BatteryDropFault `json:"battery_drop"`
+	StuckMotor    StuckMotorFault  `json:"stuck_motor"`
+
+	// Broker가 웹·AGV로 내보내는 메시지마다 판정
+	MessageDelay     MessageDelayFault `json:"message_delay"`
+	MessageReorder   FaultSpec         `json:"message_reorder"`
+	MessageDuplicate FaultSpec         `json:"message_duplicate"`
+
+	// AGV가 보낸 메시지마다 판정. 발생하면 서버가 AGV 연결을 끊는다.
+	Disconnect FaultSpec `json:"disconnect"`
+}
@@ -9,6 +9,18 @@ const (
 	MessageTypeLog         = "log"
 	MessageTypeTargetFound = "target_found"
 	MessageTypePathUpdate  = "path_update"
+	// MessageTypeTargetSelection은 서버의 타겟 선택 결과(models.TargetSelection). 타겟이 바뀔 때만 보낸다.
+	MessageTypeTargetSelection = "target_selection"
+	// MessageTypeStats는 AGV별 현재 세션 누적 통계(map[AGV ID]AGVStats)의 주기 푸시.
+	MessageTypeStats = "agv_stats"
+	// MessageTypeHello는 AGV가 접속 직후 처음 보내는 핸드셰이크(AGVHello). 이걸 보내야 AGV로 등록된다.
+	MessageTypeHello = "hello"
+	// MessageTypeCommandAck/Nack/Completed는 AGV가 명령(command_id)을 받았음/거부함/수행을 마쳤음을 알리는 응답(CommandReply).
+	MessageTypeCommandAck       = "command_ack"
+	MessageTypeCommandNack      = "command_nack"
+	MessageTypeCommandCompleted = "command_completed"
+	// MessageTypeCollision은 AGV(가상 AGV 포함)가 충돌을 감지했을 때 보내는 보고(CollisionReport). 세션 통계의 충돌 수에 더해진다.
+	MessageTypeCollision = "collision"
 )
 
 // Web -> Server -> AGV
@@ -16,6 +28,8 @@ const (
 	MessageTypeCommand       = "command"
 	MessageTypeModeChange    = "mode_change"
 	MessageTypeEmergencyStop = "emergency_stop"
+	// MessageTypeMotorControl은 바퀴 속도 직접 입력(MotorControl). 수동 모드로 전환된다.
+	MessageTypeMotorControl = "motor_control"
 )
 
 // Chat
@@ -34,12 +48,41 @@ const (
 	MessageTypeAGVConnected    = "agv_connected"
 	MessageTypeAGVDisconnected = "agv_disconnected"
 	MessageTypeError           = "error"
+	MessageTypeSimulationEnd   = "simulation_end"
+	// MessageTypeCommandStatus는 명령을 보낸 웹 클라이언트에 알리는 명령 진행 상태(CommandStatus).
+	MessageTypeCommandStatus = "command_status"
 )
 
+// Web 구독. 웹 클라이언트가 subscribe/unsubscribe(SubscriptionData)로 받을 토픽을 고르면
+// 서버는 바뀐 뒤의 전체 구독 목록을 subscriptions로 돌려준다.
+const (
+	MessageTypeSubscribe     = "subscribe"
+	MessageTypeUnsubscribe   = "unsubscribe"
+	MessageTypeSubscriptions = "subscriptions"
+)
+
+// Web 갱신 빈도. 웹 클라이언트가 set_rate(RateLimitData)로 토픽별 최대 갱신 빈도를 정하면
+// 서버는 바뀐 뒤의 설정과 서버 기본 상한을 rates로 돌려준다.
+const (
+	MessageTypeSetRate = "set_rate"
+	MessageTypeRates   = "rates"
+)
+
+// AGVIDBroadcast는 웹 명령 봉투의 agv_id에 넣어 연결된 모든 AGV에 보내는 주소. emergency_stop에만 쓸 수 있다.
+const AGVIDBroadcast = "*"
+
+// WebSocketMessage는 모든 WS 프레임의 공통 봉투. AGVID는 여러 AGV가 있을 때 어느 AGV의 메시지인지 표시하고,
+// 웹이 보내는 명령(command, mode_change, emergency_stop, motor_control)에서는 받을 AGV를 지정한다.
+// CommandID는 명령과 그 응답(command_ack 등)·진행 상태(command_status)를 잇는다. 웹이 비워 보내면 서버가 붙인다.
+// Seq는 서버가 웹 브로드캐스트에 붙이는 단조 증가 번호. 재접속한 웹 클라이언트가 마지막으로 받은 Seq로
+// 놓친 메시지를 다시 받는다(models.StreamState 참고). 한 클라이언트에만 보내는 응답에는 붙지 않는다.
 type WebSocketMessage struct {
 	Type      string      `json:"type"`
 	Data      interface{} `json:"data"`
 	Timestamp int64       `json:"timestamp"`
+	AGVID     string      `json:"agv_id,omitempty"`
+	CommandID string      `json:"command_id,omitempty"`
+	Seq       uint64      `json:"seq,omitempty"`
 }
 
 type PositionData struct {
@@ -49,6 +92,20 @@ type PositionData struct {
 	Timestamp time.Time `json:"timestamp"`
 }
 
+// PositionReport는 AGV가 보내는 position 페이로드. timestamp 형식은 AGV마다 달라(RFC3339/밀리초) 좌표만 읽는다.
+type PositionReport struct {
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+	Angle float64 `json:"angle"`
+}
+
+// CollisionReport는 AGV가 보내는 collision 페이로드. With는 부딪힌 대상(다른 AGV ID 등)으로, 모르면 비운다.
+type CollisionReport struct {
+	X    float64 `json:"x"`
+	Y    float64 `json:"y"`
+	With string  `json:"with,omitempty"`
+}
+
 type MoveCommand struct {
 	TargetX float64 `json:"target_x"`
 	TargetY float64 `json:"target_y"`
@@ -0,0 +1,12 @@
+package models
+
+// RateLimitData는 set_rate/rates 메시지의 페이로드.
+//
+// Rates는 토픽(SubscriptionData와 같은 형식) → 초당 최대 갱신 횟수(Hz)다. 0을 보내면 그 토픽 설정을 지운다.
+// 예: {"position": 2, "status@sion-001": 1}. 빈도 제한은 position·status처럼 다음 프레임이 앞 프레임을 덮는
+// 텔레메트리에만 적용되며, 간격 안에 온 프레임은 버리지 않고 마지막 것 하나로 합쳐 간격이 지나면 보낸다.
+// 서버 기본 상한(Defaults)보다 높게 적어도 상한이 우선한다.
+type RateLimitData struct {
+	Rates    map[string]float64 `json:"rates"`
+	Defaults map[string]float64 `json:"defaults,omitempty"`
+}
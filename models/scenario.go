@@ -0,0 +1,70 @@
+package models
+
+// Scenario는 시뮬레이터 월드 한 판을 기술하는 파일 포맷(JSON)이다.
+// Map이 있으면 그 그리드의 CellObstacle 셀이 장애물이 되고 맵 크기도 Map을 따른다.
+type Scenario struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+
+	MapWidth  float64    `json:"map_width"`
+	MapHeight float64    `json:"map_height"`
+	Map       *Map       `json:"map,omitempty"`
+	Obstacles []Obstacle `json:"obstacles,omitempty"`
+	// Docks가 없으면 충전소 없이 돌며 배터리 복귀도 일어나지 않는다.
+	Docks []ChargingDock `json:"docks,omitempty"`
+
+	// AGVs가 있으면 그 목록으로 여러 대를 띄우고, 없으면 AGV 한 대만 띄운다.
+	Enemies []ScenarioEnemy `json:"enemies"`
+	AGV     ScenarioAGV     `json:"agv"`
+	AGVs    []ScenarioAGV   `json:"agvs,omitempty"`
+
+	Seed    *int64           `json:"seed,omitempty"`
+	Victory VictoryCondition `json:"victory"`
+}
+
+type ScenarioEnemy struct {
+	ID          string  `json:"id,omitempty"`
+	Name        string  `json:"name"`
+	Type        string  `json:"type,omitempty"`
+	HP          int     `json:"hp"`
+	MaxHP       int     `json:"max_hp,omitempty"`
+	X           float64 `json:"x"`
+	Y           float64 `json:"y"`
+	Speed       float64 `json:"speed,omitempty"`
+	ThreatLevel string  `json:"threat_level,omitempty"`
+
+	Behavior      string           `json:"behavior,omitempty"`
+	Waypoints     []RealCoordinate `json:"waypoints,omitempty"`
+	TriggerRadius float64          `json:"trigger_radius,omitempty"`
+}
+
+// ScenarioAGV는 AGV 시작 상태. Battery를 생략하면 100%로 시작한다.
+// ID/Name을 생략하면 순서대로 sion-001, sion-002, ...가 붙는다.
+type ScenarioAGV struct {
+	ID      string  `json:"id,omitempty"`
+	Name    string  `json:"name,omitempty"`
+	X       float64 `json:"x"`
+	Y       float64 `json:"y"`
+	Angle   float64 `json:"angle"`
+	Battery *int    `json:"battery,omitempty"`
+	Mode    string  `json:"mode,omitempty"`
+}
+
+// VictoryCondition은 시뮬레이션 종료 조건. 둘 다 비어 있으면 수동으로 멈출 때까지 계속 돈다.
+// TimeoutSec은 시뮬레이션 시간(틱 수 × UpdateInterval) 기준이다.
+type VictoryCondition struct {
+	AllEnemiesDefeated bool    `json:"all_enemies_defeated"`
+	TimeoutSec         float64 `json:"timeout_sec,omitempty"`
+}
+
+const (
+	SimOutcomeVictory = "victory"
+	SimOutcomeTimeout = "timeout"
+)
+
+type SimulationEndData struct {
+	Scenario   string              `json:"scenario"`
+	Outcome    string              `json:"outcome"`
+	ElapsedSec float64             `json:"elapsed_sec"`
+	Stats      map[string]AGVStats `json:"stats"`
+}
@@ -0,0 +1,16 @@
+package models
+
+// StreamState는 웹 브로드캐스트 스트림의 위치. 웹 클라이언트 welcome(system_info)의 stream 필드로 온다.
+//
+// 재접속할 때 /websocket/web?resume_after=<마지막으로 받은 seq>&epoch=<Epoch>로 접속하면 서버는 welcome 바로 뒤에
+// 그 뒤로 놓친 브로드캐스트를 seq 순서로 다시 보내고(Resumed), 그다음부터 라이브 메시지를 보낸다.
+// 놓친 메시지가 재전송 버퍼에서 이미 밀려났거나 서버가 재시작돼 Epoch가 다르면 Snapshot이 true다.
+// 이때 클라이언트는 쌓아 둔 상태를 버리고 welcome의 상태와 뒤따르는 재전송 메시지로 다시 그린다.
+// position·status처럼 다음 프레임이 앞 프레임을 덮는 토픽은 마지막 프레임만 다시 보낸다.
+type StreamState struct {
+	Epoch    string `json:"epoch"`
+	Seq      uint64 `json:"seq"`
+	Resumed  bool   `json:"resumed,omitempty"`
+	Snapshot bool   `json:"snapshot,omitempty"`
+	Replayed int    `json:"replayed,omitempty"`
+}
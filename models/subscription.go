@@ -0,0 +1,11 @@
+package models
+
+// SubscriptionData는 subscribe/unsubscribe/subscriptions 메시지의 페이로드.
+//
+// 토픽은 "<종류>" 또는 "<종류>@<AGV ID>" 형식이다. 종류는 메시지 타입(position, status, ...)이나
+// 묶음 이름(chat, logs)이고, 두 부분 모두 path.Match 와일드카드(*, ?, [..])를 쓸 수 있다.
+// 예: "*"(전부), "status@sion-001", "*@sion-00[12]", "position@*", "chat".
+// @ 뒤를 적으면 그 AGV의 메시지만 받는다(agv_id 없는 메시지는 빠진다).
+type SubscriptionData struct {
+	Topics []string `json:"topics"`
+}
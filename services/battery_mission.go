@@ -0,0 +1,216 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sion-backend/models"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 배터리 미션 계층. AGV 상태만 보고 "충전소로 복귀할지 / 충전 중인지 / 임무에 복귀할지"를 판단하며,
+// 실제 이동·충전은 호출자(시뮬레이터 또는 실제 AGV에 명령을 보내는 Broker)가 수행한다.
+
+const (
+	// defaultDrainPerCell은 한 셀 이동당 배터리 소모(%). 시뮬레이터도 바퀴 주행량에 이 값을 곱해 소모한다.
+	defaultDrainPerCell = 2.0
+	// defaultRangeMargin은 예측 주행 거리에 더하는 여유(셀).
+	defaultRangeMargin = 3.0
+	// defaultResumeLevel은 충전을 마치고 임무에 복귀하는 배터리(%).
+	defaultResumeLevel = 95
+	// defaultDockRate는 Rate가 0인 충전소의 초당 충전량(%).
+	defaultDockRate = 5.0
+	// dockArrivalDist는 충전소 도착으로 보는 거리(셀).
+	dockArrivalDist = 0.5
+)
+
+// BatteryDecision은 Evaluate 결과. State는 ""(평상시), StateReturning, StateRecharging 중 하나.
+// Changed는 이번 호출에서 단계가 바뀌었는지, ResumeMode는 충전을 마치고 평상시로 돌아갈 때 복원할 모드.
+type BatteryDecision struct {
+	State      string
+	Dock       models.ChargingDock
+	Changed    bool
+	ResumeMode string
+}
+
+type batteryPhase struct {
+	state    string
+	dock     models.ChargingDock
+	prevMode string
+}
+
+// BatteryMission은 AGV ID별 복귀/충전 단계를 추적한다. 여러 고루틴에서 호출해도 안전하다.
+type BatteryMission struct {
+	Docks []models.ChargingDock
+	// DrainPerCell은 한 셀 이동당 소모(%). 남은 주행 거리 = Battery / DrainPerCell.
+	DrainPerCell float64
+	// Margin은 가장 가까운 충전소까지 거리에 더하는 여유(셀).
+	Margin float64
+	// ResumeLevel은 충전 완료로 보는 배터리(%).
+	ResumeLevel int
+
+	mu     sync.Mutex
+	phases map[string]*batteryPhase
+}
+
+func NewBatteryMission(docks []models.ChargingDock) *BatteryMission {
+	return &BatteryMission{
+		Docks:        docks,
+		DrainPerCell: defaultDrainPerCell,
+		Margin:       defaultRangeMargin,
+		ResumeLevel:  defaultResumeLevel,
+		phases:       make(map[string]*batteryPhase),
+	}
+}
+
+// NearestDock은 (x, y)에서 가장 가까운 충전소와 거리를 반환한다. 충전소가 없으면 ok=false.
+func (m *BatteryMission) NearestDock(x, y float64) (dock models.ChargingDock, dist float64, ok bool) {
+	dist = math.Inf(1)
+	for _, d := range m.Docks {
+		if dd := math.Hypot(d.X-x, d.Y-y); dd < dist {
+			dock, dist, ok = d, dd, true
+		}
+	}
+	return dock, dist, ok
+}
+
+// nearestByPath는 pathLen으로 잰 가장 가까운 충전소를 반환한다. 갈 수 있는 충전소가 없으면 ok=false.
+func nearestByPath(docks []models.ChargingDock, pathLen func(models.ChargingDock) (float64, bool)) (dock models.ChargingDock, dist float64, ok bool) {
+	dist = math.Inf(1)
+	for _, d := range docks {
+		if l, reachable := pathLen(d); reachable && l < dist {
+			dock, dist, ok = d, l, true
+		}
+	}
+	return dock, dist, ok
+}
+
+// RemainingRange는 현재 배터리로 갈 수 있는 예상 거리(셀).
+func (m *BatteryMission) RemainingRange(battery int) float64 {
+	return float64(battery) / m.DrainPerCell
+}
+
+// Evaluate는 status를 보고 다음 단계를 정한다. 비상 정지 중인 AGV는 판단을 멈추고 현재 단계를 유지한다.
+// 충전소까지 거리는 직선으로 잰다. 실제 AGV는 맵을 모르므로 Margin이 장애물을 돌아가는 몫을 대신한다.
+func (m *BatteryMission) Evaluate(status *models.AGVStatus) BatteryDecision {
+	return m.EvaluatePath(status, nil)
+}
+
+// EvaluatePath는 Evaluate와 같되 충전소까지 거리를 pathLen(장애물을 돌아가는 경로 길이)으로 잰다.
+// pathLen이 ok=false를 돌려주는 충전소는 갈 수 없는 것으로 보고 고르지 않는다. nil이면 직선 거리.
+func (m *BatteryMission) EvaluatePath(status *models.AGVStatus, pathLen func(dock models.ChargingDock) (float64, bool)) BatteryDecision {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	x, y := status.Position.X, status.Position.Y
+	phase := m.phases[status.ID]
+	if status.State == models.StateEmergency {
+		if phase == nil {
+			return BatteryDecision{}
+		}
+		return BatteryDecision{State: phase.state, Dock: phase.dock}
+	}
+
+	if phase == nil {
+		dock, dist, ok := m.NearestDock(x, y)
+		if pathLen != nil {
+			dock, dist, ok = nearestByPath(m.Docks, pathLen)
+		}
+		if !ok || m.RemainingRange(status.Battery) >= dist+m.Margin {
+			return BatteryDecision{}
+		}
+		// 이미 충전소 위에 있으면 이동 없이 바로 충전한다.
+		state := models.StateReturning
+		if math.Hypot(dock.X-x, dock.Y-y) <= dockArrivalDist {
+			state = models.StateRecharging
+		}
+		phase = &batteryPhase{state: state, dock: dock, prevMode: status.Mode}
+		m.phases[status.ID] = phase
+		return BatteryDecision{State: phase.state, Dock: dock, Changed: true}
+	}
+
+	switch phase.state {
+	case models.StateReturning:
+		if math.Hypot(phase.dock.X-x, phase.dock.Y-y) <= dockArrivalDist {
+			phase.state = models.StateRecharging
+			return BatteryDecision{State: phase.state, Dock: phase.dock, Changed: true}
+		}
+	case models.StateRecharging:
+		if status.Battery >= m.ResumeLevel {
+			delete(m.phases, status.ID)
+			return BatteryDecision{Dock: phase.dock, Changed: true, ResumeMode: phase.prevMode}
+		}
+	}
+	return BatteryDecision{State: phase.state, Dock: phase.dock}
+}
+
+// Reset은 모든 AGV의 진행 중 단계를 버린다 (월드 재구성 시).
+func (m *BatteryMission) Reset() {
+	m.mu.Lock()
+	m.phases = make(map[string]*batteryPhase)
+	m.mu.Unlock()
+}
+
+// clone은 충전소·설정·AGV별 진행 단계를 복사한 새 미션을 만든다 (월드 스냅샷용).
+func (m *BatteryMission) clone() *BatteryMission {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c := &BatteryMission{
+		Docks:        append([]models.ChargingDock(nil), m.Docks...),
+		DrainPerCell: m.DrainPerCell,
+		Margin:       m.Margin,
+		ResumeLevel:  m.ResumeLevel,
+		phases:       make(map[string]*batteryPhase, len(m.phases)),
+	}
+	for id, p := range m.phases {
+		phase := *p
+		c.phases[id] = &phase
+	}
+	return c
+}
+
+// Forget은 AGV 한 대의 진행 단계를 버린다 (AGV를 수동으로 옮기거나 배터리를 바꿨을 때).
+func (m *BatteryMission) Forget(agvID string) {
+	m.mu.Lock()
+	delete(m.phases, agvID)
+	m.mu.Unlock()
+}
+
+// ParseChargingDocks는 "x,y[,rate];x,y[,rate]" 형식(CHARGING_DOCKS 환경변수)을 충전소 목록으로 바꾼다.
+// ID는 순서대로 dock-1, dock-2, ...가 붙는다.
+func ParseChargingDocks(spec string) ([]models.ChargingDock, error) {
+	var docks []models.ChargingDock
+	for i, part := range strings.Split(spec, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		fields := strings.Split(part, ",")
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("충전소 %d 형식 오류: %q (x,y[,rate])", i+1, part)
+		}
+		vals := make([]float64, len(fields))
+		for j, f := range fields {
+			v, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+			if err != nil {
+				return nil, fmt.Errorf("충전소 %d 숫자 오류: %q", i+1, f)
+			}
+			vals[j] = v
+		}
+		dock := models.ChargingDock{ID: fmt.Sprintf("dock-%d", len(docks)+1), X: vals[0], Y: vals[1]}
+		if len(vals) == 3 {
+			dock.Rate = vals[2]
+		}
+		docks = append(docks, dock)
+	}
+	return docks, nil
+}
+
+// dockRate는 충전소의 초당 충전량(%)을 반환한다.
+func dockRate(d models.ChargingDock) float64 {
+	if d.Rate > 0 {
+		return d.Rate
+	}
+	return defaultDockRate
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"sion-backend/models"
+	"testing"
+)
+
+func TestBatteryMission_Transitions(t *testing.T) {
+	m := NewBatteryMission([]models.ChargingDock{{ID: "dock-1", X: 0, Y: 0}, {ID: "dock-2", X: 20, Y: 0}})
+	status := &models.AGVStatus{ID: "sion-001", Mode: models.ModeAuto, Battery: 100, Position: models.PositionData{X: 16, Y: 0}}
+
+	if d := m.Evaluate(status); d.State != "" || d.Changed {
+		t.Fatalf("배터리 충분하면 평상시여야 함: %+v", d)
+	}
+
+	// 가장 가까운 dock-2까지 4셀 + 여유 3셀 = 7셀. 2%/셀이면 14% 미만에서 복귀.
+	status.Battery = 13
+	d := m.Evaluate(status)
+	if d.State != models.StateReturning || !d.Changed || d.Dock.ID != "dock-2" {
+		t.Fatalf("dock-2로 복귀해야 함: %+v", d)
+	}
+	if d := m.Evaluate(status); d.Changed {
+		t.Fatalf("같은 단계 반복 평가는 Changed=false여야 함: %+v", d)
+	}
+
+	status.Position = models.PositionData{X: 19.8, Y: 0}
+	status.Mode = models.ModeManual // 복귀 중 모드가 바뀌어도 복원은 원래 모드로
+	if d := m.Evaluate(status); d.State != models.StateRecharging || !d.Changed {
+		t.Fatalf("도착하면 충전 단계여야 함: %+v", d)
+	}
+
+	status.Battery = 94
+	if d := m.Evaluate(status); d.State != models.StateRecharging || d.Changed {
+		t.Fatalf("ResumeLevel 전에는 계속 충전: %+v", d)
+	}
+	status.Battery = 95
+	d = m.Evaluate(status)
+	if d.State != "" || !d.Changed || d.ResumeMode != models.ModeAuto {
+		t.Fatalf("충전 완료 후 auto로 복귀해야 함: %+v", d)
+	}
+}
+
+func TestBatteryMission_EmergencyFreezesPhase(t *testing.T) {
+	m := NewBatteryMission([]models.ChargingDock{{ID: "dock-1", X: 0, Y: 0}})
+	status := &models.AGVStatus{ID: "sion-001", Battery: 5, State: models.StateEmergency, Position: models.PositionData{X: 10, Y: 0}}
+	if d := m.Evaluate(status); d.State != "" {
+		t.Fatalf("비상 정지 중에는 복귀를 시작하지 않아야 함: %+v", d)
+	}
+	if d := NewBatteryMission(nil).Evaluate(status); d.State != "" {
+		t.Fatalf("충전소가 없으면 아무 것도 하지 않아야 함: %+v", d)
+	}
+}
+
+func TestParseChargingDocks(t *testing.T) {
+	docks, err := ParseChargingDocks("1,2; 10.5,3,8")
+	if err != nil {
+		t.Fatalf("파싱 실패: %v", err)
+	}
+	if len(docks) != 2 || docks[1] != (models.ChargingDock{ID: "dock-2", X: 10.5, Y: 3, Rate: 8}) {
+		t.Fatalf("파싱 결과가 다름: %+v", docks)
+	}
+	for _, bad := range []string{"1", "a,b", "1,2,3,4"} {
+		if _, err := ParseChargingDocks(bad); err == nil {
+			t.Fatalf("%q는 실패해야 함", bad)
+		}
+	}
+}
+
+// 배터리가 부족한 AGV가 충전소로 가서 충전한 뒤 임무에 복귀한다.
+func TestSimulator_ReturnsToDockAndRecharges(t *testing.T) {
+	seed := int64(2)
+	battery := 12
+	sim := newScenarioSimulator(t, models.Scenario{
+		Name:      "dock",
+		MapWidth:  10,
+		MapHeight: 10,
+		Docks:     []models.ChargingDock{{ID: "dock-1", X: 1.5, Y: 1.5, Rate: 20}},
+		AGV:       models.ScenarioAGV{X: 5.5, Y: 1.5, Battery: &battery, Mode: models.ModeManual},
+		Seed:      &seed,
+	})
+	a := sim.agvs[0]
+
+	sim.stepLocked()
+	if a.Status.State != models.StateReturning {
+		t.Fatalf("복귀를 시작해야 함: state=%s battery=%d", a.Status.State, a.Status.Battery)
+	}
+
+	sawRecharging := false
+	for i := 0; i < 100 && a.Status.State != models.StateIdle; i++ {
+		sim.stepLocked()
+		if a.Status.State == models.StateRecharging && !sawRecharging {
+			sawRecharging = true
+			// 충전 중에 모드가 바뀌어도 충전을 마치면 복귀 전 모드(manual)로 돌아가야 한다.
+			a.Status.Mode = models.ModeAuto
+		}
+	}
+	if !sawRecharging {
+		t.Fatal("충전 단계를 거치지 않음")
+	}
+	if a.distanceTo(1.5, 1.5) > dockArrivalDist {
+		t.Fatalf("충전소에 있어야 함: %+v", a.Status.Position)
+	}
+	if a.Status.Battery < defaultResumeLevel || a.Status.State != models.StateIdle || a.Status.Mode != models.ModeManual {
+		t.Fatalf("충전 완료 후 manual 대기여야 함: state=%s mode=%s battery=%d", a.Status.State, a.Status.Mode, a.Status.Battery)
+	}
+}
+
+// 충전소가 벽 너머에 있으면 직선 거리로는 충분해 보여도 돌아가는 경로 길이로 복귀를 판단한다.
+func TestSimulator_ReturnDecisionUsesPathAroundWall(t *testing.T) {
+	seed := int64(2)
+	battery := 20 // 주행 가능 10셀: 직선 4셀+여유 3셀에는 충분, 벽을 돌아가는 경로에는 부족
+	sc := models.Scenario{
+		Name:      "dock-wall",
+		MapWidth:  10,
+		MapHeight: 10,
+		Docks:     []models.ChargingDock{{ID: "dock-1", X: 1.5, Y: 1.5}},
+		AGV:       models.ScenarioAGV{X: 5.5, Y: 1.5, Battery: &battery, Mode: models.ModeManual},
+		Seed:      &seed,
+	}
+	for row := 0; row < 9; row++ {
+		sc.Obstacles = append(sc.Obstacles, models.Obstacle{Position: models.GridCoordinate{Row: row, Col: 3}, Size: 1})
+	}
+	sim := newScenarioSimulator(t, sc)
+	a := sim.agvs[0]
+	if d := NewBatteryMission(sc.Docks).Evaluate(a.Status); d.State != "" {
+		t.Fatalf("직선 거리로는 복귀하지 않는 배터리여야 함: %+v", d)
+	}
+
+	sim.stepLocked()
+	if a.Status.State != models.StateReturning {
+		t.Fatalf("벽을 돌아가는 경로 길이로는 복귀해야 함: state=%s", a.Status.State)
+	}
+}
+
+func TestSimulator_DrainedAGVStops(t *testing.T) {
+	seed := int64(2)
+	battery := 0
+	sim := newScenarioSimulator(t, models.Scenario{
+		Name:      "drained",
+		MapWidth:  10,
+		MapHeight: 10,
+		AGV:       models.ScenarioAGV{X: 5, Y: 5, Battery: &battery},
+		Seed:      &seed,
+	})
+	for i := 0; i < 5; i++ {
+		sim.stepLocked()
+	}
+	a := sim.agvs[0]
+	if a.Status.State != models.StateStopped || a.Status.Position.X != 5 || a.Status.Position.Y != 5 {
+		t.Fatalf("방전된 AGV는 제자리에 멈춰야 함: state=%s pos=%+v", a.Status.State, a.Status.Position)
+	}
+}
@@ -2,62 +2,475 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 
 	"sion-backend/models"
+
+	"github.com/gofiber/websocket/v2"
 )
 
+// CommandSink는 실제 AGV 대신 웹 명령을 받을 수 있는 대상(시뮬레이터).
+// 명령을 처리했으면 true, 비활성 상태라 처리하지 않았으면 false를 반환한다.
+type CommandSink interface {
+	HandleWebCommand(msg models.WebSocketMessage) bool
+}
+
+// AGVConnection은 핸드셰이크를 마치고 연결된 AGV 하나의 정보.
+type AGVConnection struct {
+	AGVID        string    `json:"agv_id"`
+	Name         string    `json:"name,omitempty"`
+	Version      string    `json:"version,omitempty"`
+	Capabilities []string  `json:"capabilities"`
+	ConnectedAt  time.Time `json:"connected_at"`
+}
+
 type Broker struct {
-	cm           *ClientManager
-	agvStatus    *models.AGVStatus
-	agvConnected bool
-	mu           sync.RWMutex
+	cm *ClientManager
+	// statuses는 AGV ID별 마지막 status 보고. 연결이 끊겨도 마지막 값은 남겨 둔다.
+	statuses map[string]*models.AGVStatus
+	// lastStatusID는 가장 최근에 status를 보고한 AGV. GetAGVStatus가 이 AGV를 대표로 돌려준다.
+	lastStatusID string
+	connections  map[string]AGVConnection
+	commandSink  CommandSink
+	mission      *BatteryMission
+	targeting    *TargetSelector
+	events       *EventDetector
+	stats        *StatsTracker
+	// selfTargeting은 target_selection을 직접 보고하는 AGV(가상 AGV 등). 이 AGV들은 Broker가 타겟 선택을 대신하지 않는다.
+	selfTargeting map[string]bool
+	// faults가 있으면 웹·AGV로 내보내는 메시지에 지연·순서 바꿈·중복을, AGV 수신에 강제 연결 끊김을 일으킨다.
+	faults           *FaultInjector
+	webLink, agvLink *faultLink
+	// commands는 실제 AGV로 보낸 명령의 응답(ack/nack/completed)을 기다린다.
+	commands *CommandTracker
+	// messages는 WebSocket 수신 지점에서 메시지를 디코드·검사하는 타입 레지스트리.
+	messages *MessageRegistry
+	mu       sync.RWMutex
+
+	// streamMu는 웹 브로드캐스트 스트림 락. seq 부여·재전송 버퍼 기록·송신 큐 적재를 한 번에 해서
+	// 모든 웹 클라이언트가 seq 순서대로 받게 한다. mu보다 먼저 잡는다.
+	streamMu sync.Mutex
+	epoch    string
+	seq      uint64
+	replay   *replayBuffer
+}
+
+// ResumeRequest는 재접속한 웹 클라이언트가 마지막으로 받은 브로드캐스트 위치.
+type ResumeRequest struct {
+	After uint64
+	Epoch string
 }
 
 func NewBroker(cm *ClientManager) *Broker {
-	return &Broker{cm: cm}
+	targeting, _ := NewTargetSelector(DefaultTargetingConfig())
+	b := &Broker{
+		cm:            cm,
+		statuses:      make(map[string]*models.AGVStatus),
+		connections:   make(map[string]AGVConnection),
+		targeting:     targeting,
+		selfTargeting: make(map[string]bool),
+		webLink:       newFaultLink("web", func(_ string, raw []byte) { cm.BroadcastToWeb(raw) }),
+		epoch:         strconv.FormatInt(time.Now().UnixNano(), 36),
+		replay:        newReplayBuffer(defaultReplayPerTopic),
+		messages:      NewMessageRegistry(),
+	}
+	b.agvLink = newFaultLink("agv", b.writeToAGV)
+	b.commands = NewCommandTracker(b.sendCommand, b.notifyCommand, b.escalateCommand)
+	return b
+}
+
+// Messages는 메시지 타입 레지스트리를 반환한다. 핸들러가 수신한 프레임을 디코드할 때와 맵 크기 연결에 쓴다.
+func (b *Broker) Messages() *MessageRegistry {
+	return b.messages
+}
+
+// Commands는 명령 응답 추적기를 반환한다. 설정 변경과 대기 목록 조회에 쓴다.
+func (b *Broker) Commands() *CommandTracker {
+	return b.commands
 }
 
+// GetAGVStatus는 가장 최근에 status를 보고한 AGV의 상태를 반환한다. 특정 AGV는 GetAGVStatusByID.
 func (b *Broker) GetAGVStatus() *models.AGVStatus {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
-	return b.agvStatus
+	return b.statuses[b.lastStatusID]
+}
+
+func (b *Broker) GetAGVStatusByID(agvID string) *models.AGVStatus {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.statuses[agvID]
+}
+
+// AGVStatuses는 AGV ID별 마지막 status를 반환한다. 연결이 끊긴 AGV의 마지막 값도 포함한다.
+func (b *Broker) AGVStatuses() map[string]*models.AGVStatus {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	out := make(map[string]*models.AGVStatus, len(b.statuses))
+	for id, s := range b.statuses {
+		out[id] = s
+	}
+	return out
 }
 
 func (b *Broker) setAGVStatus(status *models.AGVStatus) {
 	b.mu.Lock()
-	b.agvStatus = status
+	b.statuses[status.ID] = status
+	b.lastStatusID = status.ID
 	b.mu.Unlock()
 }
 
 func (b *Broker) OnAGVMessage(msg models.WebSocketMessage, rawBytes []byte) {
+	if f := b.faultInjector(); f != nil && f.RollDisconnect() {
+		log.Printf("[WARN] 장애 주입: AGV 연결 강제 종료 (%s 수신 중)", msg.Type)
+		b.ForceDisconnect()
+		return
+	}
 	switch msg.Type {
 	case models.MessageTypeStatus:
-		dataRaw, err := json.Marshal(msg.Data)
+		status, err := PayloadAs[models.AGVStatus](msg)
 		if err != nil {
-			log.Printf("[WARN] status marshal 실패: %v", err)
-			break
-		}
-		var status models.AGVStatus
-		if err := json.Unmarshal(dataRaw, &status); err != nil {
 			log.Printf("[WARN] status 파싱 실패: %v", err)
 			break
 		}
+		// 봉투의 agv_id는 AGV 핸들러가 연결에 묶인 ID로 채운 값이라 본문보다 믿을 만하다.
+		if msg.AGVID != "" {
+			status.ID = msg.AGVID
+		}
 		b.setAGVStatus(&status)
+		// position을 따로 보내지 않는 AGV도 있어 status의 위치로도 주행 거리·시간을 센다.
+		b.observePosition(status.ID, status.Position)
+		b.sendWeb(rawBytes)
+		b.applyBatteryMission(&status)
+		b.applyTargeting(&status)
+		b.detectEvents(&status)
+		return
+	case models.MessageTypePosition:
+		if pos, err := PayloadAs[models.PositionReport](msg); err != nil {
+			log.Printf("[WARN] position 파싱 실패: %v", err)
+		} else {
+			b.observePosition(msg.AGVID, models.PositionData{X: pos.X, Y: pos.Y, Angle: pos.Angle})
+		}
+	case models.MessageTypeCollision:
+		b.recordCollision(msg.AGVID)
+	case models.MessageTypeTargetSelection:
+		b.mu.Lock()
+		b.selfTargeting[msg.AGVID] = true
+		b.mu.Unlock()
+	case models.MessageTypeCommandAck, models.MessageTypeCommandNack, models.MessageTypeCommandCompleted:
+		// 응답은 웹에 그대로 흘리지 않고 명령을 보낸 클라이언트에 command_status로 알린다.
+		b.onCommandReply(msg)
+		return
+	}
+
+	b.sendWeb(rawBytes)
+}
+
+// SetBatteryMission은 실제 AGV에 배터리 복귀 판단을 적용한다. nil이면 해제.
+func (b *Broker) SetBatteryMission(m *BatteryMission) {
+	b.mu.Lock()
+	b.mission = m
+	b.mu.Unlock()
+}
+
+// applyBatteryMission은 status 보고마다 배터리 미션을 평가하고, 단계가 바뀌면 AGV에 명령을 내린다.
+// 복귀 시작 → 충전소 좌표로 command, 충전 완료 → 복귀 전 모드로 mode_change. 충전 자체는 AGV가 도킹해서 수행한다.
+func (b *Broker) applyBatteryMission(status *models.AGVStatus) {
+	b.mu.RLock()
+	m := b.mission
+	b.mu.RUnlock()
+	if m == nil {
+		return
+	}
+	d := m.Evaluate(status)
+	if !d.Changed {
+		return
+	}
+
+	var cmd models.WebSocketMessage
+	var text string
+	now := time.Now().UnixMilli()
+	switch d.State {
+	case models.StateReturning:
+		cmd = models.WebSocketMessage{
+			Type:      models.MessageTypeCommand,
+			Data:      models.MoveCommand{TargetX: d.Dock.X, TargetY: d.Dock.Y, Mode: status.Mode},
+			Timestamp: now,
+		}
+		text = fmt.Sprintf("배터리 %d%% — %s 충전소로 복귀", status.Battery, d.Dock.ID)
+		go LogCommand(status.ID, "return_to_base", d.Dock.X, d.Dock.Y)
+	case models.StateRecharging:
+		text = fmt.Sprintf("%s 충전소 도착, 충전 대기", d.Dock.ID)
+	default:
+		if d.ResumeMode != "" {
+			cmd = models.WebSocketMessage{
+				Type:      models.MessageTypeModeChange,
+				Data:      models.ModeChangeCommand{Mode: d.ResumeMode},
+				Timestamp: now,
+			}
+		}
+		text = fmt.Sprintf("충전 완료 (%d%%), 임무 복귀", status.Battery)
+	}
+	log.Printf("[INFO] AGV %s", text)
+
+	if cmd.Type != "" {
+		cmd.AGVID = status.ID
+		if _, err := b.commands.Track(cmd, nil); err != nil {
+			log.Printf("[WARN] 배터리 미션 명령 전송 실패: %v", err)
+		}
+	}
+	b.BroadcastToWeb(models.WebSocketMessage{
+		Type:      models.MessageTypeLog,
+		Data:      map[string]interface{}{"message": text, "dock_id": d.Dock.ID, "battery": status.Battery},
+		Timestamp: now,
+		AGVID:     status.ID,
+	})
+}
+
+// SetTargetSelector는 실제 AGV 결정 로그에 쓸 타겟 선택 정책을 바꾼다. nil이면 해제.
+func (b *Broker) SetTargetSelector(s *TargetSelector) {
+	b.mu.Lock()
+	b.targeting = s
+	b.mu.Unlock()
+}
+
+// applyTargeting은 실제 AGV가 보고한 탐지 목록으로 타겟을 평가하고, 타겟이 바뀌면 결정을 로그로 남기고 웹에 알린다.
+// AGV는 스스로 타겟을 고르므로 명령은 보내지 않는다.
+func (b *Broker) applyTargeting(status *models.AGVStatus) {
+	b.mu.RLock()
+	s := b.targeting
+	self := b.selfTargeting[status.ID]
+	b.mu.RUnlock()
+	if s == nil || self {
+		return
+	}
+	if status.Mode != models.ModeAuto {
+		s.Clear(status.ID)
+		return
+	}
+	// 결정은 status마다 내리지만, 타겟이 그대로면 targetSelectionInterval마다 한 번만 내보낸다.
+	sel, changed := s.Select(status, status.DetectedEnemies)
+	if sel == nil || !s.ShouldEmit(status.ID, changed, time.Now()) {
+		return
+	}
+	if changed {
+		log.Printf("[INFO] AGV %s 타겟 선택: %s %s", status.ID, sel.SelectedEnemy.Name, sel.Reason)
+	}
+	go LogTargetSelection(status.ID, sel)
+	b.BroadcastToWeb(models.WebSocketMessage{
+		Type:      models.MessageTypeTargetSelection,
+		Data:      sel,
+		Timestamp: time.Now().UnixMilli(),
+		AGVID:     status.ID,
+	})
+}
+
+// SetEventDetector는 실제 AGV status 스트림에 이벤트 감지(자동 해설)를 붙인다. nil이면 해제.
+func (b *Broker) SetEventDetector(d *EventDetector) {
+	b.mu.Lock()
+	b.events = d
+	b.mu.Unlock()
+}
+
+func (b *Broker) detectEvents(status *models.AGVStatus) {
+	b.mu.RLock()
+	d := b.events
+	b.mu.RUnlock()
+	if d == nil {
+		return
+	}
+	for _, ev := range d.Observe(status) {
+		if ev == EventKill {
+			b.recordKill(status.ID)
+		}
+	}
+}
+
+// SetStatsTracker는 실제 AGV의 위치·격살 보고를 세션 통계로 쌓게 한다. nil이면 해제.
+func (b *Broker) SetStatsTracker(t *StatsTracker) {
+	b.mu.Lock()
+	b.stats = t
+	b.mu.Unlock()
+}
+
+// observePosition은 실제 AGV의 위치 보고(position 또는 status)를 세션 통계에 반영한다. agvID가 비면 마지막으로 보고한 AGV.
+func (b *Broker) observePosition(agvID string, pos models.PositionData) {
+	b.mu.RLock()
+	t := b.stats
+	if agvID == "" {
+		agvID = b.lastStatusID
+	}
+	b.mu.RUnlock()
+	if t != nil {
+		t.ObservePosition(agvID, pos, time.Now())
+	}
+}
+
+// recordCollision은 실제 AGV의 collision 보고를 세션 통계에 반영한다.
+func (b *Broker) recordCollision(agvID string) {
+	b.mu.RLock()
+	t := b.stats
+	if agvID == "" {
+		agvID = b.lastStatusID
+	}
+	b.mu.RUnlock()
+	if t != nil {
+		t.RecordCollision(agvID)
+	}
+}
+
+func (b *Broker) recordKill(agvID string) {
+	b.mu.RLock()
+	t := b.stats
+	b.mu.RUnlock()
+	if t != nil {
+		t.RecordKill(agvID)
+	}
+}
+
+// SetCommandSink는 웹 명령을 가로챌 시뮬레이터를 등록한다. nil이면 해제.
+func (b *Broker) SetCommandSink(sink CommandSink) {
+	b.mu.Lock()
+	b.commandSink = sink
+	b.mu.Unlock()
+}
+
+// ErrBroadcastNotAllowed는 emergency_stop이 아닌 명령을 모든 AGV(models.AGVIDBroadcast)에 보내려 할 때 반환된다.
+var ErrBroadcastNotAllowed = errors.New("모든 AGV 대상(*)은 emergency_stop에만 쓸 수 있습니다")
+
+// OnWebMessage는 웹 명령을 msg.AGVID의 AGV로 보낸다. 시뮬레이터가 실행 중이고 그 AGV를 가지고 있으면 시뮬레이터가 받는다.
+// agv_id가 비어 있으면 연결된 AGV가 한 대일 때만 그 AGV로 가고, 여러 대면 ErrAGVAmbiguous를 반환한다.
+// emergency_stop은 agv_id가 비어 있거나 models.AGVIDBroadcast면 시뮬레이터와 연결된 모든 AGV에 보낸다.
+// 대상이 연결되어 있지 않으면 ErrAGVOffline. 반환된 에러는 보낸 웹 클라이언트에 error로 돌려준다.
+// command·mode_change·emergency_stop은 command_id를 붙여 응답을 추적하고, 진행 상태를 origin에 command_status로 알린다.
+func (b *Broker) OnWebMessage(msg models.WebSocketMessage, origin *websocket.Conn) error {
+	b.mu.RLock()
+	sink := b.commandSink
+	b.mu.RUnlock()
+
+	tracked := isTrackedCommand(msg.Type)
+	if tracked && msg.CommandID == "" {
+		msg.CommandID = b.commands.NewCommandID()
+	}
+	if msg.AGVID == models.AGVIDBroadcast || (msg.AGVID == "" && msg.Type == models.MessageTypeEmergencyStop) {
+		if msg.Type != models.MessageTypeEmergencyStop {
+			return ErrBroadcastNotAllowed
+		}
+		return b.broadcastEmergencyStop(msg, sink, origin)
+	}
+	if sink != nil && sink.HandleWebCommand(msg) {
+		if tracked {
+			b.notifySimulatorCommand(msg, origin)
+		}
+		return nil
 	}
+	to, err := b.cm.ResolveAGV(msg.AGVID)
+	if err != nil {
+		return err
+	}
+	msg.AGVID = to
+	if tracked {
+		_, err := b.commands.Track(msg, origin)
+		return err
+	}
+	return b.sendCommand(msg)
+}
 
-	b.cm.BroadcastToWeb(rawBytes)
+// broadcastEmergencyStop은 비상 정지를 시뮬레이터와 연결된 모든 AGV에 보낸다. 실제 AGV에는 각자의 ID를 넣어 보내고
+// AGV마다 따로 응답을 기다린다.
+func (b *Broker) broadcastEmergencyStop(msg models.WebSocketMessage, sink CommandSink, origin *websocket.Conn) error {
+	msg.AGVID = models.AGVIDBroadcast
+	handled := sink != nil && sink.HandleWebCommand(msg)
+	if handled {
+		b.notifySimulatorCommand(msg, origin)
+	}
+	ids := b.cm.AGVIDs()
+	for _, id := range ids {
+		msg.AGVID = id
+		if _, err := b.commands.Track(msg, origin); err != nil {
+			log.Printf("[ERROR] %s 비상 정지 전송 실패: %v", id, err)
+		}
+	}
+	if !handled && len(ids) == 0 {
+		return ErrAGVOffline
+	}
+	log.Printf("[WARN] 전체 비상 정지 %s: AGV %v (시뮬레이터 %v)", msg.CommandID, ids, handled)
+	return nil
 }
 
-func (b *Broker) OnWebMessage(msg models.WebSocketMessage) {
+// sendCommand는 msg를 msg.AGVID의 AGV로 보낸다. 연결이 없으면 보내지 않고 에러를 반환한다.
+func (b *Broker) sendCommand(msg models.WebSocketMessage) error {
+	if _, err := b.cm.ResolveAGV(msg.AGVID); err != nil {
+		return err
+	}
 	raw, err := json.Marshal(msg)
 	if err != nil {
-		log.Printf("[ERROR] Broker.OnWebMessage marshal 실패: %v", err)
+		log.Printf("[ERROR] 명령 marshal 실패: %v", err)
+		return err
+	}
+	b.sendAGV(msg.AGVID, raw)
+	return nil
+}
+
+func (b *Broker) onCommandReply(msg models.WebSocketMessage) {
+	reply, _ := PayloadAs[models.CommandReply](msg)
+	if msg.CommandID == "" || !b.commands.Reply(msg.AGVID, msg.CommandID, msg.Type, reply.Reason) {
+		log.Printf("[WARN] 추적 중이 아닌 명령 응답: %s %s (%s)", msg.Type, msg.CommandID, msg.AGVID)
+	}
+}
+
+// notifySimulatorCommand는 시뮬레이터가 바로 적용한 명령을 acked로 알린다. 시뮬레이터는 따로 응답하지 않는다.
+func (b *Broker) notifySimulatorCommand(msg models.WebSocketMessage, origin *websocket.Conn) {
+	b.notifyCommand(origin, models.CommandStatus{
+		CommandID:   msg.CommandID,
+		AGVID:       msg.AGVID,
+		CommandType: msg.Type,
+		State:       models.CommandStateAcked,
+		Attempts:    1,
+		Reason:      "시뮬레이터 적용",
+	})
+}
+
+// notifyCommand는 명령 진행 상태를 명령을 보낸 웹 클라이언트에만 보낸다. 서버가 낸 명령(origin nil)은 로그만 남긴다.
+func (b *Broker) notifyCommand(origin *websocket.Conn, st models.CommandStatus) {
+	if origin == nil {
+		log.Printf("[INFO] 서버 명령 %s %s → %s: %s", st.CommandType, st.CommandID, st.AGVID, st.State)
 		return
 	}
-	b.cm.WriteToAGV(raw)
+	err := b.cm.WriteJSON(origin, models.WebSocketMessage{
+		Type:      models.MessageTypeCommandStatus,
+		Data:      st,
+		Timestamp: time.Now().UnixMilli(),
+		AGVID:     st.AGVID,
+		CommandID: st.CommandID,
+	})
+	if err != nil {
+		log.Printf("[WARN] command_status 전송 실패: %v", err)
+	}
+}
+
+// escalateCommand는 확인되지 않은 비상 정지를 모든 웹 클라이언트에 critical error로 알린다.
+// 멈췄는지 모르는 AGV가 있다는 뜻이므로 명령을 보낸 클라이언트만이 아니라 모두에게 보낸다.
+func (b *Broker) escalateCommand(st models.CommandStatus) {
+	log.Printf("[ERROR] !!! 비상 정지 미확인: AGV %s, 명령 %s, 상태 %s (%s) — 현장 확인 필요 !!!",
+		st.AGVID, st.CommandID, st.State, st.Reason)
+	b.BroadcastToWeb(models.WebSocketMessage{
+		Type: models.MessageTypeError,
+		Data: map[string]interface{}{
+			"message":  fmt.Sprintf("AGV %s 비상 정지가 확인되지 않았습니다: %s", st.AGVID, st.Reason),
+			"severity": "critical",
+			"command":  st,
+		},
+		Timestamp: time.Now().UnixMilli(),
+		AGVID:     st.AGVID,
+		CommandID: st.CommandID,
+	})
 }
 
 func (b *Broker) BroadcastToWeb(msg models.WebSocketMessage) {
@@ -66,38 +479,206 @@ func (b *Broker) BroadcastToWeb(msg models.WebSocketMessage) {
 		log.Printf("[ERROR] Broker.BroadcastToWeb marshal 실패: %v", err)
 		return
 	}
-	b.cm.BroadcastToWeb(raw)
+	b.sendWeb(raw)
+}
+
+// SetAGVConnected는 핸드셰이크를 마친 AGV를 연결 상태로 기록하고 웹에 agv_connected를 알린다.
+// 이미 연결된 ID면 능력 정보만 갱신하고 알리지 않는다.
+func (b *Broker) SetAGVConnected(agvID string, hello models.AGVHello) {
+	conn := AGVConnection{
+		AGVID:        agvID,
+		Name:         hello.Name,
+		Version:      hello.Version,
+		Capabilities: append([]string{}, hello.Capabilities...),
+		ConnectedAt:  time.Now(),
+	}
+	b.mu.Lock()
+	prev, existed := b.connections[agvID]
+	if existed {
+		conn.ConnectedAt = prev.ConnectedAt
+	}
+	b.connections[agvID] = conn
+	b.mu.Unlock()
+	if existed {
+		return
+	}
+	b.broadcastConnection(models.MessageTypeAGVConnected, agvID, map[string]interface{}{
+		"connected":    true,
+		"agv_id":       agvID,
+		"name":         conn.Name,
+		"capabilities": conn.Capabilities,
+	})
+	log.Printf("[INFO] AGV 연결: %s %v", agvID, conn.Capabilities)
 }
 
-func (b *Broker) SetAGVConnected(connected bool) {
+// SetAGVDisconnected는 AGV 연결이 끊겼음을 기록하고 웹에 agv_disconnected를 알린다. 세션 통계도 여기서 닫는다.
+// 연결 기록이 없는 ID면 아무 일도 하지 않으므로 여러 경로에서 불려도 한 번만 알린다.
+func (b *Broker) SetAGVDisconnected(agvID string) {
 	b.mu.Lock()
-	if b.agvConnected == connected {
+	if _, ok := b.connections[agvID]; !ok {
 		b.mu.Unlock()
 		return
 	}
-	b.agvConnected = connected
+	delete(b.connections, agvID)
+	stats := b.stats
 	b.mu.Unlock()
-
-	msgType := models.MessageTypeAGVConnected
-	if !connected {
-		msgType = models.MessageTypeAGVDisconnected
+	b.commands.DropAGV(agvID)
+	if stats != nil {
+		stats.EndSession(agvID)
 	}
+	b.broadcastConnection(models.MessageTypeAGVDisconnected, agvID, map[string]interface{}{
+		"connected": false,
+		"agv_id":    agvID,
+	})
+	log.Printf("[INFO] AGV 연결 종료: %s", agvID)
+}
 
+func (b *Broker) broadcastConnection(msgType, agvID string, data map[string]interface{}) {
 	raw, err := json.Marshal(models.WebSocketMessage{
 		Type:      msgType,
-		Data:      map[string]interface{}{"connected": connected},
+		Data:      data,
 		Timestamp: time.Now().UnixMilli(),
+		AGVID:     agvID,
 	})
 	if err != nil {
-		log.Printf("[ERROR] SetAGVConnected marshal 실패: %v", err)
+		log.Printf("[ERROR] AGV 연결 상태 marshal 실패: %v", err)
 		return
 	}
-	b.cm.BroadcastToWeb(raw)
-	log.Printf("[INFO] AGV 연결 상태 변경: %v", connected)
+	b.sendWeb(raw)
 }
 
+// IsAGVConnected는 연결된 AGV가 한 대라도 있는지 반환한다.
 func (b *Broker) IsAGVConnected() bool {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
-	return b.agvConnected
+	return len(b.connections) > 0
+}
+
+func (b *Broker) IsAGVConnectedID(agvID string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	_, ok := b.connections[agvID]
+	return ok
+}
+
+// AGVConnections는 연결된 AGV를 ID 순으로 반환한다.
+func (b *Broker) AGVConnections() []AGVConnection {
+	b.mu.RLock()
+	out := make([]AGVConnection, 0, len(b.connections))
+	for _, c := range b.connections {
+		out = append(out, c)
+	}
+	b.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].AGVID < out[j].AGVID })
+	return out
+}
+
+// SetFaultInjector는 메시지·연결 장애 주입을 붙인다. nil이면 해제.
+func (b *Broker) SetFaultInjector(f *FaultInjector) {
+	b.mu.Lock()
+	b.faults = f
+	b.mu.Unlock()
+}
+
+func (b *Broker) faultInjector() *FaultInjector {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.faults
+}
+
+// sendWeb, sendAGV는 모든 송신이 거치는 길목이다. 장애 주입이 켜져 있으면 메시지마다 지연·순서 바꿈·중복을 뽑는다.
+// sendAGV의 to는 받는 AGV ID. 비어 있으면 연결된 AGV가 한 대일 때만 그 AGV로 간다.
+// sendWeb은 브로드캐스트에 seq를 붙이고 재전송 버퍼에 남긴다.
+func (b *Broker) sendWeb(raw []byte) {
+	b.streamMu.Lock()
+	defer b.streamMu.Unlock()
+	stamped, e, err := stampSeq(raw, b.seq+1)
+	if err != nil {
+		log.Printf("[WARN] 웹 브로드캐스트 seq 부여 실패, 그대로 보냄: %v", err)
+		b.send(b.webLink, "", raw)
+		return
+	}
+	b.seq = e.seq
+	b.replay.add(e)
+	b.send(b.webLink, "", stamped)
+}
+
+func (b *Broker) sendAGV(to string, raw []byte) { b.send(b.agvLink, to, raw) }
+
+// AttachWeb은 등록해 둔 웹 연결 c에 브로드캐스트를 흘리기 시작한다. welcome이 만든 메시지를 먼저 보내고,
+// resume이 있으면 그 뒤로 놓친 브로드캐스트를 c의 구독에 맞춰 다시 보낸 다음 라이브 브로드캐스트를 켠다.
+// 모두 스트림 락 안에서 하므로 라이브 메시지가 재전송 사이에 끼지 않는다. welcome 안에서 Broker를 다시 잠그면 안 된다(mu는 괜찮다).
+func (b *Broker) AttachWeb(c *websocket.Conn, resume *ResumeRequest, welcome func(models.StreamState) models.WebSocketMessage) models.StreamState {
+	b.streamMu.Lock()
+	defer b.streamMu.Unlock()
+	state := models.StreamState{Epoch: b.epoch, Seq: b.seq}
+	var replay []replayEntry
+	if resume != nil {
+		topics := b.cm.Subscriptions(c)
+		want := func(msgType, agvID string) bool { return wantsTopic(topics, msgType, agvID) }
+		complete := false
+		if resume.Epoch == b.epoch && resume.After <= b.seq {
+			replay, complete = b.replay.since(resume.After, want)
+		}
+		if !complete {
+			replay, _ = b.replay.since(0, want)
+		}
+		// 송신 큐를 넘치게 하면 느린 소비자로 끊기므로 큐 용량의 절반까지 최근 것만 보낸다.
+		if n := len(replay) - b.cm.QueueSize/2; n > 0 {
+			replay = replay[n:]
+			complete = false
+		}
+		state.Resumed, state.Snapshot, state.Replayed = true, !complete, len(replay)
+	}
+	if err := b.cm.WriteJSON(c, welcome(state)); err != nil {
+		log.Printf("[WARN] welcome 메시지 전송 실패: %v", err)
+	}
+	for _, e := range replay {
+		b.cm.writeRaw(c, e.msgType, e.raw)
+	}
+	b.cm.activate(c)
+	if resume != nil {
+		log.Printf("[INFO] 웹 재접속 (%s): seq %d 이후 %d개 재전송, snapshot=%v", c.RemoteAddr(), resume.After, len(replay), state.Snapshot)
+	}
+	return state
+}
+
+func (b *Broker) send(l *faultLink, to string, raw []byte) {
+	f := b.faultInjector()
+	if f == nil {
+		l.send(to, raw)
+		return
+	}
+	l.deliver(to, raw, f.PlanMessage())
+}
+
+// writeToAGV는 raw를 AGV 송신 큐에 넣는다. 큐가 가득 차 명령이 거부되면 그 명령을 failed로 끝내 보낸 웹 클라이언트에 알린다.
+// 장애 주입 지연으로 Track이 돌아간 뒤에 불릴 수도 있어 에러를 돌려주지 않고 추적기로 처리한다.
+func (b *Broker) writeToAGV(to string, raw []byte) {
+	err := b.cm.WriteToAGV(to, raw)
+	if err == nil {
+		return
+	}
+	log.Printf("[WARN] AGV(%s) 전송 실패: %v", to, err)
+	if !errors.Is(err, ErrSendQueueFull) {
+		return
+	}
+	var hdr struct {
+		CommandID string `json:"command_id"`
+	}
+	if json.Unmarshal(raw, &hdr) == nil && hdr.CommandID != "" {
+		b.commands.Fail(to, hdr.CommandID, err.Error())
+	}
+}
+
+// ForceDisconnect는 서버 쪽에서 AGV 연결을 모두 끊는다. AGV 핸들러의 수신 루프가 끝나면서
+// 평소 끊김과 같은 경로(SetAGVDisconnected, Unregister)를 밟는다. 끊은 연결 수를 반환한다.
+func (b *Broker) ForceDisconnect() int {
+	ids := b.cm.AGVIDs()
+	n := b.cm.CloseClients(AGVClient)
+	for _, id := range ids {
+		b.SetAGVDisconnected(id)
+	}
+	log.Printf("[WARN] AGV 연결 강제 종료: %d개 %v", n, ids)
+	return n
 }
@@ -2,7 +2,12 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
+	"maps"
+	"sion-backend/models"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 
@@ -16,43 +21,164 @@ const (
 	WebClient ClientType = "web"
 )
 
-// clientEntry는 각 conn마다 write 직렬화를 위한 mutex를 함께 보관한다.
+var (
+	ErrAGVIDInUse   = errors.New("다른 연결이 사용 중인 AGV ID입니다")
+	ErrAGVOffline   = errors.New("연결되지 않은 AGV입니다")
+	ErrAGVAmbiguous = errors.New("AGV가 여러 대 연결되어 있어 대상 AGV ID가 필요합니다")
+	// ErrSendQueueFull은 AGV 송신 큐가 가득 차 명령을 넣지 못했을 때 반환된다.
+	ErrSendQueueFull = errors.New("AGV 송신 큐가 가득 차 명령을 보내지 못했습니다")
+)
+
+// 송신 큐 기본값. ClientManager 필드로 바꿀 수 있다.
+const (
+	defaultSendQueueSize       = 256
+	defaultSlowConsumerTimeout = 10 * time.Second
+	defaultWriteTimeout        = 5 * time.Second
+)
+
+// clientEntry는 각 conn마다 송신 큐와 write 직렬화를 위한 mutex를 함께 보관한다.
 // websocket conn은 동시 WriteMessage 호출이 안전하지 않으므로 conn당 단일 writer를 보장해야 한다.
+// 데이터 프레임은 writer 고루틴만 쓰고, ping 등 컨트롤 프레임은 writeMu를 잡고 바로 쓴다.
 type clientEntry struct {
 	ct      ClientType
 	writeMu sync.Mutex
+	queue   *sendQueue
+	// done은 writer 고루틴이 끝나면 닫힌다.
+	done chan struct{}
+	// agvIDs는 이 연결에 묶인 AGV ID. 핸드셰이크 전에는 비어 있다.
+	agvIDs []string
+	// topics는 웹 클라이언트의 구독 토픽. 정렬된 상태를 유지한다.
+	topics []string
+	// rates는 웹 클라이언트가 set_rate로 정한 토픽별 최대 빈도(Hz). throttle이 그 간격으로 텔레메트리를 합친다.
+	rates    map[string]float64
+	throttle *throttle
+	// active가 false인 웹 클라이언트는 브로드캐스트를 받지 않는다. Broker.AttachWeb이 welcome·재전송 뒤에 켠다.
+	active bool
 }
 
 type ClientManager struct {
 	clients map[*websocket.Conn]*clientEntry
-	mutex   sync.RWMutex
+	// agvs는 AGV ID → 연결. 명령은 이 표로 대상 AGV를 찾는다.
+	agvs  map[string]*websocket.Conn
+	mutex sync.RWMutex
+
+	// QueueSize는 연결별 송신 큐 용량. Register 전에 바꿔야 새 연결에 적용된다.
+	QueueSize int
+	// SlowConsumerTimeout 넘게 큐가 가득 찬 채인 연결은 느린 소비자로 끊는다.
+	SlowConsumerTimeout time.Duration
+	// WriteTimeout은 프레임 하나를 쓰는 최대 시간.
+	WriteTimeout time.Duration
+	// DefaultRates는 웹 클라이언트 모두에 적용하는 토픽별 최대 빈도(Hz). 서버 시작 전에만 바꾼다.
+	DefaultRates map[string]float64
 }
 
 func NewClientManager() *ClientManager {
 	return &ClientManager{
-		clients: make(map[*websocket.Conn]*clientEntry),
+		clients:             make(map[*websocket.Conn]*clientEntry),
+		agvs:                make(map[string]*websocket.Conn),
+		QueueSize:           defaultSendQueueSize,
+		SlowConsumerTimeout: defaultSlowConsumerTimeout,
+		WriteTimeout:        defaultWriteTimeout,
+		DefaultRates:        DefaultRateCaps(),
 	}
 }
 
+// Register는 conn을 풀에 넣고 writer 고루틴을 띄운다. 핸들러는 반환 전에 반드시 Unregister해야 한다.
+// 웹 클라이언트는 Broker.AttachWeb을 부르기 전까지 브로드캐스트를 받지 않는다.
 func (m *ClientManager) Register(conn *websocket.Conn, ct ClientType) {
 	m.mutex.Lock()
-	m.clients[conn] = &clientEntry{ct: ct}
+	entry := &clientEntry{ct: ct, queue: newSendQueue(max(m.QueueSize, 1)), done: make(chan struct{}), active: ct != WebClient}
+	if ct == WebClient {
+		entry.topics = []string{TopicAll}
+		entry.rates = make(map[string]float64)
+		entry.throttle = newThrottle()
+	}
+	m.clients[conn] = entry
 	m.mutex.Unlock()
+	go m.runWriter(conn, entry)
 	log.Printf("[INFO] 클라이언트 등록: %s (%s)", ct, conn.RemoteAddr())
 }
 
+// Unregister는 conn을 풀에서 빼고 writer가 남은 메시지를 보내고 끝날 때까지 기다린다.
+// 핸들러가 반환되면 gofiber/websocket이 conn을 재사용하므로 그 뒤에 writer가 쓰면 안 된다.
 func (m *ClientManager) Unregister(conn *websocket.Conn) {
 	m.mutex.Lock()
 	entry, ok := m.clients[conn]
 	if ok {
 		delete(m.clients, conn)
+		for _, id := range entry.agvIDs {
+			if m.agvs[id] == conn {
+				delete(m.agvs, id)
+			}
+		}
 	}
 	m.mutex.Unlock()
 	if !ok {
 		return
 	}
+	if entry.throttle != nil {
+		entry.throttle.stop()
+	}
+	entry.queue.close()
+	<-entry.done
 	_ = conn.Close()
-	log.Printf("[INFO] 클라이언트 해제: %s (%s)", entry.ct, conn.RemoteAddr())
+	log.Printf("[INFO] 클라이언트 해제: %s %v (%s)", entry.ct, entry.agvIDs, conn.RemoteAddr())
+}
+
+// BindAGV는 AGV ID를 conn에 묶는다. 새로 묶였으면 true, 이미 이 conn에 묶여 있었으면 false.
+// 다른 연결이 쓰고 있는 ID면 ErrAGVIDInUse를 반환한다. 두 번째 로봇이 같은 ID로 명령을 가로채지 못하게 하기 위함이다.
+func (m *ClientManager) BindAGV(conn *websocket.Conn, agvID string) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	entry, ok := m.clients[conn]
+	if !ok || entry.ct != AGVClient {
+		return false, ErrAGVOffline
+	}
+	switch owner := m.agvs[agvID]; {
+	case owner == conn:
+		return false, nil
+	case owner != nil:
+		return false, ErrAGVIDInUse
+	}
+	m.agvs[agvID] = conn
+	entry.agvIDs = append(entry.agvIDs, agvID)
+	return true, nil
+}
+
+// AGVIDsOf는 conn에 묶인 AGV ID를 묶인 순서대로 반환한다.
+func (m *ClientManager) AGVIDsOf(conn *websocket.Conn) []string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	entry, ok := m.clients[conn]
+	if !ok {
+		return nil
+	}
+	return append([]string(nil), entry.agvIDs...)
+}
+
+// AGVIDs는 연결된 AGV ID를 정렬해 반환한다.
+func (m *ClientManager) AGVIDs() []string {
+	m.mutex.RLock()
+	ids := make([]string, 0, len(m.agvs))
+	for id := range m.agvs {
+		ids = append(ids, id)
+	}
+	m.mutex.RUnlock()
+	sort.Strings(ids)
+	return ids
+}
+
+// CloseClients는 ct 종류 연결을 모두 닫고 풀에서 뺀다. 하이재킹된 conn은 Close만으로는 소켓이 닫히지 않으므로
+// close 프레임을 보내고 읽기 deadline을 지나게 해, 대기 중인 핸들러의 ReadMessage가 바로 에러로 끝나게 한다.
+func (m *ClientManager) CloseClients(ct ClientType) int {
+	targets := m.snapshotClients(ct)
+	for _, t := range targets {
+		_ = m.WriteControl(t.conn, websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, "server closed"), time.Now().Add(time.Second))
+		_ = t.conn.SetReadDeadline(time.Now())
+		m.Unregister(t.conn)
+	}
+	return len(targets)
 }
 
 func (m *ClientManager) GetClientCount() map[string]int {
@@ -65,11 +191,128 @@ func (m *ClientManager) GetClientCount() map[string]int {
 	return count
 }
 
-// safeWrite는 conn별 mutex를 잡고 메시지를 전송한다. 호출자가 락을 직접 다루지 않게 한다.
-func (m *ClientManager) safeWrite(conn *websocket.Conn, entry *clientEntry, data []byte) error {
+// runWriter는 conn의 송신 큐를 비우는 writer 고루틴. 쓰기에 실패하거나 느린 소비자로 끊기면
+// 읽기 deadline을 지나게 해 핸들러의 ReadMessage를 깨우고 끝난다. 풀에서 빼는 일은 핸들러의 Unregister가 한다.
+func (m *ClientManager) runWriter(conn *websocket.Conn, entry *clientEntry) {
+	defer close(entry.done)
+	var drainDeadline time.Time
+	for {
+		msg, st := entry.queue.pop()
+		switch st {
+		case popDone:
+			return
+		case popKicked:
+			_ = m.writeFrame(conn, entry, queuedMsg{
+				data:      []byte("slow consumer"),
+				closeCode: websocket.CloseTryAgainLater,
+			}, time.Now().Add(time.Second))
+			_ = conn.SetReadDeadline(time.Now())
+			return
+		case popDraining:
+			// 닫힌 뒤 남은 메시지는 WriteTimeout 안에 보낼 수 있는 만큼만 보낸다.
+			if drainDeadline.IsZero() {
+				drainDeadline = time.Now().Add(m.WriteTimeout)
+			}
+		}
+		deadline := drainDeadline
+		if deadline.IsZero() {
+			deadline = time.Now().Add(m.WriteTimeout)
+		}
+		if err := m.writeFrame(conn, entry, msg, deadline); err != nil {
+			log.Printf("[ERROR] %s 클라이언트 전송 실패 (%s): %v", entry.ct, conn.RemoteAddr(), err)
+			entry.queue.abandon(false)
+			_ = conn.SetReadDeadline(time.Now())
+			return
+		}
+		if msg.closeCode != 0 {
+			entry.queue.abandon(false)
+			_ = conn.SetReadDeadline(time.Now())
+			return
+		}
+	}
+}
+
+// writeFrame은 conn별 mutex를 잡고 프레임 하나를 deadline 안에 쓴다.
+func (m *ClientManager) writeFrame(conn *websocket.Conn, entry *clientEntry, msg queuedMsg, deadline time.Time) error {
 	entry.writeMu.Lock()
 	defer entry.writeMu.Unlock()
-	return conn.WriteMessage(websocket.TextMessage, data)
+	if msg.closeCode != 0 {
+		return conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(msg.closeCode, string(msg.data)), deadline)
+	}
+	if err := conn.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+	return conn.WriteMessage(websocket.TextMessage, msg.data)
+}
+
+// enqueue는 data를 conn의 송신 큐에 넣는다. 느린 소비자면 연결을 끊는다.
+func (m *ClientManager) enqueue(conn *websocket.Conn, entry *clientEntry, msgType string, data []byte) pushResult {
+	res := entry.queue.push(data, dropLevel(msgType), time.Now(), m.SlowConsumerTimeout)
+	switch res {
+	case pushDropped:
+		log.Printf("[WARN] %s 클라이언트 송신 큐 가득 참, 메시지 버림 (%s, %s)", entry.ct, conn.RemoteAddr(), msgType)
+	case pushRejected:
+		log.Printf("[WARN] %s 클라이언트 송신 큐 가득 참, 명령 거부 (%s, %s)", entry.ct, conn.RemoteAddr(), msgType)
+	case pushSlow:
+		log.Printf("[WARN] 느린 %s 클라이언트 연결 끊음 (%s): 송신 큐 %d개가 %v 넘게 비워지지 않음",
+			entry.ct, conn.RemoteAddr(), entry.queue.size, m.SlowConsumerTimeout)
+		entry.queue.abandon(true)
+	}
+	return res
+}
+
+// writeRaw는 이미 직렬화된 메시지를 conn의 송신 큐에 넣는다.
+func (m *ClientManager) writeRaw(conn *websocket.Conn, msgType string, data []byte) {
+	m.mutex.RLock()
+	entry, ok := m.clients[conn]
+	m.mutex.RUnlock()
+	if ok {
+		m.enqueue(conn, entry, msgType, data)
+	}
+}
+
+// activate는 웹 클라이언트가 브로드캐스트를 받기 시작하게 한다.
+func (m *ClientManager) activate(conn *websocket.Conn) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if entry, ok := m.clients[conn]; ok {
+		entry.active = true
+	}
+}
+
+// CloseConn은 지금까지 큐에 넣은 메시지를 보낸 뒤 close 프레임을 보내고 연결을 끝낸다.
+func (m *ClientManager) CloseConn(conn *websocket.Conn, code int, reason string) {
+	m.mutex.RLock()
+	entry, ok := m.clients[conn]
+	m.mutex.RUnlock()
+	if ok {
+		entry.queue.pushClose(code, reason)
+	}
+}
+
+// QueueStats는 연결별 송신 큐 지표를 종류, 원격 주소 순으로 반환한다.
+func (m *ClientManager) QueueStats() []QueueStats {
+	m.mutex.RLock()
+	out := make([]QueueStats, 0, len(m.clients))
+	for c, e := range m.clients {
+		st := e.queue.stats()
+		st.Remote = c.RemoteAddr().String()
+		st.Type = string(e.ct)
+		st.AGVIDs = append([]string(nil), e.agvIDs...)
+		st.Topics = append([]string(nil), e.topics...)
+		if e.throttle != nil {
+			st.Coalesced = e.throttle.coalescedCount()
+		}
+		out = append(out, st)
+	}
+	m.mutex.RUnlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Type != out[j].Type {
+			return out[i].Type < out[j].Type
+		}
+		return out[i].Remote < out[j].Remote
+	})
+	return out
 }
 
 // snapshotClients는 RLock 안에서 (conn, entry) 쌍을 복사해 반환한다.
@@ -95,8 +338,7 @@ func (m *ClientManager) snapshotClients(filter ClientType) []struct {
 	return out
 }
 
-// WriteJSON은 특정 conn에 JSON 페이로드를 직렬화해 안전하게 전송한다.
-// (conn별 mutex로 동시 쓰기를 직렬화)
+// WriteJSON은 특정 conn에 JSON 페이로드를 직렬화해 송신 큐에 넣는다. 실제 전송은 writer 고루틴이 한다.
 func (m *ClientManager) WriteJSON(conn *websocket.Conn, v any) error {
 	m.mutex.RLock()
 	entry, ok := m.clients[conn]
@@ -108,7 +350,23 @@ func (m *ClientManager) WriteJSON(conn *websocket.Conn, v any) error {
 	if err != nil {
 		return err
 	}
-	return m.safeWrite(conn, entry, data)
+	m.enqueue(conn, entry, messageType(v, data), data)
+	return nil
+}
+
+// messageType은 송신 큐의 버림 단계를 고를 메시지 type을 꺼낸다.
+func messageType(v any, data []byte) string {
+	switch msg := v.(type) {
+	case models.WebSocketMessage:
+		return msg.Type
+	case *models.WebSocketMessage:
+		return msg.Type
+	}
+	var hdr struct {
+		Type string `json:"type"`
+	}
+	_ = json.Unmarshal(data, &hdr)
+	return hdr.Type
 }
 
 // WriteControl은 ping/pong 등 컨트롤 프레임을 conn별 writeMu 안에서 전송한다.
@@ -125,29 +383,194 @@ func (m *ClientManager) WriteControl(conn *websocket.Conn, messageType int, data
 	return conn.WriteControl(messageType, data, deadline)
 }
 
+// Subscribe는 웹 클라이언트 구독에 topics를 더하고 바뀐 뒤의 전체 구독을 반환한다. 잘못된 토픽이 있으면 아무것도 바꾸지 않는다.
+func (m *ClientManager) Subscribe(conn *websocket.Conn, topics []string) ([]string, error) {
+	for _, t := range topics {
+		if err := ValidateTopic(t); err != nil {
+			return nil, err
+		}
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	entry, ok := m.clients[conn]
+	if !ok || entry.ct != WebClient {
+		return nil, errors.New("웹 클라이언트가 아닙니다")
+	}
+	merged := append([]string(nil), entry.topics...)
+	for _, t := range topics {
+		if !slices.Contains(merged, t) {
+			merged = append(merged, t)
+		}
+	}
+	if len(merged) > maxTopicsPerClient {
+		return nil, ErrTooManyTopics
+	}
+	sort.Strings(merged)
+	entry.topics = merged
+	return append([]string(nil), merged...), nil
+}
+
+// Unsubscribe는 웹 클라이언트 구독에서 topics와 똑같은 토픽을 빼고 남은 구독을 반환한다.
+// 와일드카드 토픽을 빼도 그 토픽에 포함된 다른 구독은 남는다.
+func (m *ClientManager) Unsubscribe(conn *websocket.Conn, topics []string) []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	entry, ok := m.clients[conn]
+	if !ok {
+		return nil
+	}
+	entry.topics = slices.DeleteFunc(entry.topics, func(t string) bool { return slices.Contains(topics, t) })
+	return append([]string{}, entry.topics...)
+}
+
+// Subscriptions는 웹 클라이언트의 현재 구독을 반환한다.
+func (m *ClientManager) Subscriptions(conn *websocket.Conn) []string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	if entry, ok := m.clients[conn]; ok {
+		return append([]string{}, entry.topics...)
+	}
+	return nil
+}
+
+// subscriber는 브로드캐스트 대상 하나. interval은 그 클라이언트에 이 메시지를 보낼 최소 간격(0이면 제한 없음).
+type subscriber struct {
+	conn     *websocket.Conn
+	entry    *clientEntry
+	interval time.Duration
+}
+
+// snapshotSubscribers는 msgType·agvID 메시지를 구독한 웹 클라이언트를 RLock 안에서 골라 복사한다.
+func (m *ClientManager) snapshotSubscribers(msgType, agvID string) []subscriber {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	out := make([]subscriber, 0, len(m.clients))
+	for c, e := range m.clients {
+		if e.ct == WebClient && e.active && wantsTopic(e.topics, msgType, agvID) {
+			out = append(out, subscriber{c, e, rateInterval(m.DefaultRates, e.rates, msgType, agvID)})
+		}
+	}
+	return out
+}
+
+// BroadcastToWeb은 data를 그 메시지의 type·agv_id 토픽을 구독한 웹 클라이언트의 송신 큐에 넣는다.
+// 느린 클라이언트가 있어도 기다리지 않는다. 빈도 제한이 걸린 텔레메트리는 클라이언트마다 간격 안의 프레임을 마지막 하나로 합친다.
 func (m *ClientManager) BroadcastToWeb(data []byte) {
-	targets := m.snapshotClients(WebClient)
-	var failed []*websocket.Conn
-	for _, t := range targets {
-		if err := m.safeWrite(t.conn, t.entry, data); err != nil {
-			log.Printf("[ERROR] BroadcastToWeb 전송 실패: %v", err)
-			failed = append(failed, t.conn)
+	var hdr struct {
+		Type  string `json:"type"`
+		AGVID string `json:"agv_id"`
+	}
+	if err := json.Unmarshal(data, &hdr); err != nil {
+		log.Printf("[WARN] BroadcastToWeb 메시지 헤더 파싱 실패: %v", err)
+	}
+	key := hdr.Type + "@" + hdr.AGVID
+	now := time.Now()
+	for _, t := range m.snapshotSubscribers(hdr.Type, hdr.AGVID) {
+		if t.interval > 0 && !t.entry.throttle.offer(key, data, t.interval, now, m.flushThrottled(t.conn, t.entry, hdr.Type, key)) {
+			continue
 		}
+		m.enqueue(t.conn, t.entry, hdr.Type, data)
 	}
-	for _, conn := range failed {
-		m.Unregister(conn)
+}
+
+// flushThrottled는 간격이 지났을 때 합쳐 둔 마지막 프레임을 보내는 타이머 함수를 만든다.
+func (m *ClientManager) flushThrottled(conn *websocket.Conn, entry *clientEntry, msgType, key string) func() {
+	return func() {
+		if data := entry.throttle.take(key, time.Now()); data != nil {
+			m.enqueue(conn, entry, msgType, data)
+		}
 	}
 }
 
-func (m *ClientManager) WriteToAGV(data []byte) {
-	targets := m.snapshotClients(AGVClient)
-	if len(targets) == 0 {
-		log.Println("[WARN] WriteToAGV: AGV 연결 없음")
-		return
+// SetRates는 웹 클라이언트의 토픽별 최대 빈도를 바꾸고 바뀐 뒤의 전체 설정을 반환한다. 0은 그 토픽 설정을 지운다.
+// 잘못된 값이 있으면 아무것도 바꾸지 않는다.
+func (m *ClientManager) SetRates(conn *websocket.Conn, rates map[string]float64) (map[string]float64, error) {
+	for topic, hz := range rates {
+		if err := ValidateRate(topic, hz); err != nil {
+			return nil, err
+		}
 	}
-	t := targets[0]
-	if err := m.safeWrite(t.conn, t.entry, data); err != nil {
-		log.Printf("[ERROR] WriteToAGV 전송 실패: %v", err)
-		m.Unregister(t.conn)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	entry, ok := m.clients[conn]
+	if !ok || entry.ct != WebClient {
+		return nil, errors.New("웹 클라이언트가 아닙니다")
+	}
+	merged := maps.Clone(entry.rates)
+	for topic, hz := range rates {
+		if hz == 0 {
+			delete(merged, topic)
+		} else {
+			merged[topic] = hz
+		}
+	}
+	if len(merged) > maxTopicsPerClient {
+		return nil, ErrTooManyTopics
+	}
+	entry.rates = merged
+	return maps.Clone(merged), nil
+}
+
+// Rates는 웹 클라이언트가 정한 토픽별 최대 빈도를 반환한다.
+func (m *ClientManager) Rates(conn *websocket.Conn) map[string]float64 {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	if entry, ok := m.clients[conn]; ok && entry.rates != nil {
+		return maps.Clone(entry.rates)
+	}
+	return map[string]float64{}
+}
+
+// WriteToAGV는 agvID가 묶인 연결로 data를 보낸다. agvID가 비어 있으면 핸드셰이크를 마친 AGV 연결이
+// 하나뿐일 때만 그 연결로 보내고, 여러 개면 아무 데도 보내지 않고 ErrAGVAmbiguous를 반환한다.
+func (m *ClientManager) WriteToAGV(agvID string, data []byte) error {
+	conn, entry, err := m.agvTarget(agvID)
+	if err != nil {
+		return err
+	}
+	if m.enqueue(conn, entry, messageType(nil, data), data) == pushRejected {
+		return ErrSendQueueFull
+	}
+	return nil
+}
+
+// ResolveAGV는 WriteToAGV(agvID, ...)가 실제로 보낼 AGV ID를 돌려준다. agvID가 비어 있고 연결이 하나뿐이면
+// 그 연결의 대표 ID(핸드셰이크 ID)다. 보낼 연결이 없으면 WriteToAGV와 같은 에러를 반환한다.
+func (m *ClientManager) ResolveAGV(agvID string) (string, error) {
+	conn, _, err := m.agvTarget(agvID)
+	if err != nil {
+		return "", err
+	}
+	if agvID != "" {
+		return agvID, nil
+	}
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	if e, ok := m.clients[conn]; ok && len(e.agvIDs) > 0 {
+		return e.agvIDs[0], nil
+	}
+	return "", ErrAGVOffline
+}
+
+func (m *ClientManager) agvTarget(agvID string) (*websocket.Conn, *clientEntry, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	if agvID != "" {
+		conn, ok := m.agvs[agvID]
+		if !ok {
+			return nil, nil, ErrAGVOffline
+		}
+		return conn, m.clients[conn], nil
+	}
+	var found *websocket.Conn
+	for _, conn := range m.agvs {
+		if found != nil && conn != found {
+			return nil, nil, ErrAGVAmbiguous
+		}
+		found = conn
+	}
+	if found == nil {
+		return nil, nil, ErrAGVOffline
 	}
+	return found, m.clients[found], nil
 }
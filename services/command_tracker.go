@@ -0,0 +1,309 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sion-backend/models"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/websocket/v2"
+)
+
+// 명령 응답 추적. Broker가 실제 AGV로 보낸 command·mode_change·emergency_stop을 (AGV ID, command_id)로 기억했다가
+// AGV 응답(command_ack/command_nack/command_completed)이 오면 명령을 보낸 웹 클라이언트에 진행 상태를 알린다.
+// 응답이 없으면 같은 command_id로 다시 보내고(AGV는 같은 ID를 한 번만 수행해야 한다), 끝내 확인되지 않은
+// 비상 정지는 모든 웹 클라이언트에 critical error로 알린다.
+
+const (
+	defaultAckTimeoutMs = 2000
+	defaultMaxRetries   = 2
+	minAckTimeoutMs     = 10
+	maxAckTimeoutMs     = 60000
+	maxCommandRetries   = 10
+)
+
+func DefaultCommandAckConfig() models.CommandAckConfig {
+	return models.CommandAckConfig{AckTimeoutMs: defaultAckTimeoutMs, MaxRetries: defaultMaxRetries}
+}
+
+func ValidateCommandAckConfig(cfg models.CommandAckConfig) error {
+	if cfg.AckTimeoutMs < minAckTimeoutMs || cfg.AckTimeoutMs > maxAckTimeoutMs {
+		return fmt.Errorf("ack_timeout_ms는 %d~%d 사이여야 합니다", minAckTimeoutMs, maxAckTimeoutMs)
+	}
+	if cfg.MaxRetries < 0 || cfg.MaxRetries > maxCommandRetries {
+		return fmt.Errorf("max_retries는 0~%d 사이여야 합니다", maxCommandRetries)
+	}
+	return nil
+}
+
+// isTrackedCommand는 응답을 추적하는 명령인지 판단한다. motor_control은 조이스틱 입력처럼 연달아 오고
+// 다음 입력이 앞 입력을 덮으므로 추적하지 않는다.
+func isTrackedCommand(msgType string) bool {
+	switch msgType {
+	case models.MessageTypeCommand, models.MessageTypeModeChange, models.MessageTypeEmergencyStop:
+		return true
+	}
+	return false
+}
+
+type commandKey struct {
+	agvID, commandID string
+}
+
+type pendingCommand struct {
+	msg    models.WebSocketMessage
+	origin *websocket.Conn
+	status models.CommandStatus
+	timer  *time.Timer
+}
+
+// commandUpdate는 락 밖에서 보낼 진행 상태 알림.
+type commandUpdate struct {
+	origin   *websocket.Conn
+	status   models.CommandStatus
+	escalate bool
+}
+
+// CommandTracker는 AGV로 보낸 명령의 응답을 기다린다. 송신·알림은 Broker가 넘긴 함수로 하며 락 밖에서 부른다.
+type CommandTracker struct {
+	send     func(msg models.WebSocketMessage) error
+	notify   func(origin *websocket.Conn, status models.CommandStatus)
+	escalate func(status models.CommandStatus)
+	seq      atomic.Uint64
+
+	mu      sync.Mutex
+	cfg     models.CommandAckConfig
+	pending map[commandKey]*pendingCommand
+}
+
+// NewCommandTracker: send는 msg.AGVID의 AGV로 msg를 보내고, notify는 origin 웹 클라이언트(nil이면 서버 내부 명령)에
+// 진행 상태를 알리며, escalate는 확인되지 않은 비상 정지를 알린다.
+func NewCommandTracker(send func(models.WebSocketMessage) error, notify func(*websocket.Conn, models.CommandStatus), escalate func(models.CommandStatus)) *CommandTracker {
+	return &CommandTracker{
+		send:     send,
+		notify:   notify,
+		escalate: escalate,
+		cfg:      DefaultCommandAckConfig(),
+		pending:  make(map[commandKey]*pendingCommand),
+	}
+}
+
+// SetConfig는 응답 대기 설정을 바꾼다. 이미 기다리는 명령은 다음 타이머부터 새 설정을 따른다.
+func (t *CommandTracker) SetConfig(cfg models.CommandAckConfig) error {
+	if err := ValidateCommandAckConfig(cfg); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.cfg = cfg
+	t.mu.Unlock()
+	return nil
+}
+
+func (t *CommandTracker) Config() models.CommandAckConfig {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.cfg
+}
+
+// NewCommandID는 서버가 붙이는 command_id를 만든다.
+func (t *CommandTracker) NewCommandID() string {
+	return fmt.Sprintf("cmd-%d-%d", time.Now().UnixMilli(), t.seq.Add(1))
+}
+
+// Pending은 응답을 기다리거나 수행 완료를 기다리는 명령을 AGV ID, command_id 순으로 반환한다.
+func (t *CommandTracker) Pending() []models.CommandStatus {
+	t.mu.Lock()
+	out := make([]models.CommandStatus, 0, len(t.pending))
+	for _, p := range t.pending {
+		out = append(out, p.status)
+	}
+	t.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].AGVID != out[j].AGVID {
+			return out[i].AGVID < out[j].AGVID
+		}
+		return out[i].CommandID < out[j].CommandID
+	})
+	return out
+}
+
+// Track은 msg를 msg.AGVID의 AGV로 보내고 응답을 기다린다. command_id가 없으면 붙인다.
+// 같은 AGV에 같은 종류 명령이 아직 추적 중이면 그 명령은 replaced로 끝낸다. 보내지 못하면 failed로 끝내고 에러를 반환한다.
+func (t *CommandTracker) Track(msg models.WebSocketMessage, origin *websocket.Conn) (string, error) {
+	if msg.CommandID == "" {
+		msg.CommandID = t.NewCommandID()
+	}
+	key := commandKey{msg.AGVID, msg.CommandID}
+	p := &pendingCommand{
+		msg:    msg,
+		origin: origin,
+		status: models.CommandStatus{
+			CommandID:   msg.CommandID,
+			AGVID:       msg.AGVID,
+			CommandType: msg.Type,
+			State:       models.CommandStatePending,
+			Attempts:    1,
+		},
+	}
+
+	// 응답이 송신보다 먼저 처리될 수 있으므로 등록하고 나서 보낸다.
+	t.mu.Lock()
+	var updates []commandUpdate
+	for k, old := range t.pending {
+		if k.agvID == msg.AGVID && old.msg.Type == msg.Type && k != key {
+			updates = append(updates, t.finishLocked(k, old, models.CommandStateReplaced, msg.CommandID+" 명령으로 대체"))
+		}
+	}
+	if old, ok := t.pending[key]; ok && old.timer != nil {
+		old.timer.Stop()
+	}
+	t.pending[key] = p
+	p.timer = time.AfterFunc(t.ackTimeoutLocked(), func() { t.expire(key, p) })
+	updates = append(updates, commandUpdate{origin: origin, status: p.status})
+	t.mu.Unlock()
+	t.deliver(updates)
+
+	if err := t.send(msg); err != nil {
+		t.mu.Lock()
+		if t.pending[key] != p {
+			t.mu.Unlock()
+			return msg.CommandID, err
+		}
+		u := t.finishLocked(key, p, models.CommandStateFailed, err.Error())
+		t.mu.Unlock()
+		t.deliver([]commandUpdate{u})
+		return msg.CommandID, err
+	}
+	return msg.CommandID, nil
+}
+
+// Reply는 AGV 응답을 반영한다. 추적 중인 명령이 아니면 false.
+func (t *CommandTracker) Reply(agvID, commandID, replyType, reason string) bool {
+	key := commandKey{agvID, commandID}
+	t.mu.Lock()
+	p, ok := t.pending[key]
+	if !ok {
+		t.mu.Unlock()
+		return false
+	}
+	var u commandUpdate
+	switch replyType {
+	case models.MessageTypeCommandAck:
+		if p.status.State == models.CommandStateAcked {
+			// 재시도로 중복 전송된 명령의 두 번째 ack
+			t.mu.Unlock()
+			return true
+		}
+		if p.timer != nil {
+			p.timer.Stop()
+			p.timer = nil
+		}
+		p.status.State = models.CommandStateAcked
+		p.status.Reason = reason
+		u = commandUpdate{origin: p.origin, status: p.status}
+	case models.MessageTypeCommandNack:
+		u = t.finishLocked(key, p, models.CommandStateNacked, reason)
+	case models.MessageTypeCommandCompleted:
+		u = t.finishLocked(key, p, models.CommandStateCompleted, reason)
+	default:
+		t.mu.Unlock()
+		return false
+	}
+	t.mu.Unlock()
+	t.deliver([]commandUpdate{u})
+	return true
+}
+
+// Fail은 보내지 못한 명령을 failed로 끝낸다. 송신 큐가 가득 차 명령이 거부됐을 때 쓴다. 추적 중이 아니면 false.
+func (t *CommandTracker) Fail(agvID, commandID, reason string) bool {
+	key := commandKey{agvID, commandID}
+	t.mu.Lock()
+	p, ok := t.pending[key]
+	if !ok {
+		t.mu.Unlock()
+		return false
+	}
+	u := t.finishLocked(key, p, models.CommandStateFailed, reason)
+	t.mu.Unlock()
+	t.deliver([]commandUpdate{u})
+	return true
+}
+
+// DropAGV는 연결이 끊긴 AGV의 명령을 모두 failed로 끝낸다.
+func (t *CommandTracker) DropAGV(agvID string) {
+	t.mu.Lock()
+	var updates []commandUpdate
+	for k, p := range t.pending {
+		if k.agvID == agvID {
+			updates = append(updates, t.finishLocked(k, p, models.CommandStateFailed, "AGV 연결 끊김"))
+		}
+	}
+	t.mu.Unlock()
+	t.deliver(updates)
+}
+
+// expire는 응답 대기 시간이 지났을 때 불린다. 재시도가 남았으면 다시 보내고, 없으면 timeout으로 끝낸다.
+func (t *CommandTracker) expire(key commandKey, p *pendingCommand) {
+	t.mu.Lock()
+	if t.pending[key] != p || p.timer == nil {
+		t.mu.Unlock()
+		return
+	}
+	if p.status.Attempts > t.cfg.MaxRetries {
+		u := t.finishLocked(key, p, models.CommandStateTimeout,
+			fmt.Sprintf("%d회 보냈지만 응답 없음", p.status.Attempts))
+		t.mu.Unlock()
+		t.deliver([]commandUpdate{u})
+		return
+	}
+	p.status.Attempts++
+	p.status.State = models.CommandStateRetrying
+	p.timer = time.AfterFunc(t.ackTimeoutLocked(), func() { t.expire(key, p) })
+	msg, u := p.msg, commandUpdate{origin: p.origin, status: p.status}
+	t.mu.Unlock()
+
+	log.Printf("[WARN] 명령 응답 없음, 재전송 %d회째: %s %s → %s", u.status.Attempts, msg.Type, msg.CommandID, msg.AGVID)
+	t.deliver([]commandUpdate{u})
+	if err := t.send(msg); err != nil {
+		t.mu.Lock()
+		if t.pending[key] != p {
+			t.mu.Unlock()
+			return
+		}
+		u := t.finishLocked(key, p, models.CommandStateFailed, err.Error())
+		t.mu.Unlock()
+		t.deliver([]commandUpdate{u})
+	}
+}
+
+// finishLocked는 명령 추적을 끝내고 보낼 알림을 돌려준다. 확인되지 않은 채 끝난 비상 정지는 escalate로 표시한다.
+func (t *CommandTracker) finishLocked(key commandKey, p *pendingCommand, state, reason string) commandUpdate {
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+	delete(t.pending, key)
+	wasAcked := p.status.State == models.CommandStateAcked
+	p.status.State = state
+	p.status.Reason = reason
+	escalate := p.msg.Type == models.MessageTypeEmergencyStop &&
+		(state == models.CommandStateNacked || state == models.CommandStateTimeout ||
+			(state == models.CommandStateFailed && !wasAcked))
+	return commandUpdate{origin: p.origin, status: p.status, escalate: escalate}
+}
+
+func (t *CommandTracker) ackTimeoutLocked() time.Duration {
+	return time.Duration(t.cfg.AckTimeoutMs) * time.Millisecond
+}
+
+func (t *CommandTracker) deliver(updates []commandUpdate) {
+	for _, u := range updates {
+		t.notify(u.origin, u.status)
+		if u.escalate {
+			t.escalate(u.status)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"errors"
+	"sion-backend/models"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/websocket/v2"
+)
+
+// trackerRecorder는 CommandTracker가 보낸 명령·알림·escalation을 모은다.
+type trackerRecorder struct {
+	mu        sync.Mutex
+	sent      []models.WebSocketMessage
+	states    []string
+	escalated []models.CommandStatus
+	sendErr   error
+}
+
+func newRecordedTracker(t *testing.T, cfg models.CommandAckConfig) (*CommandTracker, *trackerRecorder) {
+	t.Helper()
+	r := &trackerRecorder{}
+	tr := NewCommandTracker(
+		func(msg models.WebSocketMessage) error {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.sent = append(r.sent, msg)
+			return r.sendErr
+		},
+		func(_ *websocket.Conn, st models.CommandStatus) {
+			r.mu.Lock()
+			r.states = append(r.states, st.CommandID+":"+st.State)
+			r.mu.Unlock()
+		},
+		func(st models.CommandStatus) {
+			r.mu.Lock()
+			r.escalated = append(r.escalated, st)
+			r.mu.Unlock()
+		},
+	)
+	if err := tr.SetConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	return tr, r
+}
+
+func (r *trackerRecorder) snapshot() (sent int, states []string, escalated []models.CommandStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.sent), append([]string(nil), r.states...), append([]models.CommandStatus(nil), r.escalated...)
+}
+
+func waitTracker(t *testing.T, cond func() bool, msg string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal(msg)
+}
+
+func TestCommandTracker_RetriesThenEscalatesUnackedEmergencyStop(t *testing.T) {
+	tr, r := newRecordedTracker(t, models.CommandAckConfig{AckTimeoutMs: 15, MaxRetries: 2})
+	id, err := tr.Track(models.WebSocketMessage{Type: models.MessageTypeEmergencyStop, AGVID: "sion-001"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitTracker(t, func() bool { _, _, esc := r.snapshot(); return len(esc) == 1 }, "응답 없는 비상 정지는 escalate돼야 함")
+
+	sent, states, esc := r.snapshot()
+	if sent != 3 {
+		t.Fatalf("처음 1회 + 재시도 2회 = 3회 전송 기대, got %d", sent)
+	}
+	want := []string{id + ":pending", id + ":retrying", id + ":retrying", id + ":timeout"}
+	if len(states) != len(want) {
+		t.Fatalf("상태 순서 %v 기대, got %v", want, states)
+	}
+	for i := range want {
+		if states[i] != want[i] {
+			t.Fatalf("상태 순서 %v 기대, got %v", want, states)
+		}
+	}
+	if esc[0].AGVID != "sion-001" || esc[0].Attempts != 3 {
+		t.Fatalf("escalation 내용: %+v", esc[0])
+	}
+	if len(tr.Pending()) != 0 {
+		t.Fatalf("timeout 뒤에는 대기 목록에서 빠져야 함: %+v", tr.Pending())
+	}
+}
+
+func TestCommandTracker_AckStopsRetriesAndCompletedFinishes(t *testing.T) {
+	tr, r := newRecordedTracker(t, models.CommandAckConfig{AckTimeoutMs: 20, MaxRetries: 3})
+	id, _ := tr.Track(models.WebSocketMessage{Type: models.MessageTypeCommand, AGVID: "sion-001", CommandID: "web-1"}, nil)
+	if id != "web-1" {
+		t.Fatalf("웹이 준 command_id를 유지해야 함: %s", id)
+	}
+	if tr.Reply("sion-002", id, models.MessageTypeCommandAck, "") {
+		t.Fatal("다른 AGV의 응답은 매칭되면 안 됨")
+	}
+	if !tr.Reply("sion-001", id, models.MessageTypeCommandAck, "") {
+		t.Fatal("ack가 매칭돼야 함")
+	}
+	time.Sleep(80 * time.Millisecond)
+	if sent, _, _ := r.snapshot(); sent != 1 {
+		t.Fatalf("ack 뒤에는 재전송하면 안 됨: %d회 전송", sent)
+	}
+	if p := tr.Pending(); len(p) != 1 || p[0].State != models.CommandStateAcked {
+		t.Fatalf("완료 전까지 acked로 남아야 함: %+v", p)
+	}
+	tr.Reply("sion-001", id, models.MessageTypeCommandCompleted, "")
+	_, states, esc := r.snapshot()
+	if states[len(states)-1] != id+":completed" || len(esc) != 0 || len(tr.Pending()) != 0 {
+		t.Fatalf("completed로 끝나야 함: %v esc=%v", states, esc)
+	}
+}
+
+func TestCommandTracker_ReplaceDropAndSendFailure(t *testing.T) {
+	tr, r := newRecordedTracker(t, models.CommandAckConfig{AckTimeoutMs: 1000, MaxRetries: 0})
+	first, _ := tr.Track(models.WebSocketMessage{Type: models.MessageTypeCommand, AGVID: "sion-001"}, nil)
+	second, _ := tr.Track(models.WebSocketMessage{Type: models.MessageTypeCommand, AGVID: "sion-001"}, nil)
+	stop, _ := tr.Track(models.WebSocketMessage{Type: models.MessageTypeEmergencyStop, AGVID: "sion-001"}, nil)
+	tr.Reply("sion-001", stop, models.MessageTypeCommandAck, "")
+
+	tr.DropAGV("sion-001")
+	_, states, esc := r.snapshot()
+	has := func(s string) bool {
+		for _, v := range states {
+			if v == s {
+				return true
+			}
+		}
+		return false
+	}
+	if !has(first+":replaced") || !has(second+":failed") || !has(stop+":failed") {
+		t.Fatalf("replaced/failed 상태 기대: %v", states)
+	}
+	if len(esc) != 0 {
+		t.Fatalf("이미 ack된 비상 정지는 끊겨도 escalate하지 않음: %+v", esc)
+	}
+
+	r.mu.Lock()
+	r.sendErr = errors.New("offline")
+	r.mu.Unlock()
+	if _, err := tr.Track(models.WebSocketMessage{Type: models.MessageTypeEmergencyStop, AGVID: "sion-002"}, nil); err == nil {
+		t.Fatal("전송 실패는 에러로 돌아와야 함")
+	}
+	if _, _, esc := r.snapshot(); len(esc) != 1 {
+		t.Fatalf("보내지 못한 비상 정지는 escalate돼야 함: %+v", esc)
+	}
+}
+
+// 송신 큐가 가득 차 거부된 명령은 Fail로 failed가 되고, 비상 정지였다면 escalate된다.
+func TestCommandTracker_FailQueuedCommand(t *testing.T) {
+	tr, r := newRecordedTracker(t, models.CommandAckConfig{AckTimeoutMs: 1000, MaxRetries: 0})
+	id, _ := tr.Track(models.WebSocketMessage{Type: models.MessageTypeCommand, AGVID: "sion-001"}, nil)
+	if !tr.Fail("sion-001", id, ErrSendQueueFull.Error()) {
+		t.Fatal("추적 중인 명령이면 true")
+	}
+	if tr.Fail("sion-001", id, "again") {
+		t.Fatal("이미 끝난 명령이면 false")
+	}
+	if _, states, _ := r.snapshot(); states[len(states)-1] != id+":failed" || len(tr.Pending()) != 0 {
+		t.Fatalf("failed로 끝나야 함: %v", states)
+	}
+}
+
+func TestValidateCommandAckConfig(t *testing.T) {
+	if err := ValidateCommandAckConfig(DefaultCommandAckConfig()); err != nil {
+		t.Fatal(err)
+	}
+	for _, cfg := range []models.CommandAckConfig{{AckTimeoutMs: 0, MaxRetries: 1}, {AckTimeoutMs: 100, MaxRetries: -1}, {AckTimeoutMs: 100, MaxRetries: 99}} {
+		if ValidateCommandAckConfig(cfg) == nil {
+			t.Errorf("%+v는 거부돼야 함", cfg)
+		}
+	}
+}
@@ -40,6 +40,7 @@ func InitDatabase() error {
 
 	errMigrate := db.AutoMigrate(
 		&models.AGVLog{},
+		&models.AGVStatsRecord{},
 	)
 	if errMigrate != nil {
 		return fmt.Errorf("마이그레이션 실패: %v", errMigrate)
@@ -0,0 +1,286 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sion-backend/models"
+	"time"
+)
+
+// 시뮬레이터 적 행동 모델. 각 행동은 틱마다 sim.mu를 잡은 상태에서 Step이 호출되며,
+// 난수는 반드시 sim.rng에서 뽑아 seed 재현성을 유지한다.
+
+var ErrEnemyNotFound = errors.New("적을 찾을 수 없습니다")
+
+const (
+	// defaultEnemySpeed는 Speed가 0인 적이 움직일 때 쓰는 속도(셀/초). AGV 탐색 속도(1.0)보다 느리다.
+	defaultEnemySpeed = 0.8
+	// defaultTriggerRadius는 flee/escape가 AGV에 반응하기 시작하는 기본 거리.
+	defaultTriggerRadius = 6.0
+)
+
+type EnemyBehavior interface {
+	// Name은 models.EnemyBehavior* 상수 중 하나를 반환한다.
+	Name() string
+	// Step은 dt초만큼 적 e를 움직인다.
+	Step(sim *AGVSimulator, e *models.Enemy, dt float64)
+}
+
+// NewEnemyBehavior는 설정으로 행동 모델을 만든다. 빈 Behavior는 stationary로 취급한다.
+func NewEnemyBehavior(cfg models.EnemyBehaviorConfig) (EnemyBehavior, error) {
+	radius := cfg.TriggerRadius
+	if radius <= 0 {
+		radius = defaultTriggerRadius
+	}
+	switch cfg.Behavior {
+	case "", models.EnemyBehaviorStationary:
+		return stationaryBehavior{}, nil
+	case models.EnemyBehaviorPatrol:
+		if len(cfg.Waypoints) == 0 {
+			return nil, fmt.Errorf("patrol 행동에는 waypoints가 필요합니다")
+		}
+		return &patrolBehavior{waypoints: append([]models.RealCoordinate(nil), cfg.Waypoints...)}, nil
+	case models.EnemyBehaviorWander:
+		return &wanderBehavior{heading: math.NaN()}, nil
+	case models.EnemyBehaviorFlee:
+		return fleeBehavior{radius: radius}, nil
+	case models.EnemyBehaviorEscape:
+		return &escapeBehavior{radius: radius}, nil
+	default:
+		return nil, fmt.Errorf("알 수 없는 적 행동: %q", cfg.Behavior)
+	}
+}
+
+func enemySpeed(e *models.Enemy) float64 {
+	if e.Speed > 0 {
+		return e.Speed
+	}
+	return defaultEnemySpeed
+}
+
+type stationaryBehavior struct{}
+
+func (stationaryBehavior) Name() string                                     { return models.EnemyBehaviorStationary }
+func (stationaryBehavior) Step(_ *AGVSimulator, _ *models.Enemy, _ float64) {}
+
+// patrolBehavior는 waypoints를 순서대로 돌고 마지막 다음에는 처음으로 돌아간다.
+type patrolBehavior struct {
+	waypoints []models.RealCoordinate
+	next      int
+}
+
+func (b *patrolBehavior) Name() string { return models.EnemyBehaviorPatrol }
+
+func (b *patrolBehavior) Step(sim *AGVSimulator, e *models.Enemy, dt float64) {
+	wp := b.waypoints[b.next]
+	if sim.moveEnemyTowardsLocked(e, wp.X, wp.Y, enemySpeed(e)*dt) {
+		b.next = (b.next + 1) % len(b.waypoints)
+	}
+}
+
+// wanderBehavior는 가끔 방향을 바꾸며 돌아다니고, 벽이나 맵 끝에 막히면 새 방향을 뽑는다.
+type wanderBehavior struct {
+	heading float64
+}
+
+func (b *wanderBehavior) Name() string { return models.EnemyBehaviorWander }
+
+func (b *wanderBehavior) Step(sim *AGVSimulator, e *models.Enemy, dt float64) {
+	if math.IsNaN(b.heading) || sim.rng.Float64() < 0.1 {
+		b.heading = sim.rng.Float64() * 2 * math.Pi
+	}
+	step := enemySpeed(e) * dt
+	nx := e.Position.X + math.Cos(b.heading)*step
+	ny := e.Position.Y + math.Sin(b.heading)*step
+	if !sim.tryMoveEnemyLocked(e, nx, ny) {
+		b.heading = sim.rng.Float64() * 2 * math.Pi
+	}
+}
+
+// fleeBehavior는 radius 안에 AGV가 들어오면 가장 가까운 AGV의 반대 방향으로 달아난다.
+type fleeBehavior struct {
+	radius float64
+}
+
+func (b fleeBehavior) Name() string { return models.EnemyBehaviorFlee }
+
+func (b fleeBehavior) Step(sim *AGVSimulator, e *models.Enemy, dt float64) {
+	a, dist := sim.nearestAGVLocked(e.Position.X, e.Position.Y)
+	if a == nil || dist > b.radius || dist == 0 {
+		return
+	}
+	dx := (e.Position.X - a.Status.Position.X) / dist
+	dy := (e.Position.Y - a.Status.Position.Y) / dist
+	step := enemySpeed(e) * dt
+	sim.tryMoveEnemyLocked(e, e.Position.X+dx*step, e.Position.Y+dy*step)
+}
+
+// escapeBehavior는 AGV가 radius 안에 한 번이라도 들어오면 가장 가까운 맵 가장자리로 달아나고,
+// 가장자리에 닿으면 맵을 빠져나간 것으로 보고 EnemyStateEscaped가 된다.
+type escapeBehavior struct {
+	radius    float64
+	triggered bool
+}
+
+func (b *escapeBehavior) Name() string { return models.EnemyBehaviorEscape }
+
+func (b *escapeBehavior) Step(sim *AGVSimulator, e *models.Enemy, dt float64) {
+	if !b.triggered {
+		if _, dist := sim.nearestAGVLocked(e.Position.X, e.Position.Y); dist > b.radius {
+			return
+		}
+		b.triggered = true
+	}
+
+	// 가장 가까운 가장자리로 직진
+	x, y := e.Position.X, e.Position.Y
+	tx, ty := 0.0, y
+	best := x
+	if d := sim.MapWidth - x; d < best {
+		best, tx, ty = d, sim.MapWidth, y
+	}
+	if d := y; d < best {
+		best, tx, ty = d, x, 0
+	}
+	if d := sim.MapHeight - y; d < best {
+		tx, ty = x, sim.MapHeight
+	}
+	if sim.moveEnemyTowardsLocked(e, tx, ty, enemySpeed(e)*dt) {
+		e.State = models.EnemyStateEscaped
+	}
+}
+
+// moveEnemyTowardsLocked는 적을 (x, y) 쪽으로 최대 step만큼 움직이고 목표에 닿았으면 true를 반환한다.
+// 장애물에 막히면 제자리에 머문다.
+func (sim *AGVSimulator) moveEnemyTowardsLocked(e *models.Enemy, x, y, step float64) bool {
+	dx, dy := x-e.Position.X, y-e.Position.Y
+	dist := math.Hypot(dx, dy)
+	if dist <= step {
+		return sim.tryMoveEnemyLocked(e, x, y)
+	}
+	sim.tryMoveEnemyLocked(e, e.Position.X+dx/dist*step, e.Position.Y+dy/dist*step)
+	return false
+}
+
+// tryMoveEnemyLocked는 적을 (nx, ny)로 옮긴다. 맵 밖이나 장애물 셀이면 움직이지 않고 false.
+func (sim *AGVSimulator) tryMoveEnemyLocked(e *models.Enemy, nx, ny float64) bool {
+	if nx < 0 || ny < 0 || nx > sim.MapWidth || ny > sim.MapHeight {
+		return false
+	}
+	if sim.isBlockedLocked(nx, ny) {
+		return false
+	}
+	e.Position.X, e.Position.Y = nx, ny
+	return true
+}
+
+// nearestAGVLocked는 (x, y)에서 가장 가까운 AGV와 그 거리를 반환한다. AGV가 없으면 (nil, +Inf).
+func (sim *AGVSimulator) nearestAGVLocked(x, y float64) (*simAGV, float64) {
+	var nearest *simAGV
+	best := math.Inf(1)
+	for _, a := range sim.agvs {
+		if d := a.distanceTo(x, y); d < best {
+			nearest, best = a, d
+		}
+	}
+	return nearest, best
+}
+
+// stepEnemiesLocked는 살아 있는 적들을 생성 순서대로 한 틱씩 움직인다.
+func (sim *AGVSimulator) stepEnemiesLocked() {
+	dt := sim.UpdateInterval.Seconds()
+	for i := range sim.Enemies {
+		e := &sim.Enemies[i]
+		if !enemyActive(e) {
+			continue
+		}
+		b, ok := sim.enemyBehaviors[e.ID]
+		if !ok {
+			continue
+		}
+		b.Step(sim, e, dt)
+		if e.State == models.EnemyStateEscaped {
+			sim.pending = append(sim.pending, models.WebSocketMessage{
+				Type: models.MessageTypeLog,
+				Data: map[string]interface{}{
+					"message":  fmt.Sprintf("%s 도주 성공", e.Name),
+					"enemy_id": e.ID,
+					"state":    e.State,
+				},
+				Timestamp: time.Now().UnixMilli(),
+			})
+		}
+	}
+}
+
+// enemyActive는 적이 아직 전장에 남아 있는지(처치·도주 전인지) 판단한다.
+func enemyActive(e *models.Enemy) bool {
+	return e.HP > 0 && e.State != models.EnemyStateEscaped && e.State != models.EnemyStateDefeated
+}
+
+// SetEnemyBehavior는 실행 중에도 적 한 명의 행동을 바꾼다.
+func (sim *AGVSimulator) SetEnemyBehavior(enemyID string, cfg models.EnemyBehaviorConfig) error {
+	b, err := NewEnemyBehavior(cfg)
+	if err != nil {
+		return err
+	}
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	for _, e := range sim.Enemies {
+		if e.ID == enemyID {
+			sim.enemyBehaviors[enemyID] = b
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrEnemyNotFound, enemyID)
+}
+
+// randomEnemyBehavior는 기본(랜덤) 월드용 행동을 뽑는다. 모든 데모가 같아 보이지 않도록 섞는다.
+func randomEnemyBehavior(rng *rand.Rand, e models.Enemy, mapWidth, mapHeight float64) EnemyBehavior {
+	switch rng.Intn(4) {
+	case 0:
+		return stationaryBehavior{}
+	case 1:
+		// 시작점과 근처 임의 지점 사이를 왕복
+		wp := models.RealCoordinate{
+			X: clamp(e.Position.X+(rng.Float64()*8-4), 0, mapWidth),
+			Y: clamp(e.Position.Y+(rng.Float64()*8-4), 0, mapHeight),
+		}
+		return &patrolBehavior{waypoints: []models.RealCoordinate{wp, {X: e.Position.X, Y: e.Position.Y}}}
+	case 2:
+		return &wanderBehavior{heading: math.NaN()}
+	default:
+		return fleeBehavior{radius: defaultTriggerRadius}
+	}
+}
+
+// cloneEnemyBehavior는 진행 상태(다음 웨이포인트, 헤딩, 도주 시작 여부)까지 복사한 행동을 만든다.
+// 값 타입 행동(stationary, flee)은 상태가 없으므로 그대로 쓴다.
+func cloneEnemyBehavior(b EnemyBehavior) EnemyBehavior {
+	switch b := b.(type) {
+	case *patrolBehavior:
+		c := *b
+		c.waypoints = append([]models.RealCoordinate(nil), b.waypoints...)
+		return &c
+	case *wanderBehavior:
+		c := *b
+		return &c
+	case *escapeBehavior:
+		c := *b
+		return &c
+	}
+	return b
+}
+
+// EnemyBehaviors는 적 ID별 현재 행동 이름을 반환한다.
+func (sim *AGVSimulator) EnemyBehaviors() map[string]string {
+	sim.mu.RLock()
+	defer sim.mu.RUnlock()
+	out := make(map[string]string, len(sim.enemyBehaviors))
+	for id, b := range sim.enemyBehaviors {
+		out[id] = b.Name()
+	}
+	return out
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"errors"
+	"sion-backend/models"
+	"testing"
+	"time"
+)
+
+// newBehaviorSimulator는 장애물 없는 20x20 맵에 AGV 한 대(2,2)와 적 한 명(cfg 행동)을 둔 시뮬레이터를 만든다.
+func newBehaviorSimulator(t *testing.T, x, y float64, cfg models.EnemyBehaviorConfig) *AGVSimulator {
+	t.Helper()
+	seed := int64(3)
+	sim := newScenarioSimulator(t, models.Scenario{
+		Name:      "behavior",
+		MapWidth:  20,
+		MapHeight: 20,
+		Enemies: []models.ScenarioEnemy{{
+			ID: "enemy-1", Name: "아리", HP: 50, X: x, Y: y, Speed: 2,
+			Behavior: cfg.Behavior, Waypoints: cfg.Waypoints, TriggerRadius: cfg.TriggerRadius,
+		}},
+		AGV:  models.ScenarioAGV{X: 2, Y: 2},
+		Seed: &seed,
+	})
+	sim.UpdateInterval = 500 * time.Millisecond
+	return sim
+}
+
+func TestNewEnemyBehavior_Validation(t *testing.T) {
+	if b, err := NewEnemyBehavior(models.EnemyBehaviorConfig{}); err != nil || b.Name() != models.EnemyBehaviorStationary {
+		t.Fatalf("빈 설정은 stationary여야 함: %v, %v", b, err)
+	}
+	if _, err := NewEnemyBehavior(models.EnemyBehaviorConfig{Behavior: models.EnemyBehaviorPatrol}); err == nil {
+		t.Fatal("waypoints 없는 patrol은 실패해야 함")
+	}
+	if _, err := NewEnemyBehavior(models.EnemyBehaviorConfig{Behavior: "teleport"}); err == nil {
+		t.Fatal("알 수 없는 행동은 실패해야 함")
+	}
+}
+
+func TestEnemyBehavior_PatrolCyclesWaypoints(t *testing.T) {
+	sim := newBehaviorSimulator(t, 15, 15, models.EnemyBehaviorConfig{
+		Behavior:  models.EnemyBehaviorPatrol,
+		Waypoints: []models.RealCoordinate{{X: 18, Y: 15}, {X: 15, Y: 15}},
+	})
+	// 속도 2 × 0.5초 = 틱당 1칸. 3칸 거리이므로 3틱에 도착, 다시 3틱에 복귀한다.
+	var xs []float64
+	for i := 0; i < 12; i++ {
+		sim.stepEnemiesLocked()
+		xs = append(xs, sim.Enemies[0].Position.X)
+	}
+	want := []float64{16, 17, 18, 17, 16, 15, 16, 17, 18, 17, 16, 15}
+	for i := range want {
+		if xs[i] != want[i] {
+			t.Fatalf("순찰 경로가 다름: got %v, want %v", xs, want)
+		}
+	}
+}
+
+func TestEnemyBehavior_FleeIncreasesDistance(t *testing.T) {
+	sim := newBehaviorSimulator(t, 5, 5, models.EnemyBehaviorConfig{Behavior: models.EnemyBehaviorFlee})
+	before := sim.agvs[0].distanceTo(5, 5)
+	for i := 0; i < 5; i++ {
+		sim.stepEnemiesLocked()
+	}
+	e := sim.Enemies[0].Position
+	if after := sim.agvs[0].distanceTo(e.X, e.Y); after <= before {
+		t.Fatalf("도망 후 거리가 늘어야 함: %.2f -> %.2f", before, after)
+	}
+}
+
+func TestEnemyBehavior_EscapeLeavesMapAndIsIgnored(t *testing.T) {
+	sim := newBehaviorSimulator(t, 4, 3, models.EnemyBehaviorConfig{Behavior: models.EnemyBehaviorEscape})
+	for i := 0; i < 20 && sim.Enemies[0].State != models.EnemyStateEscaped; i++ {
+		sim.stepEnemiesLocked()
+	}
+	if sim.Enemies[0].State != models.EnemyStateEscaped {
+		t.Fatalf("가장자리에 닿으면 escaped여야 함: %+v", sim.Enemies[0])
+	}
+	if n := sim.aliveEnemyCountLocked(); n != 0 {
+		t.Fatalf("도주한 적은 남은 적으로 세지 않아야 함: %d", n)
+	}
+	sim.agvs[0].Status.Position = sim.Enemies[0].Position
+	if detected := sim.detectEnemiesLocked(sim.agvs[0]); len(detected) != 0 {
+		t.Fatalf("도주한 적은 탐지되지 않아야 함: %+v", detected)
+	}
+}
+
+func TestSetEnemyBehavior(t *testing.T) {
+	sim := newBehaviorSimulator(t, 10, 10, models.EnemyBehaviorConfig{})
+	if err := sim.SetEnemyBehavior("enemy-1", models.EnemyBehaviorConfig{Behavior: models.EnemyBehaviorWander}); err != nil {
+		t.Fatalf("SetEnemyBehavior 실패: %v", err)
+	}
+	if got := sim.EnemyBehaviors()["enemy-1"]; got != models.EnemyBehaviorWander {
+		t.Fatalf("행동이 바뀌지 않음: %s", got)
+	}
+	if err := sim.SetEnemyBehavior("nobody", models.EnemyBehaviorConfig{}); !errors.Is(err, ErrEnemyNotFound) {
+		t.Fatalf("없는 적은 ErrEnemyNotFound여야 함: %v", err)
+	}
+}
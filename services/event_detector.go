@@ -0,0 +1,249 @@
+package services
+
+import (
+	"log"
+	"sion-backend/models"
+	"sync"
+	"time"
+)
+
+// 상태 스트림 이벤트 감지기. 실제 AGV(Broker)와 시뮬레이터의 status 보고를 AGV별로 비교해
+// 해설 가능한 전환(target_change, charging, kill, low_battery, multiple_enemies)을 찾고 Fire를 호출한다.
+// 순간적인 흔들림은 디바운스(연속 보고 수)로 거르고, 같은 이벤트의 연속 해설은 타입별 쿨다운으로 막는다.
+
+// 감지하는 이벤트 타입. LLMService.ExplainEvent가 이해하는 이름과 같다.
+const (
+	EventTargetChange    = "target_change"
+	EventCharging        = "charging"
+	EventKill            = "kill"
+	EventLowBattery      = "low_battery"
+	EventMultipleEnemies = "multiple_enemies"
+)
+
+const (
+	defaultEventDebounce = 2
+	// defaultLowBattery 미만으로 떨어지면 low_battery. lowBatteryRearm 이상으로 회복해야 다시 울린다.
+	defaultLowBattery = 20
+	lowBatteryRearm   = 5
+	// defaultMultipleEnemies 이상 동시에 탐지되면 multiple_enemies.
+	defaultMultipleEnemies = 3
+	// defaultKillHP는 직전 타겟이 탐지 목록에서 사라졌을 때 격살로 볼 마지막 HP 상한 (시뮬레이터 1회 공격량).
+	defaultKillHP = 10
+)
+
+// DefaultEventCooldowns는 이벤트 타입별 최소 해설 간격.
+func DefaultEventCooldowns() map[string]time.Duration {
+	return map[string]time.Duration{
+		EventTargetChange:    10 * time.Second,
+		EventCharging:        15 * time.Second,
+		EventKill:            5 * time.Second,
+		EventLowBattery:      60 * time.Second,
+		EventMultipleEnemies: 30 * time.Second,
+	}
+}
+
+// eventTrack은 AGV 하나의 직전 상태와 디바운스/쿨다운 기록.
+type eventTrack struct {
+	seen       bool
+	targetID   string
+	lastTarget *models.Enemy
+	charging   bool
+	lowBattery bool
+	crowded    bool
+	pending    map[string]int // 이벤트 타입 → 조건이 연속으로 성립한 보고 수
+	pendingKey map[string]string
+	lastFired  map[string]time.Time
+}
+
+// EventDetector는 AGV ID별로 상태 전환을 추적한다. 여러 고루틴에서 호출해도 안전하다.
+type EventDetector struct {
+	// Debounce는 조건이 몇 번 연속 보고돼야 이벤트로 인정할지. kill은 순간 이벤트라 디바운스하지 않는다.
+	Debounce int
+	// Cooldowns는 AGV·이벤트 타입별 최소 발생 간격. 없는 타입은 쿨다운이 없다.
+	Cooldowns            map[string]time.Duration
+	LowBatteryThreshold  int
+	MultipleEnemiesCount int
+	KillHPThreshold      int
+	// Fire는 이벤트마다 잠금 밖에서 호출된다 (보통 handlers.ExplainAGVEvent).
+	Fire func(eventType string, status *models.AGVStatus)
+
+	now    func() time.Time
+	mu     sync.Mutex
+	tracks map[string]*eventTrack
+}
+
+func NewEventDetector(fire func(eventType string, status *models.AGVStatus)) *EventDetector {
+	return &EventDetector{
+		Debounce:             defaultEventDebounce,
+		Cooldowns:            DefaultEventCooldowns(),
+		LowBatteryThreshold:  defaultLowBattery,
+		MultipleEnemiesCount: defaultMultipleEnemies,
+		KillHPThreshold:      defaultKillHP,
+		Fire:                 fire,
+		now:                  time.Now,
+		tracks:               make(map[string]*eventTrack),
+	}
+}
+
+// Observe는 status 보고 하나를 반영하고 발생한 이벤트 타입을 반환한다. Fire가 있으면 이벤트마다 호출한다.
+func (d *EventDetector) Observe(status *models.AGVStatus) []string {
+	if status == nil {
+		return nil
+	}
+	d.mu.Lock()
+	events := d.observeLocked(status)
+	d.mu.Unlock()
+
+	if d.Fire != nil && len(events) > 0 {
+		snapshot := *status
+		snapshot.TargetEnemy = copyEnemy(status.TargetEnemy)
+		for _, ev := range events {
+			log.Printf("[INFO] AGV %s 이벤트 감지: %s", status.ID, ev)
+			d.Fire(ev, &snapshot)
+		}
+	}
+	return events
+}
+
+// Forget은 AGV의 추적 상태를 버린다 (연결 해제·월드 재구성 시).
+func (d *EventDetector) Forget(agvID string) {
+	d.mu.Lock()
+	delete(d.tracks, agvID)
+	d.mu.Unlock()
+}
+
+func (d *EventDetector) observeLocked(status *models.AGVStatus) []string {
+	t := d.tracks[status.ID]
+	if t == nil {
+		t = &eventTrack{
+			pending:    make(map[string]int),
+			pendingKey: make(map[string]string),
+			lastFired:  make(map[string]time.Time),
+		}
+		d.tracks[status.ID] = t
+	}
+
+	targetID := ""
+	if status.TargetEnemy != nil {
+		targetID = status.TargetEnemy.ID
+	}
+	charging := status.State == models.StateCharging
+	crowded := len(status.DetectedEnemies) >= d.MultipleEnemiesCount
+
+	// 첫 보고는 기준선만 잡는다. 접속 직후 현재 상태를 "변화"로 해설하지 않기 위해서다.
+	if !t.seen {
+		t.seen = true
+		t.targetID = targetID
+		t.lastTarget = copyEnemy(status.TargetEnemy)
+		t.charging = charging
+		t.lowBattery = status.Battery < d.LowBatteryThreshold
+		t.crowded = crowded
+		return nil
+	}
+
+	var events []string
+	fire := func(ev string) {
+		if cd := d.Cooldowns[ev]; cd > 0 {
+			if last, ok := t.lastFired[ev]; ok && d.now().Sub(last) < cd {
+				return
+			}
+		}
+		t.lastFired[ev] = d.now()
+		events = append(events, ev)
+	}
+
+	// kill: 직전 타겟이 탐지 목록에서 사라졌거나 HP 0으로 보고됐고, 마지막 HP가 한 번에 잡힐 만큼 낮았다.
+	if t.lastTarget != nil && targetID != t.lastTarget.ID {
+		if hp, present := enemyHP(status.DetectedEnemies, t.lastTarget.ID); (!present && t.lastTarget.HP <= d.KillHPThreshold) || (present && hp == 0) {
+			fire(EventKill)
+		}
+	}
+
+	// target_change: 새 타겟이 Debounce번 연속 유지돼야 한다.
+	if targetID != "" && targetID != t.targetID {
+		if d.debounced(t, EventTargetChange, targetID) {
+			t.targetID = targetID
+			fire(EventTargetChange)
+		}
+	} else {
+		d.resetPending(t, EventTargetChange)
+		if targetID == "" {
+			t.targetID = ""
+		}
+	}
+
+	// charging: 돌진 상태로 들어갈 때.
+	if charging && !t.charging {
+		if d.debounced(t, EventCharging, "") {
+			t.charging = true
+			fire(EventCharging)
+		}
+	} else {
+		d.resetPending(t, EventCharging)
+		t.charging = charging
+	}
+
+	// low_battery: 임계값 아래로 떨어질 때 한 번. 임계값+lowBatteryRearm 이상으로 회복하면 다시 무장한다.
+	switch {
+	case status.Battery < d.LowBatteryThreshold && !t.lowBattery:
+		if d.debounced(t, EventLowBattery, "") {
+			t.lowBattery = true
+			fire(EventLowBattery)
+		}
+	case status.Battery >= d.LowBatteryThreshold+lowBatteryRearm:
+		t.lowBattery = false
+		d.resetPending(t, EventLowBattery)
+	default:
+		d.resetPending(t, EventLowBattery)
+	}
+
+	// multiple_enemies: 동시 탐지 수가 기준 이상이 될 때.
+	if crowded && !t.crowded {
+		if d.debounced(t, EventMultipleEnemies, "") {
+			t.crowded = true
+			fire(EventMultipleEnemies)
+		}
+	} else {
+		d.resetPending(t, EventMultipleEnemies)
+		t.crowded = crowded
+	}
+
+	t.lastTarget = copyEnemy(status.TargetEnemy)
+	return events
+}
+
+// debounced는 같은 조건(key)이 연속으로 Debounce번 성립했는지 센다. key가 바뀌면 처음부터 다시 센다.
+func (d *EventDetector) debounced(t *eventTrack, ev, key string) bool {
+	if t.pendingKey[ev] != key {
+		t.pendingKey[ev] = key
+		t.pending[ev] = 0
+	}
+	t.pending[ev]++
+	if t.pending[ev] < d.Debounce {
+		return false
+	}
+	d.resetPending(t, ev)
+	return true
+}
+
+func (d *EventDetector) resetPending(t *eventTrack, ev string) {
+	delete(t.pending, ev)
+	delete(t.pendingKey, ev)
+}
+
+func copyEnemy(e *models.Enemy) *models.Enemy {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	return &c
+}
+
+func enemyHP(enemies []models.Enemy, id string) (hp int, present bool) {
+	for _, e := range enemies {
+		if e.ID == id {
+			return e.HP, true
+		}
+	}
+	return 0, false
+}
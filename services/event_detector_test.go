@@ -0,0 +1,148 @@
+package services
+
+import (
+	"sion-backend/models"
+	"testing"
+	"time"
+)
+
+func newTestDetector() (*EventDetector, *time.Time) {
+	now := time.Unix(1000, 0)
+	d := NewEventDetector(nil)
+	d.now = func() time.Time { return now }
+	return d, &now
+}
+
+func eventStatus(battery int, state string, target *models.Enemy, detected ...models.Enemy) *models.AGVStatus {
+	return &models.AGVStatus{
+		ID: "sion-001", Mode: models.ModeAuto, State: state, Battery: battery,
+		TargetEnemy: target, DetectedEnemies: detected,
+	}
+}
+
+func TestEventDetector_DebouncesTargetChangeAndCharging(t *testing.T) {
+	d, _ := newTestDetector()
+	e1 := models.Enemy{ID: "e1", HP: 50}
+	e2 := models.Enemy{ID: "e2", HP: 80}
+
+	if ev := d.Observe(eventStatus(100, models.StateSearching, nil)); len(ev) != 0 {
+		t.Fatalf("첫 보고는 기준선: %v", ev)
+	}
+	// 한 번만 보인 타겟은 무시된다.
+	if ev := d.Observe(eventStatus(100, models.StateCharging, &e1, e1, e2)); len(ev) != 0 {
+		t.Fatalf("디바운스 전에는 이벤트 없음: %v", ev)
+	}
+	d.Observe(eventStatus(100, models.StateSearching, nil, e1, e2))
+	if ev := d.Observe(eventStatus(100, models.StateCharging, &e2, e1, e2)); len(ev) != 0 {
+		t.Fatalf("흔들림은 다시 세야 함: %v", ev)
+	}
+	ev := d.Observe(eventStatus(100, models.StateCharging, &e2, e1, e2))
+	if !hasEvent(ev, EventTargetChange) || !hasEvent(ev, EventCharging) {
+		t.Fatalf("두 번 연속이면 target_change와 charging: %v", ev)
+	}
+	if ev := d.Observe(eventStatus(100, models.StateCharging, &e2, e1, e2)); len(ev) != 0 {
+		t.Fatalf("상태 유지 중에는 이벤트 없음: %v", ev)
+	}
+}
+
+func TestEventDetector_CooldownPerType(t *testing.T) {
+	d, now := newTestDetector()
+	d.Debounce = 1
+	a := models.Enemy{ID: "a", HP: 50}
+	b := models.Enemy{ID: "b", HP: 50}
+
+	d.Observe(eventStatus(100, models.StateCharging, nil))
+	if ev := d.Observe(eventStatus(100, models.StateCharging, &a, a, b)); !hasEvent(ev, EventTargetChange) {
+		t.Fatalf("target_change 기대: %v", ev)
+	}
+	*now = now.Add(time.Second)
+	if ev := d.Observe(eventStatus(100, models.StateCharging, &b, a, b)); hasEvent(ev, EventTargetChange) {
+		t.Fatalf("쿨다운 중에는 다시 울리면 안 됨: %v", ev)
+	}
+	*now = now.Add(d.Cooldowns[EventTargetChange])
+	if ev := d.Observe(eventStatus(100, models.StateCharging, &a, a, b)); !hasEvent(ev, EventTargetChange) {
+		t.Fatalf("쿨다운이 지나면 다시 울려야 함: %v", ev)
+	}
+}
+
+func TestEventDetector_KillLowBatteryMultipleEnemies(t *testing.T) {
+	d, _ := newTestDetector()
+	var fired []string
+	d.Fire = func(ev string, status *models.AGVStatus) { fired = append(fired, ev) }
+
+	weak := models.Enemy{ID: "weak", HP: 10}
+	others := []models.Enemy{{ID: "x"}, {ID: "y"}}
+	d.Observe(eventStatus(50, models.StateCharging, &weak, weak))
+	// 마지막 HP 10인 타겟이 탐지 목록에서 사라지면 격살.
+	if ev := d.Observe(eventStatus(50, models.StateSearching, nil)); !hasEvent(ev, EventKill) {
+		t.Fatalf("kill 기대: %v", ev)
+	}
+
+	// HP가 많은 타겟이 시야에서 사라지는 건 격살이 아니다.
+	strong := models.Enemy{ID: "strong", HP: 90}
+	d.Observe(eventStatus(50, models.StateCharging, &strong, strong))
+	if ev := d.Observe(eventStatus(50, models.StateSearching, nil)); hasEvent(ev, EventKill) {
+		t.Fatalf("HP 90 타겟 이탈은 kill이 아님: %v", ev)
+	}
+
+	d.Observe(eventStatus(19, models.StateSearching, nil))
+	if ev := d.Observe(eventStatus(18, models.StateSearching, nil)); !hasEvent(ev, EventLowBattery) {
+		t.Fatalf("low_battery 기대: %v", ev)
+	}
+	if ev := d.Observe(eventStatus(17, models.StateSearching, nil)); hasEvent(ev, EventLowBattery) {
+		t.Fatalf("low_battery는 한 번만: %v", ev)
+	}
+
+	crowd := append([]models.Enemy{weak}, others...)
+	d.Observe(eventStatus(17, models.StateSearching, nil, crowd...))
+	if ev := d.Observe(eventStatus(17, models.StateSearching, nil, crowd...)); !hasEvent(ev, EventMultipleEnemies) {
+		t.Fatalf("multiple_enemies 기대: %v", ev)
+	}
+
+	want := []string{EventKill, EventLowBattery, EventMultipleEnemies}
+	if len(fired) != len(want) {
+		t.Fatalf("Fire 호출 %v, want %v", fired, want)
+	}
+	for i := range want {
+		if fired[i] != want[i] {
+			t.Fatalf("Fire 호출 %v, want %v", fired, want)
+		}
+	}
+}
+
+func TestSimulator_StatusFuncFeedsEventDetector(t *testing.T) {
+	sim := NewAGVSimulator(nil)
+	sim.LogToDB = false
+	var events []string
+	d := NewEventDetector(func(ev string, _ *models.AGVStatus) { events = append(events, ev) })
+	sim.StatusFunc = func(status *models.AGVStatus) { d.Observe(status) }
+	sc := models.Scenario{
+		Name:     "events",
+		MapWidth: 20, MapHeight: 20,
+		AGV: models.ScenarioAGV{X: 2, Y: 2},
+		Enemies: []models.ScenarioEnemy{
+			{ID: "e1", Name: "아리", HP: 50, X: 4, Y: 2},
+		},
+	}
+	if err := sim.LoadScenario(sc); err != nil {
+		t.Fatal(err)
+	}
+	// 접속 직후 기준선: 타겟 없이 탐색 중.
+	d.Observe(&models.AGVStatus{ID: "sion-001", State: models.StateSearching, Battery: 100})
+	// 헤드리스 스로틀로 브로드캐스트하지 않는 틱도 감지기에 들어가야 한다.
+	for i := 0; i < 3; i++ {
+		sim.update(false)
+	}
+	if !hasEvent(events, EventTargetChange) || !hasEvent(events, EventCharging) {
+		t.Fatalf("시뮬레이터 상태로 target_change/charging이 감지돼야 함: %v", events)
+	}
+}
+
+func hasEvent(events []string, ev string) bool {
+	for _, e := range events {
+		if e == ev {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"sion-backend/models"
+	"sync"
+	"time"
+)
+
+// 장애 주입. 개발 환경에서는 보기 힘든 현장 조건(센서 끊김, 메시지 지연·순서 뒤바뀜·중복, 연결 끊김,
+// 배터리 급락, 모터 고착)을 확률과 스케줄로 일으킨다. 시뮬레이터는 틱마다, Broker는 메시지마다 판정을 묻는다.
+
+// 장애 이름. Counts의 키이자 로그에 쓰인다.
+const (
+	FaultSensorDropout    = "sensor_dropout"
+	FaultBatteryDrop      = "battery_drop"
+	FaultStuckMotor       = "stuck_motor"
+	FaultMessageDelay     = "message_delay"
+	FaultMessageReorder   = "message_reorder"
+	FaultMessageDuplicate = "message_duplicate"
+	FaultDisconnect       = "disconnect"
+)
+
+const (
+	defaultBatteryDropAmount = 20
+	defaultStuckMotorSec     = 3.0
+	defaultMessageDelayMs    = 500
+	maxMessageDelayMs        = 30000
+	// reorderFlushTimeout은 순서를 바꾸려고 잡아 둔 메시지를 뒤따르는 메시지가 없을 때 그냥 보내기까지 기다리는 시간.
+	reorderFlushTimeout = time.Second
+)
+
+// DefaultFaultConfig는 모든 장애가 꺼진 설정. 확률만 켜면 되도록 크기 값은 채워 둔다.
+func DefaultFaultConfig() models.FaultConfig {
+	return models.FaultConfig{
+		BatteryDrop:  models.BatteryDropFault{Amount: defaultBatteryDropAmount},
+		StuckMotor:   models.StuckMotorFault{DurationSec: defaultStuckMotorSec},
+		MessageDelay: models.MessageDelayFault{MinMs: defaultMessageDelayMs, MaxMs: defaultMessageDelayMs},
+	}
+}
+
+// ValidateFaultConfig는 설정 범위를 검사한다.
+func ValidateFaultConfig(cfg models.FaultConfig) error {
+	specs := map[string]models.FaultSpec{
+		FaultSensorDropout:    cfg.SensorDropout,
+		FaultBatteryDrop:      cfg.BatteryDrop.FaultSpec,
+		FaultStuckMotor:       cfg.StuckMotor.FaultSpec,
+		FaultMessageDelay:     cfg.MessageDelay.FaultSpec,
+		FaultMessageReorder:   cfg.MessageReorder,
+		FaultMessageDuplicate: cfg.MessageDuplicate,
+		FaultDisconnect:       cfg.Disconnect,
+	}
+	for name, s := range specs {
+		if s.Probability < 0 || s.Probability > 1 {
+			return fmt.Errorf("%s.probability는 0~1 사이여야 합니다", name)
+		}
+		sch := s.Schedule
+		if sch.StartSec < 0 || sch.DurationSec < 0 || sch.PeriodSec < 0 {
+			return fmt.Errorf("%s.schedule 값은 0 이상이어야 합니다", name)
+		}
+		if sch.PeriodSec > 0 && (sch.DurationSec == 0 || sch.DurationSec > sch.PeriodSec) {
+			return fmt.Errorf("%s.schedule.period_sec를 쓰려면 duration_sec가 0보다 크고 period_sec 이하여야 합니다", name)
+		}
+	}
+	if cfg.BatteryDrop.Amount < 1 || cfg.BatteryDrop.Amount > 100 {
+		return fmt.Errorf("battery_drop.amount는 1~100 사이여야 합니다")
+	}
+	if cfg.StuckMotor.DurationSec <= 0 {
+		return fmt.Errorf("stuck_motor.duration_sec는 0보다 커야 합니다")
+	}
+	d := cfg.MessageDelay
+	if d.MinMs < 0 || d.MaxMs < d.MinMs || d.MaxMs > maxMessageDelayMs {
+		return fmt.Errorf("message_delay는 0 <= min_ms <= max_ms <= %d 이어야 합니다", maxMessageDelayMs)
+	}
+	return nil
+}
+
+// scheduleActive는 기준 시각부터 at이 지난 시점에 스케줄 시간대 안인지 판단한다.
+func scheduleActive(s models.FaultSchedule, at time.Duration) bool {
+	t := at.Seconds() - s.StartSec
+	if t < 0 {
+		return false
+	}
+	if s.PeriodSec > 0 {
+		t = math.Mod(t, s.PeriodSec)
+	}
+	return s.DurationSec == 0 || t < s.DurationSec
+}
+
+// MessagePlan은 메시지 하나에 적용할 장애. 모두 zero면 그대로 보낸다.
+type MessagePlan struct {
+	Delay     time.Duration
+	Reorder   bool
+	Duplicate bool
+}
+
+// FaultInjector는 장애 설정과 전용 난수원을 가진다. 시뮬레이터와 Broker가 함께 쓰므로 여러 고루틴에서 호출해도 안전하다.
+// 난수원이 월드 rng와 분리돼 있어 장애를 꺼 두면 시뮬레이터 재현성에 영향이 없다.
+type FaultInjector struct {
+	mu      sync.Mutex
+	cfg     models.FaultConfig
+	rng     *rand.Rand
+	armedAt time.Time
+	counts  map[string]int
+}
+
+func NewFaultInjector() *FaultInjector {
+	f := &FaultInjector{}
+	_ = f.SetConfig(DefaultFaultConfig())
+	return f
+}
+
+// SetConfig는 설정을 바꾸고 난수원·기준 시각·발생 횟수를 새로 시작한다.
+func (f *FaultInjector) SetConfig(cfg models.FaultConfig) error {
+	if err := ValidateFaultConfig(cfg); err != nil {
+		return err
+	}
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	f.mu.Lock()
+	f.cfg = cfg
+	f.rng = rand.New(rand.NewSource(seed))
+	f.armedAt = time.Now()
+	f.counts = make(map[string]int)
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *FaultInjector) Config() models.FaultConfig {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.cfg
+}
+
+// Counts는 설정 적용 이후 장애별 발생 횟수를 반환한다.
+func (f *FaultInjector) Counts() map[string]int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make(map[string]int, len(f.counts))
+	for k, v := range f.counts {
+		out[k] = v
+	}
+	return out
+}
+
+// fireLocked는 at 시점에 spec 장애가 발생하는지 뽑는다. 꺼져 있거나 시간대 밖이면 난수를 소비하지 않는다.
+func (f *FaultInjector) fireLocked(name string, spec models.FaultSpec, at time.Duration) bool {
+	if spec.Probability <= 0 || !scheduleActive(spec.Schedule, at) {
+		return false
+	}
+	if f.rng.Float64() >= spec.Probability {
+		return false
+	}
+	f.counts[name]++
+	return true
+}
+
+// AGVFaults는 시뮬레이터 AGV 한 대의 이번 틱 장애. at은 시뮬레이션 경과 시간.
+type AGVFaults struct {
+	SensorDropout bool
+	BatteryDrop   int
+	StuckFor      time.Duration
+}
+
+// RollAGV는 시뮬레이터 AGV 한 대의 이번 틱 장애를 뽑는다.
+func (f *FaultInjector) RollAGV(at time.Duration) AGVFaults {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out AGVFaults
+	out.SensorDropout = f.fireLocked(FaultSensorDropout, f.cfg.SensorDropout, at)
+	if f.fireLocked(FaultBatteryDrop, f.cfg.BatteryDrop.FaultSpec, at) {
+		out.BatteryDrop = f.cfg.BatteryDrop.Amount
+	}
+	if f.fireLocked(FaultStuckMotor, f.cfg.StuckMotor.FaultSpec, at) {
+		out.StuckFor = time.Duration(f.cfg.StuckMotor.DurationSec * float64(time.Second))
+	}
+	return out
+}
+
+// PlanMessage는 내보낼 메시지 하나에 적용할 장애를 뽑는다. 시간대는 설정 적용 시각 기준이다.
+func (f *FaultInjector) PlanMessage() MessagePlan {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	at := time.Since(f.armedAt)
+	var plan MessagePlan
+	if d := f.cfg.MessageDelay; f.fireLocked(FaultMessageDelay, d.FaultSpec, at) {
+		ms := d.MinMs
+		if d.MaxMs > d.MinMs {
+			ms += f.rng.Intn(d.MaxMs - d.MinMs + 1)
+		}
+		plan.Delay = time.Duration(ms) * time.Millisecond
+	}
+	plan.Reorder = f.fireLocked(FaultMessageReorder, f.cfg.MessageReorder, at)
+	plan.Duplicate = f.fireLocked(FaultMessageDuplicate, f.cfg.MessageDuplicate, at)
+	return plan
+}
+
+// RollDisconnect는 AGV 메시지 하나를 받았을 때 연결을 끊을지 뽑는다.
+func (f *FaultInjector) RollDisconnect() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.fireLocked(FaultDisconnect, f.cfg.Disconnect, time.Since(f.armedAt))
+}
+
+// RecordForced는 API로 직접 일으킨 장애도 발생 횟수에 넣는다.
+func (f *FaultInjector) RecordForced(name string) {
+	f.mu.Lock()
+	f.counts[name]++
+	f.mu.Unlock()
+}
+
+// faultLink는 한 방향(웹 또는 AGV) 송신 경로에 메시지 장애를 적용한다.
+// 순서 바꾸기는 메시지 하나를 잡아 두었다가 다음 메시지 뒤에 보낸다. to는 받는 AGV ID이고 웹 쪽은 비워 둔다.
+type faultLink struct {
+	name string
+	send func(to string, raw []byte)
+
+	mu    sync.Mutex
+	held  *linkPacket
+	timer *time.Timer
+}
+
+type linkPacket struct {
+	to  string
+	raw []byte
+}
+
+func newFaultLink(name string, send func(to string, raw []byte)) *faultLink {
+	return &faultLink{name: name, send: send}
+}
+
+// deliver는 plan대로 raw를 to에게 보낸다.
+func (l *faultLink) deliver(to string, raw []byte, plan MessagePlan) {
+	out := func() {
+		l.send(to, raw)
+		if plan.Duplicate {
+			l.send(to, raw)
+		}
+	}
+	if plan.Delay > 0 {
+		log.Printf("[WARN] 장애 주입: %s 메시지 %v 지연", l.name, plan.Delay)
+		time.AfterFunc(plan.Delay, out)
+		return
+	}
+
+	l.mu.Lock()
+	if plan.Reorder && l.held == nil && !plan.Duplicate {
+		// 다음 메시지가 오면 그 뒤에 보낸다. 한동안 오지 않으면 그냥 보낸다.
+		l.held = &linkPacket{to: to, raw: raw}
+		l.timer = time.AfterFunc(reorderFlushTimeout, l.flush)
+		l.mu.Unlock()
+		return
+	}
+	held := l.held
+	l.held = nil
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+	l.mu.Unlock()
+
+	out()
+	if held != nil {
+		log.Printf("[WARN] 장애 주입: %s 메시지 순서 바꿈", l.name)
+		l.send(held.to, held.raw)
+	}
+}
+
+func (l *faultLink) flush() {
+	l.mu.Lock()
+	held := l.held
+	l.held = nil
+	l.timer = nil
+	l.mu.Unlock()
+	if held != nil {
+		l.send(held.to, held.raw)
+	}
+}
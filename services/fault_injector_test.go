@@ -0,0 +1,149 @@
+package services
+
+import (
+	"sion-backend/models"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestScheduleActive(t *testing.T) {
+	s := models.FaultSchedule{StartSec: 10, DurationSec: 2, PeriodSec: 5}
+	cases := map[float64]bool{0: false, 9.9: false, 10: true, 11.9: true, 12: false, 15: true, 17.5: false}
+	for sec, want := range cases {
+		if got := scheduleActive(s, time.Duration(sec*float64(time.Second))); got != want {
+			t.Errorf("%.1fs: got %v want %v", sec, got, want)
+		}
+	}
+	if !scheduleActive(models.FaultSchedule{}, time.Hour) {
+		t.Error("빈 스케줄은 항상 켜져 있어야 함")
+	}
+}
+
+func TestValidateFaultConfig(t *testing.T) {
+	if err := ValidateFaultConfig(DefaultFaultConfig()); err != nil {
+		t.Fatalf("기본 설정은 유효해야 함: %v", err)
+	}
+	bad := DefaultFaultConfig()
+	bad.MessageDuplicate.Probability = 1.5
+	if err := ValidateFaultConfig(bad); err == nil {
+		t.Error("1을 넘는 확률은 거부돼야 함")
+	}
+	bad = DefaultFaultConfig()
+	bad.StuckMotor.Schedule = models.FaultSchedule{PeriodSec: 5}
+	if err := ValidateFaultConfig(bad); err == nil {
+		t.Error("duration 없는 주기 스케줄은 거부돼야 함")
+	}
+	bad = DefaultFaultConfig()
+	bad.MessageDelay.MinMs, bad.MessageDelay.MaxMs = 300, 100
+	if err := ValidateFaultConfig(bad); err == nil {
+		t.Error("min_ms > max_ms는 거부돼야 함")
+	}
+}
+
+func TestSimulatorFaults_BatteryDropStuckMotorAndSensorDropout(t *testing.T) {
+	sim := newReconfigSimulator(t, 8)
+	f := NewFaultInjector()
+	cfg := DefaultFaultConfig()
+	cfg.Seed = 1
+	cfg.BatteryDrop.Probability = 1
+	cfg.BatteryDrop.Schedule = models.FaultSchedule{StartSec: 1, DurationSec: 0.5}
+	cfg.StuckMotor.Probability = 1
+	cfg.StuckMotor.DurationSec = 2
+	cfg.StuckMotor.Schedule = models.FaultSchedule{StartSec: 3, DurationSec: 0.5}
+	cfg.SensorDropout.Probability = 1
+	cfg.SensorDropout.Schedule = models.FaultSchedule{StartSec: 6, DurationSec: 0.5}
+	if err := f.SetConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	sim.SetFaultInjector(f)
+	a := sim.agvs[0]
+
+	// 0~1초: 장애 없음
+	sim.update(false)
+	sim.update(false)
+	before := a.Status.Battery
+	sim.update(false) // elapsed 1.0s → 배터리 급락
+	if got := a.Status.Battery; got > before-cfg.BatteryDrop.Amount {
+		t.Fatalf("배터리가 %d%% 이상 떨어져야 함: %d → %d", cfg.BatteryDrop.Amount, before, got)
+	}
+
+	for sim.elapsed < 3*time.Second {
+		sim.update(false)
+	}
+	sim.update(false) // elapsed 3.0s → 모터 고착 2초
+	stuckAt := a.Status.Position
+	for i := 0; i < 3; i++ {
+		sim.update(false)
+		if a.Status.Position != stuckAt || a.Status.Drive != (models.DriveState{}) {
+			t.Fatalf("고착 중에는 움직이면 안 됨: %+v -> %+v", stuckAt, a.Status.Position)
+		}
+	}
+	for sim.elapsed < 6*time.Second {
+		sim.update(false)
+	}
+	if a.stuckLeft != 0 {
+		t.Fatalf("고착이 풀려야 함: 남은 %v", a.stuckLeft)
+	}
+	sim.update(false) // elapsed 6.0s → 센서 끊김
+	if a.Status.Sensors != (models.SensorData{}) {
+		t.Fatalf("센서 끊김 틱에는 측정값이 비어야 함: %+v", a.Status.Sensors)
+	}
+	sim.update(false)
+	if a.Status.Sensors.FrontDistance == 0 && !a.Status.Sensors.CameraActive {
+		t.Fatal("시간대가 지나면 센서가 돌아와야 함")
+	}
+
+	counts := f.Counts()
+	if counts[FaultBatteryDrop] != 1 || counts[FaultStuckMotor] != 1 || counts[FaultSensorDropout] != 1 {
+		t.Fatalf("장애별 1회씩 발생해야 함: %+v", counts)
+	}
+}
+
+func TestFaultLink_ReorderDuplicateDelay(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+	l := newFaultLink("test", func(_ string, raw []byte) {
+		mu.Lock()
+		got = append(got, string(raw))
+		mu.Unlock()
+	})
+	snapshot := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), got...)
+	}
+
+	l.deliver("", []byte("a"), MessagePlan{Reorder: true})
+	l.deliver("", []byte("b"), MessagePlan{})
+	l.deliver("", []byte("c"), MessagePlan{Duplicate: true})
+	if s := snapshot(); len(s) != 4 || s[0] != "b" || s[1] != "a" || s[2] != "c" || s[3] != "c" {
+		t.Fatalf("b, a, c, c 순서여야 함: %v", s)
+	}
+
+	l.deliver("", []byte("late"), MessagePlan{Delay: 30 * time.Millisecond})
+	l.deliver("", []byte("d"), MessagePlan{})
+	if s := snapshot(); s[len(s)-1] != "d" {
+		t.Fatalf("지연 메시지는 뒤 메시지보다 늦어야 함: %v", s)
+	}
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if s := snapshot(); s[len(s)-1] == "late" {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("지연 메시지가 도착하지 않음: %v", snapshot())
+}
+
+func TestFaultInjector_DisabledConsumesNothing(t *testing.T) {
+	f := NewFaultInjector()
+	for i := 0; i < 100; i++ {
+		if plan := f.PlanMessage(); plan != (MessagePlan{}) || f.RollDisconnect() {
+			t.Fatal("기본 설정에서는 장애가 없어야 함")
+		}
+	}
+	if len(f.Counts()) != 0 {
+		t.Fatalf("발생 횟수가 없어야 함: %+v", f.Counts())
+	}
+}
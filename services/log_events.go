@@ -63,6 +63,26 @@ func LogTargetFound(agvID string, target *models.Enemy) {
 	})
 }
 
+// LogTargetSelection은 타겟 선택 결정을 이유·대안과 함께 남긴다.
+func LogTargetSelection(agvID string, sel *models.TargetSelection) {
+	dataJSON, err := json.Marshal(sel)
+	if err != nil {
+		log.Printf("[WARN] 타겟 선택 직렬화 실패: %v", err)
+		dataJSON = nil
+	}
+	AddLog(models.AGVLog{
+		CreatedAt:       time.Now(),
+		EventType:       "target_selected",
+		MessageType:     models.MessageTypeTargetSelection,
+		AGVID:           agvID,
+		TargetEnemyID:   sel.SelectedEnemy.ID,
+		TargetEnemyHP:   sel.SelectedEnemy.HP,
+		TargetEnemyName: sel.SelectedEnemy.Name,
+		AIExplanation:   sel.Reason,
+		DataJSON:        string(dataJSON),
+	})
+}
+
 func LogCommand(agvID string, commandType string, targetX, targetY float64) {
 	AddLog(models.AGVLog{
 		CreatedAt:   time.Now(),
@@ -108,7 +128,10 @@ func LogWebSocketMessage(agvID string, msg models.WebSocketMessage) {
 func extractLogData(logEntry *models.AGVLog, msg models.WebSocketMessage) {
 	dataMap, ok := msg.Data.(map[string]interface{})
 	if !ok {
-		return
+		// 레지스트리로 디코드된 페이로드는 타입 값이므로 직렬화한 DataJSON에서 같은 키를 읽는다.
+		if err := json.Unmarshal([]byte(logEntry.DataJSON), &dataMap); err != nil || dataMap == nil {
+			return
+		}
 	}
 
 	if x, ok := dataMap["x"].(float64); ok {
@@ -153,6 +176,8 @@ func inferEventType(msgType string) string {
 		return "status_update"
 	case "target_found":
 		return "target_detected"
+	case "target_selection":
+		return "target_selected"
 	case "chat":
 		return "user_question"
 	case "command":
@@ -0,0 +1,493 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sion-backend/models"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// 메시지 타입 레지스트리. 타입마다 페이로드 Go 타입과 검사 규칙을 등록해 두고, WebSocket 수신 지점에서
+// 한 번만 디코드한다. 디코드된 WebSocketMessage.Data는 Payload[T]이므로 뒤에서는 PayloadAs로 꺼내 쓴다.
+// 다시 직렬화하면 받은 data가 그대로 나가므로, 서버가 모르는 필드도 AGV까지 전달된다.
+// 등록되지 않은 타입은 Data를 그대로(map 등) 두며, 받을지 말지는 수신 쪽이 정한다.
+
+const (
+	// defaultArenaWidth, defaultArenaHeight는 SetArenaBounds 전의 실제 AGV 작업 영역 크기. 시뮬레이터 기본 맵과 같다.
+	defaultArenaWidth  = 30.0
+	defaultArenaHeight = 30.0
+	// maxMotorSpeed는 motor_control 바퀴 속도 상한(m/s). 실제 AGV가 자기 한계로 다시 자른다.
+	maxMotorSpeed = 10.0
+	// maxMotorDurationMs는 motor_control 입력 하나가 유지되는 최대 시간.
+	maxMotorDurationMs = 10000
+	// maxChatLength는 chat 한 번에 보낼 수 있는 글자 수. 그대로 LLM 프롬프트에 들어간다.
+	maxChatLength = 2000
+)
+
+// ErrMalformedMessage는 JSON 봉투 자체를 읽지 못했을 때 반환된다.
+var ErrMalformedMessage = errors.New("잘못된 메시지 형식")
+
+// FieldError는 페이로드의 어느 필드가 왜 잘못됐는지 알린다. Field는 봉투 기준 경로(예: data.target_x).
+type FieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e *FieldError) Error() string {
+	if e.Field == "" {
+		return e.Reason
+	}
+	return e.Field + ": " + e.Reason
+}
+
+func fieldErr(field, format string, args ...any) *FieldError {
+	return &FieldError{Field: field, Reason: fmt.Sprintf(format, args...)}
+}
+
+// PayloadRule은 페이로드 타입 T의 검사 규칙.
+type PayloadRule[T any] struct {
+	// Required는 data에 반드시 있어야 하는 필드. 0과 빠진 값을 가르기 위함이다.
+	Required []string
+	// Strict면 T에 없는 필드를 거부한다. 구독처럼 서버가 해석하고 끝나는 메시지에만 쓴다. AGV로 가는 명령에
+	// 쓰면 지금까지 받던 확장 필드(speed 등)가 붙은 명령이 거부된다.
+	Strict bool
+	// Lenient면 형식이 틀려도 거부하지 않고, 읽을 수 있는 만큼만 값에 담는다. 비상 정지처럼 어떤 경우에도
+	// 막혀서는 안 되는 메시지에 쓴다. 봉투 필드(timestamp 등)의 타입 오류도 넘어가며, Required·Strict·Validate는
+	// 무시된다.
+	Lenient bool
+	// Normalize는 거부할 정도는 아닌 범위 밖 값을 고친다(배터리 잔량 자르기 등). 고친 필드를 돌려주면 경고를
+	// 남기고, 내보낼 data에도 고친 값을 쓴다. Validate보다 먼저 불린다.
+	Normalize func(v *T) []*FieldError
+	// Validate는 디코드된 값의 범위를 검사한다. env는 data를 뺀 봉투(대상 agv_id 등). 필드 이름은 data 아래
+	// 경로로 적는다(예: target_x).
+	Validate func(r *MessageRegistry, env models.WebSocketMessage, v T) error
+}
+
+// Payload는 Decode가 msg.Data에 넣는 값. Value는 등록된 타입으로 디코드한 값이고 Raw는 받은 data 원본이다.
+type Payload[T any] struct {
+	Value T
+	Raw   json.RawMessage
+	// normalized는 Normalize가 값을 고쳐 Raw가 받은 data와 달라졌는지.
+	normalized bool
+}
+
+// Normalized는 Normalize가 data를 고쳤는지 반환한다. 받은 프레임을 그대로 전달하는 쪽은 이때 data를 다시 써야 한다.
+func (p Payload[T]) Normalized() bool { return p.normalized }
+
+// MarshalJSON은 받은 data를 그대로 내보낸다. data가 없었으면 null.
+func (p Payload[T]) MarshalJSON() ([]byte, error) {
+	if len(p.Raw) == 0 {
+		return []byte("null"), nil
+	}
+	return p.Raw, nil
+}
+
+type payloadSpec struct {
+	decode func(r *MessageRegistry, env models.WebSocketMessage, data json.RawMessage) (any, error)
+	// lenient면 봉투 필드(timestamp 등)의 타입이 틀려도 읽은 만큼으로 디코드를 계속한다.
+	lenient bool
+}
+
+// MessageRegistry는 메시지 타입 → 페이로드 타입·검사 규칙 표.
+type MessageRegistry struct {
+	mu    sync.RWMutex
+	specs map[string]payloadSpec
+	// simBounds는 시뮬레이터가 agvID 명령을 받을 때 그 맵 크기를 알려 준다. 받지 않으면 ok=false.
+	simBounds               func(agvID string) (width, height float64, ok bool)
+	arenaWidth, arenaHeight float64
+}
+
+// NewMessageRegistry는 AGV·웹 프로토콜의 기본 타입을 등록한 레지스트리를 만든다.
+func NewMessageRegistry() *MessageRegistry {
+	r := &MessageRegistry{
+		specs:       make(map[string]payloadSpec),
+		arenaWidth:  defaultArenaWidth,
+		arenaHeight: defaultArenaHeight,
+	}
+	registerBuiltinPayloads(r)
+	return r
+}
+
+// RegisterPayload는 msgType의 페이로드를 T로 디코드하고 rule로 검사하게 한다. 이미 있으면 바꾼다.
+func RegisterPayload[T any](r *MessageRegistry, msgType string, rule PayloadRule[T]) {
+	spec := payloadSpec{decode: func(r *MessageRegistry, env models.WebSocketMessage, data json.RawMessage) (any, error) {
+		var v T
+		if rule.Lenient {
+			_ = decodePayload(data, &v, nil, false)
+			return Payload[T]{Value: v, Raw: data}, nil
+		}
+		if err := decodePayload(data, &v, rule.Required, rule.Strict); err != nil {
+			return nil, err
+		}
+		normalized := false
+		if rule.Normalize != nil {
+			if fixed := rule.Normalize(&v); len(fixed) > 0 {
+				for _, fe := range fixed {
+					log.Printf("[WARN] %s 페이로드 보정 (agv=%s): data.%s", env.Type, env.AGVID, fe.Error())
+				}
+				data = overlayPayload(data, v)
+				normalized = true
+			}
+		}
+		if rule.Validate != nil {
+			if err := rule.Validate(r, env, v); err != nil {
+				var fe *FieldError
+				if errors.As(err, &fe) {
+					return nil, &FieldError{Field: joinField("data", fe.Field), Reason: fe.Reason}
+				}
+				return nil, &FieldError{Field: "data", Reason: err.Error()}
+			}
+		}
+		return Payload[T]{Value: v, Raw: data, normalized: normalized}, nil
+	}, lenient: rule.Lenient}
+	r.mu.Lock()
+	r.specs[msgType] = spec
+	r.mu.Unlock()
+}
+
+// Known은 msgType이 등록된 타입인지 반환한다.
+func (r *MessageRegistry) Known(msgType string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.specs[msgType]
+	return ok
+}
+
+// SetSimulatorBounds는 시뮬레이터가 받는 명령의 범위 검사에 쓸 함수를 정한다. 시나리오로 맵이 바뀌어도 따라가게
+// 함수로 받는다. 시뮬레이터가 받지 않는 명령(실제 AGV)에는 SetArenaBounds 크기를 쓴다.
+func (r *MessageRegistry) SetSimulatorBounds(bounds func(agvID string) (width, height float64, ok bool)) {
+	r.mu.Lock()
+	r.simBounds = bounds
+	r.mu.Unlock()
+}
+
+// SetArenaBounds는 실제 AGV 명령의 범위 검사에 쓸 작업 영역 크기를 정한다.
+func (r *MessageRegistry) SetArenaBounds(width, height float64) error {
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("작업 영역 크기는 0보다 커야 합니다: %gx%g", width, height)
+	}
+	r.mu.Lock()
+	r.arenaWidth, r.arenaHeight = width, height
+	r.mu.Unlock()
+	return nil
+}
+
+// MapBounds는 agvID에 가는 명령의 목표 좌표 범위를 반환한다. 시뮬레이터가 받으면 그 맵, 아니면 실제 AGV 작업 영역.
+func (r *MessageRegistry) MapBounds(agvID string) (width, height float64) {
+	r.mu.RLock()
+	simBounds := r.simBounds
+	width, height = r.arenaWidth, r.arenaHeight
+	r.mu.RUnlock()
+	if simBounds != nil {
+		if w, h, ok := simBounds(agvID); ok {
+			return w, h
+		}
+	}
+	return width, height
+}
+
+// ParseArenaBounds는 "<가로>,<세로>" 형식(AGV_ARENA_SIZE)을 읽는다.
+func ParseArenaBounds(s string) (width, height float64, err error) {
+	ws, hs, ok := strings.Cut(s, ",")
+	if !ok {
+		return 0, 0, fmt.Errorf("잘못된 작업 영역 %q: <가로>,<세로> 형식이어야 합니다", s)
+	}
+	if width, err = strconv.ParseFloat(strings.TrimSpace(ws), 64); err == nil {
+		height, err = strconv.ParseFloat(strings.TrimSpace(hs), 64)
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("잘못된 작업 영역 %q: %w", s, err)
+	}
+	if width <= 0 || height <= 0 {
+		return 0, 0, fmt.Errorf("잘못된 작업 영역 %q: 0보다 커야 합니다", s)
+	}
+	return width, height, nil
+}
+
+// Decode는 WebSocket 프레임 하나를 봉투와 페이로드까지 디코드한다. 봉투를 읽었으면 에러가 있어도 Type 등 봉투 필드는 채워 돌려준다.
+// 봉투가 JSON이 아니면 ErrMalformedMessage, 페이로드가 틀리면 *FieldError.
+func (r *MessageRegistry) Decode(raw []byte) (models.WebSocketMessage, error) {
+	var env struct {
+		models.WebSocketMessage
+		Data json.RawMessage `json:"data"`
+	}
+	envErr := json.Unmarshal(raw, &env)
+	var te *json.UnmarshalTypeError
+	if envErr != nil && !errors.As(envErr, &te) {
+		return models.WebSocketMessage{}, ErrMalformedMessage
+	}
+	msg := env.WebSocketMessage
+	r.mu.RLock()
+	spec, ok := r.specs[msg.Type]
+	r.mu.RUnlock()
+	// encoding/json은 타입이 틀린 필드만 건너뛰고 나머지는 채우므로, 비상 정지는 type만 읽혔으면 그대로 보낸다.
+	if te != nil {
+		if !ok || !spec.lenient {
+			return msg, fieldErr(te.Field, "%s 타입이어야 합니다", te.Type)
+		}
+		log.Printf("[WARN] %s 봉투 필드 %s 타입 오류 — 무시하고 처리", msg.Type, te.Field)
+	}
+	if msg.Type == "" {
+		return msg, fieldErr("type", "메시지 타입이 필요합니다")
+	}
+	if !ok {
+		if len(env.Data) > 0 {
+			_ = json.Unmarshal(env.Data, &msg.Data)
+		}
+		return msg, nil
+	}
+	data, err := spec.decode(r, msg, env.Data)
+	if err != nil {
+		return msg, err
+	}
+	msg.Data = data
+	return msg, nil
+}
+
+// decodePayload는 data를 v로 디코드한다. 타입이 틀린 필드·(strict면) 모르는 필드·빠진 필수 필드를 FieldError로 알린다.
+func decodePayload(data json.RawMessage, v any, required []string, strict bool) error {
+	if len(data) == 0 || bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		if len(required) > 0 {
+			return fieldErr(joinField("data", required[0]), "필요합니다")
+		}
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(v); err != nil {
+		var te *json.UnmarshalTypeError
+		if errors.As(err, &te) {
+			if te.Field == "" {
+				return fieldErr("data", "%s 타입이어야 합니다", te.Type)
+			}
+			return fieldErr(joinField("data", te.Field), "%s 타입이어야 합니다", te.Type)
+		}
+		// encoding/json은 모르는 필드를 `json: unknown field "x"` 문자열로만 알린다.
+		if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			return fieldErr(joinField("data", strings.Trim(name, `"`)), "알 수 없는 필드입니다")
+		}
+		return fieldErr("data", "%v", err)
+	}
+	// 빠진 필드는 디코드 뒤에 본다. target_x를 x로 잘못 쓴 경우 "x를 모른다"가 더 쓸모 있다.
+	if len(required) > 0 {
+		var present map[string]json.RawMessage
+		if err := json.Unmarshal(data, &present); err != nil {
+			return fieldErr("data", "객체여야 합니다")
+		}
+		for _, f := range required {
+			if _, ok := present[f]; !ok {
+				return fieldErr(joinField("data", f), "필요합니다")
+			}
+		}
+	}
+	return nil
+}
+
+// overlayPayload는 받은 data에서 v에도 있는 필드만 v의 값으로 바꾼다. 서버가 모르는 필드는 그대로 남는다.
+func overlayPayload(data json.RawMessage, v any) json.RawMessage {
+	var fields, fixed map[string]json.RawMessage
+	if json.Unmarshal(data, &fields) != nil {
+		return data
+	}
+	raw, err := json.Marshal(v)
+	if err != nil || json.Unmarshal(raw, &fixed) != nil {
+		return data
+	}
+	for k := range fields {
+		if f, ok := fixed[k]; ok {
+			fields[k] = f
+		}
+	}
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return data
+	}
+	return out
+}
+
+func joinField(prefix, field string) string {
+	if field == "" {
+		return prefix
+	}
+	return prefix + "." + field
+}
+
+// PayloadAs는 msg.Data를 T로 꺼낸다. Decode를 거친 메시지는 그대로 꺼내고, 서버 안에서 만든 메시지처럼
+// map 등 다른 모양이면 JSON을 거쳐 바꾼다.
+func PayloadAs[T any](msg models.WebSocketMessage) (T, error) {
+	switch v := msg.Data.(type) {
+	case Payload[T]:
+		return v.Value, nil
+	case T:
+		return v, nil
+	case *T:
+		if v != nil {
+			return *v, nil
+		}
+	}
+	var out T
+	raw, err := json.Marshal(msg.Data)
+	if err != nil {
+		return out, err
+	}
+	err = json.Unmarshal(raw, &out)
+	return out, err
+}
+
+func registerBuiltinPayloads(r *MessageRegistry) {
+	// AGV → 서버
+	RegisterPayload(r, models.MessageTypeHello, PayloadRule[models.AGVHello]{
+		Required: []string{"agv_id"},
+		Validate: func(_ *MessageRegistry, _ models.WebSocketMessage, h models.AGVHello) error {
+			if strings.TrimSpace(h.AGVID) == "" {
+				return fieldErr("agv_id", "비어 있으면 안 됩니다")
+			}
+			for i, id := range h.AGVIDs {
+				if strings.TrimSpace(id) == "" {
+					return fieldErr(fmt.Sprintf("agv_ids[%d]", i), "비어 있으면 안 됩니다")
+				}
+			}
+			return nil
+		},
+	})
+	// 배터리 잔량이 범위를 벗어나도 위치 등 나머지 상태는 살려야 하므로 거부하지 않고 0~100으로 자른다.
+	RegisterPayload(r, models.MessageTypeStatus, PayloadRule[models.AGVStatus]{
+		Normalize: func(s *models.AGVStatus) []*FieldError {
+			if s.Battery >= 0 && s.Battery <= 100 {
+				return nil
+			}
+			fe := fieldErr("battery", "0~100 밖의 값 %d를 잘랐습니다", s.Battery)
+			s.Battery = min(max(s.Battery, 0), 100)
+			return []*FieldError{fe}
+		},
+	})
+	RegisterPayload(r, models.MessageTypePosition, PayloadRule[models.PositionReport]{Required: []string{"x", "y"}})
+	RegisterPayload(r, models.MessageTypeCollision, PayloadRule[models.CollisionReport]{})
+	for _, t := range []string{models.MessageTypeCommandAck, models.MessageTypeCommandNack, models.MessageTypeCommandCompleted} {
+		RegisterPayload(r, t, PayloadRule[models.CommandReply]{})
+	}
+
+	// 웹 → 서버
+	RegisterPayload(r, models.MessageTypeChat, PayloadRule[models.ChatMessageData]{
+		Required: []string{"message"},
+		Validate: func(_ *MessageRegistry, _ models.WebSocketMessage, c models.ChatMessageData) error {
+			if strings.TrimSpace(c.Message) == "" {
+				return fieldErr("message", "비어 있으면 안 됩니다")
+			}
+			if utf8.RuneCountInString(c.Message) > maxChatLength {
+				return fieldErr("message", "%d자 이하여야 합니다", maxChatLength)
+			}
+			return nil
+		},
+	})
+	RegisterPayload(r, models.MessageTypeCommand, PayloadRule[models.MoveCommand]{
+		Required: []string{"target_x", "target_y"},
+		Validate: func(r *MessageRegistry, env models.WebSocketMessage, c models.MoveCommand) error {
+			w, h := r.MapBounds(env.AGVID)
+			if c.TargetX < 0 || c.TargetX > w {
+				return fieldErr("target_x", "맵 범위(0~%g) 밖입니다", w)
+			}
+			if c.TargetY < 0 || c.TargetY > h {
+				return fieldErr("target_y", "맵 범위(0~%g) 밖입니다", h)
+			}
+			if c.Mode != "" {
+				return validateMode("mode", c.Mode)
+			}
+			return nil
+		},
+	})
+	RegisterPayload(r, models.MessageTypeModeChange, PayloadRule[models.ModeChangeCommand]{
+		Required: []string{"mode"},
+		Validate: func(_ *MessageRegistry, _ models.WebSocketMessage, c models.ModeChangeCommand) error {
+			return validateMode("mode", c.Mode)
+		},
+	})
+	// 비상 정지는 data가 없거나 문자열이거나 모르는 필드가 있어도 그대로 AGV에 보낸다.
+	RegisterPayload(r, models.MessageTypeEmergencyStop, PayloadRule[models.EmergencyStopCommand]{Lenient: true})
+	RegisterPayload(r, models.MessageTypeMotorControl, PayloadRule[models.MotorControl]{
+		Required: []string{"left_speed", "right_speed"},
+		Validate: func(_ *MessageRegistry, _ models.WebSocketMessage, c models.MotorControl) error {
+			if c.LeftSpeed < -maxMotorSpeed || c.LeftSpeed > maxMotorSpeed {
+				return fieldErr("left_speed", "-%g~%g 사이여야 합니다", maxMotorSpeed, maxMotorSpeed)
+			}
+			if c.RightSpeed < -maxMotorSpeed || c.RightSpeed > maxMotorSpeed {
+				return fieldErr("right_speed", "-%g~%g 사이여야 합니다", maxMotorSpeed, maxMotorSpeed)
+			}
+			if c.Duration < 0 || c.Duration > maxMotorDurationMs {
+				return fieldErr("duration", "0~%d ms 사이여야 합니다", maxMotorDurationMs)
+			}
+			return nil
+		},
+	})
+	RegisterPayload(r, models.MessageTypeSubscribe, PayloadRule[models.SubscriptionData]{
+		Required: []string{"topics"},
+		Strict:   true,
+		Validate: func(_ *MessageRegistry, _ models.WebSocketMessage, d models.SubscriptionData) error {
+			if len(d.Topics) == 0 {
+				return fieldErr("topics", "비어 있으면 안 됩니다")
+			}
+			for i, t := range d.Topics {
+				if err := ValidateTopic(t); err != nil {
+					return fieldErr(fmt.Sprintf("topics[%d]", i), "%v", err)
+				}
+			}
+			return nil
+		},
+	})
+	RegisterPayload(r, models.MessageTypeUnsubscribe, PayloadRule[models.SubscriptionData]{
+		Required: []string{"topics"},
+		Strict:   true,
+		Validate: func(_ *MessageRegistry, _ models.WebSocketMessage, d models.SubscriptionData) error {
+			if len(d.Topics) == 0 {
+				return fieldErr("topics", "비어 있으면 안 됩니다")
+			}
+			return nil
+		},
+	})
+	RegisterPayload(r, models.MessageTypeSetRate, PayloadRule[models.RateLimitData]{
+		Required: []string{"rates"},
+		Validate: func(_ *MessageRegistry, _ models.WebSocketMessage, d models.RateLimitData) error {
+			if len(d.Rates) == 0 {
+				return fieldErr("rates", "비어 있으면 안 됩니다")
+			}
+			for topic, hz := range d.Rates {
+				if err := ValidateRate(topic, hz); err != nil {
+					return fieldErr("rates."+topic, "%v", err)
+				}
+			}
+			return nil
+		},
+	})
+}
+
+func validateMode(field, mode string) error {
+	if mode != models.ModeAuto && mode != models.ModeManual {
+		return fieldErr(field, "%q 또는 %q여야 합니다", models.ModeAuto, models.ModeManual)
+	}
+	return nil
+}
+
+// PatchEnvelope은 JSON 봉투 raw의 최상위 필드를 set 값으로 바꾼다. data와 모르는 필드는 원본 그대로 둔다.
+// AGV가 보낸 프레임을 웹에 흘리기 전에 agv_id·timestamp만 채울 때 쓴다.
+func PatchEnvelope(raw []byte, set map[string]any) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	for k, v := range set {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		fields[k] = b
+	}
+	return json.Marshal(fields)
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sion-backend/models"
+	"testing"
+)
+
+func TestMessageRegistry_DecodeTypedPayload(t *testing.T) {
+	r := NewMessageRegistry()
+	msg, err := r.Decode([]byte(`{"type":"command","agv_id":"sion-001","command_id":"c1","data":{"target_x":3,"target_y":4.5,"speed":2}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, ok := msg.Data.(Payload[models.MoveCommand])
+	cmd := p.Value
+	if !ok || cmd.TargetX != 3 || cmd.TargetY != 4.5 {
+		t.Fatalf("MoveCommand로 디코드돼야 함: %#v", msg.Data)
+	}
+	if msg.AGVID != "sion-001" || msg.CommandID != "c1" {
+		t.Fatalf("봉투 필드 불일치: %+v", msg)
+	}
+	if got, err := PayloadAs[models.MoveCommand](msg); err != nil || got != cmd {
+		t.Fatalf("PayloadAs는 디코드된 값을 그대로 꺼내야 함: %+v, %v", got, err)
+	}
+	// 다시 직렬화하면 모르는 필드(speed)까지 받은 그대로 AGV에 간다.
+	raw, _ := json.Marshal(msg)
+	var out struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &out); err != nil || out.Data["speed"] != 2.0 {
+		t.Fatalf("원본 data가 유지돼야 함: %s", raw)
+	}
+
+	// 등록되지 않은 타입은 그대로 통과한다.
+	msg, err = r.Decode([]byte(`{"type":"custom","data":{"k":1}}`))
+	if err != nil || msg.Data.(map[string]any)["k"] != 1.0 {
+		t.Fatalf("모르는 타입은 map으로 둬야 함: %+v, %v", msg, err)
+	}
+}
+
+func TestMessageRegistry_FieldErrors(t *testing.T) {
+	r := NewMessageRegistry()
+	if err := r.SetArenaBounds(10, 20); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name, raw, field string
+	}{
+		{"no type", `{"data":{}}`, "type"},
+		{"envelope type", `{"type":"command","agv_id":7}`, "agv_id"},
+		{"missing target", `{"type":"command","data":{"target_x":1}}`, "data.target_y"},
+		{"null data", `{"type":"command"}`, "data.target_x"},
+		{"unknown field", `{"type":"subscribe","data":{"topics":["status"],"topic":"x"}}`, "data.topic"},
+		{"wrong type", `{"type":"command","data":{"target_x":"1","target_y":1}}`, "data.target_x"},
+		{"outside width", `{"type":"command","data":{"target_x":11,"target_y":1}}`, "data.target_x"},
+		{"outside height", `{"type":"command","data":{"target_x":1,"target_y":-1}}`, "data.target_y"},
+		{"bad mode", `{"type":"command","data":{"target_x":1,"target_y":1,"mode":"fast"}}`, "data.mode"},
+		{"motor duration", `{"type":"motor_control","data":{"left_speed":1,"right_speed":1,"duration":60000}}`, "data.duration"},
+		{"empty chat", `{"type":"chat","data":{"message":"  "}}`, "data.message"},
+		{"bad topic", `{"type":"subscribe","data":{"topics":["[x"]}}`, "data.topics[0]"},
+		{"hello id", `{"type":"hello","data":{}}`, "data.agv_id"},
+	}
+	for _, tc := range cases {
+		_, err := r.Decode([]byte(tc.raw))
+		var fe *FieldError
+		if !errors.As(err, &fe) || fe.Field != tc.field {
+			t.Errorf("%s: field=%s FieldError 기대, got %v", tc.name, tc.field, err)
+		}
+	}
+
+	if _, err := r.Decode([]byte(`not json`)); !errors.Is(err, ErrMalformedMessage) {
+		t.Fatalf("JSON이 아니면 ErrMalformedMessage, got %v", err)
+	}
+	msg, err := r.Decode([]byte(`{"type":"command","agv_id":"sion-002","data":{"target_x":99,"target_y":1}}`))
+	if err == nil || msg.Type != models.MessageTypeCommand || msg.AGVID != "sion-002" {
+		t.Fatalf("에러여도 봉투는 채워 돌려줘야 함: %+v, %v", msg, err)
+	}
+}
+
+// 비상 정지는 data 모양과 상관없이 통과하고, 받은 data를 그대로 내보낸다.
+func TestMessageRegistry_EmergencyStopNeverRejected(t *testing.T) {
+	r := NewMessageRegistry()
+	for _, raw := range []string{
+		`{"type":"emergency_stop"}`,
+		`{"type":"emergency_stop","data":null}`,
+		`{"type":"emergency_stop","data":{}}`,
+		`{"type":"emergency_stop","data":"stop"}`,
+		`{"type":"emergency_stop","data":[1,2]}`,
+		`{"type":"emergency_stop","data":{"reason":"x","source":"ui"}}`,
+		`{"type":"emergency_stop","data":{"reason":7}}`,
+		`{"type":"emergency_stop","timestamp":"now","agv_id":"sion-001"}`,
+		`{"type":"emergency_stop","agv_id":7,"command_id":false}`,
+	} {
+		msg, err := r.Decode([]byte(raw))
+		if err != nil {
+			t.Errorf("%s: 거부되면 안 됨: %v", raw, err)
+			continue
+		}
+		if _, err := json.Marshal(msg); err != nil {
+			t.Errorf("%s: 다시 직렬화 실패: %v", raw, err)
+		}
+	}
+	msg, _ := r.Decode([]byte(`{"type":"emergency_stop","data":{"reason":"x","source":"ui"}}`))
+	if cmd, _ := PayloadAs[models.EmergencyStopCommand](msg); cmd.Reason != "x" {
+		t.Fatalf("읽을 수 있는 필드는 읽어야 함: %+v", cmd)
+	}
+	// 봉투 필드 타입이 틀려도 나머지 봉투는 읽는다. agv_id를 못 읽으면 전체 비상 정지가 된다.
+	msg, _ = r.Decode([]byte(`{"type":"emergency_stop","timestamp":"now","agv_id":"sion-001","data":{"reason":"x"}}`))
+	if msg.AGVID != "sion-001" {
+		t.Fatalf("타입이 맞는 봉투 필드는 읽어야 함: %+v", msg)
+	}
+}
+
+// 범위를 벗어난 배터리 잔량은 status를 거부하지 않고 0~100으로 잘라 내보낸다.
+func TestMessageRegistry_StatusBatteryClamped(t *testing.T) {
+	r := NewMessageRegistry()
+	for raw, want := range map[string]int{
+		`{"type":"status","data":{"battery":150,"position":{"x":1,"y":2},"firmware":"1.2"}}`: 100,
+		`{"type":"status","data":{"battery":-5,"position":{"x":1,"y":2},"firmware":"1.2"}}`:  0,
+	} {
+		msg, err := r.Decode([]byte(raw))
+		if err != nil {
+			t.Fatalf("%s: 거부되면 안 됨: %v", raw, err)
+		}
+		status, _ := PayloadAs[models.AGVStatus](msg)
+		if status.Battery != want || status.Position.Y != 2 {
+			t.Fatalf("%s: battery %d 기대, got %+v", raw, want, status)
+		}
+		out, _ := json.Marshal(msg.Data)
+		var data map[string]any
+		if err := json.Unmarshal(out, &data); err != nil || data["battery"] != float64(want) || data["firmware"] != "1.2" {
+			t.Fatalf("내보내는 data도 잘린 값이고 모르는 필드는 남아야 함: %s", out)
+		}
+	}
+}
+
+// 목표 좌표는 시뮬레이터가 받는 AGV면 시뮬레이터 맵, 아니면 실제 AGV 작업 영역으로 검사한다.
+func TestMessageRegistry_CommandBoundsPerTarget(t *testing.T) {
+	r := NewMessageRegistry()
+	if err := r.SetArenaBounds(100, 100); err != nil {
+		t.Fatal(err)
+	}
+	r.SetSimulatorBounds(func(agvID string) (float64, float64, bool) { return 10, 10, agvID == "sim-001" })
+
+	far := `{"type":"command","agv_id":"%s","data":{"target_x":50,"target_y":50}}`
+	if _, err := r.Decode([]byte(fmt.Sprintf(far, "real-001"))); err != nil {
+		t.Fatalf("실제 AGV는 작업 영역 안이면 통과: %v", err)
+	}
+	var fe *FieldError
+	if _, err := r.Decode([]byte(fmt.Sprintf(far, "sim-001"))); !errors.As(err, &fe) || fe.Field != "data.target_x" {
+		t.Fatalf("시뮬레이터 AGV는 시뮬레이터 맵으로 검사: %v", err)
+	}
+	if _, err := r.Decode([]byte(`{"type":"command","agv_id":"real-001","data":{"target_x":150,"target_y":1}}`)); err == nil {
+		t.Fatal("작업 영역 밖은 거부")
+	}
+
+	if w, h, err := ParseArenaBounds(" 40, 25.5"); err != nil || w != 40 || h != 25.5 {
+		t.Fatalf("ParseArenaBounds: %v %v %v", w, h, err)
+	}
+	for _, bad := range []string{"40", "a,1", "0,10"} {
+		if _, _, err := ParseArenaBounds(bad); err == nil {
+			t.Errorf("%q는 에러여야 함", bad)
+		}
+	}
+}
+
+// 서버 안에서 map으로 만든 메시지도 PayloadAs로 꺼낼 수 있다.
+func TestPayloadAs_FallsBackToJSON(t *testing.T) {
+	msg := models.WebSocketMessage{Type: models.MessageTypeCommand, Data: map[string]any{"target_x": 2.0, "target_y": 3.0}}
+	cmd, err := PayloadAs[models.MoveCommand](msg)
+	if err != nil || cmd.TargetX != 2 || cmd.TargetY != 3 {
+		t.Fatalf("map 페이로드 변환 실패: %+v, %v", cmd, err)
+	}
+	msg.Data = &models.MoveCommand{TargetX: 5}
+	if cmd, _ = PayloadAs[models.MoveCommand](msg); cmd.TargetX != 5 {
+		t.Fatalf("포인터 페이로드 변환 실패: %+v", cmd)
+	}
+}
+
+func TestPatchEnvelope_KeepsUnknownFields(t *testing.T) {
+	raw := []byte(`{"type":"status","data":{"battery":50,"vendor":"x"},"extra":true}`)
+	patched, err := PatchEnvelope(raw, map[string]any{"agv_id": "sion-001", "timestamp": int64(7)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]any
+	if err := json.Unmarshal(patched, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out["agv_id"] != "sion-001" || out["timestamp"] != 7.0 || out["extra"] != true || out["data"].(map[string]any)["vendor"] != "x" {
+		t.Fatalf("지정한 필드만 바뀌어야 함: %s", patched)
+	}
+}
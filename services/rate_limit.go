@@ -0,0 +1,248 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"sion-backend/models"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 웹 클라이언트 갱신 빈도 제한. 형식은 models.RateLimitData 참고.
+// 나가는 텔레메트리는 클라이언트·(type, agv_id)마다 간격 안에 온 프레임을 마지막 하나로 합쳐 보내고,
+// 들어오는 메시지는 InboundLimiter가 종류별 토큰 버킷으로 막는다.
+
+// maxRateHz는 set_rate로 적을 수 있는 최대 빈도.
+const maxRateHz = 100
+
+// defaultRateCaps는 서버 기본 상한. AGV가 얼마나 자주 보내든 웹에는 이보다 자주 가지 않는다.
+var defaultRateCaps = map[string]float64{
+	models.MessageTypePosition: 10,
+	models.MessageTypeStatus:   5,
+}
+
+// DefaultRateCaps는 서버 기본 상한의 복사본을 반환한다.
+func DefaultRateCaps() map[string]float64 {
+	return maps.Clone(defaultRateCaps)
+}
+
+// isThrottled는 빈도 제한을 적용하는 메시지인지 판단한다. 다음 프레임이 앞 프레임을 덮는 텔레메트리만 합친다.
+func isThrottled(msgType string) bool {
+	return dropLevel(msgType) < dropNormal
+}
+
+// ValidateRate는 토픽과 빈도를 검사한다. 0은 설정 삭제다.
+func ValidateRate(topic string, hz float64) error {
+	if err := ValidateTopic(topic); err != nil {
+		return err
+	}
+	if hz < 0 || hz > maxRateHz {
+		return fmt.Errorf("%s 빈도는 0~%d Hz 사이여야 합니다", topic, maxRateHz)
+	}
+	return nil
+}
+
+// ParseRateList는 "position=10,status@sion-001=2" 형식(WS_DEFAULT_RATES, 접속 URL의 ?rates= 등)을 검사해 나눈다.
+func ParseRateList(s string) (map[string]float64, error) {
+	out := make(map[string]float64)
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		topic, v, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("잘못된 빈도 %q: <토픽>=<Hz> 형식이어야 합니다", item)
+		}
+		hz, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("잘못된 빈도 %q: %w", item, err)
+		}
+		if err := ValidateRate(topic, hz); err != nil {
+			return nil, err
+		}
+		out[topic] = hz
+	}
+	return out, nil
+}
+
+// rateInterval은 msgType·agvID 메시지의 최소 전송 간격이다. 서버 상한과 클라이언트 설정 중 맞는 토픽의
+// 가장 낮은 빈도를 따른다. 제한이 없으면 0.
+func rateInterval(caps, rates map[string]float64, msgType, agvID string) time.Duration {
+	if !isThrottled(msgType) {
+		return 0
+	}
+	lowest := 0.0
+	for _, set := range []map[string]float64{caps, rates} {
+		for topic, hz := range set {
+			if hz > 0 && (lowest == 0 || hz < lowest) && topicMatches(topic, msgType, agvID) {
+				lowest = hz
+			}
+		}
+	}
+	if lowest == 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / lowest)
+}
+
+// throttle은 웹 클라이언트 하나의 (type, agv_id)별 합치기 상태.
+type throttle struct {
+	mu        sync.Mutex
+	slots     map[string]*throttleSlot
+	stopped   bool
+	coalesced uint64
+}
+
+type throttleSlot struct {
+	last    time.Time
+	pending []byte
+	timer   *time.Timer
+}
+
+func newThrottle() *throttle {
+	return &throttle{slots: make(map[string]*throttleSlot)}
+}
+
+// offer는 data를 지금 보내도 되면 true. 간격 안이면 data를 보류 중인 프레임으로 바꿔 두고,
+// 보류 프레임이 처음 생길 때 간격이 끝나는 시각에 flush를 부른다.
+func (t *throttle) offer(key string, data []byte, interval time.Duration, now time.Time, flush func()) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopped {
+		return false
+	}
+	slot, ok := t.slots[key]
+	if !ok {
+		slot = &throttleSlot{}
+		t.slots[key] = slot
+	}
+	if slot.pending == nil && now.Sub(slot.last) >= interval {
+		slot.last = now
+		return true
+	}
+	if slot.pending != nil {
+		t.coalesced++
+	}
+	slot.pending = data
+	if slot.timer == nil {
+		slot.timer = time.AfterFunc(slot.last.Add(interval).Sub(now), flush)
+	}
+	return false
+}
+
+// take는 key의 보류 프레임을 꺼낸다. 보낸 시각을 now로 기록한다.
+func (t *throttle) take(key string, now time.Time) []byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	slot, ok := t.slots[key]
+	if !ok || t.stopped {
+		return nil
+	}
+	data := slot.pending
+	slot.pending, slot.timer = nil, nil
+	if data != nil {
+		slot.last = now
+	}
+	return data
+}
+
+// stop은 보류 프레임과 타이머를 모두 버린다. 연결이 끝날 때 부른다.
+func (t *throttle) stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopped = true
+	for _, slot := range t.slots {
+		if slot.timer != nil {
+			slot.timer.Stop()
+		}
+	}
+	t.slots = nil
+}
+
+func (t *throttle) coalescedCount() uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.coalesced
+}
+
+// inboundLimit은 들어오는 메시지 종류 하나의 토큰 버킷 설정.
+type inboundLimit struct {
+	rate  float64 // 초당 보충 토큰
+	burst float64
+}
+
+// inboundLimits는 웹 클라이언트가 보내는 메시지 종류별 한도. 채팅은 LLM 호출로 이어지므로 가장 좁다.
+// emergency_stop은 어떤 경우에도 막지 않는다.
+var inboundLimits = map[string]inboundLimit{
+	"chat":    {rate: 0.5, burst: 3},
+	"command": {rate: 5, burst: 10},
+	"control": {rate: 20, burst: 20}, // motor_control: 조이스틱 입력
+	"other":   {rate: 20, burst: 40},
+}
+
+func inboundClass(msgType string) string {
+	switch msgType {
+	case models.MessageTypeEmergencyStop:
+		return ""
+	case models.MessageTypeChat:
+		return "chat"
+	case models.MessageTypeCommand, models.MessageTypeModeChange:
+		return "command"
+	case models.MessageTypeMotorControl:
+		return "control"
+	}
+	return "other"
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// InboundLimiter는 웹 연결 하나가 보내는 메시지를 종류별로 제한한다. 연결마다 하나씩 만든다.
+type InboundLimiter struct {
+	name string
+
+	mu       sync.Mutex
+	buckets  map[string]*tokenBucket
+	rejected map[string]int // 종류별 연속 거부 수. 처음 거부될 때만 로그를 남긴다.
+}
+
+func NewInboundLimiter(name string) *InboundLimiter {
+	return &InboundLimiter{
+		name:     name,
+		buckets:  make(map[string]*tokenBucket),
+		rejected: make(map[string]int),
+	}
+}
+
+// Allow는 msgType 메시지를 지금 처리해도 되는지 판단한다. 안 되면 다시 보낼 수 있을 때까지 남은 시간을 함께 반환한다.
+func (l *InboundLimiter) Allow(msgType string, now time.Time) (bool, time.Duration) {
+	class := inboundClass(msgType)
+	if class == "" {
+		return true, 0
+	}
+	limit := inboundLimits[class]
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.buckets[class]
+	if !ok {
+		b = &tokenBucket{tokens: limit.burst, last: now}
+		l.buckets[class] = b
+	}
+	b.tokens = min(limit.burst, b.tokens+now.Sub(b.last).Seconds()*limit.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		l.rejected[class] = 0
+		return true, 0
+	}
+	l.rejected[class]++
+	if l.rejected[class] == 1 {
+		log.Printf("[WARN] 웹 클라이언트 %s 요청 한도 초과 (%s: 초당 %.1f회, 최대 %.0f회 연속)", l.name, class, limit.rate, limit.burst)
+	}
+	return false, time.Duration((1 - b.tokens) / limit.rate * float64(time.Second))
+}
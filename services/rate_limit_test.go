@@ -0,0 +1,108 @@
+package services
+
+import (
+	"sion-backend/models"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateInterval(t *testing.T) {
+	caps := map[string]float64{models.MessageTypePosition: 10}
+	cases := []struct {
+		rates          map[string]float64
+		msgType, agvID string
+		want           time.Duration
+	}{
+		{nil, models.MessageTypePosition, "sion-001", 100 * time.Millisecond},
+		{map[string]float64{"position": 2}, models.MessageTypePosition, "sion-001", 500 * time.Millisecond},
+		{map[string]float64{"position": 50}, models.MessageTypePosition, "sion-001", 100 * time.Millisecond},
+		{map[string]float64{"*@sion-002": 1}, models.MessageTypePosition, "sion-001", 100 * time.Millisecond},
+		{map[string]float64{"*@sion-002": 1}, models.MessageTypePosition, "sion-002", time.Second},
+		{map[string]float64{"status": 4}, models.MessageTypeStatus, "", 250 * time.Millisecond},
+		{nil, models.MessageTypeStatus, "", 0},
+		{map[string]float64{"*": 1}, models.MessageTypeChatResponse, "", 0},
+	}
+	for _, c := range cases {
+		if got := rateInterval(caps, c.rates, c.msgType, c.agvID); got != c.want {
+			t.Errorf("%v %s@%s: got %v want %v", c.rates, c.msgType, c.agvID, got, c.want)
+		}
+	}
+}
+
+func TestParseRateList(t *testing.T) {
+	got, err := ParseRateList(" position=2, status@sion-001=0.5 ")
+	if err != nil || got["position"] != 2 || got["status@sion-001"] != 0.5 {
+		t.Fatalf("파싱 결과 불일치: %v %v", got, err)
+	}
+	for _, bad := range []string{"position", "position=x", "position=1000", "position=-1", "@x=1"} {
+		if _, err := ParseRateList(bad); err == nil {
+			t.Errorf("%q는 거부돼야 함", bad)
+		}
+	}
+}
+
+// 간격 안에 온 프레임은 마지막 것 하나로 합쳐 간격이 끝날 때 보낸다.
+func TestThrottle_CoalescesToLatest(t *testing.T) {
+	th := newThrottle()
+	interval := 50 * time.Millisecond
+	var flushed atomic.Int32
+	flush := func() { flushed.Add(1) }
+
+	now := time.Now()
+	if !th.offer("position@a", []byte("1"), interval, now, flush) {
+		t.Fatal("첫 프레임은 바로 보내야 함")
+	}
+	for i, d := range []string{"2", "3", "4"} {
+		if th.offer("position@a", []byte(d), interval, now.Add(time.Duration(i+1)*time.Millisecond), flush) {
+			t.Fatalf("간격 안의 프레임 %s는 보류돼야 함", d)
+		}
+	}
+	if !th.offer("position@b", []byte("b1"), interval, now, flush) {
+		t.Fatal("다른 AGV 프레임은 따로 센다")
+	}
+	if th.coalescedCount() != 2 {
+		t.Fatalf("합쳐진 프레임 2개 기대, got %d", th.coalescedCount())
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for flushed.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := string(th.take("position@a", time.Now())); got != "4" {
+		t.Fatalf("마지막 프레임 4 기대, got %q", got)
+	}
+	if th.take("position@a", time.Now()) != nil {
+		t.Fatal("보낸 뒤 보류 프레임이 없어야 함")
+	}
+
+	th.stop()
+	if th.offer("position@a", []byte("5"), interval, time.Now().Add(time.Hour), flush) {
+		t.Fatal("멈춘 뒤에는 보내지 않아야 함")
+	}
+}
+
+func TestInboundLimiter(t *testing.T) {
+	l := NewInboundLimiter("test")
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow(models.MessageTypeChat, now); !ok {
+			t.Fatalf("chat %d번째는 허용돼야 함", i+1)
+		}
+	}
+	ok, retry := l.Allow(models.MessageTypeChat, now)
+	if ok || retry <= 0 || retry > 2*time.Second {
+		t.Fatalf("chat 한도 초과 기대: ok=%v retry=%v", ok, retry)
+	}
+	if ok, _ := l.Allow(models.MessageTypeCommand, now); !ok {
+		t.Fatal("종류가 다르면 따로 센다")
+	}
+	if ok, _ := l.Allow(models.MessageTypeChat, now.Add(retry)); !ok {
+		t.Fatal("retry 뒤에는 다시 허용돼야 함")
+	}
+	for i := 0; i < 100; i++ {
+		if ok, _ := l.Allow(models.MessageTypeEmergencyStop, now); !ok {
+			t.Fatal("emergency_stop은 막지 않아야 함")
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+)
+
+// 웹 브로드캐스트 재전송 버퍼. Broker.sendWeb이 브로드캐스트마다 seq를 붙이고 (type, agv_id) 토픽별로 최근 메시지를
+// 남겨 두면, 재접속한 웹 클라이언트가 마지막으로 받은 seq 뒤의 메시지를 다시 받는다. 형식은 models.StreamState 참고.
+
+const (
+	// defaultReplayPerTopic은 토픽 하나에 남기는 메시지 수. 다음 프레임이 앞 프레임을 덮는 텔레메트리는 마지막 하나만 남긴다.
+	defaultReplayPerTopic = 200
+	// maxReplayTopics를 넘으면 가장 오래 조용한 토픽을 통째로 버린다.
+	maxReplayTopics = 1024
+)
+
+type replayEntry struct {
+	seq            uint64
+	msgType, agvID string
+	raw            []byte
+}
+
+type replayTopic struct {
+	entries []replayEntry
+	// evicted는 이 토픽에서 밀려난 가장 큰 seq. 그보다 앞에서 이어 받으려는 클라이언트는 빈틈이 생긴다.
+	evicted uint64
+}
+
+// replayBuffer는 Broker의 스트림 락 안에서만 쓴다.
+type replayBuffer struct {
+	perTopic int
+	topics   map[string]*replayTopic
+	// lost는 토픽째 버린 메시지 중 가장 큰 seq.
+	lost uint64
+}
+
+func newReplayBuffer(perTopic int) *replayBuffer {
+	return &replayBuffer{perTopic: perTopic, topics: make(map[string]*replayTopic)}
+}
+
+func (r *replayBuffer) add(e replayEntry) {
+	key := e.msgType + "@" + e.agvID
+	t, ok := r.topics[key]
+	if !ok {
+		if len(r.topics) >= maxReplayTopics {
+			r.dropQuietestTopic()
+		}
+		t = &replayTopic{}
+		r.topics[key] = t
+	}
+	limit := r.perTopic
+	if isThrottled(e.msgType) {
+		limit = 1
+	}
+	t.entries = append(t.entries, e)
+	if n := len(t.entries) - limit; n > 0 {
+		t.evicted = t.entries[n-1].seq
+		t.entries = append([]replayEntry(nil), t.entries[n:]...)
+	}
+}
+
+func (r *replayBuffer) dropQuietestTopic() {
+	var quietest string
+	var last uint64
+	for key, t := range r.topics {
+		if s := t.entries[len(t.entries)-1].seq; quietest == "" || s < last {
+			quietest, last = key, s
+		}
+	}
+	r.lost = max(r.lost, last)
+	delete(r.topics, quietest)
+}
+
+// since는 want가 고른 토픽에서 after보다 큰 seq 메시지를 seq 순으로 반환한다. 텔레메트리가 아닌 토픽에서
+// after 뒤의 메시지가 이미 밀려났으면 complete=false.
+func (r *replayBuffer) since(after uint64, want func(msgType, agvID string) bool) (out []replayEntry, complete bool) {
+	complete = after >= r.lost
+	for _, t := range r.topics {
+		first := t.entries[0]
+		if !want(first.msgType, first.agvID) {
+			continue
+		}
+		if t.evicted > after && !isThrottled(first.msgType) {
+			complete = false
+		}
+		for _, e := range t.entries {
+			if e.seq > after {
+				out = append(out, e)
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].seq < out[j].seq })
+	return out, complete
+}
+
+// stampSeq는 브로드캐스트 JSON 봉투에 seq를 넣고, 토픽을 가를 type·agv_id를 함께 꺼낸다.
+// AGV가 보낸 원본을 그대로 흘리는 경우도 있으므로 모르는 필드는 건드리지 않는다.
+func stampSeq(raw []byte, seq uint64) ([]byte, replayEntry, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, replayEntry{}, err
+	}
+	e := replayEntry{seq: seq}
+	if v, ok := fields["type"]; ok {
+		_ = json.Unmarshal(v, &e.msgType)
+	}
+	if v, ok := fields["agv_id"]; ok {
+		_ = json.Unmarshal(v, &e.agvID)
+	}
+	fields["seq"] = json.RawMessage(strconv.FormatUint(seq, 10))
+	stamped, err := json.Marshal(fields)
+	if err != nil {
+		return nil, replayEntry{}, err
+	}
+	e.raw = stamped
+	return stamped, e, nil
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"encoding/json"
+	"sion-backend/models"
+	"testing"
+)
+
+func TestStampSeq_KeepsUnknownFields(t *testing.T) {
+	raw := []byte(`{"type":"status","agv_id":"sion-001","data":{"battery":50},"timestamp":1,"extra":"x"}`)
+	stamped, e, err := stampSeq(raw, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.seq != 42 || e.msgType != models.MessageTypeStatus || e.agvID != "sion-001" {
+		t.Fatalf("토픽 정보 불일치: %+v", e)
+	}
+	var out map[string]any
+	if err := json.Unmarshal(stamped, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out["seq"] != 42.0 || out["extra"] != "x" || out["data"].(map[string]any)["battery"] != 50.0 {
+		t.Fatalf("seq만 더해져야 함: %s", stamped)
+	}
+	if _, _, err := stampSeq([]byte("not json"), 1); err == nil {
+		t.Fatal("JSON이 아니면 에러")
+	}
+}
+
+func TestReplayBuffer_Since(t *testing.T) {
+	r := newReplayBuffer(2)
+	add := func(seq uint64, msgType, agvID string) {
+		r.add(replayEntry{seq: seq, msgType: msgType, agvID: agvID})
+	}
+	add(1, models.MessageTypeChatResponse, "")
+	add(2, models.MessageTypePosition, "sion-001")
+	add(3, models.MessageTypeChatResponse, "")
+	add(4, models.MessageTypePosition, "sion-001")
+	add(5, models.MessageTypeChatResponse, "") // seq 1 chat이 밀려남
+	all := func(string, string) bool { return true }
+
+	got, complete := r.since(1, all)
+	if !complete || len(got) != 3 || got[0].seq != 3 || got[1].seq != 4 || got[2].seq != 5 {
+		t.Fatalf("seq 1 이후: 밀려난 position은 빈틈이 아님, got %+v complete=%v", got, complete)
+	}
+	if _, complete := r.since(0, all); complete {
+		t.Fatal("밀려난 chat 앞에서 이으면 빈틈")
+	}
+	chatOnly := func(msgType, _ string) bool { return msgType == models.MessageTypeChatResponse }
+	if got, _ := r.since(3, chatOnly); len(got) != 1 || got[0].seq != 5 {
+		t.Fatalf("구독한 토픽만 골라야 함: %+v", got)
+	}
+}
+
+func TestReplayBuffer_DropsQuietestTopic(t *testing.T) {
+	r := newReplayBuffer(4)
+	for i := 0; i < maxReplayTopics+1; i++ {
+		r.add(replayEntry{seq: uint64(i + 1), msgType: models.MessageTypeLog, agvID: string(rune('A' + i))})
+	}
+	if len(r.topics) != maxReplayTopics || r.lost != 1 {
+		t.Fatalf("가장 오래 조용한 토픽 하나를 버려야 함: topics=%d lost=%d", len(r.topics), r.lost)
+	}
+	if _, complete := r.since(0, func(string, string) bool { return true }); complete {
+		t.Fatal("버린 토픽 앞에서 이으면 빈틈")
+	}
+}
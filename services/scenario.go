@@ -0,0 +1,342 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sion-backend/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrScenarioNotFound = errors.New("시나리오를 찾을 수 없습니다")
+
+// 시나리오 이름은 파일명으로도 쓰이므로 경로 문자를 허용하지 않는다.
+var scenarioNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// ScenarioStore는 이름 → 시나리오 저장소. dir이 설정되어 있으면 저장 시 <dir>/<name>.json으로도 기록해
+// 서버 재시작 후에도 업로드한 시나리오가 남는다.
+type ScenarioStore struct {
+	mu        sync.RWMutex
+	scenarios map[string]models.Scenario
+	dir       string
+}
+
+func NewScenarioStore(dir string) *ScenarioStore {
+	return &ScenarioStore{
+		scenarios: make(map[string]models.Scenario),
+		dir:       dir,
+	}
+}
+
+// LoadDir은 store 디렉터리의 *.json 시나리오를 모두 읽는다. 잘못된 파일은 경고 후 건너뛴다.
+func (s *ScenarioStore) LoadDir() error {
+	if s.dir == "" {
+		return nil
+	}
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		raw, err := os.ReadFile(f)
+		if err != nil {
+			log.Printf("[WARN] 시나리오 읽기 실패 (%s): %v", f, err)
+			continue
+		}
+		var sc models.Scenario
+		if err := json.Unmarshal(raw, &sc); err != nil {
+			log.Printf("[WARN] 시나리오 파싱 실패 (%s): %v", f, err)
+			continue
+		}
+		if err := ValidateScenario(&sc); err != nil {
+			log.Printf("[WARN] 시나리오 검증 실패 (%s): %v", f, err)
+			continue
+		}
+		s.mu.Lock()
+		s.scenarios[sc.Name] = sc
+		s.mu.Unlock()
+	}
+	log.Printf("[INFO] 시나리오 %d개 로드 (%s)", len(s.List()), s.dir)
+	return nil
+}
+
+// Save는 시나리오를 검증한 뒤 저장한다. 같은 이름이 있으면 덮어쓴다.
+func (s *ScenarioStore) Save(sc models.Scenario) error {
+	if err := ValidateScenario(&sc); err != nil {
+		return err
+	}
+	if s.dir != "" {
+		raw, err := json.MarshalIndent(sc, "", "  ")
+		if err != nil {
+			return fmt.Errorf("시나리오 직렬화 실패: %w", err)
+		}
+		if err := os.MkdirAll(s.dir, 0o755); err != nil {
+			return fmt.Errorf("시나리오 디렉터리 생성 실패: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(s.dir, sc.Name+".json"), raw, 0o644); err != nil {
+			return fmt.Errorf("시나리오 파일 저장 실패: %w", err)
+		}
+	}
+	s.mu.Lock()
+	s.scenarios[sc.Name] = sc
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *ScenarioStore) Get(name string) (models.Scenario, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sc, ok := s.scenarios[name]
+	if !ok {
+		return models.Scenario{}, ErrScenarioNotFound
+	}
+	return sc, nil
+}
+
+// List는 이름순으로 정렬된 시나리오 목록을 반환한다.
+func (s *ScenarioStore) List() []models.Scenario {
+	s.mu.RLock()
+	out := make([]models.Scenario, 0, len(s.scenarios))
+	for _, sc := range s.scenarios {
+		out = append(out, sc)
+	}
+	s.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// ValidateScenario는 시나리오의 필수 필드와 좌표 범위를 검사하고, Map이 있으면 맵 크기를 Map 기준으로 맞춘다.
+// 맵 크기·AGV 수·적 수 상한은 월드 파라미터(ValidateWorldParams)와 같다. 그리드는 맵 크기만큼 할당되므로
+// 업로드한 시나리오로 상한을 우회할 수 없어야 한다.
+func ValidateScenario(sc *models.Scenario) error {
+	if !scenarioNamePattern.MatchString(sc.Name) {
+		return fmt.Errorf("name은 영문/숫자/_/- 1~64자여야 합니다: %q", sc.Name)
+	}
+	if sc.Map != nil {
+		if sc.Map.Height != len(sc.Map.Grid) {
+			return fmt.Errorf("map.grid 행 수 %d가 height %d와 다릅니다", len(sc.Map.Grid), sc.Map.Height)
+		}
+		for row, cells := range sc.Map.Grid {
+			if len(cells) != sc.Map.Width {
+				return fmt.Errorf("map.grid[%d] 길이 %d가 width %d와 다릅니다", row, len(cells), sc.Map.Width)
+			}
+		}
+		sc.MapWidth = float64(sc.Map.Width)
+		sc.MapHeight = float64(sc.Map.Height)
+	}
+	for _, side := range []struct {
+		name string
+		v    float64
+	}{{"map_width", sc.MapWidth}, {"map_height", sc.MapHeight}} {
+		if side.v < minMapSize || side.v > maxMapSize || side.v != math.Trunc(side.v) {
+			return fmt.Errorf("%s는 %d~%d 사이 정수여야 합니다: %g", side.name, minMapSize, maxMapSize, side.v)
+		}
+	}
+	agvs := scenarioAGVs(sc)
+	if len(agvs) > maxSimAGVs {
+		return fmt.Errorf("AGV 수는 1~%d 사이여야 합니다: %d", maxSimAGVs, len(agvs))
+	}
+	if len(sc.Enemies) > maxSimEnemies {
+		return fmt.Errorf("적 수는 0~%d 사이여야 합니다: %d", maxSimEnemies, len(sc.Enemies))
+	}
+	inMap := func(x, y float64) bool {
+		return x >= 0 && y >= 0 && x <= sc.MapWidth && y <= sc.MapHeight
+	}
+	w, h := int(sc.MapWidth), int(sc.MapHeight)
+	blocked := make(map[models.GridCoordinate]bool)
+	for i, ob := range sc.Obstacles {
+		if ob.Size < 1 || ob.Size > w || ob.Size > h {
+			return fmt.Errorf("obstacles[%d].size는 1~%d 사이여야 합니다: %d", i, min(w, h), ob.Size)
+		}
+		p := ob.Position
+		if p.Row < 0 || p.Col < 0 || p.Col+ob.Size > w || p.Row+ob.Size > h {
+			return fmt.Errorf("obstacles[%d] (row %d, col %d, size %d)가 맵 밖입니다", i, p.Row, p.Col, ob.Size)
+		}
+		for dy := 0; dy < ob.Size; dy++ {
+			for dx := 0; dx < ob.Size; dx++ {
+				blocked[models.GridCoordinate{Row: p.Row + dy, Col: p.Col + dx}] = true
+			}
+		}
+	}
+	if sc.Map != nil {
+		for row, cells := range sc.Map.Grid {
+			for col, cell := range cells {
+				if cell == models.CellObstacle {
+					blocked[models.GridCoordinate{Row: row, Col: col}] = true
+				}
+			}
+		}
+	}
+	ids := make(map[string]bool)
+	for i, agv := range agvs {
+		if !inMap(agv.X, agv.Y) {
+			return fmt.Errorf("agvs[%d] 시작 위치 (%.1f, %.1f)가 맵 밖입니다", i, agv.X, agv.Y)
+		}
+		// 맵 경계(x == width)는 시뮬레이터와 같이 마지막 셀로 본다.
+		col, row := min(int(math.Floor(agv.X)), w-1), min(int(math.Floor(agv.Y)), h-1)
+		if blocked[models.GridCoordinate{Row: row, Col: col}] {
+			return fmt.Errorf("agvs[%d] 시작 위치 (%.1f, %.1f)가 장애물 위입니다", i, agv.X, agv.Y)
+		}
+		if b := agv.Battery; b != nil && (*b < 0 || *b > 100) {
+			return fmt.Errorf("agvs[%d].battery는 0~100이어야 합니다", i)
+		}
+		if agv.Mode != "" && agv.Mode != models.ModeAuto && agv.Mode != models.ModeManual {
+			return fmt.Errorf("agvs[%d].mode는 auto 또는 manual이어야 합니다", i)
+		}
+		id := agv.ID
+		if id == "" {
+			id = defaultAGVID(i)
+		}
+		if ids[id] {
+			return fmt.Errorf("agvs[%d].id %q가 중복됩니다", i, id)
+		}
+		ids[id] = true
+	}
+	for i, e := range sc.Enemies {
+		if e.HP <= 0 {
+			return fmt.Errorf("enemies[%d].hp는 1 이상이어야 합니다", i)
+		}
+		if !inMap(e.X, e.Y) {
+			return fmt.Errorf("enemies[%d] 위치 (%.1f, %.1f)가 맵 밖입니다", i, e.X, e.Y)
+		}
+		if _, err := NewEnemyBehavior(scenarioEnemyBehavior(e)); err != nil {
+			return fmt.Errorf("enemies[%d]: %w", i, err)
+		}
+	}
+	for i, d := range sc.Docks {
+		if !inMap(d.X, d.Y) {
+			return fmt.Errorf("docks[%d] 위치 (%.1f, %.1f)가 맵 밖입니다", i, d.X, d.Y)
+		}
+		if d.Rate < 0 {
+			return fmt.Errorf("docks[%d].rate는 0 이상이어야 합니다", i)
+		}
+	}
+	if sc.Victory.TimeoutSec < 0 {
+		return fmt.Errorf("victory.timeout_sec는 0 이상이어야 합니다")
+	}
+	return nil
+}
+
+// LoadScenario는 시나리오로 월드를 다시 구성한다. 실행 중이면 ErrSimulatorRunning을 반환한다.
+// 시나리오에 seed가 없으면 현재 시각으로 seed를 정해 랜덤 요소(랜덤 워크·공격 판정)에만 쓴다.
+func (sim *AGVSimulator) LoadScenario(sc models.Scenario) error {
+	if err := ValidateScenario(&sc); err != nil {
+		return err
+	}
+	if sim.IsRunning() {
+		return ErrSimulatorRunning
+	}
+	sim.endStatsSessions()
+
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+
+	if sc.Seed == nil {
+		seed := time.Now().UnixNano()
+		sc.Seed = &seed
+	}
+	sim.loadScenarioLocked(sc)
+	return nil
+}
+
+// loadScenarioLocked는 검증을 마치고 seed가 정해진 시나리오로 월드를 구성한다.
+func (sim *AGVSimulator) loadScenarioLocked(sc models.Scenario) {
+	seed := *sc.Seed
+	sim.seedLocked(seed)
+
+	sim.MapWidth, sim.MapHeight = sc.MapWidth, sc.MapHeight
+	sim.Obstacles = append([]models.Obstacle(nil), sc.Obstacles...)
+	if sc.Map != nil {
+		for row, cells := range sc.Map.Grid {
+			for col, cell := range cells {
+				if cell != models.CellObstacle {
+					continue
+				}
+				sim.Obstacles = append(sim.Obstacles, models.Obstacle{
+					ID:       fmt.Sprintf("map-%d-%d", row, col),
+					Type:     "static",
+					Position: models.GridCoordinate{Row: row, Col: col},
+					Size:     1,
+				})
+			}
+		}
+	}
+
+	sim.Enemies = make([]models.Enemy, len(sc.Enemies))
+	sim.enemyBehaviors = make(map[string]EnemyBehavior, len(sc.Enemies))
+	for i, e := range sc.Enemies {
+		id := e.ID
+		if id == "" {
+			id = fmt.Sprintf("enemy-%d", i+1)
+		}
+		maxHP := e.MaxHP
+		if maxHP < e.HP {
+			maxHP = e.HP
+		}
+		sim.Enemies[i] = models.Enemy{
+			ID:          id,
+			Type:        e.Type,
+			Name:        e.Name,
+			State:       models.EnemyStateAlive,
+			Position:    models.PositionData{X: e.X, Y: e.Y},
+			HP:          e.HP,
+			MaxHP:       maxHP,
+			Speed:       e.Speed,
+			ThreatLevel: e.ThreatLevel,
+		}
+		// ValidateScenario에서 이미 검증했으므로 에러가 나지 않는다.
+		sim.enemyBehaviors[id], _ = NewEnemyBehavior(scenarioEnemyBehavior(e))
+	}
+
+	sim.mission = NewBatteryMission(append([]models.ChargingDock(nil), sc.Docks...))
+
+	agvs := scenarioAGVs(&sc)
+	sim.agvs = make([]*simAGV, len(agvs))
+	for i, agv := range agvs {
+		id, name := agv.ID, agv.Name
+		if id == "" {
+			id = defaultAGVID(i)
+		}
+		if name == "" {
+			name = defaultAGVName(i)
+		}
+		mode := agv.Mode
+		if mode == "" {
+			mode = models.ModeAuto
+		}
+		battery := 100
+		if agv.Battery != nil {
+			battery = *agv.Battery
+		}
+		sim.agvs[i] = newSimAGV(id, name, agv.X, agv.Y, agv.Angle, battery, mode)
+	}
+
+	sim.resetRunLocked(sc.Name, sc.Victory)
+	sim.rebuildGridLocked()
+	sim.loaded = &sc
+	log.Printf("[INFO] 시나리오 로드: %s (맵 %.0fx%.0f, AGV %d, 적 %d, 장애물 %d, seed=%d)",
+		sc.Name, sim.MapWidth, sim.MapHeight, len(sim.agvs), len(sim.Enemies), len(sim.Obstacles), seed)
+}
+
+func scenarioEnemyBehavior(e models.ScenarioEnemy) models.EnemyBehaviorConfig {
+	return models.EnemyBehaviorConfig{
+		Behavior:      e.Behavior,
+		Waypoints:     e.Waypoints,
+		TriggerRadius: e.TriggerRadius,
+	}
+}
+
+// scenarioAGVs는 시나리오가 띄울 AGV 목록을 반환한다. agvs가 비어 있으면 agv 한 대.
+func scenarioAGVs(sc *models.Scenario) []models.ScenarioAGV {
+	if len(sc.AGVs) > 0 {
+		return sc.AGVs
+	}
+	return []models.ScenarioAGV{sc.AGV}
+}
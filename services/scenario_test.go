@@ -0,0 +1,192 @@
+package services
+
+import (
+	"sion-backend/models"
+	"testing"
+)
+
+func duelScenario() models.Scenario {
+	seed := int64(1)
+	return models.Scenario{
+		Name:      "duel",
+		MapWidth:  10,
+		MapHeight: 10,
+		Enemies: []models.ScenarioEnemy{
+			{ID: "enemy-1", Name: "아리", HP: 20, X: 4, Y: 4},
+		},
+		AGV:     models.ScenarioAGV{X: 1, Y: 1},
+		Seed:    &seed,
+		Victory: models.VictoryCondition{AllEnemiesDefeated: true, TimeoutSec: 300},
+	}
+}
+
+func emptyMap(w, h int) *models.Map {
+	grid := make([][]int, h)
+	for i := range grid {
+		grid[i] = make([]int, w)
+	}
+	return &models.Map{Width: w, Height: h, Grid: grid}
+}
+
+func TestValidateScenario(t *testing.T) {
+	badBattery := 150
+	cases := []struct {
+		name   string
+		mutate func(*models.Scenario)
+	}{
+		{"이름에 경로 문자", func(sc *models.Scenario) { sc.Name = "../etc" }},
+		{"맵 크기 0", func(sc *models.Scenario) { sc.MapWidth = 0 }},
+		{"AGV 맵 밖", func(sc *models.Scenario) { sc.AGV.X = 11 }},
+		{"배터리 범위 초과", func(sc *models.Scenario) { sc.AGV.Battery = &badBattery }},
+		{"적 HP 0", func(sc *models.Scenario) { sc.Enemies[0].HP = 0 }},
+		{"적 맵 밖", func(sc *models.Scenario) { sc.Enemies[0].Y = -1 }},
+		{"map.grid 크기 불일치", func(sc *models.Scenario) {
+			sc.Map = emptyMap(10, 10)
+			sc.Map.Grid = sc.Map.Grid[:9]
+		}},
+		{"map.grid 행 길이 불일치", func(sc *models.Scenario) {
+			sc.Map = emptyMap(10, 10)
+			sc.Map.Grid[5] = sc.Map.Grid[5][:3]
+		}},
+		{"맵 크기 상한 초과", func(sc *models.Scenario) { sc.MapWidth = maxMapSize + 1 }},
+		{"맵 크기 하한 미만", func(sc *models.Scenario) { sc.MapHeight = minMapSize - 1 }},
+		{"AGV 수 상한 초과", func(sc *models.Scenario) {
+			sc.AGVs = make([]models.ScenarioAGV, maxSimAGVs+1)
+			for i := range sc.AGVs {
+				sc.AGVs[i].ID = defaultAGVID(i)
+			}
+		}},
+		{"적 수 상한 초과", func(sc *models.Scenario) {
+			for len(sc.Enemies) <= maxSimEnemies {
+				sc.Enemies = append(sc.Enemies, models.ScenarioEnemy{HP: 1, X: 5, Y: 5})
+			}
+		}},
+		{"장애물 맵 밖", func(sc *models.Scenario) {
+			sc.Obstacles = []models.Obstacle{{Position: models.GridCoordinate{Row: 9, Col: 9}, Size: 2}}
+		}},
+		{"장애물 size 0", func(sc *models.Scenario) {
+			sc.Obstacles = []models.Obstacle{{Position: models.GridCoordinate{Row: 5, Col: 5}}}
+		}},
+		{"장애물 size가 맵보다 큼", func(sc *models.Scenario) {
+			sc.Obstacles = []models.Obstacle{{Size: 1 << 20}}
+		}},
+		{"AGV 시작 위치가 장애물", func(sc *models.Scenario) {
+			sc.Obstacles = []models.Obstacle{{Position: models.GridCoordinate{Row: 0, Col: 0}, Size: 2}}
+		}},
+		{"AGV 시작 위치가 map.grid 장애물", func(sc *models.Scenario) {
+			sc.Map = emptyMap(10, 10)
+			sc.Map.Grid[1][1] = models.CellObstacle
+		}},
+	}
+	ok := duelScenario()
+	if err := ValidateScenario(&ok); err != nil {
+		t.Fatalf("정상 시나리오 검증 실패: %v", err)
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sc := duelScenario()
+			tc.mutate(&sc)
+			if err := ValidateScenario(&sc); err == nil {
+				t.Fatal("검증 에러 기대")
+			}
+		})
+	}
+}
+
+func TestLoadScenario_MapGridBecomesObstacles(t *testing.T) {
+	sc := duelScenario()
+	sc.Map = emptyMap(12, 10)
+	sc.Map.Grid[0][1] = models.CellObstacle
+	sc.Map.Grid[1][2] = models.CellObstacle
+	sc.Enemies[0].X, sc.Enemies[0].Y = 2, 0
+
+	sim := NewAGVSimulator(nil)
+	if err := sim.LoadScenario(sc); err != nil {
+		t.Fatalf("LoadScenario 실패: %v", err)
+	}
+	if sim.MapWidth != 12 || sim.MapHeight != 10 {
+		t.Fatalf("맵 크기 12x10 기대, got %.0fx%.0f", sim.MapWidth, sim.MapHeight)
+	}
+	if !sim.grid.IsObstacle(1, 0) || !sim.grid.IsObstacle(2, 1) || sim.grid.IsObstacle(0, 0) {
+		t.Fatal("map.grid의 CellObstacle 셀만 장애물이어야 함")
+	}
+	if b := sim.agvs[0].Status.Battery; b != 100 {
+		t.Fatalf("battery 생략 시 100 기대, got %d", b)
+	}
+}
+
+func TestLoadScenario_RunsToVictory(t *testing.T) {
+	var ended *models.SimulationEndData
+	sim := NewAGVSimulator(func(msg models.WebSocketMessage) {
+		if msg.Type == models.MessageTypeSimulationEnd {
+			data := msg.Data.(models.SimulationEndData)
+			ended = &data
+		}
+	})
+	if err := sim.LoadScenario(duelScenario()); err != nil {
+		t.Fatalf("LoadScenario 실패: %v", err)
+	}
+
+	var outcome string
+	for i := 0; i < 1000 && outcome == ""; i++ {
+		outcome = sim.update(true)
+	}
+	if outcome != models.SimOutcomeVictory {
+		t.Fatalf("victory 기대, got %q", outcome)
+	}
+	if ended == nil || ended.Scenario != "duel" || ended.Outcome != models.SimOutcomeVictory {
+		t.Fatalf("simulation_end 브로드캐스트 기대, got %+v", ended)
+	}
+}
+
+func TestLoadScenario_Timeout(t *testing.T) {
+	sc := duelScenario()
+	sc.Victory.TimeoutSec = 1
+	sim := NewAGVSimulator(nil)
+	if err := sim.LoadScenario(sc); err != nil {
+		t.Fatalf("LoadScenario 실패: %v", err)
+	}
+	// 기본 UpdateInterval 500ms → 두 번째 틱에 1초 도달
+	if out := sim.update(true); out != "" {
+		t.Fatalf("첫 틱에는 진행 중이어야 함, got %q", out)
+	}
+	if out := sim.update(true); out != models.SimOutcomeTimeout {
+		t.Fatalf("timeout 기대, got %q", out)
+	}
+}
+
+func TestScenarioStore_SaveAndLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	store := NewScenarioStore(dir)
+	if err := store.Save(duelScenario()); err != nil {
+		t.Fatalf("Save 실패: %v", err)
+	}
+
+	reloaded := NewScenarioStore(dir)
+	if err := reloaded.LoadDir(); err != nil {
+		t.Fatalf("LoadDir 실패: %v", err)
+	}
+	sc, err := reloaded.Get("duel")
+	if err != nil {
+		t.Fatalf("재시작 후 duel 시나리오 기대: %v", err)
+	}
+	if len(sc.Enemies) != 1 || sc.Enemies[0].Name != "아리" {
+		t.Fatalf("저장된 시나리오 내용 불일치: %+v", sc)
+	}
+	if _, err := reloaded.Get("missing"); err != ErrScenarioNotFound {
+		t.Fatalf("없는 이름 → ErrScenarioNotFound 기대, got %v", err)
+	}
+}
+
+// 저장소에 포함된 데모 시나리오가 모두 검증을 통과하는지 확인한다.
+func TestBundledScenariosAreValid(t *testing.T) {
+	store := NewScenarioStore("../scenarios")
+	if err := store.LoadDir(); err != nil {
+		t.Fatalf("LoadDir 실패: %v", err)
+	}
+	for _, name := range []string{"duel", "wall", "chase"} {
+		if _, err := store.Get(name); err != nil {
+			t.Fatalf("번들 시나리오 %q 로드 실패: %v", name, err)
+		}
+	}
+}
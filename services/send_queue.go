@@ -0,0 +1,235 @@
+package services
+
+import (
+	"sion-backend/models"
+	"sync"
+	"time"
+)
+
+// 연결별 송신 큐. 브로드캐스트·응답은 큐에 넣기만 하고, 연결마다 writer 고루틴 하나가 꺼내 보낸다.
+// 느린 브라우저 하나가 다른 클라이언트나 AGV 메시지 경로를 막지 않게 하기 위함이다.
+//
+// 큐가 가득 차면 버릴 메시지를 dropLevel로 고른다. 가장 낮은 단계의 가장 오래된 메시지를 먼저 버리고,
+// 새로 들어온 메시지의 단계가 큐 안의 어떤 메시지보다 낮으면 새 메시지를 버린다. 큐에 들어간 AGV 명령은 버리지 않고,
+// 들어갈 자리가 없는 명령은 조용히 버리는 대신 pushRejected로 돌려줘 보낸 쪽이 실패로 처리하게 한다.
+// chat·error·비상 정지는 버리지 않으며, 버릴 것이 없으면 용량의 두 배까지 잠시 넘치게 둔다.
+// 큐가 오래 가득 찬 채면 느린 소비자로 보고 연결을 끊는다.
+
+// 버리는 순서. 숫자가 작을수록 먼저 버린다.
+const (
+	dropPosition = iota // 위치 프레임: 다음 프레임이 곧 덮는다
+	dropPeriodic        // status·agv_stats 등 주기 스냅샷
+	dropNormal          // 그 밖의 메시지
+	dropCommand         // command·mode_change: 큐에 든 것은 버리지 않고, 못 넣으면 거부한다
+	dropNever           // chat·error·emergency_stop: 버리지 않는다
+)
+
+func dropLevel(msgType string) int {
+	switch msgType {
+	case models.MessageTypePosition:
+		return dropPosition
+	case models.MessageTypeStatus, models.MessageTypeStats, models.MessageTypePathUpdate:
+		return dropPeriodic
+	case models.MessageTypeCommand, models.MessageTypeModeChange:
+		return dropCommand
+	case models.MessageTypeChat, models.MessageTypeChatResponse, models.MessageTypeError, models.MessageTypeEmergencyStop:
+		return dropNever
+	}
+	return dropNormal
+}
+
+type queuedMsg struct {
+	data  []byte
+	level int
+	// closeCode가 0이 아니면 data를 사유로 한 close 프레임. 보낸 뒤 writer는 끝난다.
+	closeCode int
+}
+
+// pushResult는 sendQueue.push 결과.
+type pushResult int
+
+const (
+	pushQueued   pushResult = iota
+	pushDropped             // 큐가 가득 차 메시지 하나(새 것 또는 큐 안의 것)를 버렸다
+	pushRejected            // 큐가 가득 차 명령을 넣지 못했다. 보낸 쪽이 실패로 처리해야 한다
+	pushSlow                // 느린 소비자: 연결을 끊어야 한다
+	pushClosed
+)
+
+type sendQueue struct {
+	size int
+	wake chan struct{}
+
+	mu        sync.Mutex
+	items     []queuedMsg
+	closed    bool      // 더 받지 않는다. 남은 메시지는 writer가 마저 보낸다.
+	kick      bool      // 느린 소비자로 끊김. 남은 메시지를 버리고 close 프레임만 보낸다.
+	fullSince time.Time // 큐가 가득 찬 뒤 절반 아래로 내려가지 않은 시작 시각
+	sent      uint64
+	dropped   uint64
+	maxDepth  int
+}
+
+func newSendQueue(size int) *sendQueue {
+	return &sendQueue{size: size, wake: make(chan struct{}, 1)}
+}
+
+// push는 data를 큐에 넣는다. 큐가 slowAfter 넘게 가득 찬 채이거나 버릴 수 없는 메시지로 용량의 두 배를 넘으면 pushSlow.
+func (q *sendQueue) push(data []byte, level int, now time.Time, slowAfter time.Duration) pushResult {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return pushClosed
+	}
+	res := pushQueued
+	if len(q.items) >= q.size {
+		if q.fullSince.IsZero() {
+			q.fullSince = now
+		} else if now.Sub(q.fullSince) > slowAfter {
+			return pushSlow
+		}
+		switch victim := q.victimLocked(level); {
+		case victim >= 0:
+			q.items = append(q.items[:victim], q.items[victim+1:]...)
+			q.dropped++
+			res = pushDropped
+		case level == dropCommand:
+			q.dropped++
+			return pushRejected
+		case level < dropNever:
+			q.dropped++
+			return pushDropped
+		case len(q.items) >= 2*q.size:
+			return pushSlow
+		}
+	}
+	q.appendLocked(queuedMsg{data: data, level: level})
+	return res
+}
+
+// pushClose는 지금까지 넣은 메시지 뒤에 close 프레임을 넣고 큐를 닫는다.
+func (q *sendQueue) pushClose(code int, reason string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return
+	}
+	q.appendLocked(queuedMsg{data: []byte(reason), level: dropNever, closeCode: code})
+	q.closed = true
+}
+
+func (q *sendQueue) appendLocked(m queuedMsg) {
+	q.items = append(q.items, m)
+	q.maxDepth = max(q.maxDepth, len(q.items))
+	q.signal()
+}
+
+func (q *sendQueue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// victimLocked는 level 메시지를 넣기 위해 버릴 큐 안 메시지의 위치를 고른다. 명령 이상 단계는 고르지 않는다.
+// 새 메시지를 버려야 하면 -1.
+func (q *sendQueue) victimLocked(level int) int {
+	victim, victimLevel := -1, dropCommand
+	for i, m := range q.items {
+		if m.level < victimLevel {
+			victim, victimLevel = i, m.level
+		}
+	}
+	if victim < 0 || level < victimLevel {
+		return -1
+	}
+	return victim
+}
+
+// popState는 sendQueue.pop이 writer에 알려 주는 상태.
+type popState int
+
+const (
+	popMessage  popState = iota // 보낼 메시지가 있다
+	popDraining                 // 큐가 닫혔고 남은 메시지를 보내는 중
+	popDone                     // 닫혔고 남은 메시지가 없다
+	popKicked                   // 느린 소비자로 끊겼다
+)
+
+// pop은 메시지가 생길 때까지 기다렸다가 가장 오래된 것을 꺼낸다.
+func (q *sendQueue) pop() (queuedMsg, popState) {
+	for {
+		q.mu.Lock()
+		if q.kick {
+			q.mu.Unlock()
+			return queuedMsg{}, popKicked
+		}
+		if len(q.items) > 0 {
+			m := q.items[0]
+			q.items[0] = queuedMsg{}
+			q.items = q.items[1:]
+			q.sent++
+			if len(q.items) < q.size/2 {
+				q.fullSince = time.Time{}
+			}
+			st := popMessage
+			if q.closed {
+				st = popDraining
+			}
+			q.mu.Unlock()
+			return m, st
+		}
+		if q.closed {
+			q.mu.Unlock()
+			return queuedMsg{}, popDone
+		}
+		q.mu.Unlock()
+		<-q.wake
+	}
+}
+
+// close는 큐를 닫는다. writer는 남은 메시지를 보내고 끝난다.
+func (q *sendQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.signal()
+}
+
+// abandon은 남은 메시지를 버리고 큐를 닫는다. kick이면 writer가 느린 소비자 close 프레임을 보낸다.
+func (q *sendQueue) abandon(kick bool) {
+	q.mu.Lock()
+	q.closed = true
+	q.kick = q.kick || kick
+	q.dropped += uint64(len(q.items))
+	q.items = nil
+	q.mu.Unlock()
+	q.signal()
+}
+
+// QueueStats는 연결 하나의 송신 큐 지표.
+type QueueStats struct {
+	Remote   string   `json:"remote"`
+	Type     string   `json:"type"`
+	AGVIDs   []string `json:"agv_ids,omitempty"`
+	Depth    int      `json:"depth"`
+	MaxDepth int      `json:"max_depth"`
+	Capacity int      `json:"capacity"`
+	Sent     uint64   `json:"sent"`
+	Dropped  uint64   `json:"dropped"`
+	// Coalesced는 빈도 제한으로 합쳐져 보내지 않은 텔레메트리 프레임 수.
+	Coalesced uint64   `json:"coalesced"`
+	Topics    []string `json:"topics,omitempty"`
+}
+
+func (q *sendQueue) stats() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return QueueStats{
+		Depth:    len(q.items),
+		MaxDepth: q.maxDepth,
+		Capacity: q.size,
+		Sent:     q.sent,
+		Dropped:  q.dropped,
+	}
+}
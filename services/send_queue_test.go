@@ -0,0 +1,142 @@
+package services
+
+import (
+	"sion-backend/models"
+	"testing"
+	"time"
+)
+
+func popData(t *testing.T, q *sendQueue) string {
+	t.Helper()
+	m, st := q.pop()
+	if st != popMessage && st != popDraining {
+		t.Fatalf("메시지 기대, got state %d", st)
+	}
+	return string(m.data)
+}
+
+// 가득 차면 가장 오래된 position부터 버리고, 새 position이 가장 낮은 단계면 새 것을 버린다.
+func TestSendQueue_DropsOldestPositionFirst(t *testing.T) {
+	q := newSendQueue(3)
+	now := time.Now()
+	q.push([]byte("pos1"), dropLevel(models.MessageTypePosition), now, time.Minute)
+	q.push([]byte("status"), dropLevel(models.MessageTypeStatus), now, time.Minute)
+	q.push([]byte("pos2"), dropLevel(models.MessageTypePosition), now, time.Minute)
+
+	if got := q.push([]byte("chat"), dropLevel(models.MessageTypeChat), now, time.Minute); got != pushDropped {
+		t.Fatalf("가득 찬 큐는 pushDropped 기대, got %d", got)
+	}
+	if got := q.push([]byte("status2"), dropLevel(models.MessageTypeStatus), now, time.Minute); got != pushDropped {
+		t.Fatalf("pushDropped 기대, got %d", got)
+	}
+	// 큐: status, chat, status2 — position은 큐 안의 어떤 것보다 낮아 새 것이 버려진다.
+	if got := q.push([]byte("pos3"), dropLevel(models.MessageTypePosition), now, time.Minute); got != pushDropped {
+		t.Fatalf("pushDropped 기대, got %d", got)
+	}
+	for _, want := range []string{"status", "chat", "status2"} {
+		if got := popData(t, q); got != want {
+			t.Fatalf("순서가 유지돼야 함: got %s want %s", got, want)
+		}
+	}
+	if st := q.stats(); st.Dropped != 3 || st.MaxDepth != 3 || st.Sent != 3 {
+		t.Fatalf("지표 불일치: %+v", st)
+	}
+}
+
+// chat·error는 버리지 않고 용량의 두 배까지 넘치며, 그 너머는 느린 소비자다.
+func TestSendQueue_NeverDropsChatOrError(t *testing.T) {
+	q := newSendQueue(2)
+	now := time.Now()
+	for i, typ := range []string{models.MessageTypeChat, models.MessageTypeError, models.MessageTypeChatResponse, models.MessageTypeError} {
+		if got := q.push([]byte(typ), dropLevel(typ), now, time.Minute); got != pushQueued {
+			t.Fatalf("%d번째 %s는 큐에 들어가야 함, got %d", i, typ, got)
+		}
+	}
+	if got := q.push([]byte("chat"), dropLevel(models.MessageTypeChat), now, time.Minute); got != pushSlow {
+		t.Fatalf("용량 두 배를 넘으면 pushSlow 기대, got %d", got)
+	}
+	if st := q.stats(); st.Dropped != 0 || st.Depth != 4 {
+		t.Fatalf("버린 메시지가 없어야 함: %+v", st)
+	}
+}
+
+// AGV 큐가 가득 차도 비상 정지·명령은 밀려나지 않는다. 자리가 없는 명령은 pushRejected로 돌려준다.
+func TestSendQueue_AGVQueueKeepsEmergencyStop(t *testing.T) {
+	q := newSendQueue(3)
+	now := time.Now()
+	push := func(data, typ string) pushResult { return q.push([]byte(data), dropLevel(typ), now, time.Minute) }
+	push("estop", models.MessageTypeEmergencyStop)
+	push("cmd1", models.MessageTypeCommand)
+	push("motor1", models.MessageTypeMotorControl)
+
+	// 가득 찬 큐에 새 명령이 오면 motor_control만 밀려난다.
+	if got := push("cmd2", models.MessageTypeCommand); got != pushDropped {
+		t.Fatalf("motor_control을 버리고 들어가야 함, got %d", got)
+	}
+	// 이제 큐는 비상 정지와 명령뿐: 일반 메시지는 자기가 버려지고, 명령은 거부된다.
+	if got := push("motor2", models.MessageTypeMotorControl); got != pushDropped {
+		t.Fatalf("새 일반 메시지가 버려져야 함, got %d", got)
+	}
+	if got := push("mode", models.MessageTypeModeChange); got != pushRejected {
+		t.Fatalf("자리가 없는 명령은 pushRejected, got %d", got)
+	}
+	// 비상 정지는 넘치더라도 들어간다.
+	if got := push("estop2", models.MessageTypeEmergencyStop); got != pushQueued {
+		t.Fatalf("비상 정지는 거부·버림 없이 들어가야 함, got %d", got)
+	}
+	for _, want := range []string{"estop", "cmd1", "cmd2", "estop2"} {
+		if got := popData(t, q); got != want {
+			t.Fatalf("got %s want %s", got, want)
+		}
+	}
+}
+
+// 큐가 slowAfter 넘게 가득 찬 채면 느린 소비자다. 절반 아래로 비우면 다시 센다.
+func TestSendQueue_SlowConsumerAfterTimeout(t *testing.T) {
+	q := newSendQueue(2)
+	start := time.Now()
+	lvl := dropLevel(models.MessageTypePosition)
+	q.push([]byte("a"), lvl, start, time.Second)
+	q.push([]byte("b"), lvl, start, time.Second)
+	if got := q.push([]byte("c"), lvl, start, time.Second); got != pushDropped {
+		t.Fatalf("처음 가득 차면 버리기만 해야 함, got %d", got)
+	}
+	if got := q.push([]byte("d"), lvl, start.Add(2*time.Second), time.Second); got != pushSlow {
+		t.Fatalf("slowAfter가 지나면 pushSlow 기대, got %d", got)
+	}
+
+	popData(t, q)
+	popData(t, q)
+	if got := q.push([]byte("e"), lvl, start.Add(3*time.Second), time.Second); got != pushQueued {
+		t.Fatalf("비운 뒤에는 다시 받아야 함, got %d", got)
+	}
+}
+
+// close 뒤에도 남은 메시지와 close 프레임은 순서대로 나오고, abandon은 남은 메시지를 버린다.
+func TestSendQueue_CloseDrainsAndAbandonKicks(t *testing.T) {
+	q := newSendQueue(4)
+	q.push([]byte("err"), dropNever, time.Now(), time.Minute)
+	q.pushClose(1008, "bye")
+	if got := q.push([]byte("late"), dropNormal, time.Now(), time.Minute); got != pushClosed {
+		t.Fatalf("닫힌 큐는 pushClosed 기대, got %d", got)
+	}
+	if got := popData(t, q); got != "err" {
+		t.Fatalf("남은 메시지 먼저: %s", got)
+	}
+	if m, st := q.pop(); st != popDraining || m.closeCode != 1008 {
+		t.Fatalf("close 프레임 기대: %+v %d", m, st)
+	}
+	if _, st := q.pop(); st != popDone {
+		t.Fatalf("popDone 기대, got %d", st)
+	}
+
+	q = newSendQueue(4)
+	q.push([]byte("x"), dropNormal, time.Now(), time.Minute)
+	q.abandon(true)
+	if _, st := q.pop(); st != popKicked {
+		t.Fatalf("popKicked 기대, got %d", st)
+	}
+	if st := q.stats(); st.Depth != 0 || st.Dropped != 1 {
+		t.Fatalf("abandon은 남은 메시지를 버려야 함: %+v", st)
+	}
+}
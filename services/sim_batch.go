@@ -0,0 +1,263 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sion-backend/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 헤드리스 배치 실행. 웹 서버·DB 없이 seed를 고정한 에피소드를 병렬로 돌려 전략(타겟 정책 등)을 통계적으로 비교한다.
+// 에피소드마다 독립된 AGVSimulator를 쓰므로 같은 설정·seed면 결과가 항상 같다.
+
+// defaultEpisodeTicks는 MaxTicks를 생략했을 때의 에피소드 길이 (0.5초 틱 기준 10분).
+const defaultEpisodeTicks = 1200
+
+// EpisodeConfig는 에피소드 하나의 설정. Scenario가 있으면 그 월드를(seed는 Seed로 덮어씀), 없으면 Seed·AGVCount로 만든 기본 월드를 쓴다.
+type EpisodeConfig struct {
+	Seed      int64
+	AGVCount  int
+	Targeting models.TargetingConfig
+	Scenario  *models.Scenario
+	// MaxTicks를 넘기면 timeout으로 끝낸다. 0이면 defaultEpisodeTicks.
+	MaxTicks int
+}
+
+// EpisodeResult는 에피소드 하나의 지표. 여러 AGV의 값은 합산한다.
+// TimeToClearSec은 모든 적을 정리(격살·도주)하는 데 걸린 시뮬레이션 시간이며, 정리하지 못했으면 -1.
+type EpisodeResult struct {
+	Seed           int64   `json:"seed"`
+	Scenario       string  `json:"scenario,omitempty"`
+	AGVCount       int     `json:"agv_count"`
+	Policy         string  `json:"policy"`
+	Stickiness     float64 `json:"stickiness"`
+	Outcome        string  `json:"outcome"`
+	Ticks          int     `json:"ticks"`
+	TimeToClearSec float64 `json:"time_to_clear_sec"`
+	Enemies        int     `json:"enemies"`
+	Kills          int     `json:"kills"`
+	BatteryUsed    int     `json:"battery_used"`
+	Collisions     int     `json:"collisions"`
+	Distance       float64 `json:"distance"`
+	Error          string  `json:"error,omitempty"`
+}
+
+// RunEpisode는 에피소드 하나를 끝까지 돌린다. 설정 오류만 에러로 반환한다.
+func RunEpisode(cfg EpisodeConfig) (EpisodeResult, error) {
+	res := EpisodeResult{
+		Seed:           cfg.Seed,
+		AGVCount:       cfg.AGVCount,
+		Policy:         cfg.Targeting.Policy,
+		Stickiness:     cfg.Targeting.Stickiness,
+		TimeToClearSec: -1,
+	}
+	maxTicks := cfg.MaxTicks
+	if maxTicks <= 0 {
+		maxTicks = defaultEpisodeTicks
+	}
+
+	sim := NewAGVSimulator(nil)
+	sim.LogToDB = false
+	if cfg.Scenario != nil {
+		// 시나리오의 고정 seed 대신 에피소드 seed를 써서 같은 월드에서 난수(적 행동·공격)만 바꾼다.
+		sc := *cfg.Scenario
+		sc.Seed = &cfg.Seed
+		if err := sim.LoadScenario(sc); err != nil {
+			return res, err
+		}
+		res.Scenario = cfg.Scenario.Name
+	} else {
+		if cfg.AGVCount > 0 {
+			sim.AGVCount = cfg.AGVCount
+		}
+		if err := sim.Reseed(cfg.Seed); err != nil {
+			return res, err
+		}
+	}
+	if err := sim.SetTargetingConfig(cfg.Targeting); err != nil {
+		return res, err
+	}
+
+	sim.mu.Lock()
+	// 시나리오의 timeout보다 MaxTicks가 먼저 오면 아래 루프가 끝낸다.
+	sim.victory.AllEnemiesDefeated = true
+	res.AGVCount = len(sim.agvs)
+	res.Enemies = len(sim.Enemies)
+	prevBattery := make([]int, len(sim.agvs))
+	for i, a := range sim.agvs {
+		prevBattery[i] = a.Status.Battery
+	}
+	sim.mu.Unlock()
+
+	for res.Ticks < maxTicks && res.Outcome == "" {
+		res.Outcome = sim.update(false)
+		res.Ticks++
+		sim.mu.RLock()
+		for i, a := range sim.agvs {
+			if used := prevBattery[i] - a.Status.Battery; used > 0 {
+				res.BatteryUsed += used
+			}
+			prevBattery[i] = a.Status.Battery
+		}
+		sim.mu.RUnlock()
+	}
+	if res.Outcome == "" {
+		res.Outcome = models.SimOutcomeTimeout
+	}
+
+	sim.mu.RLock()
+	defer sim.mu.RUnlock()
+	if res.Outcome == models.SimOutcomeVictory {
+		res.TimeToClearSec = sim.elapsed.Seconds()
+	}
+	for _, a := range sim.agvs {
+		res.Kills += a.Stats.EnemiesDefeated
+		res.Collisions += a.Stats.Collisions
+		res.Distance += a.Stats.TotalDistance
+	}
+	return res, nil
+}
+
+// RunBatch는 에피소드들을 workers개 고루틴으로 나눠 돌리고 입력 순서대로 결과를 반환한다.
+// ctx가 취소되면 아직 시작하지 않은 에피소드는 건너뛰고 Error를 채운다.
+func RunBatch(ctx context.Context, cfgs []EpisodeConfig, workers int, progress func(done, total int)) []EpisodeResult {
+	if workers < 1 {
+		workers = 1
+	}
+	results := make([]EpisodeResult, len(cfgs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				res, err := RunEpisode(cfgs[i])
+				if err != nil {
+					res.Error = err.Error()
+				}
+				results[i] = res
+				if progress != nil {
+					mu.Lock()
+					done++
+					progress(done, len(cfgs))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range cfgs {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			for j := i; j < len(cfgs); j++ {
+				results[j] = EpisodeResult{Seed: cfgs[j].Seed, Policy: cfgs[j].Targeting.Policy, Error: ctx.Err().Error()}
+			}
+			close(jobs)
+			wg.Wait()
+			return results
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// BatchSummary는 같은 설정(정책·stickiness·AGV 수·시나리오) 에피소드들의 평균 지표.
+// MeanTimeToClearSec은 정리에 성공한 에피소드만으로 낸 평균이며, 하나도 없으면 -1.
+type BatchSummary struct {
+	Scenario           string  `json:"scenario,omitempty"`
+	AGVCount           int     `json:"agv_count"`
+	Policy             string  `json:"policy"`
+	Stickiness         float64 `json:"stickiness"`
+	Episodes           int     `json:"episodes"`
+	Errors             int     `json:"errors"`
+	ClearRate          float64 `json:"clear_rate"`
+	MeanTimeToClearSec float64 `json:"mean_time_to_clear_sec"`
+	MeanKills          float64 `json:"mean_kills"`
+	MeanBatteryUsed    float64 `json:"mean_battery_used"`
+	MeanCollisions     float64 `json:"mean_collisions"`
+	MeanDistance       float64 `json:"mean_distance"`
+}
+
+// SummarizeBatch는 결과를 설정별로 묶어 평균을 낸다. 출력 순서는 시나리오·정책·stickiness·AGV 수 순.
+func SummarizeBatch(results []EpisodeResult) []BatchSummary {
+	type key struct {
+		scenario   string
+		agvs       int
+		policy     string
+		stickiness float64
+	}
+	type acc struct {
+		sum     BatchSummary
+		cleared int
+		clearT  float64
+	}
+	groups := make(map[key]*acc)
+	for _, r := range results {
+		k := key{r.Scenario, r.AGVCount, r.Policy, r.Stickiness}
+		g := groups[k]
+		if g == nil {
+			g = &acc{sum: BatchSummary{Scenario: r.Scenario, AGVCount: r.AGVCount, Policy: r.Policy, Stickiness: r.Stickiness}}
+			groups[k] = g
+		}
+		if r.Error != "" {
+			g.sum.Errors++
+			continue
+		}
+		g.sum.Episodes++
+		g.sum.MeanKills += float64(r.Kills)
+		g.sum.MeanBatteryUsed += float64(r.BatteryUsed)
+		g.sum.MeanCollisions += float64(r.Collisions)
+		g.sum.MeanDistance += r.Distance
+		if r.TimeToClearSec >= 0 {
+			g.cleared++
+			g.clearT += r.TimeToClearSec
+		}
+	}
+
+	out := make([]BatchSummary, 0, len(groups))
+	for _, g := range groups {
+		s := g.sum
+		s.MeanTimeToClearSec = -1
+		if n := float64(s.Episodes); n > 0 {
+			s.ClearRate = float64(g.cleared) / n
+			s.MeanKills /= n
+			s.MeanBatteryUsed /= n
+			s.MeanCollisions /= n
+			s.MeanDistance /= n
+		}
+		if g.cleared > 0 {
+			s.MeanTimeToClearSec = g.clearT / float64(g.cleared)
+		}
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Scenario != b.Scenario {
+			return a.Scenario < b.Scenario
+		}
+		if a.Policy != b.Policy {
+			return a.Policy < b.Policy
+		}
+		if a.Stickiness != b.Stickiness {
+			return a.Stickiness < b.Stickiness
+		}
+		return a.AGVCount < b.AGVCount
+	})
+	return out
+}
+
+// String은 요약 한 줄 (콘솔 출력용).
+func (s BatchSummary) String() string {
+	ttc := "-"
+	if s.MeanTimeToClearSec >= 0 {
+		ttc = (time.Duration(s.MeanTimeToClearSec * float64(time.Second))).Round(100 * time.Millisecond).String()
+	}
+	return fmt.Sprintf("%-14s stick=%.2f agvs=%d  n=%d  clear=%.0f%%  ttc=%s  kills=%.2f  battery=%.1f  collisions=%.2f",
+		s.Policy, s.Stickiness, s.AGVCount, s.Episodes, s.ClearRate*100, ttc, s.MeanKills, s.MeanBatteryUsed, s.MeanCollisions)
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"sion-backend/models"
+	"testing"
+)
+
+func TestRunEpisode_DeterministicAndClears(t *testing.T) {
+	sc := models.Scenario{
+		Name:     "batch",
+		MapWidth: 20, MapHeight: 20,
+		AGV: models.ScenarioAGV{X: 2, Y: 2},
+		Enemies: []models.ScenarioEnemy{
+			{ID: "e1", Name: "아리", HP: 20, X: 5, Y: 2},
+			{ID: "e2", Name: "제드", HP: 20, X: 2, Y: 6},
+		},
+	}
+	cfg := EpisodeConfig{Seed: 3, Targeting: DefaultTargetingConfig(), Scenario: &sc, MaxTicks: 500}
+	a, err := RunEpisode(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := RunEpisode(cfg)
+	if a != b {
+		t.Fatalf("같은 설정·seed는 같은 결과여야 함:\n%+v\n%+v", a, b)
+	}
+	if a.Outcome != models.SimOutcomeVictory || a.TimeToClearSec <= 0 || a.Kills != 2 || a.Enemies != 2 {
+		t.Fatalf("가까운 적 둘은 정리돼야 함: %+v", a)
+	}
+	if a.BatteryUsed <= 0 || a.Distance <= 0 {
+		t.Fatalf("배터리·거리 지표가 채워져야 함: %+v", a)
+	}
+
+	short, _ := RunEpisode(EpisodeConfig{Seed: 3, Targeting: DefaultTargetingConfig(), Scenario: &sc, MaxTicks: 2})
+	if short.Outcome != models.SimOutcomeTimeout || short.TimeToClearSec != -1 || short.Ticks != 2 {
+		t.Fatalf("MaxTicks를 넘기면 timeout: %+v", short)
+	}
+}
+
+func TestRunBatch_PreservesOrderAndSummarizes(t *testing.T) {
+	var cfgs []EpisodeConfig
+	for _, policy := range []string{models.TargetPolicyLowestHP, models.TargetPolicyNearest} {
+		for seed := int64(1); seed <= 3; seed++ {
+			cfgs = append(cfgs, EpisodeConfig{
+				Seed:      seed,
+				AGVCount:  1,
+				Targeting: models.TargetingConfig{Policy: policy},
+				MaxTicks:  50,
+			})
+		}
+	}
+	cfgs = append(cfgs, EpisodeConfig{Seed: 9, Targeting: models.TargetingConfig{Policy: "bogus"}, MaxTicks: 50})
+
+	results := RunBatch(context.Background(), cfgs, 3, nil)
+	for i, r := range results[:6] {
+		if r.Seed != cfgs[i].Seed || r.Policy != cfgs[i].Targeting.Policy || r.Error != "" {
+			t.Fatalf("결과 %d 순서·내용이 틀림: %+v", i, r)
+		}
+		if r.Ticks != 50 && r.Outcome != models.SimOutcomeVictory {
+			t.Fatalf("결과 %d: %+v", i, r)
+		}
+	}
+	if results[6].Error == "" {
+		t.Fatalf("잘못된 정책은 Error로 기록돼야 함")
+	}
+
+	summaries := SummarizeBatch(results)
+	if len(summaries) != 3 {
+		t.Fatalf("정책별 요약 3개 기대: %+v", summaries)
+	}
+	var lowest BatchSummary
+	for _, s := range summaries {
+		if s.Policy == models.TargetPolicyLowestHP {
+			lowest = s
+		}
+	}
+	var kills float64
+	for _, r := range results[:3] {
+		kills += float64(r.Kills)
+	}
+	if lowest.Episodes != 3 || lowest.MeanKills != kills/3 {
+		t.Fatalf("lowest_hp 요약이 틀림: %+v", lowest)
+	}
+	if summaries[0].Policy != "bogus" || summaries[0].Errors != 1 || summaries[0].Episodes != 0 {
+		t.Fatalf("오류만 있는 그룹: %+v", summaries[0])
+	}
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// 시뮬레이션 시계. 한 틱은 항상 시뮬레이션 시간 UpdateInterval만큼 진행되고,
+// 시계는 그 틱을 실제 시간에 얼마나 자주 돌릴지(배속·일시정지·헤드리스)만 정한다.
+// 따라서 같은 seed라면 배속이나 헤드리스 여부와 관계없이 월드 상태가 동일하게 재현된다.
+
+const (
+	MinSimSpeed = 0.25
+	MaxSimSpeed = 50.0
+	// maxStepTicks는 한 번의 Step 요청으로 진행할 수 있는 최대 틱 수.
+	maxStepTicks = 10000
+)
+
+var (
+	ErrSimulatorNotPaused = errors.New("실행 중에는 일시정지 상태에서만 단계 실행할 수 있습니다")
+	ErrInvalidSimSpeed    = fmt.Errorf("배속은 %.2f~%.0f 사이여야 합니다", MinSimSpeed, MaxSimSpeed)
+)
+
+// ClockState는 시뮬레이션 시계의 현재 설정.
+type ClockState struct {
+	Paused     bool    `json:"paused"`
+	Speed      float64 `json:"speed"`
+	Headless   bool    `json:"headless"`
+	ElapsedSec float64 `json:"elapsed_sec"`
+}
+
+type simClock struct {
+	mu       sync.Mutex
+	paused   bool
+	speed    float64
+	headless bool
+	// changed는 설정이 바뀌었음을 실행 루프에 알린다 (버퍼 1, 중복 알림은 합쳐짐).
+	changed chan struct{}
+	// stepReq는 일시정지 중인 실행 루프에 n틱 진행을 요청한다. 루프는 진행 후 결과를 reply로 돌려준다.
+	stepReq chan stepRequest
+}
+
+type stepRequest struct {
+	ticks int
+	reply chan string
+}
+
+func newSimClock() *simClock {
+	return &simClock{
+		speed:   1,
+		changed: make(chan struct{}, 1),
+		stepReq: make(chan stepRequest),
+	}
+}
+
+func (c *simClock) notify() {
+	select {
+	case c.changed <- struct{}{}:
+	default:
+	}
+}
+
+func (c *simClock) state() (paused bool, speed float64, headless bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused, c.speed, c.headless
+}
+
+// tickPeriod는 배속을 반영한 실제 틱 간격.
+func tickPeriod(interval time.Duration, speed float64) time.Duration {
+	d := time.Duration(float64(interval) / speed)
+	if d <= 0 {
+		d = time.Microsecond
+	}
+	return d
+}
+
+// Pause는 실행 루프를 멈춘다. 정지 상태에서 호출하면 다음 Start가 일시정지 상태로 시작한다.
+func (sim *AGVSimulator) Pause() {
+	sim.clock.mu.Lock()
+	sim.clock.paused = true
+	sim.clock.mu.Unlock()
+	sim.clock.notify()
+	log.Println("[INFO] 시뮬레이터 일시정지")
+}
+
+func (sim *AGVSimulator) Resume() {
+	sim.clock.mu.Lock()
+	sim.clock.paused = false
+	sim.clock.mu.Unlock()
+	sim.clock.notify()
+	log.Println("[INFO] 시뮬레이터 재개")
+}
+
+// SetSpeed는 배속을 바꾼다. 실행 중에도 바로 반영된다.
+func (sim *AGVSimulator) SetSpeed(speed float64) error {
+	if speed < MinSimSpeed || speed > MaxSimSpeed {
+		return ErrInvalidSimSpeed
+	}
+	sim.clock.mu.Lock()
+	sim.clock.speed = speed
+	sim.clock.mu.Unlock()
+	sim.clock.notify()
+	log.Printf("[INFO] 시뮬레이터 배속: %.2fx", speed)
+	return nil
+}
+
+// SetHeadless는 헤드리스 모드를 켜고 끈다. 헤드리스에서는 배속을 무시하고 최대한 빨리 틱을 돌리며,
+// 브로드캐스트·DB 로그는 실제 시간 UpdateInterval마다 한 번(그리고 종료 시)만 보낸다.
+func (sim *AGVSimulator) SetHeadless(enabled bool) {
+	sim.clock.mu.Lock()
+	sim.clock.headless = enabled
+	sim.clock.mu.Unlock()
+	sim.clock.notify()
+	log.Printf("[INFO] 시뮬레이터 헤드리스: %v", enabled)
+}
+
+func (sim *AGVSimulator) ClockState() ClockState {
+	paused, speed, headless := sim.clock.state()
+	sim.mu.RLock()
+	elapsed := sim.elapsed
+	sim.mu.RUnlock()
+	return ClockState{
+		Paused:     paused,
+		Speed:      speed,
+		Headless:   headless,
+		ElapsedSec: elapsed.Seconds(),
+	}
+}
+
+// Step은 ticks틱만큼 진행하고 종료 결과(있다면)를 반환한다. 정지 상태에서는 호출한 고루틴에서 바로 진행하고,
+// 실행 중이면 일시정지 상태여야 하며 실행 루프에 요청해 틱이 한 곳에서만 돌게 한다.
+func (sim *AGVSimulator) Step(ticks int) (string, error) {
+	if ticks < 1 || ticks > maxStepTicks {
+		return "", fmt.Errorf("ticks는 1~%d 사이여야 합니다", maxStepTicks)
+	}
+	if !sim.IsRunning() {
+		sim.stepMu.Lock()
+		defer sim.stepMu.Unlock()
+		return sim.runTicks(ticks), nil
+	}
+	if paused, _, _ := sim.clock.state(); !paused {
+		return "", ErrSimulatorNotPaused
+	}
+	sim.mu.RLock()
+	done := sim.doneChan
+	sim.mu.RUnlock()
+	req := stepRequest{ticks: ticks, reply: make(chan string, 1)}
+	select {
+	case sim.clock.stepReq <- req:
+		return <-req.reply, nil
+	case <-done:
+		// 요청 직전에 루프가 끝났다 (Stop 또는 종료 조건).
+		return "", ErrSimulatorNotPaused
+	}
+}
+
+// runTicks는 n틱을 진행하고 마지막 틱의 상태만 브로드캐스트한다. 수천 틱을 한 번에 진행해도
+// 웹에는 결과 한 번만 간다. 중간에 끝나면 종료 틱의 메시지(simulation_end 포함)가 나간다.
+func (sim *AGVSimulator) runTicks(n int) string {
+	for i := 0; i < n; i++ {
+		if outcome := sim.update(i == n-1); outcome != "" {
+			return outcome
+		}
+	}
+	return ""
+}
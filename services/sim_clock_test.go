@@ -0,0 +1,159 @@
+package services
+
+import (
+	"errors"
+	"sion-backend/models"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSimClock_SpeedBounds(t *testing.T) {
+	sim := NewAGVSimulator(nil)
+	for _, speed := range []float64{0.1, 0, 51} {
+		if err := sim.SetSpeed(speed); !errors.Is(err, ErrInvalidSimSpeed) {
+			t.Fatalf("배속 %.2f는 거부돼야 함: %v", speed, err)
+		}
+	}
+	for _, speed := range []float64{MinSimSpeed, 1, MaxSimSpeed} {
+		if err := sim.SetSpeed(speed); err != nil {
+			t.Fatalf("배속 %.2f는 허용돼야 함: %v", speed, err)
+		}
+	}
+	if got := sim.ClockState().Speed; got != MaxSimSpeed {
+		t.Fatalf("Speed=%.2f, want %.2f", got, MaxSimSpeed)
+	}
+}
+
+func TestSimClock_StepWhileStopped(t *testing.T) {
+	var statuses int
+	sim := NewAGVSimulator(func(msg models.WebSocketMessage) {
+		if msg.Type == models.MessageTypeStatus {
+			statuses++
+		}
+	})
+	sim.LogToDB = false
+	if _, err := sim.Step(4); err != nil {
+		t.Fatalf("정지 상태 Step 실패: %v", err)
+	}
+	if got, want := sim.ClockState().ElapsedSec, 4*sim.UpdateInterval.Seconds(); got != want {
+		t.Fatalf("elapsed=%.2f, want %.2f", got, want)
+	}
+	// 여러 틱을 진행해도 마지막 상태만 브로드캐스트한다.
+	if statuses != 1 {
+		t.Fatalf("Step(4)는 status 1번만 보내야 함, got %d", statuses)
+	}
+	if _, err := sim.Step(0); err == nil {
+		t.Fatal("ticks=0은 거부돼야 함")
+	}
+}
+
+// 정지 상태에서 Step으로 연 통계 세션은 Stop이나 월드 리셋에서 끝난다.
+func TestSimClock_StepSessionsEndOnStopOrReset(t *testing.T) {
+	sim := NewAGVSimulator(nil)
+	sim.LogToDB = false
+	tr := NewStatsTracker()
+	sim.SetStatsTracker(tr)
+
+	ended := func() int {
+		records, _ := tr.History("sion-001", 10)
+		return len(records)
+	}
+	if _, err := sim.Step(3); err != nil {
+		t.Fatal(err)
+	}
+	if _, open := tr.Live()["sion-001"]; !open || ended() != 0 {
+		t.Fatal("Step 중에는 세션이 열려 있어야 함")
+	}
+	sim.Stop()
+	if _, open := tr.Live()["sion-001"]; open || ended() != 1 {
+		t.Fatal("Stop에서 세션이 끝나야 함")
+	}
+
+	if _, err := sim.Step(3); err != nil {
+		t.Fatal(err)
+	}
+	if err := sim.ResetWorld(nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, open := tr.Live()["sion-001"]; open || ended() != 2 {
+		t.Fatal("월드 리셋에서 세션이 끝나야 함")
+	}
+}
+
+func TestSimClock_PauseAndStepWhileRunning(t *testing.T) {
+	sim := NewAGVSimulator(nil)
+	sim.UpdateInterval = 5 * time.Millisecond
+	sim.Start()
+	defer sim.Stop()
+
+	if _, err := sim.Step(1); !errors.Is(err, ErrSimulatorNotPaused) {
+		t.Fatalf("일시정지 전 Step은 ErrSimulatorNotPaused여야 함: %v", err)
+	}
+
+	sim.Pause()
+	time.Sleep(20 * time.Millisecond) // 진행 중이던 틱이 끝나길 기다린다
+	before := sim.ClockState().ElapsedSec
+	time.Sleep(50 * time.Millisecond)
+	if after := sim.ClockState().ElapsedSec; after != before {
+		t.Fatalf("일시정지 중 시간이 흐름: %.3f -> %.3f", before, after)
+	}
+
+	if _, err := sim.Step(3); err != nil {
+		t.Fatalf("일시정지 중 Step 실패: %v", err)
+	}
+	want := before + 3*sim.UpdateInterval.Seconds()
+	if got := sim.ClockState().ElapsedSec; got < want-1e-9 || got > want+1e-9 {
+		t.Fatalf("Step(3) 후 elapsed=%.3f, want %.3f", got, want)
+	}
+
+	sim.Resume()
+	time.Sleep(30 * time.Millisecond)
+	if got := sim.ClockState().ElapsedSec; got <= want {
+		t.Fatalf("재개 후 시간이 흘러야 함: %.3f", got)
+	}
+}
+
+// 헤드리스는 UpdateInterval(500ms)과 무관하게 최대한 빨리 돌고, 매 틱을 브로드캐스트하지 않는다.
+func TestSimClock_HeadlessFastForward(t *testing.T) {
+	var broadcasts atomic.Int64
+	var ended atomic.Bool
+	sim := NewAGVSimulator(func(msg models.WebSocketMessage) {
+		broadcasts.Add(1)
+		if msg.Type == models.MessageTypeSimulationEnd {
+			ended.Store(true)
+		}
+	})
+	seed := int64(9)
+	err := sim.LoadScenario(models.Scenario{
+		Name:      "headless",
+		MapWidth:  10,
+		MapHeight: 10,
+		AGV:       models.ScenarioAGV{X: 1, Y: 1, Mode: models.ModeManual},
+		Seed:      &seed,
+		Victory:   models.VictoryCondition{TimeoutSec: 600}, // 1200틱
+	})
+	if err != nil {
+		t.Fatalf("LoadScenario 실패: %v", err)
+	}
+	sim.SetHeadless(true)
+	sim.Start()
+	defer sim.Stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for sim.IsRunning() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if sim.IsRunning() {
+		t.Fatal("헤드리스 실행이 5초 안에 600초 시뮬레이션을 끝내지 못함")
+	}
+	if _, outcome := sim.ScenarioInfo(); outcome != models.SimOutcomeTimeout {
+		t.Fatalf("outcome=%q, want timeout", outcome)
+	}
+	if !ended.Load() {
+		t.Fatal("헤드리스에서도 simulation_end는 브로드캐스트돼야 함")
+	}
+	if n := broadcasts.Load(); n >= 1200 {
+		t.Fatalf("헤드리스가 매 틱 브로드캐스트함: %d", n)
+	}
+}
@@ -1,25 +1,124 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"math"
 	"math/rand"
+	"sion-backend/algorithms"
 	"sion-backend/models"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
+// ErrSimulatorRunning은 실행 중에는 허용되지 않는 재구성(시드 변경 등)을 시도했을 때 반환된다.
+var ErrSimulatorRunning = errors.New("시뮬레이터 실행 중에는 변경할 수 없습니다")
+
+// agvCollisionRadius는 두 AGV 중심 사이가 이 거리보다 가까우면 충돌로 보는 기준(셀 단위).
+const agvCollisionRadius = 0.8
+
+// simAGV는 시뮬레이터가 호스팅하는 AGV 한 대. 상태·통계·경로 추종 상태를 AGV별로 가진다.
+type simAGV struct {
+	Status *models.AGVStatus
+	Stats  models.AGVStats
+
+	// path는 현재 추종 중인 A* 경로(셀 좌표).
+	path        []algorithms.Point
+	pathIdx     int
+	pathGoal    [2]int
+	hasPathGoal bool
+
+	// moveTarget은 웹 command로 받은 수동 이동 목표. 도착하면 nil로 돌아간다.
+	moveTarget *models.RealCoordinate
+	// motor는 웹 motor_control로 받은 바퀴 속도 입력. motorLeft가 다 지나면 nil로 돌아간다.
+	motor     *models.MotorControl
+	motorLeft time.Duration
+	// driven은 이번 틱에 주행 명령이 있었는지. 없으면 틱 끝에 감속한다.
+	// realign은 충돌 뒤 제자리 회전으로 헤딩을 다시 맞추는 중인지.
+	driven, realign bool
+	// blockedBy는 직전 이동을 막은 AGV, blockedByWall은 직전 이동이 장애물·벽에 막혔는지.
+	// 움직이는 데 성공하면 둘 다 풀린다.
+	blockedBy     *simAGV
+	blockedByWall bool
+	// walkHeading은 랜덤 워크 목표 헤딩. walking은 이번 틱, wasWalking은 직전 틱에 랜덤 워크했는지.
+	walkHeading         float64
+	walking, wasWalking bool
+	// chargeAcc는 아직 Battery(정수 %)에 반영되지 않은 충전량, drainAcc는 소모량.
+	chargeAcc float64
+	drainAcc  float64
+	// wheelTravel은 이번 틱 바퀴 주행량(좌우 평균, 셀). 배터리 소모의 기준이다.
+	wheelTravel float64
+	sensors     sensorState
+	// stuckLeft는 모터 고착 장애가 남은 시간, sensorBlackout은 이번 틱 센서 끊김 장애 여부.
+	stuckLeft      time.Duration
+	sensorBlackout bool
+}
+
 type AGVSimulator struct {
 	mu             sync.RWMutex
-	Status         *models.AGVStatus
 	MapWidth       float64
 	MapHeight      float64
 	Enemies        []models.Enemy
 	Obstacles      []models.Obstacle
 	UpdateInterval time.Duration
 	BroadcastFunc  func(models.WebSocketMessage)
+	// AGVCount는 기본(랜덤) 월드에서 생성할 AGV 수. 시나리오는 자체 AGV 목록을 쓴다.
+	AGVCount int
+	// EnemyCount는 기본(랜덤) 월드와 RespawnEnemies가 배치하는 적 수.
+	EnemyCount int
+	// LogToDB가 false면 status/target_found를 로그 버퍼에 직접 남기지 않는다.
+	// 가상 AGV 모드에서는 서버가 수신 메시지를 기록하므로 중복을 피하려고 끈다.
+	LogToDB bool
+	// StatusFunc가 있으면 매 틱 AGV별 상태를 넘긴다 (이벤트 감지 등). 헤드리스 스로틀과 무관하게 모든 틱에서 호출된다.
+	StatusFunc func(status *models.AGVStatus)
+	// stats가 있으면 AGV별 주행·격살·충돌을 세션 통계로 함께 쌓는다. 실행(Start~Stop/종료) 한 번이 한 세션.
+	stats *StatsTracker
+	// faults가 있으면 틱마다 AGV별 장애(센서 끊김·배터리 급락·모터 고착)를 뽑는다.
+	faults *FaultInjector
+
+	// agvs는 같은 월드를 공유하는 AGV들. 첫 번째가 Snapshot/GetStats의 대표 AGV다.
+	agvs []*simAGV
+	// grid는 Obstacles로부터 만든 A* 그리드.
+	grid *algorithms.Grid
+	// dockPaths는 (AGV 셀, 충전소 좌표) → A* 경로 길이 캐시. 갈 수 없으면 -1. 그리드를 다시 만들면 비운다.
+	dockPaths map[dockPathKey]float64
+	// mission은 충전소 목록과 AGV별 복귀/충전 단계.
+	mission *BatteryMission
+	// enemyBehaviors는 적 ID → 행동 모델. 없는 적은 움직이지 않는다.
+	enemyBehaviors map[string]EnemyBehavior
+	// pending은 update 도중 잠금 안에서 쌓인 추가 브로드캐스트(path_update 등).
+	pending []models.WebSocketMessage
+
+	// rng는 시뮬레이터 전용 난수원. 적/장애물 생성, 랜덤 워크, 공격 판정이 모두 여기서 뽑으므로
+	// 같은 seed와 같은 입력이면 월드 상태가 틱 단위로 동일하게 재현된다. sim.mu로 보호한다.
+	rng  *rand.Rand
+	seed int64
+	// sensorRng는 센서 노이즈 전용 난수원. 같은 seed에서 파생되지만 월드 rng와 분리돼 있다.
+	sensorRng *rand.Rand
+	// rngSrc, sensorSrc는 두 난수원이 뽑은 횟수를 센다. 스냅샷 복원 시 난수 상태를 재현하는 데 쓴다.
+	rngSrc, sensorSrc *countingSource
+	sensorConfig      models.SensorConfig
+	kinematics        models.KinematicsConfig
+	targeting         *TargetSelector
+
+	// scenario는 마지막으로 로드한 시나리오 이름(기본 랜덤 월드면 빈 문자열).
+	// elapsed는 시뮬레이션 시간으로, 틱마다 UpdateInterval만큼 증가한다.
+	scenario string
+	// loaded는 마지막으로 로드한 시나리오(seed 확정본). ResetWorld가 다시 로드한다. 랜덤 월드면 nil.
+	loaded  *models.Scenario
+	victory models.VictoryCondition
+	elapsed time.Duration
+	outcome string
+
+	// clock은 틱을 실제 시간에 돌리는 방식(일시정지·배속·헤드리스). stepMu는 실행 루프와
+	// 정지 상태의 Step이 동시에 틱을 돌리지 않게 한다.
+	clock  *simClock
+	stepMu sync.Mutex
+
+	// snapshots는 이름 → 저장해 둔 월드 사본 (SaveSnapshot/RestoreSnapshot).
+	snapshots map[string]*worldSnapshot
 
 	running  atomic.Bool
 	stopChan chan struct{}
@@ -27,238 +126,618 @@ type AGVSimulator struct {
 }
 
 func NewAGVSimulator(broadcastFunc func(models.WebSocketMessage)) *AGVSimulator {
-	return &AGVSimulator{
+	sim := &AGVSimulator{
+		MapWidth:       30.0,
+		MapHeight:      30.0,
+		UpdateInterval: 500 * time.Millisecond,
+		BroadcastFunc:  broadcastFunc,
+		AGVCount:       1,
+		EnemyCount:     defaultEnemyCount,
+		LogToDB:        true,
+		clock:          newSimClock(),
+		sensorConfig:   DefaultSensorConfig(),
+		kinematics:     DefaultKinematicsConfig(),
+	}
+	sim.targeting, _ = NewTargetSelector(DefaultTargetingConfig())
+	sim.resetWorldLocked(time.Now().UnixNano())
+	return sim
+}
+
+// resetWorldLocked는 seed로 난수원을 다시 만들고 AGV 상태·적·장애물·통계를 초기화한다.
+// 생성 순서(적 → 장애물)도 재현성의 일부이므로 바꾸지 않는다.
+func (sim *AGVSimulator) resetWorldLocked(seed int64) {
+	sim.seedLocked(seed)
+
+	count := sim.AGVCount
+	if count < 1 {
+		count = 1
+	}
+	sim.agvs = make([]*simAGV, count)
+	keepClear := make([]models.GridCoordinate, count)
+	for i := range sim.agvs {
+		// 대표 AGV는 기존과 같은 (5,5)에서, 나머지는 x축으로 2칸씩 띄워 시작한다.
+		x, y := 5.0+2*float64(i), 5.0
+		sim.agvs[i] = newSimAGV(defaultAGVID(i), defaultAGVName(i), x, y, 0, 100, models.ModeAuto)
+		keepClear[i] = models.GridCoordinate{Row: int(y), Col: int(x)}
+	}
+	sim.loaded = nil
+	sim.Enemies = generateRandomEnemies(sim.rng, sim.EnemyCount, sim.MapWidth, sim.MapHeight)
+	sim.Obstacles = generateRandomObstacles(sim.rng, 10, sim.MapWidth, sim.MapHeight, keepClear...)
+	sim.enemyBehaviors = make(map[string]EnemyBehavior, len(sim.Enemies))
+	for _, e := range sim.Enemies {
+		sim.enemyBehaviors[e.ID] = randomEnemyBehavior(sim.rng, e, sim.MapWidth, sim.MapHeight)
+	}
+	// 기본 월드는 대표 AGV 출발점을 기지(충전소)로 쓴다.
+	sim.mission = NewBatteryMission([]models.ChargingDock{{ID: "dock-1", X: 5, Y: 5}})
+	sim.resetRunLocked("", models.VictoryCondition{})
+	sim.rebuildGridLocked()
+}
+
+// seedLocked는 월드 rng와 센서 rng를 seed로 다시 만든다.
+func (sim *AGVSimulator) seedLocked(seed int64) {
+	sim.seed = seed
+	sim.rngSrc = newCountingSource(seed)
+	sim.sensorSrc = newCountingSource(seed ^ 0x5e45015)
+	sim.rng = rand.New(sim.rngSrc)
+	sim.sensorRng = rand.New(sim.sensorSrc)
+}
+
+// countingSource는 뽑은 횟수를 세는 난수원. rand.Rand의 내부 상태는 복사할 수 없으므로
+// 스냅샷은 seed와 횟수만 저장하고, 복원할 때 같은 seed에서 그만큼 다시 뽑아 상태를 재현한다.
+type countingSource struct {
+	src   rand.Source64
+	draws uint64
+}
+
+func newCountingSource(seed int64) *countingSource {
+	return &countingSource{src: rand.NewSource(seed).(rand.Source64)}
+}
+
+func (s *countingSource) Int63() int64 {
+	s.draws++
+	return s.src.Int63()
+}
+
+func (s *countingSource) Uint64() uint64 {
+	s.draws++
+	return s.src.Uint64()
+}
+
+func (s *countingSource) Seed(seed int64) {
+	s.src.Seed(seed)
+	s.draws = 0
+}
+
+// skipTo는 뽑은 횟수가 n이 될 때까지 버린다.
+func (s *countingSource) skipTo(n uint64) {
+	for s.draws < n {
+		s.Int63()
+	}
+}
+
+func newSimAGV(id, name string, x, y, angle float64, battery int, mode string) *simAGV {
+	return &simAGV{
 		Status: &models.AGVStatus{
-			ID:   "sion-001",
-			Name: "사이온",
+			ID:   id,
+			Name: name,
 			Position: models.PositionData{
-				X:     5.0,
-				Y:     5.0,
-				Angle: 0,
+				X:     x,
+				Y:     y,
+				Angle: angle,
 			},
-			Mode:    models.ModeAuto,
+			Mode:    mode,
 			State:   models.StateIdle,
 			Speed:   0,
-			Battery: 100,
+			Battery: battery,
 		},
-		MapWidth:       30.0,
-		MapHeight:      30.0,
-		Enemies:        generateRandomEnemies(5, 30, 30),
-		Obstacles:      generateRandomObstacles(10, 30, 30),
-		UpdateInterval: 500 * time.Millisecond,
-		BroadcastFunc:  broadcastFunc,
 	}
 }
 
+// defaultAGVID는 i번째 AGV의 기본 ID(sion-001, sion-002, ...)를 만든다.
+func defaultAGVID(i int) string {
+	return fmt.Sprintf("sion-%03d", i+1)
+}
+
+func defaultAGVName(i int) string {
+	if i == 0 {
+		return "사이온"
+	}
+	return fmt.Sprintf("사이온 %d", i+1)
+}
+
+// resetRunLocked는 한 판 단위의 진행 상태(통계·경과 시간·결과)를 초기화한다.
+func (sim *AGVSimulator) resetRunLocked(scenario string, victory models.VictoryCondition) {
+	sim.scenario = scenario
+	sim.victory = victory
+	sim.elapsed = 0
+	sim.outcome = ""
+	for _, a := range sim.agvs {
+		a.Stats = models.AGVStats{}
+	}
+	if sim.mission != nil {
+		sim.mission.Reset()
+	}
+	sim.targeting.Reset()
+	sim.pending = nil
+}
+
+// Reseed는 주어진 seed로 월드를 다시 생성한다. 실행 중이면 ErrSimulatorRunning을 반환한다.
+func (sim *AGVSimulator) Reseed(seed int64) error {
+	if sim.IsRunning() {
+		return ErrSimulatorRunning
+	}
+	sim.endStatsSessions()
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	sim.resetWorldLocked(seed)
+	log.Printf("[INFO] 시뮬레이터 seed 설정: %d", seed)
+	return nil
+}
+
+const (
+	// maxSimAGVs는 한 시뮬레이터가 호스팅할 수 있는 AGV 수 상한.
+	maxSimAGVs = 16
+	// defaultEnemyCount는 기본(랜덤) 월드의 적 수, maxSimEnemies는 상한.
+	defaultEnemyCount = 5
+	maxSimEnemies     = 50
+)
+
+// SetAGVCount는 AGV 수를 바꾸고 현재 seed로 월드를 다시 생성한다. 실행 중이면 ErrSimulatorRunning.
+func (sim *AGVSimulator) SetAGVCount(n int) error {
+	if n < 1 || n > maxSimAGVs {
+		return fmt.Errorf("AGV 수는 1~%d 사이여야 합니다: %d", maxSimAGVs, n)
+	}
+	if sim.IsRunning() {
+		return ErrSimulatorRunning
+	}
+	sim.endStatsSessions()
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	sim.AGVCount = n
+	sim.resetWorldLocked(sim.seed)
+	return nil
+}
+
+// Seed는 현재 월드를 생성한 seed를 반환한다.
+func (sim *AGVSimulator) Seed() int64 {
+	sim.mu.RLock()
+	defer sim.mu.RUnlock()
+	return sim.seed
+}
+
+// ScenarioInfo는 현재 로드된 시나리오 이름과 종료 결과(진행 중이면 빈 문자열)를 반환한다.
+func (sim *AGVSimulator) ScenarioInfo() (name, outcome string) {
+	sim.mu.RLock()
+	defer sim.mu.RUnlock()
+	return sim.scenario, sim.outcome
+}
+
 // IsRunning은 외부 핸들러가 시뮬레이터 상태를 안전하게 읽기 위한 접근자.
 func (sim *AGVSimulator) IsRunning() bool {
 	return sim.running.Load()
 }
 
-// Snapshot은 외부에서 읽을 수 있는 현재 상태 사본을 반환한다.
+// Snapshot은 외부에서 읽을 수 있는 현재 상태 사본을 반환한다. status는 대표(첫 번째) AGV다.
 // (시뮬레이터 고루틴이 매 틱 Status를 변경하므로 직접 노출하지 않는다.)
 func (sim *AGVSimulator) Snapshot() (status models.AGVStatus, enemies []models.Enemy, mapW, mapH float64) {
 	sim.mu.RLock()
 	defer sim.mu.RUnlock()
-	if sim.Status != nil {
-		status = *sim.Status
+	if len(sim.agvs) > 0 {
+		status = *sim.agvs[0].Status
 	}
 	enemies = make([]models.Enemy, len(sim.Enemies))
 	copy(enemies, sim.Enemies)
 	return status, enemies, sim.MapWidth, sim.MapHeight
 }
 
+// AGVSnapshots는 모든 AGV 상태 사본을 생성 순서대로 반환한다.
+func (sim *AGVSimulator) AGVSnapshots() []models.AGVStatus {
+	sim.mu.RLock()
+	defer sim.mu.RUnlock()
+	out := make([]models.AGVStatus, len(sim.agvs))
+	for i, a := range sim.agvs {
+		out[i] = *a.Status
+	}
+	return out
+}
+
+// GetStats는 대표 AGV의 누적 통계(충돌 횟수 등) 사본을 반환한다.
+func (sim *AGVSimulator) GetStats() models.AGVStats {
+	sim.mu.RLock()
+	defer sim.mu.RUnlock()
+	if len(sim.agvs) == 0 {
+		return models.AGVStats{}
+	}
+	return sim.agvs[0].Stats
+}
+
+// StatsByAGV는 AGV ID별 누적 통계 사본을 반환한다.
+func (sim *AGVSimulator) StatsByAGV() map[string]models.AGVStats {
+	sim.mu.RLock()
+	defer sim.mu.RUnlock()
+	return sim.statsByAGVLocked()
+}
+
+func (sim *AGVSimulator) statsByAGVLocked() map[string]models.AGVStats {
+	out := make(map[string]models.AGVStats, len(sim.agvs))
+	for _, a := range sim.agvs {
+		out[a.Status.ID] = a.Stats
+	}
+	return out
+}
+
 func (sim *AGVSimulator) Start() {
 	if !sim.running.CompareAndSwap(false, true) {
 		log.Println("[WARN] 시뮬레이터가 이미 실행 중")
 		return
 	}
+	sim.mu.Lock()
 	sim.stopChan = make(chan struct{})
 	sim.doneChan = make(chan struct{})
+	stopChan, doneChan := sim.stopChan, sim.doneChan
+	if sim.outcome != "" {
+		// 이미 끝난 판을 다시 시작하면 종료 조건만 재평가되도록 결과와 시간을 되돌린다.
+		sim.outcome = ""
+		sim.elapsed = 0
+	}
+	now := time.Now()
+	for _, a := range sim.agvs {
+		if a.Stats.StartTime.IsZero() {
+			a.Stats.StartTime = now
+		}
+	}
+	sim.mu.Unlock()
 	log.Println("[INFO] AGV 시뮬레이터 시작")
-	go sim.runSimulation()
+	go sim.runSimulation(stopChan, doneChan)
 }
 
 func (sim *AGVSimulator) Stop() {
 	if !sim.running.CompareAndSwap(true, false) {
+		// 정지 상태에서 Step으로 진행하며 열린 세션도 Stop으로 끝낸다.
+		sim.endStatsSessions()
 		return
 	}
 	close(sim.stopChan)
 	<-sim.doneChan
+	sim.endStatsSessions()
 	log.Println("[INFO] AGV 시뮬레이터 중지")
 }
 
-func (sim *AGVSimulator) runSimulation() {
-	defer close(sim.doneChan)
-	ticker := time.NewTicker(sim.UpdateInterval)
+// runSimulation은 Start 시점의 채널을 인자로 받는다. 종료 조건으로 스스로 끝난 뒤 곧바로
+// Start가 새 채널을 만들어도, 이전 고루틴이 새 doneChan을 닫는 일이 없게 하기 위함이다.
+// 틱 간격은 시계 설정(일시정지·배속·헤드리스)을 따르며, 설정이 바뀌면 즉시 다시 계산한다.
+func (sim *AGVSimulator) runSimulation(stopChan <-chan struct{}, doneChan chan struct{}) {
+	defer close(doneChan)
+	paused, speed, headless := sim.clock.state()
+	ticker := time.NewTicker(tickPeriod(sim.UpdateInterval, speed))
 	defer ticker.Stop()
+	lastBroadcast := time.Now()
+
+	finish := func(outcome string) bool {
+		if outcome == "" {
+			return false
+		}
+		// Stop()과 경쟁하면 Stop 쪽이 CAS에 성공해 doneChan을 기다리므로 그대로 반환하면 된다.
+		if sim.running.CompareAndSwap(true, false) {
+			sim.endStatsSessions()
+			log.Printf("[INFO] 시뮬레이션 종료: %s", outcome)
+		}
+		return true
+	}
 
 	for {
+		// 헤드리스는 틱 사이에 기다리지 않는다. 설정 변경·중지 요청만 확인하고 바로 다음 틱으로 간다.
+		if headless && !paused {
+			select {
+			case <-stopChan:
+				return
+			case <-sim.clock.changed:
+				paused, speed, headless = sim.clock.state()
+				ticker.Reset(tickPeriod(sim.UpdateInterval, speed))
+				continue
+			default:
+			}
+			broadcast := time.Since(lastBroadcast) >= sim.UpdateInterval
+			if broadcast {
+				lastBroadcast = time.Now()
+			}
+			sim.stepMu.Lock()
+			outcome := sim.update(broadcast)
+			sim.stepMu.Unlock()
+			if finish(outcome) {
+				return
+			}
+			continue
+		}
+
+		var tick <-chan time.Time
+		if !paused {
+			tick = ticker.C
+		}
 		select {
-		case <-ticker.C:
-			sim.update()
-		case <-sim.stopChan:
+		case <-tick:
+			sim.stepMu.Lock()
+			outcome := sim.update(true)
+			sim.stepMu.Unlock()
+			if finish(outcome) {
+				return
+			}
+		case req := <-sim.clock.stepReq:
+			sim.stepMu.Lock()
+			outcome := sim.runTicks(req.ticks)
+			sim.stepMu.Unlock()
+			req.reply <- outcome
+			if finish(outcome) {
+				return
+			}
+		case <-sim.clock.changed:
+			paused, speed, headless = sim.clock.state()
+			ticker.Reset(tickPeriod(sim.UpdateInterval, speed))
+		case <-stopChan:
 			return
 		}
 	}
 }
 
-func (sim *AGVSimulator) update() {
+// update는 한 틱을 진행하고, broadcast면 메시지를 브로드캐스트하고 status를 DB 로그에 남긴다.
+// 종료 틱의 메시지(simulation_end 포함)는 broadcast와 관계없이 항상 보낸다.
+// 종료 조건을 만족하면 결과(victory/timeout)를, 아니면 빈 문자열을 반환한다.
+func (sim *AGVSimulator) update(broadcast bool) string {
 	sim.mu.Lock()
-	defer sim.mu.Unlock()
+	msgs, statuses, outcome := sim.stepLocked()
+	sim.mu.Unlock()
 
-	detectedEnemies := sim.detectEnemiesLocked()
-	sim.Status.DetectedEnemies = detectedEnemies
+	if sim.StatusFunc != nil {
+		for i := range statuses {
+			sim.StatusFunc(&statuses[i])
+		}
+	}
+	if !broadcast && outcome == "" {
+		return ""
+	}
+	// DB 로그·브로드캐스트는 잠금 밖에서 수행
+	for i := range statuses {
+		status := statuses[i]
+		if sim.LogToDB {
+			go LogAGVStatus(status.ID, &status)
+		}
+	}
+	if sim.BroadcastFunc != nil {
+		for _, msg := range msgs {
+			sim.BroadcastFunc(msg)
+		}
+	}
+	return outcome
+}
+
+// stepLocked는 모든 AGV를 생성 순서대로 한 틱씩 진행한다. 순서가 고정이어야 seed 재현성이 유지된다.
+func (sim *AGVSimulator) stepLocked() (msgs []models.WebSocketMessage, statuses []models.AGVStatus, outcome string) {
+	if sim.stats != nil {
+		// 세션 첫 틱의 출발점. 이미 진행 중인 세션에는 직전 보고와 같은 점이라 영향이 없다.
+		for _, a := range sim.agvs {
+			sim.stats.ObservePosition(a.Status.ID, a.Status.Position, time.Time{}.Add(sim.elapsed))
+		}
+	}
+	sim.stepEnemiesLocked()
+	for _, a := range sim.agvs {
+		a.driven = false
+		a.wasWalking, a.walking = a.walking, false
+		sim.rollFaultsLocked(a)
+		sim.stepAGVLocked(a)
+		if !a.driven {
+			// 이번 틱에 주행하지 않았으면(정지·충전·비상 정지·도착) 최대 감속으로 멈춘다.
+			sim.brakeLocked(a)
+		}
+		sim.consumeBatteryLocked(a)
+	}
+	sim.elapsed += sim.UpdateInterval
+	for _, a := range sim.agvs {
+		sim.updateSensorsLocked(a)
+		if a.sensorBlackout {
+			a.Status.Sensors = models.SensorData{}
+		}
+		a.Stats.TotalTime = int64(sim.elapsed.Seconds())
+		if sim.stats != nil {
+			// 간격은 시뮬레이션 시간으로 잰다. 배속·헤드리스에서도 주행 시간이 틱 수에 비례한다.
+			sim.stats.ObservePosition(a.Status.ID, a.Status.Position, time.Time{}.Add(sim.elapsed))
+		}
+	}
+
+	msgs = sim.pending
+	sim.pending = nil
+	statuses = make([]models.AGVStatus, len(sim.agvs))
+	for i, a := range sim.agvs {
+		statusMsg, positionMsg := sim.buildBroadcastMessagesLocked(a)
+		msgs = append(msgs, statusMsg, positionMsg)
+		statuses[i] = *a.Status
+	}
 
-	if len(detectedEnemies) > 0 && sim.Status.Mode == models.ModeAuto {
-		lowestHPEnemy := sim.findLowestHPEnemy(detectedEnemies)
-		sim.Status.TargetEnemy = &lowestHPEnemy
-		sim.Status.State = models.StateCharging
-		sim.Status.Speed = 2.5
-		LogTargetFound(sim.Status.ID, &lowestHPEnemy)
+	if outcome = sim.checkOutcomeLocked(); outcome != "" {
+		msgs = append(msgs, models.WebSocketMessage{
+			Type: models.MessageTypeSimulationEnd,
+			Data: models.SimulationEndData{
+				Scenario:   sim.scenario,
+				Outcome:    outcome,
+				ElapsedSec: sim.elapsed.Seconds(),
+				Stats:      sim.statsByAGVLocked(),
+			},
+			Timestamp: time.Now().UnixMilli(),
+		})
+	}
+	return msgs, statuses, outcome
+}
+
+func (sim *AGVSimulator) stepAGVLocked(a *simAGV) {
+	detectedEnemies := sim.detectEnemiesLocked(a)
+	a.Status.DetectedEnemies = detectedEnemies
+
+	// 비상 정지는 mode_change로 해제될 때까지 유지된다.
+	if a.Status.State == models.StateEmergency {
+		a.Status.TargetEnemy = nil
+		a.Status.Speed = 0
+		sim.targeting.Clear(a.Status.ID)
+		return
+	}
+	if sim.stepBatteryMissionLocked(a) {
+		sim.targeting.Clear(a.Status.ID)
+		return
+	}
+	if a.Status.Mode == models.ModeManual {
+		sim.targeting.Clear(a.Status.ID)
+		sim.stepManualLocked(a)
+		return
+	}
+
+	var selection *models.TargetSelection
+	if a.Status.Mode == models.ModeAuto {
+		var changed bool
+		selection, changed = sim.targeting.Select(a.Status, detectedEnemies)
+		sim.recordTargetSelectionLocked(a, selection, changed)
+	}
+	if selection != nil {
+		target := *selection.SelectedEnemy
+		a.Status.TargetEnemy = &target
+		a.Status.State = models.StateCharging
+		a.Status.Speed = 2.5
 	} else {
-		sim.Status.TargetEnemy = nil
-		sim.Status.State = models.StateSearching
-		sim.Status.Speed = 1.0
+		a.Status.TargetEnemy = nil
+		a.Status.State = models.StateSearching
+		a.Status.Speed = 1.0
 	}
 
-	if sim.Status.Mode == models.ModeAuto {
-		if sim.Status.TargetEnemy != nil {
-			sim.moveTowardsLocked(sim.Status.TargetEnemy.Position.X, sim.Status.TargetEnemy.Position.Y)
-			dist := sim.distanceToLocked(sim.Status.TargetEnemy.Position.X, sim.Status.TargetEnemy.Position.Y)
+	if a.Status.Mode == models.ModeAuto {
+		if a.Status.TargetEnemy != nil {
+			sim.moveTowardsLocked(a, a.Status.TargetEnemy.Position.X, a.Status.TargetEnemy.Position.Y)
+			dist := a.distanceTo(a.Status.TargetEnemy.Position.X, a.Status.TargetEnemy.Position.Y)
 			if dist < 2.0 {
-				sim.attackTargetLocked()
+				sim.attackTargetLocked(a)
 			}
 		} else {
-			sim.randomWalkLocked()
+			sim.randomWalkLocked(a)
 		}
 	}
+}
 
-	sim.consumeBatteryLocked()
-	statusMsg, positionMsg := sim.buildBroadcastMessagesLocked()
-	statusCopy := *sim.Status
+// checkOutcomeLocked는 시나리오 종료 조건을 평가한다. 승리가 타임아웃보다 우선한다.
+func (sim *AGVSimulator) checkOutcomeLocked() string {
+	if sim.outcome != "" {
+		return sim.outcome
+	}
+	if sim.victory.AllEnemiesDefeated && sim.aliveEnemyCountLocked() == 0 {
+		sim.outcome = models.SimOutcomeVictory
+	} else if sim.victory.TimeoutSec > 0 && sim.elapsed.Seconds() >= sim.victory.TimeoutSec {
+		sim.outcome = models.SimOutcomeTimeout
+	}
+	return sim.outcome
+}
 
-	// DB 로그·브로드캐스트는 잠금 밖에서 수행
-	go LogAGVStatus(statusCopy.ID, &statusCopy)
-	if sim.BroadcastFunc != nil {
-		sim.BroadcastFunc(statusMsg)
-		sim.BroadcastFunc(positionMsg)
+func (sim *AGVSimulator) aliveEnemyCountLocked() int {
+	n := 0
+	for i := range sim.Enemies {
+		if enemyActive(&sim.Enemies[i]) {
+			n++
+		}
 	}
+	return n
 }
 
-func (sim *AGVSimulator) detectEnemiesLocked() []models.Enemy {
+func (sim *AGVSimulator) detectEnemiesLocked(a *simAGV) []models.Enemy {
 	const detectionRange = 10.0
 	var detected []models.Enemy
 	for _, enemy := range sim.Enemies {
-		dist := sim.distanceToLocked(enemy.Position.X, enemy.Position.Y)
-		if dist <= detectionRange && enemy.HP > 0 {
+		dist := a.distanceTo(enemy.Position.X, enemy.Position.Y)
+		if dist <= detectionRange && enemyActive(&enemy) {
 			detected = append(detected, enemy)
 		}
 	}
 	return detected
 }
 
-func (sim *AGVSimulator) findLowestHPEnemy(enemies []models.Enemy) models.Enemy {
-	lowest := enemies[0]
-	for _, enemy := range enemies {
-		if enemy.HP < lowest.HP {
-			lowest = enemy
-		}
+// randomWalkLocked는 목표 헤딩을 가끔 새로 뽑고 그쪽으로 서서히 돌며 주행한다.
+func (sim *AGVSimulator) randomWalkLocked(a *simAGV) {
+	if !a.wasWalking {
+		// 추격·복귀 등에서 막 넘어왔으면 현재 헤딩 그대로 출발한다.
+		a.walkHeading = a.Status.Position.Angle
 	}
-	return lowest
-}
-
-func (sim *AGVSimulator) moveTowardsLocked(targetX, targetY float64) {
-	dx := targetX - sim.Status.Position.X
-	dy := targetY - sim.Status.Position.Y
-	dist := math.Sqrt(dx*dx + dy*dy)
-	if dist > 0.1 {
-		dx /= dist
-		dy /= dist
-		moveSpeed := sim.Status.Speed * 0.5
-		sim.Status.Position.X += dx * moveSpeed
-		sim.Status.Position.Y += dy * moveSpeed
-		sim.Status.Position.Angle = math.Atan2(dy, dx)
-		sim.clampPositionLocked()
+	a.walking = true
+	if sim.rng.Float64() < 0.1 {
+		a.walkHeading = sim.rng.Float64() * 2 * math.Pi
 	}
-}
-
-func (sim *AGVSimulator) randomWalkLocked() {
-	if rand.Float64() < 0.1 {
-		sim.Status.Position.Angle = rand.Float64() * 2 * math.Pi
+	a.clearPath()
+	moved := sim.driveLocked(a, func() (float64, float64) {
+		return sim.turnToLocked(a, a.walkHeading, a.Status.Speed)
+	})
+	if !moved {
+		// 벽에 부딪히면 다른 방향으로 빠져나가도록 목표 헤딩을 새로 뽑는다.
+		a.walkHeading = sim.rng.Float64() * 2 * math.Pi
 	}
-	moveSpeed := sim.Status.Speed * 0.5
-	sim.Status.Position.X += math.Cos(sim.Status.Position.Angle) * moveSpeed
-	sim.Status.Position.Y += math.Sin(sim.Status.Position.Angle) * moveSpeed
-	sim.clampPositionLocked()
 }
 
-func (sim *AGVSimulator) attackTargetLocked() {
-	if sim.Status.TargetEnemy == nil {
+func (sim *AGVSimulator) attackTargetLocked(a *simAGV) {
+	if a.Status.TargetEnemy == nil {
 		return
 	}
-	if rand.Float64() >= 0.2 {
+	if sim.rng.Float64() >= 0.2 {
 		return
 	}
 	for i := range sim.Enemies {
-		if sim.Enemies[i].ID != sim.Status.TargetEnemy.ID {
+		if sim.Enemies[i].ID != a.Status.TargetEnemy.ID {
 			continue
 		}
 		sim.Enemies[i].HP -= 10
 		if sim.Enemies[i].HP < 0 {
 			sim.Enemies[i].HP = 0
 		}
-		sim.Status.TargetEnemy.HP = sim.Enemies[i].HP
+		a.Status.TargetEnemy.HP = sim.Enemies[i].HP
 		log.Printf("[INFO] 타겟 공격: %s HP: %d", sim.Enemies[i].Name, sim.Enemies[i].HP)
 		if sim.Enemies[i].HP == 0 {
+			sim.Enemies[i].State = models.EnemyStateDefeated
+			a.Stats.EnemiesDefeated++
+			if sim.stats != nil {
+				sim.stats.RecordKill(a.Status.ID)
+			}
 			log.Printf("[INFO] 타겟 제거: %s", sim.Enemies[i].Name)
-			sim.Status.TargetEnemy = nil
+			a.Status.TargetEnemy = nil
 		}
 		return
 	}
 }
 
-func (sim *AGVSimulator) consumeBatteryLocked() {
-	if sim.Status.Speed > 0 {
-		sim.Status.Battery -= 1
-		if sim.Status.Battery < 0 {
-			sim.Status.Battery = 0
-			sim.Status.State = models.StateStopped
-			sim.Status.Speed = 0
-			log.Println("[WARN] 배터리 방전, AGV 정지")
-		}
+// consumeBatteryLocked는 이번 틱 바퀴 주행량만큼 배터리를 줄인다. 배터리 미션의 DrainPerCell과 같은 기준이라
+// 가감속·제자리 회전이 섞여도 남은 주행 거리 예측이 맞는다.
+func (sim *AGVSimulator) consumeBatteryLocked(a *simAGV) {
+	drainPerCell := defaultDrainPerCell
+	if sim.mission != nil && sim.mission.DrainPerCell > 0 {
+		drainPerCell = sim.mission.DrainPerCell
 	}
-	if sim.Status.Battery <= 20 && sim.Status.Battery > 0 {
-		if rand.Float64() < 0.05 {
-			log.Printf("[WARN] 배터리 부족: %d%%", sim.Status.Battery)
+	a.drainAcc += a.wheelTravel * drainPerCell
+	a.wheelTravel = 0
+	if whole := int(a.drainAcc); whole > 0 && a.Status.Battery > 0 {
+		a.drainAcc -= float64(whole)
+		a.Status.Battery -= whole
+		if a.Status.Battery <= 0 {
+			a.Status.Battery = 0
+			a.Status.State = models.StateStopped
+			a.Status.Speed = 0
+			log.Printf("[WARN] %s 배터리 방전, AGV 정지", a.Status.ID)
 		}
 	}
-}
-
-func (sim *AGVSimulator) clampPositionLocked() {
-	if sim.Status.Position.X < 0 {
-		sim.Status.Position.X = 0
-	}
-	if sim.Status.Position.X > sim.MapWidth {
-		sim.Status.Position.X = sim.MapWidth
-	}
-	if sim.Status.Position.Y < 0 {
-		sim.Status.Position.Y = 0
-	}
-	if sim.Status.Position.Y > sim.MapHeight {
-		sim.Status.Position.Y = sim.MapHeight
+	if a.Status.Battery <= 20 && a.Status.Battery > 0 {
+		if sim.rng.Float64() < 0.05 {
+			log.Printf("[WARN] %s 배터리 부족: %d%%", a.Status.ID, a.Status.Battery)
+		}
 	}
 }
 
-func (sim *AGVSimulator) distanceToLocked(x, y float64) float64 {
-	dx := x - sim.Status.Position.X
-	dy := y - sim.Status.Position.Y
+func (a *simAGV) distanceTo(x, y float64) float64 {
+	dx := x - a.Status.Position.X
+	dy := y - a.Status.Position.Y
 	return math.Sqrt(dx*dx + dy*dy)
 }
 
-func (sim *AGVSimulator) buildBroadcastMessagesLocked() (statusMsg, positionMsg models.WebSocketMessage) {
-	flatEnemies := make([]map[string]interface{}, len(sim.Status.DetectedEnemies))
-	for i, enemy := range sim.Status.DetectedEnemies {
+func (sim *AGVSimulator) buildBroadcastMessagesLocked(a *simAGV) (statusMsg, positionMsg models.WebSocketMessage) {
+	flatEnemies := make([]map[string]interface{}, len(a.Status.DetectedEnemies))
+	for i, enemy := range a.Status.DetectedEnemies {
 		flatEnemies[i] = map[string]interface{}{
 			"id":   enemy.ID,
 			"name": enemy.Name,
@@ -269,13 +748,13 @@ func (sim *AGVSimulator) buildBroadcastMessagesLocked() (statusMsg, positionMsg
 	}
 
 	var flatTarget map[string]interface{}
-	if sim.Status.TargetEnemy != nil {
+	if a.Status.TargetEnemy != nil {
 		flatTarget = map[string]interface{}{
-			"id":   sim.Status.TargetEnemy.ID,
-			"name": sim.Status.TargetEnemy.Name,
-			"hp":   sim.Status.TargetEnemy.HP,
-			"x":    sim.Status.TargetEnemy.Position.X,
-			"y":    sim.Status.TargetEnemy.Position.Y,
+			"id":   a.Status.TargetEnemy.ID,
+			"name": a.Status.TargetEnemy.Name,
+			"hp":   a.Status.TargetEnemy.HP,
+			"x":    a.Status.TargetEnemy.Position.X,
+			"y":    a.Status.TargetEnemy.Position.Y,
 		}
 	}
 
@@ -283,58 +762,81 @@ func (sim *AGVSimulator) buildBroadcastMessagesLocked() (statusMsg, positionMsg
 	statusMsg = models.WebSocketMessage{
 		Type: models.MessageTypeStatus,
 		Data: map[string]interface{}{
-			"battery":          sim.Status.Battery,
-			"speed":            sim.Status.Speed,
-			"mode":             sim.Status.Mode,
-			"state":            sim.Status.State,
+			"id":               a.Status.ID,
+			"battery":          a.Status.Battery,
+			"speed":            a.Status.Speed,
+			"mode":             a.Status.Mode,
+			"state":            a.Status.State,
 			"detected_enemies": flatEnemies,
 			"target_enemy":     flatTarget,
+			"sensors":          a.Status.Sensors,
+			"drive":            a.Status.Drive,
 		},
 		Timestamp: now.UnixMilli(),
+		AGVID:     a.Status.ID,
 	}
 	positionMsg = models.WebSocketMessage{
 		Type: models.MessageTypePosition,
 		Data: models.PositionData{
-			X:         sim.Status.Position.X,
-			Y:         sim.Status.Position.Y,
-			Angle:     sim.Status.Position.Angle,
+			X:         a.Status.Position.X,
+			Y:         a.Status.Position.Y,
+			Angle:     a.Status.Position.Angle,
 			Timestamp: now,
 		},
 		Timestamp: now.UnixMilli(),
+		AGVID:     a.Status.ID,
 	}
 	return statusMsg, positionMsg
 }
 
-func generateRandomEnemies(count int, mapWidth, mapHeight float64) []models.Enemy {
+func generateRandomEnemies(rng *rand.Rand, count int, mapWidth, mapHeight float64) []models.Enemy {
 	enemyNames := []string{"아리", "야스오", "지글스", "룩스", "제드"}
 	enemies := make([]models.Enemy, count)
 
 	for i := 0; i < count; i++ {
+		hp := rng.Intn(81) + 20
 		enemies[i] = models.Enemy{
-			ID:   fmt.Sprintf("enemy-%d", i+1),
-			Name: enemyNames[rand.Intn(len(enemyNames))],
-			HP:   rand.Intn(81) + 20,
+			ID:    fmt.Sprintf("enemy-%d", i+1),
+			Name:  enemyNames[rng.Intn(len(enemyNames))],
+			State: models.EnemyStateAlive,
+			HP:    hp,
+			MaxHP: hp,
 			Position: models.PositionData{
-				X: rand.Float64() * mapWidth,
-				Y: rand.Float64() * mapHeight,
+				X: rng.Float64() * mapWidth,
+				Y: rng.Float64() * mapHeight,
 			},
 		}
 	}
 	return enemies
 }
 
-func generateRandomObstacles(count int, mapWidth, mapHeight float64) []models.Obstacle {
-	obstacles := make([]models.Obstacle, count)
-	for i := 0; i < count; i++ {
-		obstacles[i] = models.Obstacle{
-			ID:   fmt.Sprintf("obstacle-%d", i+1),
-			Type: "static",
-			Position: models.GridCoordinate{
-				Row: rand.Intn(int(mapHeight)),
-				Col: rand.Intn(int(mapWidth)),
-			},
-			Size: 1,
+// generateRandomObstacles는 count개의 1x1 정적 장애물을 만든다.
+// keepClear 셀(AGV 시작 위치)에는 배치하지 않는다.
+func generateRandomObstacles(rng *rand.Rand, count int, mapWidth, mapHeight float64, keepClear ...models.GridCoordinate) []models.Obstacle {
+	obstacles := make([]models.Obstacle, 0, count)
+	for len(obstacles) < count {
+		pos := models.GridCoordinate{
+			Row: rng.Intn(int(mapHeight)),
+			Col: rng.Intn(int(mapWidth)),
+		}
+		if containsCell(keepClear, pos) {
+			continue
 		}
+		obstacles = append(obstacles, models.Obstacle{
+			ID:       fmt.Sprintf("obstacle-%d", len(obstacles)+1),
+			Type:     "static",
+			Position: pos,
+			Size:     1,
+		})
 	}
 	return obstacles
 }
+
+func containsCell(cells []models.GridCoordinate, c models.GridCoordinate) bool {
+	for _, cell := range cells {
+		if cell == c {
+			return true
+		}
+	}
+	return false
+}
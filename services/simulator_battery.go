@@ -0,0 +1,143 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sion-backend/algorithms"
+	"sion-backend/models"
+	"time"
+)
+
+// returnSpeed는 충전소로 복귀할 때의 속도.
+const returnSpeed = 1.0
+
+// stepBatteryMissionLocked는 배터리 미션을 평가해 복귀·충전 중이면 이번 틱을 대신 처리하고 true를 반환한다.
+// 평상시면 false를 반환해 원래 auto/manual 로직이 돌게 한다.
+func (sim *AGVSimulator) stepBatteryMissionLocked(a *simAGV) bool {
+	if sim.mission == nil {
+		return sim.stopIfDrainedLocked(a)
+	}
+	// 벽 너머의 충전소는 직선보다 멀다. 복귀 판단은 실제로 갈 경로 길이로 한다.
+	d := sim.mission.EvaluatePath(a.Status, func(dock models.ChargingDock) (float64, bool) {
+		return sim.dockPathLengthLocked(a, dock)
+	})
+	if d.Changed {
+		sim.logBatteryTransitionLocked(a, d)
+	}
+
+	switch d.State {
+	case models.StateReturning:
+		if sim.stopIfDrainedLocked(a) {
+			return true
+		}
+		if d.Changed {
+			// 교전·수동 이동을 중단하고 기존 경로를 버린다.
+			a.moveTarget = nil
+			a.clearPath()
+		}
+		a.Status.TargetEnemy = nil
+		a.Status.State = models.StateReturning
+		a.Status.Speed = returnSpeed
+		sim.moveTowardsLocked(a, d.Dock.X, d.Dock.Y)
+		return true
+	case models.StateRecharging:
+		a.Status.TargetEnemy = nil
+		a.Status.State = models.StateRecharging
+		a.Status.Speed = 0
+		a.chargeAcc += dockRate(d.Dock) * sim.UpdateInterval.Seconds()
+		if whole := int(a.chargeAcc); whole > 0 {
+			a.chargeAcc -= float64(whole)
+			a.Status.Battery += whole
+			if a.Status.Battery > 100 {
+				a.Status.Battery = 100
+			}
+		}
+		return true
+	}
+	if d.Changed {
+		a.chargeAcc = 0
+		// 충전하러 가기 전의 모드로 돌아간다. 충전 중 수동으로 바꿨어도 임무 복귀는 원래 모드로 한다.
+		if d.ResumeMode != "" {
+			a.Status.Mode = d.ResumeMode
+		}
+	}
+	return sim.stopIfDrainedLocked(a)
+}
+
+type dockPathKey struct {
+	cx, cy int
+	dx, dy float64
+}
+
+// dockPathLengthLocked는 a의 현재 셀에서 dock까지 A* 경로 길이(셀)를 반환한다. 갈 수 없으면 ok=false.
+// 매 틱 평가하므로 셀·충전소별로 캐시한다.
+func (sim *AGVSimulator) dockPathLengthLocked(a *simAGV, dock models.ChargingDock) (float64, bool) {
+	sx, sy := sim.cellOf(a.Status.Position.X, a.Status.Position.Y)
+	key := dockPathKey{cx: sx, cy: sy, dx: dock.X, dy: dock.Y}
+	if l, ok := sim.dockPaths[key]; ok {
+		return l, l >= 0
+	}
+	length := -1.0
+	gx, gy := sim.cellOf(dock.X, dock.Y)
+	if gx, gy, ok := sim.nearestFreeCellLocked(gx, gy); ok {
+		if path := sim.grid.FindPath(
+			algorithms.Point{X: float64(sx), Y: float64(sy)},
+			algorithms.Point{X: float64(gx), Y: float64(gy)},
+		); path != nil {
+			length = 0
+			for i := 1; i < len(path); i++ {
+				length += math.Hypot(path[i].X-path[i-1].X, path[i].Y-path[i-1].Y)
+			}
+		}
+	}
+	if sim.dockPaths == nil {
+		sim.dockPaths = make(map[dockPathKey]float64)
+	}
+	sim.dockPaths[key] = length
+	return length, length >= 0
+}
+
+// stopIfDrainedLocked는 배터리가 바닥난 AGV를 그 자리에 세운다.
+func (sim *AGVSimulator) stopIfDrainedLocked(a *simAGV) bool {
+	if a.Status.Battery > 0 {
+		return false
+	}
+	a.Status.TargetEnemy = nil
+	a.Status.State = models.StateStopped
+	a.Status.Speed = 0
+	return true
+}
+
+func (sim *AGVSimulator) logBatteryTransitionLocked(a *simAGV, d BatteryDecision) {
+	var text string
+	switch d.State {
+	case models.StateReturning:
+		text = fmt.Sprintf("%s 배터리 %d%% — %s 충전소로 복귀", a.Status.Name, a.Status.Battery, d.Dock.ID)
+	case models.StateRecharging:
+		text = fmt.Sprintf("%s %s 충전소 도착, 충전 시작", a.Status.Name, d.Dock.ID)
+	default:
+		text = fmt.Sprintf("%s 충전 완료 (%d%%), 임무 복귀", a.Status.Name, a.Status.Battery)
+	}
+	log.Printf("[INFO] %s", text)
+	sim.pending = append(sim.pending, models.WebSocketMessage{
+		Type: models.MessageTypeLog,
+		Data: map[string]interface{}{
+			"message": text,
+			"dock_id": d.Dock.ID,
+			"battery": a.Status.Battery,
+		},
+		Timestamp: time.Now().UnixMilli(),
+		AGVID:     a.Status.ID,
+	})
+}
+
+// Docks는 현재 월드의 충전소 목록을 반환한다.
+func (sim *AGVSimulator) Docks() []models.ChargingDock {
+	sim.mu.RLock()
+	defer sim.mu.RUnlock()
+	if sim.mission == nil {
+		return nil
+	}
+	return append([]models.ChargingDock(nil), sim.mission.Docks...)
+}
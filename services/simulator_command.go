@@ -0,0 +1,181 @@
+package services
+
+import (
+	"log"
+	"sion-backend/models"
+	"time"
+)
+
+// 시뮬레이터가 실제 AGV 대신 웹 명령(command, mode_change, emergency_stop, motor_control)을 받는 경로.
+// Broker는 시뮬레이터가 실행 중일 때 WriteToAGV 대신 HandleWebCommand로 명령을 넘긴다.
+
+// manualSpeed는 수동 이동 명령을 수행할 때의 속도.
+const manualSpeed = 1.5
+
+// HandleWebCommand는 웹 명령을 시뮬레이터 AGV에 적용한다. 실행 중이 아니거나 msg.AGVID가 시뮬레이터에
+// 없는 AGV면 false를 반환해 Broker가 실제 AGV로 전달하게 한다. msg.AGVID가 비어 있으면
+// command/mode_change/motor_control은 대표 AGV에, emergency_stop은 모든 AGV에 적용한다.
+// models.AGVIDBroadcast면 모든 AGV에 적용한다.
+func (sim *AGVSimulator) HandleWebCommand(msg models.WebSocketMessage) bool {
+	if !sim.IsRunning() {
+		return false
+	}
+
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+
+	targets := sim.commandTargetsLocked(msg)
+	if len(targets) == 0 {
+		return false
+	}
+
+	switch msg.Type {
+	case models.MessageTypeCommand:
+		cmd, err := PayloadAs[models.MoveCommand](msg)
+		if err != nil {
+			log.Printf("[WARN] command 파싱 실패: %v", err)
+			return true
+		}
+		// 이동 명령은 별도 모드가 없으면 수동 조작으로 본다.
+		mode := cmd.Mode
+		if mode == "" {
+			mode = models.ModeManual
+		}
+		for _, a := range targets {
+			if a.Status.State == models.StateEmergency {
+				log.Printf("[WARN] %s 비상 정지 중 — 이동 명령 무시", a.Status.ID)
+				continue
+			}
+			a.Status.Mode = mode
+			a.motor = nil
+			a.moveTarget = &models.RealCoordinate{
+				X: clamp(cmd.TargetX, 0, sim.MapWidth),
+				Y: clamp(cmd.TargetY, 0, sim.MapHeight),
+			}
+			a.clearPath()
+			log.Printf("[INFO] %s 이동 명령: (%.1f, %.1f) mode=%s", a.Status.ID, a.moveTarget.X, a.moveTarget.Y, mode)
+		}
+	case models.MessageTypeModeChange:
+		cmd, err := PayloadAs[models.ModeChangeCommand](msg)
+		if err != nil {
+			log.Printf("[WARN] mode_change 파싱 실패: %v", err)
+			return true
+		}
+		if cmd.Mode != models.ModeAuto && cmd.Mode != models.ModeManual {
+			log.Printf("[WARN] 알 수 없는 모드: %q", cmd.Mode)
+			return true
+		}
+		for _, a := range targets {
+			a.Status.Mode = cmd.Mode
+			a.moveTarget = nil
+			a.motor = nil
+			a.clearPath()
+			if a.Status.State == models.StateEmergency {
+				a.Status.State = models.StateIdle
+				log.Printf("[INFO] %s 비상 정지 해제", a.Status.ID)
+			}
+			log.Printf("[INFO] %s 모드 변경: %s", a.Status.ID, cmd.Mode)
+		}
+	case models.MessageTypeEmergencyStop:
+		cmd, _ := PayloadAs[models.EmergencyStopCommand](msg) // 사유는 선택 사항
+		for _, a := range targets {
+			a.Status.State = models.StateEmergency
+			a.Status.Speed = 0
+			// 비상 정지는 구동 전원을 끊는 것으로 보고 가감속 한계 없이 즉시 세운다.
+			a.Status.Drive = models.DriveState{}
+			a.Status.TargetEnemy = nil
+			a.moveTarget = nil
+			a.motor = nil
+			a.clearPath()
+			log.Printf("[WARN] %s 비상 정지: %s", a.Status.ID, cmd.Reason)
+		}
+	case models.MessageTypeMotorControl:
+		cmd, err := PayloadAs[models.MotorControl](msg)
+		if err != nil {
+			log.Printf("[WARN] motor_control 파싱 실패: %v", err)
+			return true
+		}
+		duration := time.Duration(cmd.Duration) * time.Millisecond
+		if duration <= 0 {
+			duration = defaultMotorDuration
+		}
+		for _, a := range targets {
+			if a.Status.State == models.StateEmergency {
+				log.Printf("[WARN] %s 비상 정지 중 — 바퀴 입력 무시", a.Status.ID)
+				continue
+			}
+			// 바퀴 입력은 수동 조작이다. 진행 중이던 이동 목표는 버린다.
+			a.Status.Mode = models.ModeManual
+			a.moveTarget = nil
+			a.clearPath()
+			m := cmd
+			a.motor = &m
+			a.motorLeft = duration
+			log.Printf("[INFO] %s 바퀴 입력: L=%.2f R=%.2f %v", a.Status.ID, cmd.LeftSpeed, cmd.RightSpeed, duration)
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// CommandBounds는 agvID에 가는 command를 시뮬레이터가 받으면 현재 맵 크기와 true를 반환한다.
+// HandleWebCommand와 같은 기준(실행 중이고 그 AGV가 있음)으로 판단한다. 맵은 시나리오를 불러오면 바뀐다.
+func (sim *AGVSimulator) CommandBounds(agvID string) (width, height float64, ok bool) {
+	if !sim.IsRunning() {
+		return 0, 0, false
+	}
+	sim.mu.RLock()
+	defer sim.mu.RUnlock()
+	if len(sim.commandTargetsLocked(models.WebSocketMessage{Type: models.MessageTypeCommand, AGVID: agvID})) == 0 {
+		return 0, 0, false
+	}
+	return sim.MapWidth, sim.MapHeight, true
+}
+
+// commandTargetsLocked는 명령을 적용할 AGV 목록을 고른다.
+func (sim *AGVSimulator) commandTargetsLocked(msg models.WebSocketMessage) []*simAGV {
+	if msg.AGVID == models.AGVIDBroadcast {
+		return sim.agvs
+	}
+	if msg.AGVID != "" {
+		for _, a := range sim.agvs {
+			if a.Status.ID == msg.AGVID {
+				return []*simAGV{a}
+			}
+		}
+		return nil
+	}
+	if msg.Type == models.MessageTypeEmergencyStop {
+		return sim.agvs
+	}
+	if len(sim.agvs) == 0 {
+		return nil
+	}
+	return sim.agvs[:1]
+}
+
+// stepManualLocked는 수동 모드 AGV를 한 틱 움직인다. 바퀴 입력이 이동 목표보다 우선하며,
+// 둘 다 없으면 제자리에서 대기한다.
+func (sim *AGVSimulator) stepManualLocked(a *simAGV) {
+	a.Status.TargetEnemy = nil
+	if a.motor != nil {
+		sim.stepMotorLocked(a)
+		return
+	}
+	if a.moveTarget == nil {
+		a.Status.State = models.StateIdle
+		a.Status.Speed = 0
+		return
+	}
+	a.Status.State = models.StateMoving
+	a.Status.Speed = manualSpeed
+	sim.moveTowardsLocked(a, a.moveTarget.X, a.moveTarget.Y)
+	if a.distanceTo(a.moveTarget.X, a.moveTarget.Y) <= arriveDist {
+		log.Printf("[INFO] %s 목표 도착: (%.1f, %.1f)", a.Status.ID, a.moveTarget.X, a.moveTarget.Y)
+		a.moveTarget = nil
+		a.clearPath()
+		a.Status.State = models.StateIdle
+		a.Status.Speed = 0
+	}
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"sion-backend/models"
+	"testing"
+)
+
+// newCommandSimulator는 장애물 없는 10x10 맵에 AGV 두 대(두 번째는 manual 대기)를 띄우고 실행 중 상태로 만든다.
+// 루프 고루틴 없이 stepLocked를 직접 돌려 틱 단위로 검증하기 위해 running 플래그만 세운다.
+func newCommandSimulator(t *testing.T) *AGVSimulator {
+	t.Helper()
+	seed := int64(5)
+	sim := newScenarioSimulator(t, models.Scenario{
+		Name:      "command",
+		MapWidth:  10,
+		MapHeight: 10,
+		AGVs: []models.ScenarioAGV{
+			{ID: "sion-001", X: 1, Y: 1},
+			{ID: "sion-002", X: 1, Y: 8, Mode: models.ModeManual},
+		},
+		Seed: &seed,
+	})
+	sim.running.Store(true)
+	t.Cleanup(func() { sim.running.Store(false) })
+	return sim
+}
+
+func TestHandleWebCommand_IgnoredWhenStopped(t *testing.T) {
+	sim := NewAGVSimulator(nil)
+	if sim.HandleWebCommand(models.WebSocketMessage{Type: models.MessageTypeEmergencyStop}) {
+		t.Fatal("정지 상태에서는 명령을 가로채면 안 됨")
+	}
+}
+
+func TestHandleWebCommand_MoveCommandDrivesToTarget(t *testing.T) {
+	sim := newCommandSimulator(t)
+	ok := sim.HandleWebCommand(models.WebSocketMessage{
+		Type: models.MessageTypeCommand,
+		Data: models.MoveCommand{TargetX: 6.5, TargetY: 1.5},
+	})
+	if !ok {
+		t.Fatal("실행 중에는 명령을 처리해야 함")
+	}
+	a := sim.agvs[0]
+	if a.Status.Mode != models.ModeManual {
+		t.Fatalf("이동 명령은 manual 모드로 전환해야 함: %s", a.Status.Mode)
+	}
+	for i := 0; i < 40 && a.moveTarget != nil; i++ {
+		sim.stepLocked()
+	}
+	if a.moveTarget != nil || a.distanceTo(6.5, 1.5) > 0.1 {
+		t.Fatalf("목표에 도착하지 못함: %+v", a.Status.Position)
+	}
+	if a.Status.State != models.StateIdle {
+		t.Fatalf("도착 후 idle이어야 함: %s", a.Status.State)
+	}
+	if p := sim.agvs[1].Status.Position; p.X != 1 || p.Y != 8 {
+		t.Fatalf("대상이 아닌 AGV는 움직이면 안 됨: %+v", p)
+	}
+}
+
+func TestHandleWebCommand_EmergencyStopLatchesUntilModeChange(t *testing.T) {
+	sim := newCommandSimulator(t)
+	sim.HandleWebCommand(models.WebSocketMessage{
+		Type:  models.MessageTypeCommand,
+		Data:  models.MoveCommand{TargetX: 8, TargetY: 8},
+		AGVID: "sion-002",
+	})
+	sim.stepLocked()
+	sim.HandleWebCommand(models.WebSocketMessage{
+		Type: models.MessageTypeEmergencyStop,
+		Data: models.EmergencyStopCommand{Reason: "테스트"},
+	})
+
+	frozen := sim.agvs[1].Status.Position
+	for i := 0; i < 5; i++ {
+		sim.stepLocked()
+	}
+	for _, a := range sim.agvs {
+		if a.Status.State != models.StateEmergency || a.Status.Speed != 0 {
+			t.Fatalf("%s 비상 정지가 유지돼야 함: state=%s speed=%.1f", a.Status.ID, a.Status.State, a.Status.Speed)
+		}
+	}
+	if p := sim.agvs[1].Status.Position; p.X != frozen.X || p.Y != frozen.Y {
+		t.Fatalf("비상 정지 중 이동함: %+v -> %+v", frozen, p)
+	}
+
+	// 비상 정지 중 이동 명령은 무시된다.
+	sim.HandleWebCommand(models.WebSocketMessage{
+		Type: models.MessageTypeCommand,
+		Data: models.MoveCommand{TargetX: 5, TargetY: 5},
+	})
+	if sim.agvs[0].moveTarget != nil {
+		t.Fatal("비상 정지 중 이동 명령이 적용됨")
+	}
+
+	sim.HandleWebCommand(models.WebSocketMessage{
+		Type:  models.MessageTypeModeChange,
+		Data:  models.ModeChangeCommand{Mode: models.ModeManual},
+		AGVID: "sion-001",
+	})
+	sim.stepLocked()
+	if s := sim.agvs[0].Status.State; s != models.StateIdle {
+		t.Fatalf("mode_change 후 비상 정지가 풀려야 함: %s", s)
+	}
+	if s := sim.agvs[1].Status.State; s != models.StateEmergency {
+		t.Fatalf("다른 AGV의 비상 정지는 유지돼야 함: %s", s)
+	}
+}
+
+func TestHandleWebCommand_UnknownTargetFallsThroughAndBroadcastHitsAll(t *testing.T) {
+	sim := newCommandSimulator(t)
+	if sim.HandleWebCommand(models.WebSocketMessage{
+		Type:  models.MessageTypeCommand,
+		Data:  models.MoveCommand{TargetX: 5, TargetY: 5},
+		AGVID: "sion-404",
+	}) {
+		t.Fatal("시뮬레이터에 없는 AGV 명령은 Broker가 실제 AGV로 넘기도록 false여야 함")
+	}
+	if !sim.HandleWebCommand(models.WebSocketMessage{
+		Type:  models.MessageTypeEmergencyStop,
+		AGVID: models.AGVIDBroadcast,
+	}) {
+		t.Fatal("전체 비상 정지는 시뮬레이터가 처리해야 함")
+	}
+	for _, a := range sim.agvs {
+		if a.Status.State != models.StateEmergency {
+			t.Fatalf("%s 비상 정지 기대: %s", a.Status.ID, a.Status.State)
+		}
+	}
+}
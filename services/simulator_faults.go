@@ -0,0 +1,63 @@
+package services
+
+import (
+	"log"
+	"sion-backend/models"
+	"time"
+)
+
+// SetFaultInjector는 시뮬레이터 AGV에 장애 주입을 붙인다. nil이면 해제.
+func (sim *AGVSimulator) SetFaultInjector(f *FaultInjector) {
+	sim.mu.Lock()
+	sim.faults = f
+	sim.mu.Unlock()
+}
+
+// rollFaultsLocked는 AGV 한 대의 이번 틱 장애를 뽑아 적용한다. 장애 판정 시각은 시뮬레이션 경과 시간이다.
+// 장애 난수는 FaultInjector 전용이라 월드 rng 소비 순서는 바뀌지 않는다.
+func (sim *AGVSimulator) rollFaultsLocked(a *simAGV) {
+	if a.stuckLeft > 0 {
+		a.stuckLeft -= sim.UpdateInterval
+		if a.stuckLeft <= 0 {
+			a.stuckLeft = 0
+			log.Printf("[INFO] 장애 해제: %s 모터 고착 풀림", a.Status.ID)
+		}
+	}
+	a.sensorBlackout = false
+	if sim.faults == nil {
+		return
+	}
+	f := sim.faults.RollAGV(sim.elapsed)
+	a.sensorBlackout = f.SensorDropout
+	if f.BatteryDrop > 0 {
+		before := a.Status.Battery
+		a.Status.Battery = max(0, before-f.BatteryDrop)
+		sim.pending = append(sim.pending, faultLogMessage(a.Status.ID, FaultBatteryDrop,
+			map[string]interface{}{"from": before, "to": a.Status.Battery}))
+		log.Printf("[WARN] 장애 주입: %s 배터리 급락 %d%% → %d%%", a.Status.ID, before, a.Status.Battery)
+	}
+	if f.StuckFor > 0 && a.stuckLeft == 0 {
+		a.stuckLeft = f.StuckFor
+		a.Status.Drive = models.DriveState{}
+		sim.pending = append(sim.pending, faultLogMessage(a.Status.ID, FaultStuckMotor,
+			map[string]interface{}{"duration_sec": f.StuckFor.Seconds()}))
+		log.Printf("[WARN] 장애 주입: %s 모터 고착 %v", a.Status.ID, f.StuckFor)
+	}
+}
+
+// faultLogMessage는 장애 발생을 웹 이벤트 피드에 알리는 log 메시지를 만든다.
+func faultLogMessage(agvID, fault string, detail map[string]interface{}) models.WebSocketMessage {
+	data := map[string]interface{}{
+		"message": "장애 주입: " + fault,
+		"fault":   fault,
+	}
+	for k, v := range detail {
+		data[k] = v
+	}
+	return models.WebSocketMessage{
+		Type:      models.MessageTypeLog,
+		Data:      data,
+		Timestamp: time.Now().UnixMilli(),
+		AGVID:     agvID,
+	}
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sion-backend/models"
+	"time"
+)
+
+// 시뮬레이터 구동 모델. AGV를 차동 구동 로봇으로 보고, 제어기가 낸 목표 선속도·각속도를
+// 가감속 한계 → 최소 회전 반경 → 바퀴 속도 한계 순으로 제한한 뒤 한 틱을 서브스텝으로 나눠 원호로 적분한다.
+// 경로 추종, 랜덤 워크, 수동 바퀴 입력(MotorControl)이 모두 이 모델을 거치므로 헤딩과 속도가 서서히 바뀐다.
+
+const (
+	// kinematicsSubstep은 적분 간격. 틱(기본 0.5초)을 이 간격으로 나눠 제어·충돌 판정을 반복한다.
+	kinematicsSubstep = 50 * time.Millisecond
+	// pivotAngle보다 헤딩 오차가 크면 감속해 멈춘 뒤 제자리 회전으로 방향을 맞춘다.
+	pivotAngle = math.Pi / 3
+	// realignAngle은 충돌 직후 제자리 회전을 끝내는 헤딩 오차. 원호로 출발하다 같은 장애물에 다시 부딪히지 않게 한다.
+	realignAngle = 0.05
+	// headingGain은 헤딩 오차(rad) → 목표 각속도(rad/s) 비례 이득.
+	headingGain = 4.0
+	// arriveDist는 이동 목표 도착으로 보는 거리(셀).
+	arriveDist = 0.1
+	// defaultMotorDuration은 Duration 없이 들어온 바퀴 입력을 유지하는 시간. 명령이 끊기면 멈추게 하는 안전장치다.
+	defaultMotorDuration = time.Second
+)
+
+// DefaultKinematicsConfig는 소형 실내 AGV 섀시 정도를 흉내 낸 기본값.
+// 최고 바퀴 속도는 돌진 속도(2.5셀/초)보다 약간 높게 잡았다.
+func DefaultKinematicsConfig() models.KinematicsConfig {
+	return models.KinematicsConfig{
+		WheelBase:       0.5,
+		MaxWheelSpeed:   3.0,
+		MaxLinearAccel:  2.0,
+		MaxAngularAccel: 6.0,
+		MinTurnRadius:   0.3,
+	}
+}
+
+// ValidateKinematicsConfig는 설정 범위를 검사한다.
+func ValidateKinematicsConfig(cfg models.KinematicsConfig) error {
+	if cfg.WheelBase <= 0 {
+		return fmt.Errorf("wheel_base는 0보다 커야 합니다")
+	}
+	if cfg.MaxWheelSpeed <= 0 {
+		return fmt.Errorf("max_wheel_speed는 0보다 커야 합니다")
+	}
+	if cfg.MaxLinearAccel <= 0 {
+		return fmt.Errorf("max_linear_accel은 0보다 커야 합니다")
+	}
+	if cfg.MaxAngularAccel <= 0 {
+		return fmt.Errorf("max_angular_accel은 0보다 커야 합니다")
+	}
+	if cfg.MinTurnRadius < 0 {
+		return fmt.Errorf("min_turn_radius는 0 이상이어야 합니다")
+	}
+	return nil
+}
+
+// SetKinematicsConfig는 구동 한계를 바꾼다. 실행 중에도 다음 틱부터 반영된다.
+func (sim *AGVSimulator) SetKinematicsConfig(cfg models.KinematicsConfig) error {
+	if err := ValidateKinematicsConfig(cfg); err != nil {
+		return err
+	}
+	sim.mu.Lock()
+	sim.kinematics = cfg
+	sim.mu.Unlock()
+	return nil
+}
+
+func (sim *AGVSimulator) KinematicsConfig() models.KinematicsConfig {
+	sim.mu.RLock()
+	defer sim.mu.RUnlock()
+	return sim.kinematics
+}
+
+// driveLocked는 한 틱 동안 control이 주는 목표 (선속도, 각속도)를 구동 한계 안에서 따라가며 a를 움직인다.
+// control은 서브스텝마다 호출된다. 충돌하면 그 자리에서 멈추고(속도 0) false를 반환한다.
+// 모터 고착 장애 중이면 명령과 무관하게 그 자리에 선다.
+func (sim *AGVSimulator) driveLocked(a *simAGV, control func() (v, w float64)) bool {
+	a.driven = true
+	if a.stuckLeft > 0 {
+		a.Status.Drive = models.DriveState{}
+		return true
+	}
+	n := int(math.Round(float64(sim.UpdateInterval) / float64(kinematicsSubstep)))
+	if n < 1 {
+		n = 1
+	}
+	step := sim.UpdateInterval / time.Duration(n)
+	dt := step.Seconds()
+	for i := 0; i < n; i++ {
+		vCmd, wCmd := control()
+		v, w := sim.limitVelocityLocked(a, vCmd, wCmd, dt)
+		if !sim.integrateLocked(a, v, w, dt) {
+			return false
+		}
+		if sim.stats != nil {
+			// 원호 주행 거리를 트래커도 시뮬레이터와 같은 해상도로 재도록 서브스텝마다 보고한다.
+			sim.stats.ObservePosition(a.Status.ID, a.Status.Position, time.Time{}.Add(sim.elapsed+time.Duration(i+1)*step))
+		}
+	}
+	return true
+}
+
+// brakeLocked는 최대 감속으로 멈춘다. 이번 틱에 주행 명령이 없던 AGV(정지·충전·비상 정지)에 쓴다.
+func (sim *AGVSimulator) brakeLocked(a *simAGV) {
+	if a.Status.Drive.Linear == 0 && a.Status.Drive.Angular == 0 {
+		return
+	}
+	sim.driveLocked(a, func() (float64, float64) { return 0, 0 })
+}
+
+// limitVelocityLocked는 목표 속도를 가감속 한계, 최소 회전 반경, 바퀴 속도 한계로 제한한다.
+func (sim *AGVSimulator) limitVelocityLocked(a *simAGV, vCmd, wCmd, dt float64) (float64, float64) {
+	cfg := sim.kinematics
+	cur := a.Status.Drive
+	v := cur.Linear + clamp(vCmd-cur.Linear, -cfg.MaxLinearAccel*dt, cfg.MaxLinearAccel*dt)
+	w := cur.Angular + clamp(wCmd-cur.Angular, -cfg.MaxAngularAccel*dt, cfg.MaxAngularAccel*dt)
+	if cfg.MinTurnRadius > 0 && v != 0 {
+		maxW := math.Abs(v) / cfg.MinTurnRadius
+		w = clamp(w, -maxW, maxW)
+	}
+	// 바퀴 하나라도 한계를 넘으면 곡률을 유지한 채 둘 다 줄인다.
+	left, right := wheelSpeeds(v, w, cfg.WheelBase)
+	if peak := math.Max(math.Abs(left), math.Abs(right)); peak > cfg.MaxWheelSpeed {
+		k := cfg.MaxWheelSpeed / peak
+		v *= k
+		w *= k
+	}
+	return v, w
+}
+
+// integrateLocked는 (v, w)로 dt 동안 원호를 따라 움직인다. 이동 후 위치가 막혀 있으면 멈추고 false.
+func (sim *AGVSimulator) integrateLocked(a *simAGV, v, w, dt float64) bool {
+	x, y, th := a.Status.Position.X, a.Status.Position.Y, a.Status.Position.Angle
+	if v != 0 {
+		var nx, ny float64
+		if math.Abs(w) < 1e-9 {
+			nx = x + v*math.Cos(th)*dt
+			ny = y + v*math.Sin(th)*dt
+		} else {
+			r := v / w
+			nx = x + r*(math.Sin(th+w*dt)-math.Sin(th))
+			ny = y - r*(math.Cos(th+w*dt)-math.Cos(th))
+		}
+		if !sim.tryMoveLocked(a, nx, ny) {
+			a.Status.Drive = models.DriveState{}
+			a.realign = true
+			return false
+		}
+	}
+	a.Status.Position.Angle = wrapAngle(th + w*dt)
+	left, right := wheelSpeeds(v, w, sim.kinematics.WheelBase)
+	a.Status.Drive = models.DriveState{Linear: v, Angular: w, LeftWheel: left, RightWheel: right}
+	a.wheelTravel += (math.Abs(left) + math.Abs(right)) / 2 * dt
+	return true
+}
+
+// seekLocked는 (x, y)로 향하는 목표 속도를 낸다. 헤딩 오차가 크면 제자리 회전부터 하고,
+// 원호로 목표점을 지날 수 있는 속도와 stopDist 안에 멈출 수 있는 속도를 넘지 않는다.
+func (sim *AGVSimulator) seekLocked(a *simAGV, x, y, speed, stopDist float64) (float64, float64) {
+	dx, dy := x-a.Status.Position.X, y-a.Status.Position.Y
+	dist := math.Hypot(dx, dy)
+	if dist == 0 {
+		return 0, 0
+	}
+	e := wrapAngle(math.Atan2(dy, dx) - a.Status.Position.Angle)
+	w := sim.headingRate(e)
+	if a.mustPivot(e) {
+		return 0, w
+	}
+	v := speed * math.Cos(e)
+	if s := math.Sin(math.Abs(e)); s > 1e-6 {
+		// 현재 헤딩에 접하면서 목표점을 지나는 원의 반경은 dist/(2 sin e). 그보다 크게 돌면 목표 주위를 맴돈다.
+		v = math.Min(v, math.Abs(w)*dist/(2*s))
+	}
+	v = math.Min(v, math.Sqrt(2*sim.kinematics.MaxLinearAccel*stopDist))
+	return v, w
+}
+
+// turnToLocked는 heading 방향으로 speed만큼 나아가는 목표 속도를 낸다 (랜덤 워크용).
+func (sim *AGVSimulator) turnToLocked(a *simAGV, heading, speed float64) (float64, float64) {
+	e := wrapAngle(heading - a.Status.Position.Angle)
+	w := sim.headingRate(e)
+	if a.mustPivot(e) {
+		return 0, w
+	}
+	return speed * math.Cos(e), w
+}
+
+// mustPivot는 헤딩 오차 e에서 제자리 회전해야 하는지 판단한다. 충돌 뒤에는 거의 정확히 맞출 때까지 회전한다.
+func (a *simAGV) mustPivot(e float64) bool {
+	if a.realign {
+		if math.Abs(e) > realignAngle || a.Status.Drive.Linear != 0 {
+			return true
+		}
+		a.realign = false
+	}
+	return math.Abs(e) > pivotAngle
+}
+
+// headingRate는 헤딩 오차 e를 최대 각가속도로 감속하며 0으로 줄이는 목표 각속도.
+func (sim *AGVSimulator) headingRate(e float64) float64 {
+	rate := math.Min(headingGain*math.Abs(e), math.Sqrt(2*sim.kinematics.MaxAngularAccel*math.Abs(e)))
+	return math.Copysign(rate, e)
+}
+
+// stepMotorLocked는 수동 바퀴 입력을 한 틱 적용하고, 유지 시간이 끝나면 입력을 지운다.
+func (sim *AGVSimulator) stepMotorLocked(a *simAGV) {
+	m := a.motor
+	v := (m.LeftSpeed + m.RightSpeed) / 2
+	w := (m.RightSpeed - m.LeftSpeed) / sim.kinematics.WheelBase
+	a.Status.State = models.StateMoving
+	a.Status.Speed = (math.Abs(m.LeftSpeed) + math.Abs(m.RightSpeed)) / 2
+	sim.driveLocked(a, func() (float64, float64) { return v, w })
+	a.motorLeft -= sim.UpdateInterval
+	if a.motorLeft <= 0 {
+		a.motor = nil
+		a.Status.State = models.StateIdle
+		a.Status.Speed = 0
+	}
+}
+
+func wheelSpeeds(v, w, wheelBase float64) (left, right float64) {
+	return v - w*wheelBase/2, v + w*wheelBase/2
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"math"
+	"sion-backend/models"
+	"testing"
+)
+
+func TestKinematics_HeadingAndSpeedChangeGradually(t *testing.T) {
+	sim := newCommandSimulator(t)
+	cfg := sim.KinematicsConfig()
+	a := sim.agvs[1] // (1,8)에서 헤딩 0으로 manual 대기
+	// 헤딩(+x)과 거의 직각인 -y 방향 목표라 제자리 회전부터 해야 한다.
+	sim.HandleWebCommand(models.WebSocketMessage{
+		Type:  models.MessageTypeCommand,
+		Data:  models.MoveCommand{TargetX: 1.5, TargetY: 2.5},
+		AGVID: "sion-002",
+	})
+
+	prevAngle := a.Status.Position.Angle
+	prevV := 0.0
+	tick := sim.UpdateInterval.Seconds()
+	arrived := false
+	for i := 0; i < 40 && !arrived; i++ {
+		sim.stepLocked()
+		d := a.Status.Drive
+		if dv := math.Abs(d.Linear - prevV); dv > cfg.MaxLinearAccel*tick+1e-9 {
+			t.Fatalf("틱 %d: 선속도 변화 %.3f가 가속 한계를 넘음", i, dv)
+		}
+		// 각속도는 틱 안에서 0부터 최대 각가속도로 올렸다 내릴 수 있는 만큼만 헤딩을 바꾼다.
+		maxTurn := cfg.MaxAngularAccel * tick * tick
+		if dth := math.Abs(wrapAngle(a.Status.Position.Angle - prevAngle)); dth > maxTurn {
+			t.Fatalf("틱 %d: 헤딩이 %.2f rad 튐 (한계 %.2f)", i, dth, maxTurn)
+		}
+		if left, right := math.Abs(d.LeftWheel), math.Abs(d.RightWheel); left > cfg.MaxWheelSpeed+1e-9 || right > cfg.MaxWheelSpeed+1e-9 {
+			t.Fatalf("틱 %d: 바퀴 속도 한계 초과 %+v", i, d)
+		}
+		prevAngle, prevV = a.Status.Position.Angle, d.Linear
+		arrived = a.moveTarget == nil
+	}
+	if !arrived {
+		t.Fatalf("목표에 도착해야 함: %+v", a.Status.Position)
+	}
+	for i := 0; i < 3; i++ {
+		sim.stepLocked()
+	}
+	if d := a.Status.Drive; d.Linear != 0 || d.Angular != 0 {
+		t.Fatalf("도착 후에는 감속해 멈춰야 함: %+v", d)
+	}
+}
+
+func TestKinematics_MotorControlWheelInputs(t *testing.T) {
+	sim := newCommandSimulator(t)
+	a := sim.agvs[1]
+	start := a.Status.Position
+
+	// 양 바퀴를 반대로 돌리면 제자리 회전한다.
+	sim.HandleWebCommand(models.WebSocketMessage{
+		Type:  models.MessageTypeMotorControl,
+		Data:  models.MotorControl{LeftSpeed: -0.5, RightSpeed: 0.5, Duration: 1000},
+		AGVID: "sion-002",
+	})
+	sim.stepLocked()
+	sim.stepLocked()
+	if p := a.Status.Position; p.X != start.X || p.Y != start.Y || p.Angle <= start.Angle {
+		t.Fatalf("제자리 반시계 회전이어야 함: %+v -> %+v", start, p)
+	}
+	if a.motor != nil {
+		t.Fatal("Duration이 지나면 바퀴 입력이 지워져야 함")
+	}
+	sim.stepLocked()
+	if d := a.Status.Drive; d != (models.DriveState{}) {
+		t.Fatalf("입력이 끝나면 감속해 멈춰야 함: %+v", d)
+	}
+
+	// 같은 속도면 현재 헤딩으로 직진하고, 가속 한계 때문에 첫 틱은 목표 속도에 못 미친다.
+	heading := a.Status.Position.Angle
+	sim.HandleWebCommand(models.WebSocketMessage{
+		Type:  models.MessageTypeMotorControl,
+		Data:  models.MotorControl{LeftSpeed: 2, RightSpeed: 2, Duration: 1500},
+		AGVID: "sion-002",
+	})
+	from := a.Status.Position
+	sim.stepLocked()
+	if v := a.Status.Drive.Linear; v >= 2 || v <= 0 {
+		t.Fatalf("첫 틱은 가속 중이어야 함: v=%.2f", v)
+	}
+	p := a.Status.Position
+	if got := math.Atan2(p.Y-from.Y, p.X-from.X); math.Abs(wrapAngle(got-heading)) > 1e-6 {
+		t.Fatalf("직진 방향 %.3f, 헤딩 %.3f", got, heading)
+	}
+	if a.Status.Mode != models.ModeManual || a.Status.State != models.StateMoving {
+		t.Fatalf("바퀴 입력은 수동 주행: mode=%s state=%s", a.Status.Mode, a.Status.State)
+	}
+
+	// 비상 정지는 즉시 세우고 이후 바퀴 입력을 무시한다.
+	sim.HandleWebCommand(models.WebSocketMessage{Type: models.MessageTypeEmergencyStop})
+	sim.HandleWebCommand(models.WebSocketMessage{
+		Type:  models.MessageTypeMotorControl,
+		Data:  models.MotorControl{LeftSpeed: 1, RightSpeed: 1},
+		AGVID: "sion-002",
+	})
+	frozen := a.Status.Position
+	sim.stepLocked()
+	if a.motor != nil || a.Status.Position != frozen || a.Status.Drive != (models.DriveState{}) {
+		t.Fatalf("비상 정지 후 움직이면 안 됨: %+v -> %+v", frozen, a.Status.Position)
+	}
+}
+
+func TestKinematics_RespectsTurnRadiusDuringAutoRun(t *testing.T) {
+	sim := NewAGVSimulator(nil)
+	sim.LogToDB = false
+	if err := sim.Reseed(11); err != nil {
+		t.Fatal(err)
+	}
+	cfg := sim.KinematicsConfig()
+	for i := 0; i < 200; i++ {
+		sim.update(false)
+		d := sim.agvs[0].Status.Drive
+		if d.Linear != 0 && math.Abs(d.Angular) > math.Abs(d.Linear)/cfg.MinTurnRadius+1e-9 {
+			t.Fatalf("틱 %d: 회전 반경 %.3f < 최소 %.3f", i, math.Abs(d.Linear/d.Angular), cfg.MinTurnRadius)
+		}
+	}
+	if sim.GetStats().TotalDistance == 0 {
+		t.Fatal("auto 모드에서 주행해야 함")
+	}
+}
+
+func TestSetKinematicsConfig_Validates(t *testing.T) {
+	sim := NewAGVSimulator(nil)
+	bad := DefaultKinematicsConfig()
+	bad.WheelBase = 0
+	if err := sim.SetKinematicsConfig(bad); err == nil {
+		t.Fatal("wheel_base 0은 거부돼야 함")
+	}
+	bad = DefaultKinematicsConfig()
+	bad.MinTurnRadius = -1
+	if err := sim.SetKinematicsConfig(bad); err == nil {
+		t.Fatal("음수 회전 반경은 거부돼야 함")
+	}
+	good := DefaultKinematicsConfig()
+	good.MaxLinearAccel = 5
+	if err := sim.SetKinematicsConfig(good); err != nil || sim.KinematicsConfig() != good {
+		t.Fatalf("유효한 설정이 반영돼야 함: %v %+v", err, sim.KinematicsConfig())
+	}
+}
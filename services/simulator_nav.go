@@ -0,0 +1,260 @@
+package services
+
+import (
+	"log"
+	"math"
+	"sion-backend/algorithms"
+	"sion-backend/models"
+	"time"
+)
+
+// 시뮬레이터 내비게이션: 장애물 그리드 구성, A* 경로 계획, 경로 추종, 충돌 판정을 담당한다.
+// 모든 함수는 sim.mu를 잡은 상태에서 호출돼야 한다 (Locked 접미사).
+
+// waypointReachedDist는 웨이포인트 도달로 간주하는 거리(셀 단위).
+const waypointReachedDist = 0.2
+
+// rebuildGridLocked는 현재 Obstacles로 A* 그리드를 다시 만든다.
+// 맵 크기나 장애물이 바뀌면 반드시 호출해야 하며, 기존 경로는 무효화된다.
+func (sim *AGVSimulator) rebuildGridLocked() {
+	grid := algorithms.NewGrid(int(math.Ceil(sim.MapWidth)), int(math.Ceil(sim.MapHeight)))
+	// AGV는 원호로 주행하므로 장애물 모서리를 대각선으로 스치는 경로는 실제로 지나갈 수 없다.
+	grid.NoCornerCutting = true
+	for _, ob := range sim.Obstacles {
+		size := ob.Size
+		if size < 1 {
+			size = 1
+		}
+		for dy := 0; dy < size; dy++ {
+			for dx := 0; dx < size; dx++ {
+				grid.AddObstacle(ob.Position.Col+dx, ob.Position.Row+dy)
+			}
+		}
+	}
+	sim.grid = grid
+	sim.dockPaths = nil
+	for _, a := range sim.agvs {
+		a.clearPath()
+	}
+}
+
+// cellOf는 연속 좌표를 그리드 셀로 변환한다. 맵 경계(x == MapWidth)는 마지막 셀로 취급한다.
+func (sim *AGVSimulator) cellOf(x, y float64) (int, int) {
+	cx, cy := int(math.Floor(x)), int(math.Floor(y))
+	if cx >= sim.grid.Width {
+		cx = sim.grid.Width - 1
+	}
+	if cy >= sim.grid.Height {
+		cy = sim.grid.Height - 1
+	}
+	if cx < 0 {
+		cx = 0
+	}
+	if cy < 0 {
+		cy = 0
+	}
+	return cx, cy
+}
+
+func (sim *AGVSimulator) isBlockedLocked(x, y float64) bool {
+	cx, cy := sim.cellOf(x, y)
+	return sim.grid.IsObstacle(cx, cy)
+}
+
+// nearestFreeCellLocked는 (cx, cy)가 장애물이면 가장 가까운 통과 가능 셀을 찾는다.
+// 장애물 위에 선 적을 추격할 때 목표 셀이 막혀 A*가 실패하는 것을 막는다.
+func (sim *AGVSimulator) nearestFreeCellLocked(cx, cy int) (int, int, bool) {
+	if sim.grid.IsValid(cx, cy) {
+		return cx, cy, true
+	}
+	maxR := sim.grid.Width
+	if sim.grid.Height > maxR {
+		maxR = sim.grid.Height
+	}
+	for r := 1; r < maxR; r++ {
+		bestD := math.Inf(1)
+		bx, by, found := 0, 0, false
+		for dy := -r; dy <= r; dy++ {
+			for dx := -r; dx <= r; dx++ {
+				if abs(dx) != r && abs(dy) != r {
+					continue
+				}
+				nx, ny := cx+dx, cy+dy
+				if !sim.grid.IsValid(nx, ny) {
+					continue
+				}
+				d := float64(dx*dx + dy*dy)
+				if d < bestD {
+					bestD, bx, by, found = d, nx, ny, true
+				}
+			}
+		}
+		if found {
+			return bx, by, true
+		}
+	}
+	return 0, 0, false
+}
+
+func (a *simAGV) clearPath() {
+	a.path = nil
+	a.pathIdx = 0
+	a.hasPathGoal = false
+	a.Status.CurrentPath = nil
+}
+
+// planPathLocked는 a의 현재 위치에서 goal 셀까지 A* 경로를 계획하고 path_update를 예약한다.
+// 경로를 찾지 못하면 false를 반환하고 기존 경로를 비운다.
+func (sim *AGVSimulator) planPathLocked(a *simAGV, goalX, goalY int) bool {
+	sx, sy := sim.cellOf(a.Status.Position.X, a.Status.Position.Y)
+	a.clearPath()
+	a.pathGoal = [2]int{goalX, goalY}
+	a.hasPathGoal = true
+
+	path := sim.grid.FindPath(
+		algorithms.Point{X: float64(sx), Y: float64(sy)},
+		algorithms.Point{X: float64(goalX), Y: float64(goalY)},
+	)
+	if path == nil {
+		log.Printf("[WARN] %s 경로 탐색 실패: (%d,%d) -> (%d,%d)", a.Status.ID, sx, sy, goalX, goalY)
+		return false
+	}
+
+	// 셀 중심을 웨이포인트로 사용. 첫 점은 현재 셀이므로 건너뛴다.
+	a.path = path
+	a.pathIdx = 1
+
+	points := make([]models.PositionData, len(path))
+	length := 0.0
+	for i, p := range path {
+		points[i] = models.PositionData{X: p.X + 0.5, Y: p.Y + 0.5}
+		if i > 0 {
+			length += math.Hypot(p.X-path[i-1].X, p.Y-path[i-1].Y)
+		}
+	}
+	pathData := &models.PathData{
+		Points:    points,
+		Length:    length,
+		Algorithm: "astar",
+		CreatedAt: time.Now(),
+	}
+	a.Status.CurrentPath = pathData
+	sim.pending = append(sim.pending, models.WebSocketMessage{
+		Type:      models.MessageTypePathUpdate,
+		Data:      *pathData,
+		Timestamp: time.Now().UnixMilli(),
+		AGVID:     a.Status.ID,
+	})
+	return true
+}
+
+// moveTowardsLocked는 a를 (targetX, targetY)까지 A* 경로를 따라 한 틱만큼 주행시킨다.
+// 목표 셀이 바뀌었거나 경로가 없으면 재계획한다. 도착했으면 주행하지 않아 틱 끝에 감속·정지한다.
+func (sim *AGVSimulator) moveTowardsLocked(a *simAGV, targetX, targetY float64) {
+	if a.distanceTo(targetX, targetY) <= arriveDist {
+		return
+	}
+
+	gx, gy := sim.cellOf(targetX, targetY)
+	gx, gy, ok := sim.nearestFreeCellLocked(gx, gy)
+	if !ok {
+		return
+	}
+	if !a.hasPathGoal || a.pathGoal != [2]int{gx, gy} || a.path == nil {
+		if !sim.planPathLocked(a, gx, gy) {
+			return
+		}
+	}
+
+	sim.driveLocked(a, func() (float64, float64) {
+		goalDist := a.distanceTo(targetX, targetY)
+		if goalDist <= arriveDist {
+			return 0, 0
+		}
+		for a.pathIdx < len(a.path) && a.distanceTo(a.path[a.pathIdx].X+0.5, a.path[a.pathIdx].Y+0.5) <= waypointReachedDist {
+			a.pathIdx++
+		}
+		// 경로 끝에 도달했으면 목표 지점으로 직접 접근 (같은 셀 안이므로 장애물 없음).
+		wx, wy := targetX, targetY
+		if a.pathIdx < len(a.path) {
+			wp := a.path[a.pathIdx]
+			wx, wy = wp.X+0.5, wp.Y+0.5
+		}
+		return sim.seekLocked(a, wx, wy, a.Status.Speed, goalDist)
+	})
+}
+
+// tryMoveLocked는 a를 (nx, ny)로 이동시키려 시도한다.
+// 장애물 셀이거나 다른 AGV와 agvCollisionRadius 안으로 더 파고들면 충돌로 처리하고 제자리에 머문다.
+// (이미 겹친 상태에서 멀어지는 이동은 허용해 서로 갇히지 않게 한다.)
+// AGV끼리 부딪히면 양쪽 모두 충돌 횟수가 오른다. 같은 AGV나 장애물에 막힌 채 다시 밀어붙이는 것(충전소 대기,
+// 벽을 향한 바퀴 입력 등)은 새 충돌로 세지 않는다.
+func (sim *AGVSimulator) tryMoveLocked(a *simAGV, nx, ny float64) bool {
+	nx = clamp(nx, 0, sim.MapWidth)
+	ny = clamp(ny, 0, sim.MapHeight)
+	if sim.isBlockedLocked(nx, ny) {
+		a.clearPath()
+		if a.blockedByWall {
+			return false
+		}
+		a.blockedByWall = true
+		sim.recordCollisionLocked(a, "")
+		log.Printf("[WARN] %s 장애물 충돌: (%.1f, %.1f) 누적 %d회", a.Status.ID, nx, ny, a.Stats.Collisions)
+		return false
+	}
+	for _, other := range sim.agvs {
+		if other == a {
+			continue
+		}
+		ox, oy := other.Status.Position.X, other.Status.Position.Y
+		newDist := math.Hypot(ox-nx, oy-ny)
+		if newDist < agvCollisionRadius && newDist < a.distanceTo(ox, oy) {
+			if a.blockedBy == other {
+				return false
+			}
+			a.blockedBy = other
+			sim.recordCollisionLocked(a, other.Status.ID)
+			sim.recordCollisionLocked(other, a.Status.ID)
+			a.clearPath()
+			log.Printf("[WARN] AGV 충돌: %s ↔ %s", a.Status.ID, other.Status.ID)
+			return false
+		}
+	}
+	a.blockedBy, a.blockedByWall = nil, false
+	a.Stats.TotalDistance += a.distanceTo(nx, ny)
+	a.Status.Position.X = nx
+	a.Status.Position.Y = ny
+	return true
+}
+
+// recordCollisionLocked는 a의 충돌을 세고 collision 보고를 예약한다. with는 부딪힌 AGV ID(장애물이면 빈 문자열).
+// 가상 AGV 모드에서는 이 보고가 실제 AGV의 collision처럼 서버로 올라가 세션 통계에 쌓인다.
+func (sim *AGVSimulator) recordCollisionLocked(a *simAGV, with string) {
+	a.Stats.Collisions++
+	if sim.stats != nil {
+		sim.stats.RecordCollision(a.Status.ID)
+	}
+	sim.pending = append(sim.pending, models.WebSocketMessage{
+		Type:      models.MessageTypeCollision,
+		Data:      models.CollisionReport{X: a.Status.Position.X, Y: a.Status.Position.Y, With: with},
+		Timestamp: time.Now().UnixMilli(),
+		AGVID:     a.Status.ID,
+	})
+}
+
+func clamp(v, lo, hi float64) float64 {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,453 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"sion-backend/models"
+	"sort"
+	"time"
+)
+
+// 정지 상태의 시뮬레이터 재구성: 월드 리셋, 적 재배치, AGV 배치·상태 변경, 월드 파라미터 변경,
+// 월드 스냅샷 저장/복원. 틱 도중 월드가 바뀌지 않도록 스냅샷 저장을 뺀 모든 변경은 실행 중이면
+// ErrSimulatorRunning을 반환한다 (일시정지도 실행 중으로 본다).
+
+var (
+	ErrAGVNotFound      = errors.New("AGV를 찾을 수 없습니다")
+	ErrSnapshotNotFound = errors.New("스냅샷을 찾을 수 없습니다")
+)
+
+const (
+	// maxSnapshots는 보관할 수 있는 스냅샷 수. 같은 이름으로 저장하면 덮어쓴다.
+	maxSnapshots = 16
+	// minMapSize, maxMapSize는 월드 파라미터로 정할 수 있는 맵 한 변의 범위(셀).
+	minMapSize = 10
+	maxMapSize = 200
+	// 틱 간격 범위(ms). 서브스텝(50ms)보다 짧으면 구동 모델이 한 틱을 나눌 수 없다.
+	minUpdateIntervalMs = 50
+	maxUpdateIntervalMs = 5000
+)
+
+var snapshotNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// WorldParams는 정지 상태에서 바꿀 수 있는 월드 파라미터.
+type WorldParams struct {
+	MapWidth         float64 `json:"map_width"`
+	MapHeight        float64 `json:"map_height"`
+	AGVCount         int     `json:"agv_count"`
+	EnemyCount       int     `json:"enemy_count"`
+	UpdateIntervalMs int     `json:"update_interval_ms"`
+}
+
+// AGVUpdate는 AGV 한 대의 배치·상태 변경. nil 필드는 바꾸지 않는다.
+type AGVUpdate struct {
+	X       *float64 `json:"x"`
+	Y       *float64 `json:"y"`
+	Angle   *float64 `json:"angle"`
+	Battery *int     `json:"battery"`
+	Mode    *string  `json:"mode"`
+}
+
+// SnapshotInfo는 저장된 스냅샷 요약.
+type SnapshotInfo struct {
+	Name       string    `json:"name"`
+	Seed       int64     `json:"seed"`
+	Scenario   string    `json:"scenario"`
+	ElapsedSec float64   `json:"elapsed_sec"`
+	AGVs       int       `json:"agvs"`
+	Enemies    int       `json:"enemies"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// worldSnapshot은 한 시점의 월드 전체 사본. 난수원은 seed와 뽑은 횟수로 저장한다.
+// 복원 후 같은 입력이면 저장 시점 이후와 틱 단위로 같은 진행을 재현한다.
+type worldSnapshot struct {
+	info SnapshotInfo
+
+	mapWidth, mapHeight  float64
+	updateInterval       time.Duration
+	agvCount, enemyCount int
+
+	agvs         []*simAGV
+	enemies      []models.Enemy
+	obstacles    []models.Obstacle
+	behaviors    map[string]EnemyBehavior
+	mission      *BatteryMission
+	targeting    *TargetSelector
+	sensorConfig models.SensorConfig
+	kinematics   models.KinematicsConfig
+
+	seed                  int64
+	rngDraws, sensorDraws uint64
+
+	scenario string
+	loaded   *models.Scenario
+	victory  models.VictoryCondition
+	elapsed  time.Duration
+	outcome  string
+}
+
+// ResetWorld는 월드를 처음 상태로 되돌린다. 시나리오를 로드했으면 그 시나리오를, 아니면 랜덤 월드를 다시 만든다.
+// seed가 nil이면 현재 seed를 그대로 써서 같은 월드가 나온다.
+func (sim *AGVSimulator) ResetWorld(seed *int64) error {
+	if sim.IsRunning() {
+		return ErrSimulatorRunning
+	}
+	sim.endStatsSessions()
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	s := sim.seed
+	if seed != nil {
+		s = *seed
+	}
+	if sim.loaded != nil {
+		sc := *sim.loaded
+		sc.Seed = &s
+		sim.loadScenarioLocked(sc)
+	} else {
+		sim.resetWorldLocked(s)
+	}
+	log.Printf("[INFO] 시뮬레이터 월드 리셋 (seed=%d)", s)
+	return nil
+}
+
+// RespawnEnemies는 장애물과 AGV 위치는 그대로 두고 적 count명을 새로 배치한 뒤 판을 새로 시작한다.
+// 적 위치·행동은 월드 rng에서 이어서 뽑으므로 같은 seed와 같은 호출 순서면 결과가 같다.
+func (sim *AGVSimulator) RespawnEnemies(count int) error {
+	if count < 0 || count > maxSimEnemies {
+		return fmt.Errorf("적 수는 0~%d 사이여야 합니다: %d", maxSimEnemies, count)
+	}
+	if sim.IsRunning() {
+		return ErrSimulatorRunning
+	}
+	sim.endStatsSessions()
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	sim.EnemyCount = count
+	sim.Enemies = generateRandomEnemies(sim.rng, count, sim.MapWidth, sim.MapHeight)
+	sim.enemyBehaviors = make(map[string]EnemyBehavior, count)
+	for _, e := range sim.Enemies {
+		sim.enemyBehaviors[e.ID] = randomEnemyBehavior(sim.rng, e, sim.MapWidth, sim.MapHeight)
+	}
+	for _, a := range sim.agvs {
+		a.Status.TargetEnemy = nil
+		a.Status.DetectedEnemies = nil
+	}
+	sim.resetRunLocked(sim.scenario, sim.victory)
+	log.Printf("[INFO] 적 재배치: %d명", count)
+	return nil
+}
+
+// UpdateAGV는 AGV 한 대를 옮기거나 배터리·모드를 바꾼다. 위치를 바꾸면 진행 중이던 주행(경로, 이동 목표,
+// 바퀴 입력, 속도)을 모두 버리고 정지 상태로 둔다. 맵 밖, 장애물 셀, 다른 AGV와 겹치는 위치는 거부한다.
+func (sim *AGVSimulator) UpdateAGV(id string, u AGVUpdate) error {
+	if u.Battery != nil && (*u.Battery < 0 || *u.Battery > 100) {
+		return fmt.Errorf("battery는 0~100 사이여야 합니다: %d", *u.Battery)
+	}
+	if u.Mode != nil && *u.Mode != models.ModeAuto && *u.Mode != models.ModeManual {
+		return fmt.Errorf("알 수 없는 모드: %q", *u.Mode)
+	}
+	if sim.IsRunning() {
+		return ErrSimulatorRunning
+	}
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+
+	var a *simAGV
+	for _, cand := range sim.agvs {
+		if cand.Status.ID == id {
+			a = cand
+			break
+		}
+	}
+	if a == nil {
+		return fmt.Errorf("%w: %s", ErrAGVNotFound, id)
+	}
+
+	x, y := a.Status.Position.X, a.Status.Position.Y
+	if u.X != nil {
+		x = *u.X
+	}
+	if u.Y != nil {
+		y = *u.Y
+	}
+	if x < 0 || y < 0 || x > sim.MapWidth || y > sim.MapHeight {
+		return fmt.Errorf("맵 밖 위치입니다: (%.1f, %.1f)", x, y)
+	}
+	if sim.isBlockedLocked(x, y) {
+		return fmt.Errorf("장애물 위치입니다: (%.1f, %.1f)", x, y)
+	}
+	for _, other := range sim.agvs {
+		if other != a && other.distanceTo(x, y) < agvCollisionRadius {
+			return fmt.Errorf("%s와 겹치는 위치입니다: (%.1f, %.1f)", other.Status.ID, x, y)
+		}
+	}
+
+	a.Status.Position.X, a.Status.Position.Y = x, y
+	if u.Angle != nil {
+		a.Status.Position.Angle = wrapAngle(*u.Angle)
+	}
+	if u.Battery != nil {
+		a.Status.Battery = *u.Battery
+		a.chargeAcc, a.drainAcc = 0, 0
+	}
+	if u.Mode != nil {
+		a.Status.Mode = *u.Mode
+	}
+	a.Status.State = models.StateIdle
+	a.Status.Speed = 0
+	a.Status.Drive = models.DriveState{}
+	a.Status.TargetEnemy = nil
+	a.moveTarget = nil
+	a.motor = nil
+	a.realign = false
+	a.blockedBy, a.blockedByWall = nil, false
+	a.walking, a.wasWalking = false, false
+	a.clearPath()
+	// 순간 이동을 IMU가 가속으로 읽지 않도록 센서 이력도 새로 시작한다.
+	a.sensors = sensorState{}
+	sim.mission.Forget(id)
+	sim.targeting.Clear(id)
+	log.Printf("[INFO] %s 배치 변경: (%.1f, %.1f) angle=%.2f battery=%d mode=%s",
+		id, x, y, a.Status.Position.Angle, a.Status.Battery, a.Status.Mode)
+	return nil
+}
+
+// WorldParams는 현재 월드 파라미터를 반환한다.
+func (sim *AGVSimulator) WorldParams() WorldParams {
+	sim.mu.RLock()
+	defer sim.mu.RUnlock()
+	return WorldParams{
+		MapWidth:         sim.MapWidth,
+		MapHeight:        sim.MapHeight,
+		AGVCount:         sim.AGVCount,
+		EnemyCount:       sim.EnemyCount,
+		UpdateIntervalMs: int(sim.UpdateInterval / time.Millisecond),
+	}
+}
+
+// ValidateWorldParams는 파라미터 범위를 검사한다. 기본 월드는 AGV를 (5,5)부터 x축으로 2칸씩 띄워 세우므로
+// 맵 너비가 그만큼은 돼야 한다.
+func ValidateWorldParams(p WorldParams) error {
+	for _, side := range []struct {
+		name string
+		v    float64
+	}{{"map_width", p.MapWidth}, {"map_height", p.MapHeight}} {
+		if side.v < minMapSize || side.v > maxMapSize || side.v != math.Trunc(side.v) {
+			return fmt.Errorf("%s는 %d~%d 사이 정수여야 합니다: %g", side.name, minMapSize, maxMapSize, side.v)
+		}
+	}
+	if p.AGVCount < 1 || p.AGVCount > maxSimAGVs {
+		return fmt.Errorf("AGV 수는 1~%d 사이여야 합니다: %d", maxSimAGVs, p.AGVCount)
+	}
+	if lastX := 5 + 2*float64(p.AGVCount-1); lastX >= p.MapWidth {
+		return fmt.Errorf("map_width %g에는 AGV %d대를 배치할 수 없습니다", p.MapWidth, p.AGVCount)
+	}
+	if p.EnemyCount < 0 || p.EnemyCount > maxSimEnemies {
+		return fmt.Errorf("적 수는 0~%d 사이여야 합니다: %d", maxSimEnemies, p.EnemyCount)
+	}
+	if p.UpdateIntervalMs < minUpdateIntervalMs || p.UpdateIntervalMs > maxUpdateIntervalMs {
+		return fmt.Errorf("update_interval_ms는 %d~%d 사이여야 합니다: %d", minUpdateIntervalMs, maxUpdateIntervalMs, p.UpdateIntervalMs)
+	}
+	return nil
+}
+
+// SetWorldParams는 월드 파라미터를 바꾼다. 맵 크기·AGV 수·적 수가 바뀌면 현재 seed로 랜덤 월드를 다시 만들고
+// (로드한 시나리오는 버린다), 틱 간격만 바뀌면 월드는 그대로 둔다. 실행 중이면 ErrSimulatorRunning.
+func (sim *AGVSimulator) SetWorldParams(p WorldParams) error {
+	if err := ValidateWorldParams(p); err != nil {
+		return err
+	}
+	if sim.IsRunning() {
+		return ErrSimulatorRunning
+	}
+	sim.mu.RLock()
+	regenerate := p.MapWidth != sim.MapWidth || p.MapHeight != sim.MapHeight ||
+		p.AGVCount != sim.AGVCount || p.EnemyCount != sim.EnemyCount
+	sim.mu.RUnlock()
+	if regenerate {
+		sim.endStatsSessions()
+	}
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	sim.UpdateInterval = time.Duration(p.UpdateIntervalMs) * time.Millisecond
+	if regenerate {
+		sim.MapWidth, sim.MapHeight = p.MapWidth, p.MapHeight
+		sim.AGVCount, sim.EnemyCount = p.AGVCount, p.EnemyCount
+		sim.resetWorldLocked(sim.seed)
+	}
+	log.Printf("[INFO] 월드 파라미터 변경: 맵 %.0fx%.0f, AGV %d, 적 %d, 틱 %dms",
+		p.MapWidth, p.MapHeight, p.AGVCount, p.EnemyCount, p.UpdateIntervalMs)
+	return nil
+}
+
+// SaveSnapshot은 현재 월드를 name으로 저장한다. 읽기만 하므로 실행 중에도 저장할 수 있다 (틱 사이 시점).
+func (sim *AGVSimulator) SaveSnapshot(name string) (SnapshotInfo, error) {
+	if !snapshotNamePattern.MatchString(name) {
+		return SnapshotInfo{}, fmt.Errorf("스냅샷 이름은 영문·숫자·-·_ 1~64자여야 합니다: %q", name)
+	}
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	if _, exists := sim.snapshots[name]; !exists && len(sim.snapshots) >= maxSnapshots {
+		return SnapshotInfo{}, fmt.Errorf("스냅샷은 최대 %d개까지 저장할 수 있습니다", maxSnapshots)
+	}
+
+	snap := &worldSnapshot{
+		info: SnapshotInfo{
+			Name:       name,
+			Seed:       sim.seed,
+			Scenario:   sim.scenario,
+			ElapsedSec: sim.elapsed.Seconds(),
+			AGVs:       len(sim.agvs),
+			Enemies:    len(sim.Enemies),
+			CreatedAt:  time.Now(),
+		},
+		mapWidth:       sim.MapWidth,
+		mapHeight:      sim.MapHeight,
+		updateInterval: sim.UpdateInterval,
+		agvCount:       sim.AGVCount,
+		enemyCount:     sim.EnemyCount,
+		agvs:           cloneSimAGVs(sim.agvs),
+		enemies:        append([]models.Enemy(nil), sim.Enemies...),
+		obstacles:      append([]models.Obstacle(nil), sim.Obstacles...),
+		behaviors:      cloneEnemyBehaviors(sim.enemyBehaviors),
+		mission:        sim.mission.clone(),
+		targeting:      sim.targeting.clone(),
+		sensorConfig:   sim.sensorConfig,
+		kinematics:     sim.kinematics,
+		seed:           sim.seed,
+		rngDraws:       sim.rngSrc.draws,
+		sensorDraws:    sim.sensorSrc.draws,
+		scenario:       sim.scenario,
+		loaded:         sim.loaded,
+		victory:        sim.victory,
+		elapsed:        sim.elapsed,
+		outcome:        sim.outcome,
+	}
+	if sim.snapshots == nil {
+		sim.snapshots = make(map[string]*worldSnapshot)
+	}
+	sim.snapshots[name] = snap
+	log.Printf("[INFO] 월드 스냅샷 저장: %s (경과 %.1fs)", name, snap.info.ElapsedSec)
+	return snap.info, nil
+}
+
+// RestoreSnapshot은 저장해 둔 월드로 되돌린다. 스냅샷은 그대로 남아 여러 번 복원할 수 있다.
+// 실행 중이면 ErrSimulatorRunning.
+func (sim *AGVSimulator) RestoreSnapshot(name string) error {
+	if sim.IsRunning() {
+		return ErrSimulatorRunning
+	}
+	sim.endStatsSessions()
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	snap, ok := sim.snapshots[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrSnapshotNotFound, name)
+	}
+
+	sim.seedLocked(snap.seed)
+	sim.rngSrc.skipTo(snap.rngDraws)
+	sim.sensorSrc.skipTo(snap.sensorDraws)
+
+	sim.MapWidth, sim.MapHeight = snap.mapWidth, snap.mapHeight
+	sim.UpdateInterval = snap.updateInterval
+	sim.AGVCount, sim.EnemyCount = snap.agvCount, snap.enemyCount
+	sim.Enemies = append([]models.Enemy(nil), snap.enemies...)
+	sim.Obstacles = append([]models.Obstacle(nil), snap.obstacles...)
+	sim.enemyBehaviors = cloneEnemyBehaviors(snap.behaviors)
+	sim.mission = snap.mission.clone()
+	sim.targeting = snap.targeting.clone()
+	sim.sensorConfig = snap.sensorConfig
+	sim.kinematics = snap.kinematics
+	sim.scenario, sim.loaded, sim.victory = snap.scenario, snap.loaded, snap.victory
+	sim.elapsed, sim.outcome = snap.elapsed, snap.outcome
+	sim.pending = nil
+	// 그리드를 다시 만들면 AGV 경로가 지워지므로, 경로까지 복원하려면 AGV를 나중에 넣는다.
+	sim.agvs = nil
+	sim.rebuildGridLocked()
+	sim.agvs = cloneSimAGVs(snap.agvs)
+	log.Printf("[INFO] 월드 스냅샷 복원: %s (경과 %.1fs)", name, snap.info.ElapsedSec)
+	return nil
+}
+
+// Snapshots는 저장된 스냅샷 요약을 이름순으로 반환한다.
+func (sim *AGVSimulator) Snapshots() []SnapshotInfo {
+	sim.mu.RLock()
+	defer sim.mu.RUnlock()
+	out := make([]SnapshotInfo, 0, len(sim.snapshots))
+	for _, snap := range sim.snapshots {
+		out = append(out, snap.info)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// DeleteSnapshot은 스냅샷을 지운다.
+func (sim *AGVSimulator) DeleteSnapshot(name string) error {
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	if _, ok := sim.snapshots[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrSnapshotNotFound, name)
+	}
+	delete(sim.snapshots, name)
+	return nil
+}
+
+// cloneSimAGVs는 AGV 목록을 깊은 복사한다. blockedBy는 새 목록의 같은 AGV를 가리키도록 다시 잇는다.
+func cloneSimAGVs(agvs []*simAGV) []*simAGV {
+	out := make([]*simAGV, len(agvs))
+	index := make(map[*simAGV]int, len(agvs))
+	for i, a := range agvs {
+		index[a] = i
+		c := *a
+		c.Status = cloneAGVStatus(a.Status)
+		c.path = append(c.path[:0:0], a.path...)
+		if a.moveTarget != nil {
+			t := *a.moveTarget
+			c.moveTarget = &t
+		}
+		if a.motor != nil {
+			m := *a.motor
+			c.motor = &m
+		}
+		c.sensors.history = append([]models.SensorData(nil), a.sensors.history...)
+		out[i] = &c
+	}
+	for i, a := range agvs {
+		if j, ok := index[a.blockedBy]; ok {
+			out[i].blockedBy = out[j]
+		}
+	}
+	return out
+}
+
+func cloneAGVStatus(s *models.AGVStatus) *models.AGVStatus {
+	c := *s
+	if s.CurrentPath != nil {
+		p := *s.CurrentPath
+		p.Points = append([]models.PositionData(nil), s.CurrentPath.Points...)
+		c.CurrentPath = &p
+	}
+	if s.TargetPos != nil {
+		t := *s.TargetPos
+		c.TargetPos = &t
+	}
+	if s.TargetEnemy != nil {
+		e := *s.TargetEnemy
+		c.TargetEnemy = &e
+	}
+	c.DetectedEnemies = append([]models.Enemy(nil), s.DetectedEnemies...)
+	return &c
+}
+
+func cloneEnemyBehaviors(behaviors map[string]EnemyBehavior) map[string]EnemyBehavior {
+	out := make(map[string]EnemyBehavior, len(behaviors))
+	for id, b := range behaviors {
+		out[id] = cloneEnemyBehavior(b)
+	}
+	return out
+}
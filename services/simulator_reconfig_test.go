@@ -0,0 +1,229 @@
+package services
+
+import (
+	"errors"
+	"sion-backend/models"
+	"testing"
+	"time"
+)
+
+type worldFingerprint struct {
+	agvs    []models.PositionData
+	battery []int
+	enemies []models.Enemy
+	elapsed time.Duration
+}
+
+func fingerprint(sim *AGVSimulator) worldFingerprint {
+	var fp worldFingerprint
+	for _, a := range sim.agvs {
+		fp.agvs = append(fp.agvs, a.Status.Position)
+		fp.battery = append(fp.battery, a.Status.Battery)
+	}
+	fp.enemies = append(fp.enemies, sim.Enemies...)
+	fp.elapsed = sim.elapsed
+	return fp
+}
+
+func sameFingerprint(a, b worldFingerprint) bool {
+	if a.elapsed != b.elapsed || len(a.agvs) != len(b.agvs) || len(a.enemies) != len(b.enemies) {
+		return false
+	}
+	for i := range a.agvs {
+		if a.agvs[i] != b.agvs[i] || a.battery[i] != b.battery[i] {
+			return false
+		}
+	}
+	for i := range a.enemies {
+		if a.enemies[i].Position != b.enemies[i].Position || a.enemies[i].HP != b.enemies[i].HP || a.enemies[i].State != b.enemies[i].State {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSnapshot_RestoreReplaysIdentically(t *testing.T) {
+	sim := newReconfigSimulator(t, 21)
+	if err := sim.SetAGVCount(2); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 30; i++ {
+		sim.update(false)
+	}
+	if _, err := sim.SaveSnapshot("mid"); err != nil {
+		t.Fatal(err)
+	}
+	saved := fingerprint(sim)
+	for i := 0; i < 60; i++ {
+		sim.update(false)
+	}
+	want := fingerprint(sim)
+
+	for round := 0; round < 2; round++ {
+		if err := sim.RestoreSnapshot("mid"); err != nil {
+			t.Fatal(err)
+		}
+		if !sameFingerprint(fingerprint(sim), saved) {
+			t.Fatalf("복원 %d: 저장 시점 상태로 돌아가야 함", round)
+		}
+		for i := 0; i < 60; i++ {
+			sim.update(false)
+		}
+		if !sameFingerprint(fingerprint(sim), want) {
+			t.Fatalf("복원 %d: 같은 진행을 재현해야 함\ngot  %+v\nwant %+v", round, fingerprint(sim).agvs, want.agvs)
+		}
+	}
+
+	if err := sim.RestoreSnapshot("nope"); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Fatalf("없는 스냅샷은 ErrSnapshotNotFound, got %v", err)
+	}
+	if _, err := sim.SaveSnapshot("../evil"); err == nil {
+		t.Fatal("경로 문자가 든 이름은 거부돼야 함")
+	}
+}
+
+func TestReconfig_RejectedWhileRunning(t *testing.T) {
+	sim := newReconfigSimulator(t, 3)
+	if _, err := sim.SaveSnapshot("s"); err != nil {
+		t.Fatal(err)
+	}
+	sim.Pause()
+	sim.Start()
+	defer sim.Stop()
+
+	x := 2.5
+	checks := map[string]error{
+		"reset":   sim.ResetWorld(nil),
+		"respawn": sim.RespawnEnemies(3),
+		"agv":     sim.UpdateAGV("sion-001", AGVUpdate{X: &x}),
+		"params":  sim.SetWorldParams(sim.WorldParams()),
+		"restore": sim.RestoreSnapshot("s"),
+	}
+	for name, err := range checks {
+		if !errors.Is(err, ErrSimulatorRunning) {
+			t.Errorf("%s: 실행 중에는 ErrSimulatorRunning, got %v", name, err)
+		}
+	}
+	// 저장은 읽기만 하므로 실행 중에도 된다.
+	if _, err := sim.SaveSnapshot("live"); err != nil {
+		t.Fatalf("실행 중 스냅샷 저장 실패: %v", err)
+	}
+}
+
+func TestResetWorld_ReloadsScenarioAndKeepsSeed(t *testing.T) {
+	sim := newReconfigSimulator(t, 5)
+	seed := int64(77)
+	sc := models.Scenario{
+		Name:      "reset-test",
+		MapWidth:  20,
+		MapHeight: 20,
+		Seed:      &seed,
+		AGV:       models.ScenarioAGV{X: 2, Y: 2},
+		Enemies:   []models.ScenarioEnemy{{ID: "e1", X: 15, Y: 15, HP: 50}},
+	}
+	if err := sim.LoadScenario(sc); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		sim.update(false)
+	}
+	if err := sim.ResetWorld(nil); err != nil {
+		t.Fatal(err)
+	}
+	if name, _ := sim.ScenarioInfo(); name != "reset-test" || sim.Seed() != seed {
+		t.Fatalf("시나리오를 같은 seed로 다시 로드해야 함: %q seed=%d", name, sim.Seed())
+	}
+	if p := sim.agvs[0].Status.Position; p.X != 2 || p.Y != 2 || sim.elapsed != 0 {
+		t.Fatalf("AGV가 시작 위치로 돌아가야 함: %+v elapsed=%v", p, sim.elapsed)
+	}
+
+	// 랜덤 월드는 같은 seed면 같은 적 배치가 나온다.
+	if err := sim.Reseed(9); err != nil {
+		t.Fatal(err)
+	}
+	before := append([]models.Enemy(nil), sim.Enemies...)
+	sim.update(false)
+	if err := sim.ResetWorld(nil); err != nil {
+		t.Fatal(err)
+	}
+	for i := range before {
+		if sim.Enemies[i].Position != before[i].Position {
+			t.Fatalf("리셋 후 적 %d 위치가 달라짐", i)
+		}
+	}
+}
+
+func TestRespawnEnemiesAndUpdateAGV(t *testing.T) {
+	sim := newReconfigSimulator(t, 4)
+	obstacles := append([]models.Obstacle(nil), sim.Obstacles...)
+	if err := sim.RespawnEnemies(8); err != nil {
+		t.Fatal(err)
+	}
+	if len(sim.Enemies) != 8 || len(sim.EnemyBehaviors()) != 8 || sim.WorldParams().EnemyCount != 8 {
+		t.Fatalf("적 8명이 행동과 함께 배치돼야 함: %d", len(sim.Enemies))
+	}
+	if len(sim.Obstacles) != len(obstacles) || sim.Obstacles[0] != obstacles[0] {
+		t.Fatal("적 재배치는 장애물을 바꾸면 안 됨")
+	}
+	if err := sim.RespawnEnemies(maxSimEnemies + 1); err == nil {
+		t.Fatal("상한을 넘는 적 수는 거부돼야 함")
+	}
+
+	a := sim.agvs[0]
+	a.Status.Drive = models.DriveState{Linear: 1}
+	a.moveTarget = &models.RealCoordinate{X: 9, Y: 9}
+	ob := sim.Obstacles[0].Position
+	ox, oy := float64(ob.Col)+0.5, float64(ob.Row)+0.5
+	if err := sim.UpdateAGV("sion-001", AGVUpdate{X: &ox, Y: &oy}); err == nil {
+		t.Fatal("장애물 위로 옮기면 거부돼야 함")
+	}
+	x, y, battery, mode := 1.5, 1.5, 42, models.ModeManual
+	for sim.isBlockedLocked(x, y) {
+		x++
+	}
+	if err := sim.UpdateAGV("sion-001", AGVUpdate{X: &x, Y: &y, Battery: &battery, Mode: &mode}); err != nil {
+		t.Fatal(err)
+	}
+	if p := a.Status.Position; p.X != x || p.Y != y || a.Status.Battery != 42 || a.Status.Mode != models.ModeManual {
+		t.Fatalf("배치 변경이 반영돼야 함: %+v", a.Status)
+	}
+	if a.Status.Drive != (models.DriveState{}) || a.moveTarget != nil {
+		t.Fatal("옮긴 AGV는 주행 상태를 버려야 함")
+	}
+	if err := sim.UpdateAGV("ghost", AGVUpdate{}); !errors.Is(err, ErrAGVNotFound) {
+		t.Fatalf("없는 AGV는 ErrAGVNotFound, got %v", err)
+	}
+}
+
+func TestSetWorldParams(t *testing.T) {
+	sim := newReconfigSimulator(t, 6)
+	p := sim.WorldParams()
+	p.UpdateIntervalMs = 250
+	obstacles := append([]models.Obstacle(nil), sim.Obstacles...)
+	if err := sim.SetWorldParams(p); err != nil {
+		t.Fatal(err)
+	}
+	if sim.UpdateInterval != 250*time.Millisecond || sim.Obstacles[0] != obstacles[0] {
+		t.Fatal("틱 간격만 바꾸면 월드는 그대로여야 함")
+	}
+
+	p.MapWidth, p.MapHeight, p.AGVCount, p.EnemyCount = 40, 20, 3, 2
+	if err := sim.SetWorldParams(p); err != nil {
+		t.Fatal(err)
+	}
+	if sim.MapWidth != 40 || sim.MapHeight != 20 || len(sim.agvs) != 3 || len(sim.Enemies) != 2 || sim.grid.Width != 40 {
+		t.Fatalf("월드가 새 파라미터로 다시 생성돼야 함: %+v", sim.WorldParams())
+	}
+
+	bad := p
+	bad.MapWidth = 12
+	bad.AGVCount = 6
+	if err := sim.SetWorldParams(bad); err == nil {
+		t.Fatal("맵에 들어가지 않는 AGV 수는 거부돼야 함")
+	}
+	bad = p
+	bad.UpdateIntervalMs = 10
+	if err := sim.SetWorldParams(bad); err == nil {
+		t.Fatal("서브스텝보다 짧은 틱은 거부돼야 함")
+	}
+}
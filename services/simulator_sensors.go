@@ -0,0 +1,240 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sion-backend/models"
+)
+
+// 시뮬레이터 센서 모델. 매 틱 이동이 끝난 뒤 참값(ray-cast 거리, 운동에서 유도한 IMU, 카메라 시야 내 적 수)을
+// 계산해 기록하고, 채널별 지연·누락·가우시안 노이즈를 거친 값을 AGVStatus.Sensors에 채운다.
+// 노이즈는 월드 rng와 분리된 sensorRng에서 뽑으므로 센서 설정을 바꿔도 월드 진행은 달라지지 않는다.
+
+const (
+	gravity = 9.81
+	// rayStep은 ray-cast 진행 간격(셀).
+	rayStep = 0.05
+	// enemyHitRadius는 ray-cast에서 적/AGV를 원으로 볼 때의 반지름(셀).
+	enemyHitRadius = 0.4
+	// maxSensorLatencyTicks는 지연 설정 상한. 이력 버퍼 크기를 제한한다.
+	maxSensorLatencyTicks = 100
+)
+
+// DefaultSensorConfig는 저가형 초음파 + MEMS IMU + 단안 카메라 정도를 흉내 낸 기본값.
+func DefaultSensorConfig() models.SensorConfig {
+	return models.SensorConfig{
+		MaxRange:     10,
+		CameraFOVDeg: 60,
+		Range:        models.SensorNoise{StdDev: 0.05, Dropout: 0.01},
+		IMU:          models.SensorNoise{StdDev: 0.02},
+		Camera:       models.SensorNoise{Dropout: 0.02},
+	}
+}
+
+// ValidateSensorConfig는 설정 범위를 검사한다.
+func ValidateSensorConfig(cfg models.SensorConfig) error {
+	if cfg.MaxRange <= 0 {
+		return fmt.Errorf("max_range는 0보다 커야 합니다")
+	}
+	if cfg.CameraFOVDeg <= 0 || cfg.CameraFOVDeg > 360 {
+		return fmt.Errorf("camera_fov_deg는 0~360 사이여야 합니다")
+	}
+	for name, n := range map[string]models.SensorNoise{"range": cfg.Range, "imu": cfg.IMU, "camera": cfg.Camera} {
+		if n.StdDev < 0 {
+			return fmt.Errorf("%s.std_dev는 0 이상이어야 합니다", name)
+		}
+		if n.Dropout < 0 || n.Dropout > 1 {
+			return fmt.Errorf("%s.dropout은 0~1 사이여야 합니다", name)
+		}
+		if n.LatencyTicks < 0 || n.LatencyTicks > maxSensorLatencyTicks {
+			return fmt.Errorf("%s.latency_ticks는 0~%d 사이여야 합니다", name, maxSensorLatencyTicks)
+		}
+	}
+	return nil
+}
+
+// SetSensorConfig는 센서 노이즈 설정을 바꾼다. 실행 중에도 다음 틱부터 반영된다.
+func (sim *AGVSimulator) SetSensorConfig(cfg models.SensorConfig) error {
+	if err := ValidateSensorConfig(cfg); err != nil {
+		return err
+	}
+	sim.mu.Lock()
+	sim.sensorConfig = cfg
+	sim.mu.Unlock()
+	return nil
+}
+
+func (sim *AGVSimulator) SensorConfig() models.SensorConfig {
+	sim.mu.RLock()
+	defer sim.mu.RUnlock()
+	return sim.sensorConfig
+}
+
+// sensorState는 IMU 유도와 지연 재현을 위한 AGV별 이전 상태.
+type sensorState struct {
+	hasPrev        bool
+	prevX, prevY   float64
+	prevAngle      float64
+	prevVX, prevVY float64
+	history        []models.SensorData // 참값 이력, 마지막이 이번 틱
+}
+
+// updateSensorsLocked는 이번 틱의 참값을 계산하고 노이즈 모델을 적용해 a.Status.Sensors를 갱신한다.
+func (sim *AGVSimulator) updateSensorsLocked(a *simAGV) {
+	cfg := sim.sensorConfig
+	truth := sim.trueSensorsLocked(a, cfg)
+
+	s := &a.sensors
+	s.history = append(s.history, truth)
+	keep := 1 + max(cfg.Range.LatencyTicks, cfg.IMU.LatencyTicks, cfg.Camera.LatencyTicks)
+	if len(s.history) > keep {
+		s.history = s.history[len(s.history)-keep:]
+	}
+	delayed := func(ticks int) models.SensorData {
+		if ticks >= len(s.history) {
+			return s.history[0]
+		}
+		return s.history[len(s.history)-1-ticks]
+	}
+
+	var out models.SensorData
+	r := delayed(cfg.Range.LatencyTicks)
+	out.FrontDistance = sim.noisyRange(r.FrontDistance, cfg)
+	out.LeftDistance = sim.noisyRange(r.LeftDistance, cfg)
+	out.RightDistance = sim.noisyRange(r.RightDistance, cfg)
+
+	if imu := delayed(cfg.IMU.LatencyTicks); !sim.dropped(cfg.IMU) {
+		out.AccelX = imu.AccelX + sim.gauss(cfg.IMU.StdDev)
+		out.AccelY = imu.AccelY + sim.gauss(cfg.IMU.StdDev)
+		out.AccelZ = imu.AccelZ + sim.gauss(cfg.IMU.StdDev)
+		out.GyroX = imu.GyroX + sim.gauss(cfg.IMU.StdDev)
+		out.GyroY = imu.GyroY + sim.gauss(cfg.IMU.StdDev)
+		out.GyroZ = imu.GyroZ + sim.gauss(cfg.IMU.StdDev)
+	}
+
+	if cam := delayed(cfg.Camera.LatencyTicks); !sim.dropped(cfg.Camera) {
+		out.CameraActive = true
+		n := float64(cam.ObjectsDetected) + sim.gauss(cfg.Camera.StdDev)
+		out.ObjectsDetected = int(math.Max(0, math.Round(n)))
+	}
+	a.Status.Sensors = out
+}
+
+func (sim *AGVSimulator) noisyRange(v float64, cfg models.SensorConfig) float64 {
+	if sim.dropped(cfg.Range) {
+		return 0
+	}
+	return clamp(v+sim.gauss(cfg.Range.StdDev), 0, cfg.MaxRange)
+}
+
+func (sim *AGVSimulator) dropped(n models.SensorNoise) bool {
+	return n.Dropout > 0 && sim.sensorRng.Float64() < n.Dropout
+}
+
+func (sim *AGVSimulator) gauss(stddev float64) float64 {
+	if stddev == 0 {
+		return 0
+	}
+	return sim.sensorRng.NormFloat64() * stddev
+}
+
+// trueSensorsLocked는 노이즈 없는 센서 참값을 계산하고 IMU용 이전 상태를 갱신한다.
+func (sim *AGVSimulator) trueSensorsLocked(a *simAGV, cfg models.SensorConfig) models.SensorData {
+	x, y, angle := a.Status.Position.X, a.Status.Position.Y, a.Status.Position.Angle
+	truth := models.SensorData{
+		FrontDistance:   sim.castRayLocked(a, angle, cfg.MaxRange),
+		LeftDistance:    sim.castRayLocked(a, angle+math.Pi/2, cfg.MaxRange),
+		RightDistance:   sim.castRayLocked(a, angle-math.Pi/2, cfg.MaxRange),
+		AccelZ:          gravity,
+		CameraActive:    true,
+		ObjectsDetected: sim.countVisibleEnemiesLocked(a, cfg),
+	}
+
+	// IMU: 위치 변화로 월드 좌표 속도를 구하고, 속도 변화를 차체 좌표(x=전방, y=좌측)로 돌려 가속도로 쓴다.
+	s := &a.sensors
+	dt := sim.UpdateInterval.Seconds()
+	if s.hasPrev && dt > 0 {
+		vx, vy := (x-s.prevX)/dt, (y-s.prevY)/dt
+		ax, ay := (vx-s.prevVX)/dt, (vy-s.prevVY)/dt
+		cos, sin := math.Cos(angle), math.Sin(angle)
+		truth.AccelX = ax*cos + ay*sin
+		truth.AccelY = -ax*sin + ay*cos
+		truth.GyroZ = wrapAngle(angle-s.prevAngle) / dt
+		s.prevVX, s.prevVY = vx, vy
+	}
+	s.hasPrev = true
+	s.prevX, s.prevY, s.prevAngle = x, y, angle
+	return truth
+}
+
+// castRayLocked는 a에서 angle 방향으로 장애물·맵 경계·적·다른 AGV에 닿을 때까지의 거리를 구한다.
+// maxRange 안에 아무 것도 없으면 maxRange.
+func (sim *AGVSimulator) castRayLocked(a *simAGV, angle, maxRange float64) float64 {
+	x0, y0 := a.Status.Position.X, a.Status.Position.Y
+	dx, dy := math.Cos(angle), math.Sin(angle)
+	for d := rayStep; d <= maxRange; d += rayStep {
+		x, y := x0+dx*d, y0+dy*d
+		if x < 0 || y < 0 || x >= sim.MapWidth || y >= sim.MapHeight || sim.isBlockedLocked(x, y) {
+			return d
+		}
+		for i := range sim.Enemies {
+			e := &sim.Enemies[i]
+			if enemyActive(e) && math.Hypot(e.Position.X-x, e.Position.Y-y) <= enemyHitRadius {
+				return d
+			}
+		}
+		for _, other := range sim.agvs {
+			if other != a && other.distanceTo(x, y) <= enemyHitRadius {
+				return d
+			}
+		}
+	}
+	return maxRange
+}
+
+// countVisibleEnemiesLocked는 카메라 시야각·거리 안에 있고 장애물에 가리지 않은 적 수를 센다.
+func (sim *AGVSimulator) countVisibleEnemiesLocked(a *simAGV, cfg models.SensorConfig) int {
+	halfFOV := cfg.CameraFOVDeg * math.Pi / 360
+	n := 0
+	for i := range sim.Enemies {
+		e := &sim.Enemies[i]
+		if !enemyActive(e) {
+			continue
+		}
+		ex, ey := e.Position.X-a.Status.Position.X, e.Position.Y-a.Status.Position.Y
+		dist := math.Hypot(ex, ey)
+		if dist > cfg.MaxRange {
+			continue
+		}
+		if dist > 0 && math.Abs(wrapAngle(math.Atan2(ey, ex)-a.Status.Position.Angle)) > halfFOV {
+			continue
+		}
+		if sim.lineOfSightLocked(a.Status.Position.X, a.Status.Position.Y, e.Position.X, e.Position.Y) {
+			n++
+		}
+	}
+	return n
+}
+
+// lineOfSightLocked는 두 점 사이에 장애물 셀이 없는지 검사한다.
+func (sim *AGVSimulator) lineOfSightLocked(x0, y0, x1, y1 float64) bool {
+	dist := math.Hypot(x1-x0, y1-y0)
+	for d := rayStep; d < dist; d += rayStep {
+		t := d / dist
+		if sim.isBlockedLocked(x0+(x1-x0)*t, y0+(y1-y0)*t) {
+			return false
+		}
+	}
+	return true
+}
+
+// wrapAngle은 각도를 (-π, π]로 정규화한다.
+func wrapAngle(a float64) float64 {
+	for a > math.Pi {
+		a -= 2 * math.Pi
+	}
+	for a <= -math.Pi {
+		a += 2 * math.Pi
+	}
+	return a
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"math"
+	"sion-backend/models"
+	"testing"
+)
+
+// newSensorSimulator는 10x10 맵에 x=8 열 벽을 세우고 AGV를 (2.5, 5.5)에 동쪽(+x)으로 둔다. 노이즈는 끈다.
+func newSensorSimulator(t *testing.T) *AGVSimulator {
+	t.Helper()
+	sc := models.Scenario{
+		Name:      "sensors",
+		MapWidth:  10,
+		MapHeight: 10,
+		AGV:       models.ScenarioAGV{X: 2.5, Y: 5.5, Mode: models.ModeManual},
+	}
+	for row := 0; row < 10; row++ {
+		sc.Obstacles = append(sc.Obstacles, models.Obstacle{ID: "wall", Position: models.GridCoordinate{Row: row, Col: 8}, Size: 1})
+	}
+	sim := newScenarioSimulator(t, sc)
+	cfg := DefaultSensorConfig()
+	cfg.Range, cfg.IMU, cfg.Camera = models.SensorNoise{}, models.SensorNoise{}, models.SensorNoise{}
+	if err := sim.SetSensorConfig(cfg); err != nil {
+		t.Fatalf("SetSensorConfig 실패: %v", err)
+	}
+	return sim
+}
+
+func near(a, b, tol float64) bool { return math.Abs(a-b) <= tol }
+
+func TestSensors_RayCastAndCamera(t *testing.T) {
+	sim := newSensorSimulator(t)
+	a := sim.agvs[0]
+	sim.Enemies = []models.Enemy{
+		{ID: "front", HP: 10, State: models.EnemyStateAlive, Position: models.PositionData{X: 6.5, Y: 5.5}},
+		{ID: "behind-wall", HP: 10, State: models.EnemyStateAlive, Position: models.PositionData{X: 9.5, Y: 5.6}},
+		{ID: "outside-fov", HP: 10, State: models.EnemyStateAlive, Position: models.PositionData{X: 2.5, Y: 8.5}},
+	}
+
+	sim.updateSensorsLocked(a)
+	s := a.Status.Sensors
+	if !near(s.FrontDistance, 4-enemyHitRadius, 2*rayStep) {
+		t.Fatalf("전방 거리는 적까지 약 %.2f여야 함: %.2f", 4-enemyHitRadius, s.FrontDistance)
+	}
+	// 좌측(+y)은 적 outside-fov에 막히고, 우측(-y)은 맵 경계까지 5.5.
+	if !near(s.LeftDistance, 3-enemyHitRadius, 2*rayStep) || !near(s.RightDistance, 5.5, 2*rayStep) {
+		t.Fatalf("좌/우 거리 오류: left=%.2f right=%.2f", s.LeftDistance, s.RightDistance)
+	}
+	if !s.CameraActive || s.ObjectsDetected != 1 {
+		t.Fatalf("카메라는 시야 안·벽 앞의 적 1명만 봐야 함: %+v", s)
+	}
+
+	sim.Enemies = nil
+	sim.updateSensorsLocked(a)
+	if !near(a.Status.Sensors.FrontDistance, 5.5, 2*rayStep) {
+		t.Fatalf("적이 없으면 벽(x=8)까지 5.5여야 함: %.2f", a.Status.Sensors.FrontDistance)
+	}
+}
+
+func TestSensors_IMUFromMotion(t *testing.T) {
+	sim := newSensorSimulator(t)
+	sim.Enemies = nil
+	a := sim.agvs[0]
+	dt := sim.UpdateInterval.Seconds()
+
+	sim.updateSensorsLocked(a) // 기준점
+	a.Status.Position.X += 1 * dt
+	sim.updateSensorsLocked(a) // 0 → 1 셀/초: 전방 가속
+	if s := a.Status.Sensors; !near(s.AccelX, 1/dt, 1e-9) || !near(s.AccelZ, gravity, 1e-9) {
+		t.Fatalf("전방 가속도 %.2f 기대: %+v", 1/dt, s)
+	}
+	a.Status.Position.X += 1 * dt
+	sim.updateSensorsLocked(a) // 등속
+	if s := a.Status.Sensors; !near(s.AccelX, 0, 1e-9) || !near(s.AccelY, 0, 1e-9) {
+		t.Fatalf("등속이면 가속도 0: %+v", s)
+	}
+	a.Status.Position.Angle = math.Pi / 2
+	sim.updateSensorsLocked(a)
+	if s := a.Status.Sensors; !near(s.GyroZ, (math.Pi/2)/dt, 1e-9) {
+		t.Fatalf("GyroZ %.2f 기대: %.2f", (math.Pi/2)/dt, s.GyroZ)
+	}
+}
+
+func TestSensors_DropoutLatencyAndNoise(t *testing.T) {
+	sim := newSensorSimulator(t)
+	sim.Enemies = nil
+	a := sim.agvs[0]
+
+	cfg := sim.SensorConfig()
+	cfg.Range.Dropout, cfg.IMU.Dropout, cfg.Camera.Dropout = 1, 1, 1
+	_ = sim.SetSensorConfig(cfg)
+	sim.updateSensorsLocked(a)
+	if a.Status.Sensors != (models.SensorData{}) {
+		t.Fatalf("dropout=1이면 모든 값이 0이어야 함: %+v", a.Status.Sensors)
+	}
+
+	// 지연 2틱: 벽 쪽으로 1셀씩 다가가도 2틱 전 거리가 보고된다.
+	cfg.Range.Dropout, cfg.IMU.Dropout, cfg.Camera.Dropout = 0, 0, 0
+	cfg.Range.LatencyTicks = 2
+	_ = sim.SetSensorConfig(cfg)
+	a.sensors = sensorState{}
+	var reported []float64
+	for i := 0; i < 4; i++ {
+		sim.updateSensorsLocked(a)
+		reported = append(reported, a.Status.Sensors.FrontDistance)
+		a.Status.Position.X++
+	}
+	if !near(reported[2], 5.5, 2*rayStep) || !near(reported[3], 4.5, 2*rayStep) {
+		t.Fatalf("2틱 지연된 거리 기대 [.. 5.5 4.5], got %v", reported)
+	}
+
+	// 가우시안 노이즈: 값이 흔들리지만 평균은 참값 근처.
+	cfg.Range = models.SensorNoise{StdDev: 0.3}
+	_ = sim.SetSensorConfig(cfg)
+	a.Status.Position.X = 2.5
+	sum, distinct := 0.0, map[float64]bool{}
+	const n = 400
+	for i := 0; i < n; i++ {
+		sim.updateSensorsLocked(a)
+		sum += a.Status.Sensors.FrontDistance
+		distinct[a.Status.Sensors.FrontDistance] = true
+	}
+	if mean := sum / n; !near(mean, 5.5, 0.1) || len(distinct) < n/2 {
+		t.Fatalf("노이즈 평균 %.2f(5.5 근처 기대), 서로 다른 값 %d개", mean, len(distinct))
+	}
+}
+
+func TestValidateSensorConfig(t *testing.T) {
+	bad := DefaultSensorConfig()
+	bad.IMU.Dropout = 1.5
+	if err := ValidateSensorConfig(bad); err == nil {
+		t.Fatal("dropout > 1은 거부돼야 함")
+	}
+	bad = DefaultSensorConfig()
+	bad.Range.LatencyTicks = -1
+	if err := ValidateSensorConfig(bad); err == nil {
+		t.Fatal("음수 지연은 거부돼야 함")
+	}
+}
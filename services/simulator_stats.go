@@ -0,0 +1,27 @@
+package services
+
+// SetStatsTracker는 시뮬레이터 AGV의 세션 통계를 t에 쌓게 한다. nil이면 해제.
+// 가상 AGV 모드에서는 Broker가 같은 보고를 받아 쌓으므로 연결하지 않는다.
+func (sim *AGVSimulator) SetStatsTracker(t *StatsTracker) {
+	sim.mu.Lock()
+	sim.stats = t
+	sim.mu.Unlock()
+}
+
+// endStatsSessions는 시뮬레이터 AGV들의 현재 세션을 끝낸다. 저장(DB)은 잠금 밖에서 한다.
+// 실행이 끝날 때와, 정지 상태에서 월드를 바꾸기 직전(Step으로 진행한 세션을 닫기 위해) 부른다.
+func (sim *AGVSimulator) endStatsSessions() {
+	sim.mu.RLock()
+	t := sim.stats
+	ids := make([]string, len(sim.agvs))
+	for i, a := range sim.agvs {
+		ids[i] = a.Status.ID
+	}
+	sim.mu.RUnlock()
+	if t == nil {
+		return
+	}
+	for _, id := range ids {
+		t.EndSession(id)
+	}
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"log"
+	"sion-backend/models"
+	"time"
+)
+
+// recordTargetSelectionLocked는 타겟 결정을 로그 버퍼와 웹(target_selection)으로 내보낸다. 타겟이 그대로인
+// 결정은 targetSelectionInterval마다 한 번만 내보내고, 서버 로그는 타겟이 바뀔 때만 남긴다.
+func (sim *AGVSimulator) recordTargetSelectionLocked(a *simAGV, sel *models.TargetSelection, changed bool) {
+	if sel == nil || !sim.targeting.ShouldEmit(a.Status.ID, changed, time.Time{}.Add(sim.elapsed)) {
+		return
+	}
+	if changed {
+		log.Printf("[INFO] %s 타겟 선택: %s %s", a.Status.Name, sel.SelectedEnemy.Name, sel.Reason)
+	}
+	if sim.LogToDB {
+		LogTargetSelection(a.Status.ID, sel)
+	}
+	sim.pending = append(sim.pending, models.WebSocketMessage{
+		Type:      models.MessageTypeTargetSelection,
+		Data:      sel,
+		Timestamp: time.Now().UnixMilli(),
+		AGVID:     a.Status.ID,
+	})
+}
+
+// SetTargetingConfig는 타겟 선택 정책을 바꾼다. 실행 중에도 다음 틱부터 반영되며, 현재 타겟 기억은 초기화된다.
+func (sim *AGVSimulator) SetTargetingConfig(cfg models.TargetingConfig) error {
+	selector, err := NewTargetSelector(cfg)
+	if err != nil {
+		return err
+	}
+	sim.mu.Lock()
+	sim.targeting = selector
+	sim.mu.Unlock()
+	return nil
+}
+
+func (sim *AGVSimulator) TargetingConfig() models.TargetingConfig {
+	sim.mu.RLock()
+	defer sim.mu.RUnlock()
+	return sim.targeting.Config()
+}
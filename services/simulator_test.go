@@ -58,3 +58,221 @@ func TestSimulatorStartStopUnderLoad(t *testing.T) {
 		t.Fatal("최종 상태는 stopped여야 함")
 	}
 }
+
+// newScenarioSimulator는 sc를 적재한 시뮬레이터를 만든다. 시드가 없으면 1로 고정해 결과를 재현 가능하게 한다.
+// 시나리오 기반 테스트는 모두 이 헬퍼로 월드를 구성한다.
+func newScenarioSimulator(t *testing.T, sc models.Scenario) *AGVSimulator {
+	t.Helper()
+	if sc.Seed == nil {
+		seed := int64(1)
+		sc.Seed = &seed
+	}
+	sim := NewAGVSimulator(nil)
+	sim.LogToDB = false
+	if err := sim.LoadScenario(sc); err != nil {
+		t.Fatalf("LoadScenario(%s) 실패: %v", sc.Name, err)
+	}
+	return sim
+}
+
+// newReconfigSimulator는 시나리오 대신 seed로 만든 랜덤 월드 시뮬레이터를 만든다.
+// 랜덤 장애물·적 배치와 ResetWorld/SetWorldParams의 랜덤 월드 의미론을 검증할 때 쓴다.
+func newReconfigSimulator(t *testing.T, seed int64) *AGVSimulator {
+	t.Helper()
+	sim := NewAGVSimulator(nil)
+	sim.LogToDB = false
+	if err := sim.Reseed(seed); err != nil {
+		t.Fatalf("Reseed(%d) 실패: %v", seed, err)
+	}
+	return sim
+}
+
+// wallScenario는 x=10 열에 y=0..19 벽을 세운 25x25 맵에 AGV를 (5.5, 5.5)에 둔다.
+// 벽은 y=20부터 열려 있으므로 반대편 목표로 가려면 벽 끝을 우회해야 한다.
+func wallScenario() models.Scenario {
+	sc := models.Scenario{
+		Name:      "wall",
+		MapWidth:  25,
+		MapHeight: 25,
+		AGV:       models.ScenarioAGV{X: 5.5, Y: 5.5},
+	}
+	for y := 0; y < 20; y++ {
+		sc.Obstacles = append(sc.Obstacles, models.Obstacle{
+			ID:       "wall",
+			Position: models.GridCoordinate{Row: y, Col: 10},
+			Size:     1,
+		})
+	}
+	return sc
+}
+
+func TestSimulator_MoveTowardsFollowsPathAroundWall(t *testing.T) {
+	sim := newScenarioSimulator(t, wallScenario())
+	a := sim.agvs[0]
+	a.Status.Speed = 2.0
+	targetX, targetY := 15.5, 5.5
+
+	for i := 0; i < 200 && a.distanceTo(targetX, targetY) > 0.1; i++ {
+		sim.moveTowardsLocked(a, targetX, targetY)
+		if sim.isBlockedLocked(a.Status.Position.X, a.Status.Position.Y) {
+			t.Fatalf("틱 %d: 장애물 셀 위에 위치함 (%.2f, %.2f)", i, a.Status.Position.X, a.Status.Position.Y)
+		}
+	}
+	if d := a.distanceTo(targetX, targetY); d > 0.1 {
+		t.Fatalf("목표 도달 기대, 남은 거리 %.2f", d)
+	}
+	if a.Stats.Collisions != 0 {
+		t.Fatalf("경로 추종 중 충돌 0회 기대, got %d", a.Stats.Collisions)
+	}
+
+	var pathUpdates int
+	for _, msg := range sim.pending {
+		if msg.Type == models.MessageTypePathUpdate {
+			pathUpdates++
+		}
+	}
+	if pathUpdates == 0 {
+		t.Fatal("경로 계획 시 path_update 브로드캐스트 예약 기대")
+	}
+}
+
+func TestSimulator_TryMoveIntoObstacleCountsCollision(t *testing.T) {
+	sim := newScenarioSimulator(t, wallScenario())
+	a := sim.agvs[0]
+	a.Status.Position = models.PositionData{X: 9.5, Y: 5.5}
+
+	if sim.tryMoveLocked(a, 10.5, 5.5) {
+		t.Fatal("장애물 셀로 이동이 허용되면 안 됨")
+	}
+	if a.Stats.Collisions != 1 {
+		t.Fatalf("Collisions=1 기대, got %d", a.Stats.Collisions)
+	}
+	if a.Status.Position.X != 9.5 {
+		t.Fatalf("충돌 시 제자리 유지 기대, got X=%.2f", a.Status.Position.X)
+	}
+}
+
+// 벽에 붙은 채 계속 밀어붙여도 접촉 한 번은 충돌 한 번이다. 떨어졌다 다시 부딪히면 새 충돌이다.
+func TestSimulator_WallContactCountsOnce(t *testing.T) {
+	sim := newScenarioSimulator(t, wallScenario())
+	a := sim.agvs[0]
+	a.Status.Position = models.PositionData{X: 9.5, Y: 5.5}
+
+	for i := 0; i < 5; i++ {
+		sim.tryMoveLocked(a, 10.5, 5.5)
+	}
+	if a.Stats.Collisions != 1 {
+		t.Fatalf("벽을 계속 밀어도 Collisions=1 기대, got %d", a.Stats.Collisions)
+	}
+	if !sim.tryMoveLocked(a, 9.0, 5.5) {
+		t.Fatal("벽에서 멀어지는 이동은 허용돼야 함")
+	}
+	sim.tryMoveLocked(a, 10.5, 5.5)
+	if a.Stats.Collisions != 2 {
+		t.Fatalf("떨어졌다 다시 부딪히면 Collisions=2 기대, got %d", a.Stats.Collisions)
+	}
+}
+
+// 같은 seed로 만든 두 시뮬레이터는 같은 틱 수만큼 진행한 뒤 월드 상태가 완전히 같아야 한다.
+func TestSimulator_SameSeedIsReproducible(t *testing.T) {
+	run := func() (models.AGVStatus, []models.Enemy, models.AGVStats) {
+		sim := NewAGVSimulator(nil)
+		if err := sim.Reseed(42); err != nil {
+			t.Fatalf("Reseed 실패: %v", err)
+		}
+		for i := 0; i < 100; i++ {
+			sim.update(true)
+		}
+		status, enemies, _, _ := sim.Snapshot()
+		return status, enemies, sim.GetStats()
+	}
+
+	s1, e1, st1 := run()
+	s2, e2, st2 := run()
+
+	if s1.Position != s2.Position || s1.Battery != s2.Battery || s1.State != s2.State {
+		t.Fatalf("AGV 상태 불일치: %+v vs %+v", s1.Position, s2.Position)
+	}
+	if st1 != st2 {
+		t.Fatalf("통계 불일치: %+v vs %+v", st1, st2)
+	}
+	if len(e1) != len(e2) {
+		t.Fatalf("적 수 불일치: %d vs %d", len(e1), len(e2))
+	}
+	for i := range e1 {
+		if e1[i] != e2[i] {
+			t.Fatalf("적[%d] 불일치: %+v vs %+v", i, e1[i], e2[i])
+		}
+	}
+}
+
+func TestSimulator_ReseedWhileRunningFails(t *testing.T) {
+	sim := NewAGVSimulator(nil)
+	sim.UpdateInterval = 5 * time.Millisecond
+	sim.Start()
+	defer sim.Stop()
+
+	if err := sim.Reseed(1); err != ErrSimulatorRunning {
+		t.Fatalf("실행 중 Reseed → ErrSimulatorRunning 기대, got %v", err)
+	}
+}
+
+func TestSimulator_MultipleAGVsShareWorldAndCollide(t *testing.T) {
+	sim := NewAGVSimulator(nil)
+	sim.AGVCount = 3
+	if err := sim.Reseed(7); err != nil {
+		t.Fatalf("Reseed 실패: %v", err)
+	}
+
+	statuses := sim.AGVSnapshots()
+	if len(statuses) != 3 {
+		t.Fatalf("AGV 3대 기대, got %d", len(statuses))
+	}
+	ids := map[string]bool{}
+	for _, s := range statuses {
+		ids[s.ID] = true
+	}
+	for _, want := range []string{"sion-001", "sion-002", "sion-003"} {
+		if !ids[want] {
+			t.Fatalf("AGV ID %s 기대, got %v", want, ids)
+		}
+	}
+
+	// 두 AGV를 붙여 놓고 서로를 향해 움직이면 충돌로 막혀야 한다.
+	a, b := sim.agvs[0], sim.agvs[1]
+	a.Status.Position = models.PositionData{X: 20.5, Y: 20.5}
+	b.Status.Position = models.PositionData{X: 21.5, Y: 20.5}
+	sim.Obstacles = nil
+	sim.rebuildGridLocked()
+	if sim.tryMoveLocked(a, 21.0, 20.5) {
+		t.Fatal("다른 AGV와 겹치는 이동은 막혀야 함")
+	}
+	if a.Stats.Collisions != 1 || b.Stats.Collisions != 1 {
+		t.Fatalf("양쪽 Collisions=1 기대, got %d / %d", a.Stats.Collisions, b.Stats.Collisions)
+	}
+	// 이미 가까운 상태에서 멀어지는 이동은 허용
+	if !sim.tryMoveLocked(a, 20.0, 20.5) {
+		t.Fatal("멀어지는 이동은 허용돼야 함")
+	}
+}
+
+func TestSimulator_BroadcastsAreTaggedWithAGVID(t *testing.T) {
+	seen := map[string]map[string]bool{}
+	sim := NewAGVSimulator(func(msg models.WebSocketMessage) {
+		if seen[msg.Type] == nil {
+			seen[msg.Type] = map[string]bool{}
+		}
+		seen[msg.Type][msg.AGVID] = true
+	})
+	sim.AGVCount = 2
+	if err := sim.Reseed(3); err != nil {
+		t.Fatalf("Reseed 실패: %v", err)
+	}
+	sim.update(true)
+
+	for _, typ := range []string{models.MessageTypeStatus, models.MessageTypePosition} {
+		if !seen[typ]["sion-001"] || !seen[typ]["sion-002"] {
+			t.Fatalf("%s 메시지가 AGV별로 태그돼야 함, got %v", typ, seen[typ])
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"log"
+	"math"
+	"sion-backend/models"
+	"sync"
+	"time"
+)
+
+// AGV 누적 통계. 위치 보고로 주행 거리·시간을, 격살·충돌 이벤트로 횟수를 쌓는다.
+// AGV별로 첫 보고부터 EndSession까지가 한 세션이며, 끝난 세션은 DB(없으면 메모리)에 남는다.
+
+const (
+	// maxStatsGap보다 긴 보고 간격(재접속·일시정지)은 주행 시간에 넣지 않는다.
+	maxStatsGap = 5 * time.Second
+	// maxStatsJump보다 큰 위치 변화는 순간이동(월드 재구성·좌표 리셋)으로 보고 거리에 넣지 않는다.
+	maxStatsJump = 5.0
+	// statsHistoryLimit는 DB가 없을 때 메모리에 보관하는 끝난 세션 수.
+	statsHistoryLimit = 100
+)
+
+type statsSession struct {
+	stats   models.AGVStats
+	hasPrev bool
+	prevPos models.PositionData
+	prevAt  time.Time
+	elapsed time.Duration
+}
+
+// StatsTracker는 AGV ID별 현재 세션 통계를 쌓는다. 여러 고루틴에서 호출해도 안전하다.
+type StatsTracker struct {
+	now      func() time.Time
+	mu       sync.Mutex
+	sessions map[string]*statsSession
+	history  []models.AGVStatsRecord
+}
+
+func NewStatsTracker() *StatsTracker {
+	return &StatsTracker{now: time.Now, sessions: make(map[string]*statsSession)}
+}
+
+func (t *StatsTracker) sessionLocked(agvID string) *statsSession {
+	s := t.sessions[agvID]
+	if s == nil {
+		s = &statsSession{stats: models.AGVStats{StartTime: t.now()}}
+		t.sessions[agvID] = s
+	}
+	return s
+}
+
+// ObservePosition은 위치 보고 하나를 반영한다. at은 보고 시각(시뮬레이터는 시뮬레이션 시간)으로 간격 계산에만 쓴다.
+func (t *StatsTracker) ObservePosition(agvID string, pos models.PositionData, at time.Time) {
+	if agvID == "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := t.sessionLocked(agvID)
+	if s.hasPrev {
+		if dt := at.Sub(s.prevAt); dt > 0 && dt <= maxStatsGap {
+			s.elapsed += dt
+			s.stats.TotalTime = int64(s.elapsed.Seconds())
+		}
+		if d := math.Hypot(pos.X-s.prevPos.X, pos.Y-s.prevPos.Y); d <= maxStatsJump {
+			s.stats.TotalDistance += d
+		}
+	}
+	s.hasPrev = true
+	s.prevPos = pos
+	s.prevAt = at
+}
+
+func (t *StatsTracker) RecordKill(agvID string) {
+	t.mu.Lock()
+	t.sessionLocked(agvID).stats.EnemiesDefeated++
+	t.mu.Unlock()
+}
+
+func (t *StatsTracker) RecordCollision(agvID string) {
+	t.mu.Lock()
+	t.sessionLocked(agvID).stats.Collisions++
+	t.mu.Unlock()
+}
+
+// Live는 진행 중인 세션의 통계 사본을 반환한다.
+func (t *StatsTracker) Live() map[string]models.AGVStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make(map[string]models.AGVStats, len(t.sessions))
+	for id, s := range t.sessions {
+		out[id] = s.stats
+	}
+	return out
+}
+
+// EndSession은 AGV의 현재 세션을 끝내고 기록을 저장한다. 진행 중인 세션이 없으면 아무 것도 하지 않는다.
+func (t *StatsTracker) EndSession(agvID string) {
+	t.mu.Lock()
+	s := t.sessions[agvID]
+	if s == nil {
+		t.mu.Unlock()
+		return
+	}
+	delete(t.sessions, agvID)
+	rec := models.AGVStatsRecord{
+		AGVID:           agvID,
+		StartTime:       s.stats.StartTime,
+		EndTime:         t.now(),
+		TotalDistance:   s.stats.TotalDistance,
+		TotalTime:       s.stats.TotalTime,
+		EnemiesDefeated: s.stats.EnemiesDefeated,
+		Collisions:      s.stats.Collisions,
+	}
+	if db == nil {
+		t.history = append(t.history, rec)
+		if len(t.history) > statsHistoryLimit {
+			t.history = t.history[len(t.history)-statsHistoryLimit:]
+		}
+	}
+	t.mu.Unlock()
+
+	if db != nil {
+		if err := db.Create(&rec).Error; err != nil {
+			log.Printf("[ERROR] AGV 통계 저장 실패 (%s): %v", agvID, err)
+		}
+	}
+	log.Printf("[INFO] AGV %s 세션 통계: 거리 %.1f, 시간 %ds, 격살 %d, 충돌 %d",
+		agvID, rec.TotalDistance, rec.TotalTime, rec.EnemiesDefeated, rec.Collisions)
+}
+
+// History는 끝난 세션 기록을 최신순으로 최대 limit개 반환한다. agvID가 비어 있으면 모든 AGV.
+func (t *StatsTracker) History(agvID string, limit int) ([]models.AGVStatsRecord, error) {
+	if db != nil {
+		var records []models.AGVStatsRecord
+		query := db.Order("end_time DESC").Limit(limit)
+		if agvID != "" {
+			query = query.Where("agv_id = ?", agvID)
+		}
+		err := query.Find(&records).Error
+		return records, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var records []models.AGVStatsRecord
+	for i := len(t.history) - 1; i >= 0 && len(records) < limit; i-- {
+		if agvID == "" || t.history[i].AGVID == agvID {
+			records = append(records, t.history[i])
+		}
+	}
+	return records, nil
+}
+
+// Run은 ctx가 끝날 때까지 interval마다 진행 중인 세션 통계를 push로 내보낸다. 세션이 없으면 보내지 않는다.
+func (t *StatsTracker) Run(ctx context.Context, interval time.Duration, push func(models.WebSocketMessage)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			live := t.Live()
+			if len(live) == 0 {
+				continue
+			}
+			push(models.WebSocketMessage{
+				Type:      models.MessageTypeStats,
+				Data:      live,
+				Timestamp: time.Now().UnixMilli(),
+			})
+		}
+	}
+}
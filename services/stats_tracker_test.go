@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sion-backend/models"
+	"testing"
+	"time"
+)
+
+func TestStatsTracker_AccumulatesAndEndsSession(t *testing.T) {
+	tr := NewStatsTracker()
+	base := time.Unix(1000, 0)
+
+	tr.ObservePosition("sion-001", models.PositionData{X: 0, Y: 0}, base)
+	tr.ObservePosition("sion-001", models.PositionData{X: 3, Y: 4}, base.Add(time.Second))
+	tr.ObservePosition("sion-001", models.PositionData{X: 3, Y: 5}, base.Add(2*time.Second))
+	// 긴 공백은 시간에 넣지 않고, 순간이동은 거리에 넣지 않는다.
+	tr.ObservePosition("sion-001", models.PositionData{X: 20, Y: 20}, base.Add(time.Minute))
+	tr.RecordKill("sion-001")
+	tr.RecordCollision("sion-001")
+	tr.RecordCollision("sion-001")
+
+	live := tr.Live()["sion-001"]
+	if live.TotalDistance != 6 || live.TotalTime != 2 || live.EnemiesDefeated != 1 || live.Collisions != 2 {
+		t.Fatalf("누적 통계가 틀림: %+v", live)
+	}
+	if live.StartTime.IsZero() {
+		t.Fatalf("StartTime이 채워져야 함")
+	}
+
+	tr.EndSession("sion-001")
+	if _, ok := tr.Live()["sion-001"]; ok {
+		t.Fatalf("끝난 세션은 Live에서 빠져야 함")
+	}
+	records, err := tr.History("sion-001", 10)
+	if err != nil || len(records) != 1 || records[0].TotalDistance != 6 || records[0].EnemiesDefeated != 1 {
+		t.Fatalf("기록이 남아야 함: %+v, %v", records, err)
+	}
+
+	// 다음 보고부터는 새 세션이다.
+	tr.ObservePosition("sion-001", models.PositionData{X: 0, Y: 0}, base)
+	if live := tr.Live()["sion-001"]; live.TotalDistance != 0 || live.EnemiesDefeated != 0 {
+		t.Fatalf("새 세션은 0부터: %+v", live)
+	}
+}
+
+func TestStatsTracker_PersistsToDB(t *testing.T) {
+	gdb, err := NewInMemoryDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetTestDB(gdb)
+	defer SetTestDB(nil)
+
+	tr := NewStatsTracker()
+	tr.RecordKill("sion-001")
+	tr.RecordKill("sion-002")
+	tr.EndSession("sion-001")
+	tr.EndSession("sion-002")
+
+	all, err := tr.History("", 10)
+	if err != nil || len(all) != 2 {
+		t.Fatalf("DB 기록 2개 기대: %+v, %v", all, err)
+	}
+	one, _ := tr.History("sion-002", 10)
+	if len(one) != 1 || one[0].AGVID != "sion-002" {
+		t.Fatalf("AGV 필터: %+v", one)
+	}
+}
+
+func TestStatsTracker_RunPushesLiveStats(t *testing.T) {
+	tr := NewStatsTracker()
+	tr.RecordKill("sion-001")
+	got := make(chan models.WebSocketMessage, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go tr.Run(ctx, 10*time.Millisecond, func(msg models.WebSocketMessage) {
+		select {
+		case got <- msg:
+		default:
+		}
+	})
+	select {
+	case msg := <-got:
+		stats, ok := msg.Data.(map[string]models.AGVStats)
+		if msg.Type != models.MessageTypeStats || !ok || stats["sion-001"].EnemiesDefeated != 1 {
+			t.Fatalf("agv_stats 푸시 내용이 틀림: %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("통계 푸시가 없음")
+	}
+}
+
+func TestSimulator_FeedsStatsTracker(t *testing.T) {
+	sim := NewAGVSimulator(nil)
+	sim.LogToDB = false
+	tr := NewStatsTracker()
+	sim.SetStatsTracker(tr)
+	if err := sim.Reseed(7); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		sim.update(false)
+	}
+	simStats := sim.GetStats()
+	live := tr.Live()["sion-001"]
+	if simStats.TotalTime != 10 || live.TotalTime != 10 {
+		t.Fatalf("20틱 × 0.5초 = 10초: sim %+v, tracker %+v", simStats, live)
+	}
+	if live.TotalDistance <= 0 || math.Abs(live.TotalDistance-simStats.TotalDistance) > 1e-9 || live.Collisions != simStats.Collisions {
+		t.Fatalf("트래커와 시뮬레이터 통계가 일치해야 함: sim %+v, tracker %+v", simStats, live)
+	}
+
+	sim.endStatsSessions()
+	if records, _ := tr.History("sion-001", 1); len(records) != 1 {
+		t.Fatalf("세션 종료 후 기록이 남아야 함")
+	}
+}
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sion-backend/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 타겟 선택 서비스. 정책이 후보마다 점수(클수록 우선)를 매기고, TargetSelector가 순위를 정해
+// 선택 이유와 대안 목록을 담은 models.TargetSelection을 만든다. 시뮬레이터와 실제 AGV(Broker) 모두 사용한다.
+
+// targetingRange는 거리 점수 정규화 기준(셀). 시뮬레이터 탐지 거리와 같다.
+const targetingRange = 10.0
+
+// targetSelectionInterval은 타겟이 그대로일 때 결정을 다시 내보내는 최소 간격. 타겟이 바뀐 결정은 바로 내보낸다.
+const targetSelectionInterval = time.Second
+
+// TargetPolicy는 후보 적 하나의 점수와 그 근거를 계산한다.
+type TargetPolicy interface {
+	Name() string
+	Score(agv *models.AGVStatus, e *models.Enemy) float64
+	Explain(agv *models.AGVStatus, e *models.Enemy, score float64) string
+}
+
+// DefaultTargetingConfig는 기존 동작(HP가 가장 낮은 적 우선)과 같은 설정.
+func DefaultTargetingConfig() models.TargetingConfig {
+	return models.TargetingConfig{
+		Policy:  models.TargetPolicyLowestHP,
+		Weights: models.PriorityConfig{DistanceWeight: 0.4, HPWeight: 0.4, ThreatWeight: 0.2},
+	}
+}
+
+// NewTargetPolicy는 설정으로 정책을 만든다.
+func NewTargetPolicy(cfg models.TargetingConfig) (TargetPolicy, error) {
+	switch cfg.Policy {
+	case models.TargetPolicyWeighted:
+		w := cfg.Weights
+		if w.DistanceWeight < 0 || w.HPWeight < 0 || w.ThreatWeight < 0 {
+			return nil, fmt.Errorf("가중치는 0 이상이어야 합니다")
+		}
+		if w.DistanceWeight+w.HPWeight+w.ThreatWeight == 0 {
+			return nil, fmt.Errorf("가중치가 모두 0입니다")
+		}
+		return weightedPolicy{w}, nil
+	case models.TargetPolicyNearest:
+		return nearestPolicy{}, nil
+	case models.TargetPolicyLowestHP:
+		return lowestHPPolicy{}, nil
+	case models.TargetPolicyHighestThreat:
+		return highestThreatPolicy{}, nil
+	default:
+		return nil, fmt.Errorf("알 수 없는 타겟 정책: %q", cfg.Policy)
+	}
+}
+
+func enemyDistance(agv *models.AGVStatus, e *models.Enemy) float64 {
+	return math.Hypot(e.Position.X-agv.Position.X, e.Position.Y-agv.Position.Y)
+}
+
+// distanceScore는 가까울수록 1, targetingRange 이상이면 0.
+func distanceScore(agv *models.AGVStatus, e *models.Enemy) float64 {
+	return clamp(1-enemyDistance(agv, e)/targetingRange, 0, 1)
+}
+
+// hpScore는 남은 HP 비율이 낮을수록 1. MaxHP를 모르면 100을 기준으로 한다.
+func hpScore(e *models.Enemy) float64 {
+	maxHP := e.MaxHP
+	if maxHP <= 0 {
+		maxHP = 100
+	}
+	return clamp(1-float64(e.HP)/float64(maxHP), 0, 1)
+}
+
+// threatScore는 위협도를 0~1로 바꾼다. 알 수 없으면 medium으로 본다.
+func threatScore(e *models.Enemy) float64 {
+	switch e.ThreatLevel {
+	case models.ThreatLow:
+		return 0.25
+	case models.ThreatHigh:
+		return 0.75
+	case models.ThreatCritical:
+		return 1
+	default:
+		return 0.5
+	}
+}
+
+type weightedPolicy struct{ w models.PriorityConfig }
+
+func (p weightedPolicy) Name() string { return models.TargetPolicyWeighted }
+
+func (p weightedPolicy) Score(agv *models.AGVStatus, e *models.Enemy) float64 {
+	total := p.w.DistanceWeight + p.w.HPWeight + p.w.ThreatWeight
+	return (p.w.DistanceWeight*distanceScore(agv, e) + p.w.HPWeight*hpScore(e) + p.w.ThreatWeight*threatScore(e)) / total
+}
+
+func (p weightedPolicy) Explain(agv *models.AGVStatus, e *models.Enemy, score float64) string {
+	return fmt.Sprintf("가중 점수 %.2f (거리 %.2f·HP %.2f·위협 %.2f)", score, distanceScore(agv, e), hpScore(e), threatScore(e))
+}
+
+type nearestPolicy struct{}
+
+func (nearestPolicy) Name() string { return models.TargetPolicyNearest }
+func (nearestPolicy) Score(agv *models.AGVStatus, e *models.Enemy) float64 {
+	return -enemyDistance(agv, e)
+}
+func (nearestPolicy) Explain(agv *models.AGVStatus, e *models.Enemy, _ float64) string {
+	return fmt.Sprintf("가장 가까운 적 (%.1fm)", enemyDistance(agv, e))
+}
+
+type lowestHPPolicy struct{}
+
+func (lowestHPPolicy) Name() string { return models.TargetPolicyLowestHP }
+func (lowestHPPolicy) Score(_ *models.AGVStatus, e *models.Enemy) float64 {
+	return -float64(e.HP)
+}
+func (lowestHPPolicy) Explain(_ *models.AGVStatus, e *models.Enemy, _ float64) string {
+	return fmt.Sprintf("HP가 가장 낮은 적 (HP %d)", e.HP)
+}
+
+// highestThreatPolicy는 위협도가 같으면 가까운 적을 고른다.
+type highestThreatPolicy struct{}
+
+func (highestThreatPolicy) Name() string { return models.TargetPolicyHighestThreat }
+func (highestThreatPolicy) Score(agv *models.AGVStatus, e *models.Enemy) float64 {
+	return threatScore(e) + 0.01*distanceScore(agv, e)
+}
+func (highestThreatPolicy) Explain(_ *models.AGVStatus, e *models.Enemy, _ float64) string {
+	level := e.ThreatLevel
+	if level == "" {
+		level = models.ThreatMedium
+	}
+	return fmt.Sprintf("위협도가 가장 높은 적 (%s)", level)
+}
+
+// TargetSelector는 정책과 AGV별 현재 타겟을 들고 선택을 수행한다. 여러 고루틴에서 호출해도 안전하다.
+type TargetSelector struct {
+	mu      sync.Mutex
+	cfg     models.TargetingConfig
+	policy  TargetPolicy
+	current map[string]string    // AGV ID → 현재 타겟 적 ID
+	emitted map[string]time.Time // AGV ID → 마지막으로 결정을 내보낸 시각
+}
+
+func NewTargetSelector(cfg models.TargetingConfig) (*TargetSelector, error) {
+	if cfg.Stickiness < 0 {
+		return nil, fmt.Errorf("stickiness는 0 이상이어야 합니다")
+	}
+	policy, err := NewTargetPolicy(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &TargetSelector{cfg: cfg, policy: policy, current: make(map[string]string), emitted: make(map[string]time.Time)}, nil
+}
+
+func (s *TargetSelector) Config() models.TargetingConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cfg
+}
+
+// Clear는 AGV의 현재 타겟을 잊는다 (탐지된 적이 없을 때).
+func (s *TargetSelector) Clear(agvID string) {
+	s.mu.Lock()
+	delete(s.current, agvID)
+	delete(s.emitted, agvID)
+	s.mu.Unlock()
+}
+
+// Reset은 모든 AGV의 현재 타겟을 잊는다 (월드 재구성 시).
+func (s *TargetSelector) Reset() {
+	s.mu.Lock()
+	s.current = make(map[string]string)
+	s.emitted = make(map[string]time.Time)
+	s.mu.Unlock()
+}
+
+// clone은 설정과 AGV별 현재 타겟을 복사한 새 선택기를 만든다 (월드 스냅샷용).
+func (s *TargetSelector) clone() *TargetSelector {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := &TargetSelector{
+		cfg:     s.cfg,
+		policy:  s.policy,
+		current: make(map[string]string, len(s.current)),
+		emitted: make(map[string]time.Time, len(s.emitted)),
+	}
+	for id, enemyID := range s.current {
+		c.current[id] = enemyID
+	}
+	for id, at := range s.emitted {
+		c.emitted[id] = at
+	}
+	return c
+}
+
+// ShouldEmit은 Select 결과를 target_selection으로 내보낼지 정하고, 내보낸다면 시각을 기록한다.
+// 타겟이 바뀌었으면 항상, 그대로면 마지막으로 내보낸 뒤 targetSelectionInterval이 지났을 때만 true.
+// now는 호출자의 시계다(시뮬레이터는 시뮬레이션 시간).
+func (s *TargetSelector) ShouldEmit(agvID string, changed bool, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if last, ok := s.emitted[agvID]; ok && !changed && now.Sub(last) < targetSelectionInterval {
+		return false
+	}
+	s.emitted[agvID] = now
+	return true
+}
+
+type rankedEnemy struct {
+	enemy models.Enemy
+	score float64
+}
+
+// Select는 후보 중 타겟을 고르고, 타겟이 이전과 달라졌으면 changed=true를 반환한다.
+// 후보가 없으면 nil. 반환된 적과 대안의 Distance/Priority(1부터 순위)는 채워져 있다.
+func (s *TargetSelector) Select(agv *models.AGVStatus, candidates []models.Enemy) (sel *models.TargetSelection, changed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(candidates) == 0 {
+		delete(s.current, agv.ID)
+		delete(s.emitted, agv.ID)
+		return nil, false
+	}
+
+	ranked := make([]rankedEnemy, len(candidates))
+	for i := range candidates {
+		e := candidates[i]
+		e.Distance = enemyDistance(agv, &e)
+		ranked[i] = rankedEnemy{enemy: e, score: s.policy.Score(agv, &e)}
+	}
+	// 동점이면 입력 순서를 유지해 결과가 결정적이다.
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+
+	chosen := 0
+	reason := s.policy.Explain(agv, &ranked[0].enemy, ranked[0].score)
+	prevID, hadPrev := s.current[agv.ID]
+	if s.cfg.Stickiness > 0 && hadPrev && ranked[0].enemy.ID != prevID {
+		for i, r := range ranked {
+			if r.enemy.ID != prevID {
+				continue
+			}
+			// 새 후보가 현재 타겟보다 Stickiness 비율 이상 낫지 않으면 유지한다.
+			gain := ranked[0].score - r.score
+			if gain <= s.cfg.Stickiness*math.Max(math.Abs(r.score), 1e-9) {
+				chosen = i
+				reason = fmt.Sprintf("현재 타겟 유지 — 최상위 후보 %s와 점수 차 %.2f가 히스테리시스 %.0f%% 이내 (%s)",
+					ranked[0].enemy.Name, gain, s.cfg.Stickiness*100, s.policy.Explain(agv, &r.enemy, r.score))
+			}
+			break
+		}
+	}
+
+	selected := ranked[chosen].enemy
+	selected.Priority = 1
+	alternatives := make([]models.Enemy, 0, len(ranked)-1)
+	for i, r := range ranked {
+		if i == chosen {
+			continue
+		}
+		alt := r.enemy
+		alt.Priority = len(alternatives) + 2
+		alternatives = append(alternatives, alt)
+	}
+
+	changed = !hadPrev || prevID != selected.ID
+	s.current[agv.ID] = selected.ID
+	return &models.TargetSelection{
+		SelectedEnemy: &selected,
+		Reason:        fmt.Sprintf("[%s] %s", s.policy.Name(), reason),
+		Alternatives:  alternatives,
+		Timestamp:     time.Now(),
+	}, changed
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"sion-backend/models"
+	"testing"
+)
+
+func targetingFixture() (*models.AGVStatus, []models.Enemy) {
+	agv := &models.AGVStatus{ID: "sion-001", Mode: models.ModeAuto, Position: models.PositionData{X: 0, Y: 0}}
+	enemies := []models.Enemy{
+		{ID: "near", Name: "가까움", HP: 90, MaxHP: 100, ThreatLevel: models.ThreatLow, Position: models.PositionData{X: 1, Y: 0}},
+		{ID: "weak", Name: "약함", HP: 10, MaxHP: 100, ThreatLevel: models.ThreatMedium, Position: models.PositionData{X: 8, Y: 0}},
+		{ID: "boss", Name: "보스", HP: 100, MaxHP: 100, ThreatLevel: models.ThreatCritical, Position: models.PositionData{X: 5, Y: 0}},
+	}
+	return agv, enemies
+}
+
+func TestTargetSelector_Policies(t *testing.T) {
+	cases := []struct {
+		cfg  models.TargetingConfig
+		want string
+	}{
+		{models.TargetingConfig{Policy: models.TargetPolicyNearest}, "near"},
+		{models.TargetingConfig{Policy: models.TargetPolicyLowestHP}, "weak"},
+		{models.TargetingConfig{Policy: models.TargetPolicyHighestThreat}, "boss"},
+		{models.TargetingConfig{Policy: models.TargetPolicyWeighted, Weights: models.PriorityConfig{DistanceWeight: 1}}, "near"},
+		{models.TargetingConfig{Policy: models.TargetPolicyWeighted, Weights: models.PriorityConfig{HPWeight: 1, DistanceWeight: 0.1}}, "weak"},
+		{models.TargetingConfig{Policy: models.TargetPolicyWeighted, Weights: models.PriorityConfig{ThreatWeight: 1}}, "boss"},
+	}
+	for _, tc := range cases {
+		s, err := NewTargetSelector(tc.cfg)
+		if err != nil {
+			t.Fatalf("%+v: %v", tc.cfg, err)
+		}
+		agv, enemies := targetingFixture()
+		sel, changed := s.Select(agv, enemies)
+		if sel == nil || !changed {
+			t.Fatalf("%s: 첫 선택은 changed여야 함", tc.cfg.Policy)
+		}
+		if sel.SelectedEnemy.ID != tc.want {
+			t.Errorf("%s %+v: got %s, want %s", tc.cfg.Policy, tc.cfg.Weights, sel.SelectedEnemy.ID, tc.want)
+		}
+		if sel.Reason == "" {
+			t.Errorf("%s: reason이 비어 있음", tc.cfg.Policy)
+		}
+		if len(sel.Alternatives) != 2 || sel.SelectedEnemy.Priority != 1 ||
+			sel.Alternatives[0].Priority != 2 || sel.Alternatives[1].Priority != 3 {
+			t.Errorf("%s: 대안 순위가 잘못됨: %+v", tc.cfg.Policy, sel.Alternatives)
+		}
+		for _, alt := range sel.Alternatives {
+			if alt.ID == sel.SelectedEnemy.ID {
+				t.Errorf("%s: 선택된 적이 대안에 포함됨", tc.cfg.Policy)
+			}
+		}
+	}
+}
+
+func TestTargetSelector_NearestRanksAlternatives(t *testing.T) {
+	s, _ := NewTargetSelector(models.TargetingConfig{Policy: models.TargetPolicyNearest})
+	agv, enemies := targetingFixture()
+	sel, _ := s.Select(agv, enemies)
+	if sel.Alternatives[0].ID != "boss" || sel.Alternatives[1].ID != "weak" {
+		t.Fatalf("가까운 순으로 정렬돼야 함: %s, %s", sel.Alternatives[0].ID, sel.Alternatives[1].ID)
+	}
+	if sel.SelectedEnemy.Distance != 1 || sel.Alternatives[1].Distance != 8 {
+		t.Fatalf("거리가 채워져야 함: %+v", sel)
+	}
+}
+
+func TestTargetSelector_StickinessPreventsFlipFlop(t *testing.T) {
+	agv := &models.AGVStatus{ID: "sion-001", Mode: models.ModeAuto}
+	a := models.Enemy{ID: "a", Name: "A", Position: models.PositionData{X: 5}}
+	b := models.Enemy{ID: "b", Name: "B", Position: models.PositionData{X: 5.2}}
+
+	plain, _ := NewTargetSelector(models.TargetingConfig{Policy: models.TargetPolicyNearest})
+	sticky, _ := NewTargetSelector(models.TargetingConfig{Policy: models.TargetPolicyNearest, Stickiness: 0.2})
+	for _, s := range []*TargetSelector{plain, sticky} {
+		if sel, _ := s.Select(agv, []models.Enemy{a, b}); sel.SelectedEnemy.ID != "a" {
+			t.Fatalf("처음에는 a를 골라야 함")
+		}
+	}
+
+	// b가 살짝 더 가까워진다: 히스테리시스가 없으면 바로 갈아탄다.
+	b.Position.X = 4.8
+	if sel, changed := plain.Select(agv, []models.Enemy{a, b}); sel.SelectedEnemy.ID != "b" || !changed {
+		t.Fatalf("stickiness 0이면 b로 바꿔야 함")
+	}
+	sel, changed := sticky.Select(agv, []models.Enemy{a, b})
+	if sel.SelectedEnemy.ID != "a" || changed {
+		t.Fatalf("stickiness 안쪽 차이는 a를 유지해야 함: %s", sel.SelectedEnemy.ID)
+	}
+	if sel.Alternatives[0].ID != "b" {
+		t.Fatalf("유지하더라도 더 나은 후보는 대안에 있어야 함")
+	}
+
+	// 차이가 충분히 크면 바꾼다.
+	b.Position.X = 2
+	if sel, changed := sticky.Select(agv, []models.Enemy{a, b}); sel.SelectedEnemy.ID != "b" || !changed {
+		t.Fatalf("차이가 크면 b로 바꿔야 함")
+	}
+
+	// 현재 타겟이 사라지면 stickiness와 무관하게 다시 고른다.
+	if sel, changed := sticky.Select(agv, []models.Enemy{a}); sel.SelectedEnemy.ID != "a" || !changed {
+		t.Fatalf("현재 타겟이 없으면 a로 바꿔야 함")
+	}
+	if sel, _ := sticky.Select(agv, nil); sel != nil {
+		t.Fatalf("후보가 없으면 nil")
+	}
+}
+
+func TestNewTargetSelector_Invalid(t *testing.T) {
+	bad := []models.TargetingConfig{
+		{Policy: "random"},
+		{Policy: models.TargetPolicyNearest, Stickiness: -1},
+		{Policy: models.TargetPolicyWeighted},
+		{Policy: models.TargetPolicyWeighted, Weights: models.PriorityConfig{HPWeight: -1, DistanceWeight: 2}},
+	}
+	for _, cfg := range bad {
+		if _, err := NewTargetSelector(cfg); err == nil {
+			t.Errorf("%+v: 에러가 나야 함", cfg)
+		}
+	}
+}
+
+func TestSimulator_EmitsTargetSelection(t *testing.T) {
+	var got []models.WebSocketMessage
+	sim := NewAGVSimulator(func(msg models.WebSocketMessage) { got = append(got, msg) })
+	sim.LogToDB = false
+	sc := models.Scenario{
+		Name:     "targeting",
+		MapWidth: 20, MapHeight: 20,
+		AGV: models.ScenarioAGV{X: 2, Y: 2},
+		Enemies: []models.ScenarioEnemy{
+			{ID: "e1", Name: "먼 적", HP: 50, X: 9, Y: 2},
+			{ID: "e2", Name: "가까운 적", HP: 80, X: 4, Y: 2},
+		},
+	}
+	if err := sim.LoadScenario(sc); err != nil {
+		t.Fatal(err)
+	}
+	if err := sim.SetTargetingConfig(models.TargetingConfig{Policy: models.TargetPolicyNearest}); err != nil {
+		t.Fatal(err)
+	}
+	sim.update(true)
+
+	var selections int
+	for _, msg := range got {
+		if msg.Type != models.MessageTypeTargetSelection {
+			continue
+		}
+		selections++
+		sel := msg.Data.(*models.TargetSelection)
+		if sel.SelectedEnemy.ID != "e2" || msg.AGVID == "" || len(sel.Alternatives) != 1 {
+			t.Fatalf("nearest 정책이면 e2를 골라야 함: %+v", sel)
+		}
+	}
+	if selections != 1 {
+		t.Fatalf("target_selection 메시지 1개 기대, got %d", selections)
+	}
+	if status, _, _, _ := sim.Snapshot(); status.TargetEnemy == nil || status.TargetEnemy.ID != "e2" {
+		t.Fatalf("AGV 타겟이 e2여야 함: %+v", status.TargetEnemy)
+	}
+
+	// 타겟이 그대로면 결정은 매 틱 내리되 targetSelectionInterval(1초 = 2틱)마다 한 번만 보낸다.
+	count := func() (n int) {
+		for _, msg := range got {
+			if msg.Type == models.MessageTypeTargetSelection {
+				n++
+			}
+		}
+		return n
+	}
+	got = nil
+	sim.update(true)
+	if n := count(); n != 0 {
+		t.Fatalf("간격 안에서는 다시 보내지 않아야 함, got %d", n)
+	}
+	sim.update(true)
+	if n := count(); n != 1 {
+		t.Fatalf("간격이 지나면 같은 타겟이어도 다시 보내야 함, got %d", n)
+	}
+}
@@ -21,7 +21,7 @@ func NewInMemoryDB() (*gorm.DB, error) {
 	if err != nil {
 		return nil, err
 	}
-	if err := gdb.AutoMigrate(&models.AGVLog{}); err != nil {
+	if err := gdb.AutoMigrate(&models.AGVLog{}, &models.AGVStatsRecord{}); err != nil {
 		return nil, err
 	}
 	return gdb, nil
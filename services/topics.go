@@ -0,0 +1,102 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sion-backend/models"
+	"strings"
+)
+
+// 웹 클라이언트 토픽 구독. 토픽 형식은 models.SubscriptionData 참고.
+// ClientManager.BroadcastToWeb은 메시지의 type·agv_id로 각 웹 클라이언트의 구독을 검사해 맞는 클라이언트에만 보낸다.
+
+// TopicAll은 모든 메시지를 받는 토픽. 새 웹 클라이언트의 기본 구독이다.
+const TopicAll = "*"
+
+// maxTopicsPerClient는 클라이언트 하나가 가질 수 있는 구독 수. 브로드캐스트마다 모두 검사하므로 상한을 둔다.
+const maxTopicsPerClient = 64
+
+// topicGroups는 여러 메시지 타입을 묶은 토픽 이름.
+var topicGroups = map[string][]string{
+	"chat": {models.MessageTypeChat, models.MessageTypeChatResponse},
+	"logs": {models.MessageTypeLog, models.MessageTypeAGVEvent, models.MessageTypeLLMExplanation},
+}
+
+// ErrTooManyTopics는 구독 수가 maxTopicsPerClient를 넘을 때 반환된다.
+var ErrTooManyTopics = fmt.Errorf("구독은 클라이언트당 %d개까지입니다", maxTopicsPerClient)
+
+// ValidateTopic은 토픽 형식과 와일드카드 문법을 검사한다.
+func ValidateTopic(topic string) error {
+	kind, agv, hasAGV := strings.Cut(topic, "@")
+	if kind == "" || (hasAGV && agv == "") {
+		return fmt.Errorf("잘못된 토픽 %q: <종류> 또는 <종류>@<AGV ID> 형식이어야 합니다", topic)
+	}
+	if _, err := path.Match(kind, ""); err != nil {
+		return fmt.Errorf("잘못된 토픽 %q: %w", topic, err)
+	}
+	if _, err := path.Match(agv, ""); err != nil {
+		return fmt.Errorf("잘못된 토픽 %q: %w", topic, err)
+	}
+	return nil
+}
+
+// isAlwaysDelivered는 구독과 관계없이 모든 웹 클라이언트에 보내는 메시지인지 판단한다.
+// 웹으로 브로드캐스트되는 error는 확인되지 않은 비상 정지 같은 경보라 놓치면 안 된다.
+func isAlwaysDelivered(msgType string) bool {
+	return msgType == models.MessageTypeError
+}
+
+// topicMatches는 토픽 하나가 msgType·agvID 메시지와 맞는지 판단한다. 토픽은 ValidateTopic을 통과한 값이어야 한다.
+func topicMatches(topic, msgType, agvID string) bool {
+	kind, agv, hasAGV := strings.Cut(topic, "@")
+	if hasAGV {
+		if ok, _ := path.Match(agv, agvID); !ok || agvID == "" {
+			return false
+		}
+	}
+	if ok, _ := path.Match(kind, msgType); ok {
+		return true
+	}
+	for group, types := range topicGroups {
+		if ok, _ := path.Match(kind, group); !ok {
+			continue
+		}
+		for _, t := range types {
+			if t == msgType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func wantsTopic(topics []string, msgType, agvID string) bool {
+	if isAlwaysDelivered(msgType) {
+		return true
+	}
+	for _, t := range topics {
+		if topicMatches(t, msgType, agvID) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTopicList는 쉼표로 구분한 토픽 목록(접속 URL의 ?topics= 등)을 검사해 나눈다.
+func ParseTopicList(s string) ([]string, error) {
+	var out []string
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); t == "" {
+			continue
+		}
+		if err := ValidateTopic(t); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("토픽 목록이 비어 있습니다")
+	}
+	return out, nil
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"sion-backend/models"
+	"testing"
+)
+
+func TestTopicMatches(t *testing.T) {
+	cases := []struct {
+		topic, msgType, agvID string
+		want                  bool
+	}{
+		{"*", models.MessageTypePosition, "", true},
+		{"status", models.MessageTypeStatus, "sion-001", true},
+		{"status", models.MessageTypePosition, "sion-001", false},
+		{"status@sion-001", models.MessageTypeStatus, "sion-001", true},
+		{"status@sion-001", models.MessageTypeStatus, "sion-002", false},
+		{"*@sion-00[12]", models.MessageTypePathUpdate, "sion-002", true},
+		{"*@sion-00[12]", models.MessageTypePathUpdate, "sion-003", false},
+		{"*@*", models.MessageTypeMapUpdate, "", false},
+		{"chat", models.MessageTypeChatResponse, "", true},
+		{"logs", models.MessageTypeAGVEvent, "sion-001", true},
+		{"logs@sion-001", models.MessageTypeLog, "sion-002", false},
+		{"agv_*", models.MessageTypeAGVConnected, "sion-001", true},
+	}
+	for _, c := range cases {
+		if got := topicMatches(c.topic, c.msgType, c.agvID); got != c.want {
+			t.Errorf("%q vs %s@%s: got %v want %v", c.topic, c.msgType, c.agvID, got, c.want)
+		}
+	}
+	if !wantsTopic([]string{"chat"}, models.MessageTypeError, "sion-001") {
+		t.Error("error는 구독과 관계없이 전달돼야 함")
+	}
+}
+
+func TestValidateTopic(t *testing.T) {
+	for _, ok := range []string{"*", "status@sion-001", "logs", "[ps]*@sion-?"} {
+		if err := ValidateTopic(ok); err != nil {
+			t.Errorf("%q는 유효해야 함: %v", ok, err)
+		}
+	}
+	for _, bad := range []string{"", "@sion-001", "status@", "[status"} {
+		if ValidateTopic(bad) == nil {
+			t.Errorf("%q는 거부돼야 함", bad)
+		}
+	}
+	if topics, err := ParseTopicList(" chat, status@sion-001 ,"); err != nil || len(topics) != 2 {
+		t.Fatalf("쉼표 목록 파싱: %v %v", topics, err)
+	}
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sion-backend/models"
+	"sync"
+	"time"
+
+	"github.com/fasthttp/websocket"
+)
+
+// VirtualAGV는 시뮬레이터를 실제 AGV처럼 /websocket/agv에 접속시키는 어댑터다.
+// 시뮬레이터가 만든 메시지를 그대로 소켓으로 보내고, 서버가 내려보내는 command/mode_change/emergency_stop을
+// 시뮬레이터에 적용한다. 서버 입장에서는 실제 AGV와 구분되지 않으므로 Broker.OnAGVMessage, SetAGVConnected,
+// AGV 로깅 경로가 모두 실행된다.
+type VirtualAGV struct {
+	URL string
+	// ReconnectDelay는 연결이 끊긴 뒤 재접속까지 기다리는 시간.
+	ReconnectDelay time.Duration
+
+	sim *AGVSimulator
+
+	mu   sync.Mutex
+	conn *websocket.Conn
+	// declared는 이번 연결의 hello로 알린 AGV ID. 서버는 여기 없는 ID의 보고를 받지 않는다.
+	declared map[string]bool
+	writeMu  sync.Mutex
+}
+
+// NewVirtualAGV는 sim의 BroadcastFunc를 소켓 송신으로 바꿔 끼우고, 서버 쪽 기록과 겹치지 않게 LogToDB를 끈다.
+// sim이 실행 중이 아닐 때 호출해야 한다.
+func NewVirtualAGV(url string, sim *AGVSimulator) *VirtualAGV {
+	v := &VirtualAGV{
+		URL:            url,
+		ReconnectDelay: 2 * time.Second,
+		sim:            sim,
+	}
+	sim.BroadcastFunc = v.send
+	sim.LogToDB = false
+	return v
+}
+
+// Connected는 현재 서버와 연결되어 있는지 반환한다.
+func (v *VirtualAGV) Connected() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.conn != nil
+}
+
+// Run은 ctx가 끝날 때까지 서버에 접속을 유지한다. 끊기면 ReconnectDelay 후 다시 접속한다.
+func (v *VirtualAGV) Run(ctx context.Context) {
+	for {
+		if err := v.serve(ctx); err != nil {
+			log.Printf("[WARN] 가상 AGV 연결 끊김 (%s): %v", v.URL, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(v.ReconnectDelay):
+		}
+	}
+}
+
+// serve는 한 번 접속해 연결이 끊기거나 ctx가 끝날 때까지 수신 루프를 돈다.
+func (v *VirtualAGV) serve(ctx context.Context) error {
+	dialer := websocket.Dialer{HandshakeTimeout: 5 * time.Second}
+	conn, _, err := dialer.DialContext(ctx, v.URL, nil)
+	if err != nil {
+		return err
+	}
+	log.Printf("[INFO] 가상 AGV 접속: %s", v.URL)
+
+	v.mu.Lock()
+	v.conn = conn
+	v.mu.Unlock()
+	defer func() {
+		v.mu.Lock()
+		v.conn = nil
+		v.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	// ctx 종료 시 ReadMessage를 깨우기 위해 정상 종료 프레임을 보내고 소켓을 닫는다.
+	stop := context.AfterFunc(ctx, func() {
+		v.writeMu.Lock()
+		_ = conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		v.writeMu.Unlock()
+		_ = conn.Close()
+	})
+	defer stop()
+
+	v.sendHello()
+
+	for {
+		_, p, err := conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		var msg models.WebSocketMessage
+		if err := json.Unmarshal(p, &msg); err != nil {
+			log.Printf("[WARN] 가상 AGV 수신 메시지 파싱 오류: %v", err)
+			continue
+		}
+		switch msg.Type {
+		case models.MessageTypeCommand,
+			models.MessageTypeModeChange,
+			models.MessageTypeEmergencyStop,
+			models.MessageTypeMotorControl:
+			if !v.sim.HandleWebCommand(msg) {
+				log.Printf("[WARN] 시뮬레이터 정지 상태 — 명령 무시: %s", msg.Type)
+				v.reply(msg, models.MessageTypeCommandNack, "시뮬레이터가 실행 중이 아니거나 없는 AGV입니다")
+				continue
+			}
+			v.reply(msg, models.MessageTypeCommandAck, "")
+			// 모드 전환·비상 정지는 받는 즉시 끝난다. 이동 명령은 도착을 따로 보고하지 않는다.
+			if msg.Type == models.MessageTypeModeChange || msg.Type == models.MessageTypeEmergencyStop {
+				v.reply(msg, models.MessageTypeCommandCompleted, "")
+			}
+		}
+	}
+}
+
+// sendHello는 현재 시뮬레이터의 AGV 전부를 hello로 알리고 declared에 기록한다.
+// 접속 직후와, 접속 뒤 늘어난 AGV를 처음 보고하기 직전에 보낸다.
+func (v *VirtualAGV) sendHello() {
+	msg := v.hello()
+	hello := msg.Data.(models.AGVHello)
+	v.mu.Lock()
+	v.declared = map[string]bool{hello.AGVID: true}
+	for _, id := range hello.AGVIDs {
+		v.declared[id] = true
+	}
+	v.mu.Unlock()
+	v.send(msg)
+}
+
+// hello는 핸드셰이크 메시지. 시뮬레이터의 AGV 전부를 이 연결 하나로 대신하므로
+// 첫 AGV를 대표 ID로, 나머지를 agv_ids로 알린다. 다시 보낼 때도 대표 ID는 서버가 처음 것을 유지한다.
+func (v *VirtualAGV) hello() models.WebSocketMessage {
+	hello := models.AGVHello{
+		Name:    "virtual-agv",
+		Version: "simulator",
+		Capabilities: []string{
+			models.CapabilityMove,
+			models.CapabilityMotorControl,
+			models.CapabilityEmergencyStop,
+			models.CapabilityTargetSelection,
+			models.CapabilitySensors,
+		},
+	}
+	for i, a := range v.sim.AGVSnapshots() {
+		if i == 0 {
+			hello.AGVID = a.ID
+		} else {
+			hello.AGVIDs = append(hello.AGVIDs, a.ID)
+		}
+	}
+	if hello.AGVID == "" {
+		hello.AGVID = "virtual-agv"
+	}
+	return models.WebSocketMessage{
+		Type:      models.MessageTypeHello,
+		Data:      hello,
+		Timestamp: time.Now().UnixMilli(),
+	}
+}
+
+// reply는 command_id가 있는 명령에 응답한다. motor_control처럼 command_id 없이 온 명령에는 응답하지 않는다.
+func (v *VirtualAGV) reply(cmd models.WebSocketMessage, replyType, reason string) {
+	if cmd.CommandID == "" {
+		return
+	}
+	v.send(models.WebSocketMessage{
+		Type:      replyType,
+		Data:      models.CommandReply{Reason: reason},
+		Timestamp: time.Now().UnixMilli(),
+		AGVID:     cmd.AGVID,
+		CommandID: cmd.CommandID,
+	})
+}
+
+// send는 시뮬레이터 메시지를 서버로 보낸다. 연결이 없으면 버린다 (실제 AGV도 오프라인이면 보고가 유실된다).
+func (v *VirtualAGV) send(msg models.WebSocketMessage) {
+	v.mu.Lock()
+	conn := v.conn
+	undeclared := msg.AGVID != "" && !v.declared[msg.AGVID]
+	v.mu.Unlock()
+	if conn == nil {
+		return
+	}
+	if undeclared {
+		v.sendHello()
+	}
+	raw, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("[ERROR] 가상 AGV 메시지 marshal 실패: %v", err)
+		return
+	}
+	v.writeMu.Lock()
+	defer v.writeMu.Unlock()
+	if err := conn.WriteMessage(websocket.TextMessage, raw); err != nil {
+		log.Printf("[WARN] 가상 AGV 송신 실패: %v", err)
+	}
+}